@@ -5,6 +5,7 @@ import (
 	_ "literally-backend/docs" // Import generated docs
 	"literally-backend/internal/handlers"
 	"literally-backend/internal/middleware"
+	"literally-backend/internal/services"
 	"log"
 	"os"
 
@@ -46,6 +47,27 @@ func main() {
 	// Run migrations and seed data
 	configs.MigrateDatabase()
 
+	// Set up product search (full-text when the database supports it)
+	services.InitSearchEngine()
+
+	// Start the inventory ledger with the stock products already had
+	if err := services.RecordOpeningBalances(); err != nil {
+		log.Printf("Failed to record opening stock balances: %v", err)
+	}
+
+	// Place stock from before warehouses existed in the default warehouse
+	if err := services.PlaceUnassignedStock(); err != nil {
+		log.Printf("Failed to place stock in warehouses: %v", err)
+	}
+
+	// Start background jobs
+	services.StartAccountDeletionJob()
+	services.StartSoftDeletePurgeJob()
+	services.StartSearchLogCleanupJob()
+	services.StartPriceScheduleJob()
+	services.StartReservationExpiryJob()
+	services.StartLowStockJob()
+
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -73,6 +95,11 @@ func setupRoutes(router *gin.Engine) {
 	// Swagger route
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Uploaded images (product galleries, avatars)
+	uploads := router.Group("/uploads")
+	uploads.Use(middleware.StaticCacheMiddleware())
+	uploads.Static("/", services.UploadDir())
+
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -103,6 +130,8 @@ func setupRoutes(router *gin.Engine) {
 		adminProfile.Use(middleware.AdminAuthMiddleware())
 		{
 			adminProfile.GET("/profile", handlers.GetAdminProfile)
+			adminProfile.GET("/notifications", handlers.GetAdminNotifications)
+			adminProfile.PUT("/notifications/:id/read", handlers.MarkAdminNotificationRead)
 		}
 
 		// Admin management routes (requires admin authentication)
@@ -110,18 +139,77 @@ func setupRoutes(router *gin.Engine) {
 		adminManagement.Use(middleware.AdminAuthMiddleware())
 		{
 			// Admin product management
-			adminManagement.GET("/products", handlers.GetProducts)
+			adminManagement.GET("/products", handlers.GetProductsAdmin)
+			adminManagement.GET("/products/export", handlers.ExportProducts)
+			adminManagement.POST("/products/import", handlers.ImportProducts)
+			adminManagement.GET("/products/import/:job_id", handlers.GetImportJob)
 			adminManagement.GET("/products/:id", handlers.GetProductByID)
 			adminManagement.POST("/products", handlers.CreateProduct)
-			adminManagement.PUT("/products/:id", handlers.UpdateProduct)
+			adminManagement.PUT("/products/:id", handlers.ReplaceProduct)
+			adminManagement.PATCH("/products/:id", handlers.UpdateProduct)
 			adminManagement.DELETE("/products/:id", handlers.DeleteProduct)
+			adminManagement.POST("/products/:id/restore", handlers.RestoreProduct)
+			adminManagement.GET("/products/:id/variants", handlers.GetProductVariants)
+			adminManagement.POST("/products/:id/variants", handlers.CreateProductVariant)
+			adminManagement.PUT("/products/:id/variants/:variant_id", handlers.UpdateProductVariant)
+			adminManagement.DELETE("/products/:id/variants/:variant_id", handlers.DeleteProductVariant)
+			adminManagement.POST("/products/:id/images", handlers.UploadProductImages)
+			adminManagement.PUT("/products/:id/images/order", handlers.ReorderProductImages)
+			adminManagement.PUT("/products/:id/images/:image_id/primary", handlers.SetPrimaryProductImage)
+			adminManagement.DELETE("/products/:id/images/:image_id", handlers.DeleteProductImage)
+			adminManagement.PUT("/products/:id/attributes", handlers.SetProductAttributes)
+			adminManagement.GET("/products/:id/price-history", handlers.GetPriceHistory)
+			adminManagement.GET("/products/:id/price-schedules", handlers.GetPriceSchedules)
+			adminManagement.POST("/products/:id/price-schedules", handlers.CreatePriceSchedule)
+			adminManagement.DELETE("/products/:id/price-schedules/:schedule_id", handlers.CancelPriceSchedule)
+
+			// Admin inventory management
+			adminManagement.POST("/products/:id/stock-adjustments", handlers.AdjustStock)
+			adminManagement.GET("/products/:id/inventory-movements", handlers.GetInventoryMovements)
+			adminManagement.GET("/inventory/reconciliation", handlers.GetStockReconciliation)
+			adminManagement.GET("/inventory/reorder-suggestions", handlers.GetReorderSuggestions)
+			adminManagement.GET("/inventory/back-in-stock-demand", handlers.GetBackInStockDemand)
+			adminManagement.GET("/products/:id/warehouse-stock", handlers.GetProductWarehouseStock)
+
+			// Admin warehouse management
+			adminManagement.GET("/warehouses", handlers.GetWarehouses)
+			adminManagement.POST("/warehouses", handlers.CreateWarehouse)
+			adminManagement.PATCH("/warehouses/:id", handlers.UpdateWarehouse)
+
+			// Admin bundle management
+			adminManagement.GET("/bundles", handlers.GetBundlesAdmin)
+			adminManagement.POST("/bundles", handlers.CreateBundle)
+			adminManagement.PUT("/bundles/:id", handlers.ReplaceBundle)
+			adminManagement.DELETE("/bundles/:id", handlers.DeleteBundle)
+
+			// Admin coupon management
+			adminManagement.GET("/coupons", handlers.GetCoupons)
+			adminManagement.GET("/coupons/report", handlers.GetCouponUsageReport)
+			adminManagement.GET("/coupons/:id", handlers.GetCouponByID)
+			adminManagement.POST("/coupons", handlers.CreateCoupon)
+			adminManagement.PUT("/coupons/:id", handlers.ReplaceCoupon)
+			adminManagement.DELETE("/coupons/:id", handlers.DeleteCoupon)
+			adminManagement.GET("/coupons/:id/redemptions", handlers.GetCouponRedemptions)
+
+			// Admin flash sale management
+			adminManagement.GET("/flash-sales", handlers.GetFlashSalesAdmin)
+			adminManagement.GET("/flash-sales/:id", handlers.GetFlashSaleSellThrough)
+			adminManagement.POST("/flash-sales", handlers.CreateFlashSale)
+			adminManagement.PUT("/flash-sales/:id", handlers.ReplaceFlashSale)
+			adminManagement.DELETE("/flash-sales/:id", handlers.DeleteFlashSale)
 
 			// Admin category management
-			adminManagement.GET("/categories", handlers.GetCategories)
+			adminManagement.GET("/categories", handlers.GetCategoriesAdmin)
 			adminManagement.GET("/categories/:id", handlers.GetCategoryByID)
 			adminManagement.POST("/categories", handlers.CreateCategory)
-			adminManagement.PUT("/categories/:id", handlers.UpdateCategory)
+			adminManagement.PUT("/categories/:id", handlers.ReplaceCategory)
+			adminManagement.PATCH("/categories/:id", handlers.UpdateCategory)
 			adminManagement.DELETE("/categories/:id", handlers.DeleteCategory)
+			adminManagement.PUT("/categories/:id/move", handlers.MoveCategory)
+			adminManagement.POST("/categories/:id/attributes", handlers.CreateCategoryAttribute)
+			adminManagement.PUT("/categories/:id/attributes/:attribute_id", handlers.UpdateCategoryAttribute)
+			adminManagement.DELETE("/categories/:id/attributes/:attribute_id", handlers.DeleteCategoryAttribute)
+			adminManagement.POST("/categories/:id/restore", handlers.RestoreCategory)
 
 			// Admin order management
 			adminManagement.GET("/orders", handlers.GetAllOrdersAdmin)
@@ -133,9 +221,24 @@ func setupRoutes(router *gin.Engine) {
 			adminManagement.GET("/users", handlers.GetUsers)
 			adminManagement.GET("/users/:id", handlers.GetUserByID)
 			adminManagement.POST("/users", handlers.CreateUser)
-			adminManagement.PUT("/users/:id", handlers.UpdateUser)
+			adminManagement.PUT("/users/:id", handlers.ReplaceUser)
+			adminManagement.PATCH("/users/:id", handlers.UpdateUser)
 			adminManagement.PUT("/users/:id/status", handlers.UpdateUserStatus)
 			adminManagement.DELETE("/users/:id", handlers.DeleteUser)
+			adminManagement.POST("/users/:id/restore", handlers.RestoreUser)
+
+			// Admin user session management
+			adminManagement.GET("/users/:id/sessions", handlers.GetUserSessionsAdmin)
+			adminManagement.DELETE("/users/:id/sessions", handlers.TerminateUserSessionsAdmin)
+			adminManagement.DELETE("/users/:id/sessions/:session_id", handlers.TerminateUserSessionAdmin)
+
+			// Admin impersonation for customer support
+			adminManagement.POST("/users/:id/impersonate", handlers.ImpersonateUser)
+			adminManagement.GET("/impersonations", handlers.GetImpersonationLogs)
+
+			// Admin search analytics
+			adminManagement.GET("/search/queries", handlers.GetSearchQueryStats)
+			adminManagement.GET("/search/zero-results", handlers.GetZeroResultSearches)
 		}
 
 		// Profile routes (requires authentication)
@@ -143,7 +246,30 @@ func setupRoutes(router *gin.Engine) {
 		profile.Use(middleware.AuthMiddleware())
 		{
 			profile.GET("/profile", handlers.GetProfile)
-			profile.PUT("/profile", handlers.UpdateProfile)
+			profile.PUT("/profile", handlers.ReplaceProfile)
+			profile.PATCH("/profile", handlers.UpdateProfile)
+			profile.DELETE("/profile", handlers.DeleteProfile)
+			profile.POST("/profile/deletion/cancel", handlers.CancelProfileDeletion)
+			profile.GET("/profile/export", handlers.ExportProfileData)
+			profile.POST("/profile/avatar", handlers.UploadAvatar)
+			profile.DELETE("/profile/avatar", handlers.DeleteAvatar)
+
+			// Session management
+			profile.GET("/profile/sessions", handlers.GetMySessions)
+			profile.DELETE("/profile/sessions", handlers.RevokeMyOtherSessions)
+			profile.DELETE("/profile/sessions/:id", handlers.RevokeMySession)
+
+			// Address book
+			profile.GET("/profile/addresses", handlers.GetAddresses)
+			profile.POST("/profile/addresses", handlers.CreateAddress)
+			profile.GET("/profile/addresses/:id", handlers.GetAddressByID)
+			profile.PUT("/profile/addresses/:id", handlers.UpdateAddress)
+			profile.PUT("/profile/addresses/:id/default", handlers.SetDefaultAddress)
+			profile.DELETE("/profile/addresses/:id", handlers.DeleteAddress)
+
+			// Notifications
+			profile.GET("/profile/notifications", handlers.GetNotifications)
+			profile.PUT("/profile/notifications/:id/read", handlers.MarkNotificationRead)
 		}
 
 		// User routes (admin only)
@@ -153,33 +279,53 @@ func setupRoutes(router *gin.Engine) {
 			users.GET("", handlers.GetUsers)
 			users.GET("/:id", handlers.GetUserByID)
 			users.POST("", handlers.CreateUser)
-			users.PUT("/:id", handlers.UpdateUser)
+			users.PUT("/:id", handlers.ReplaceUser)
+			users.PATCH("/:id", handlers.UpdateUser)
 			users.PUT("/:id/status", handlers.UpdateUserStatus)
 			users.DELETE("/:id", handlers.DeleteUser)
+			users.POST("/:id/restore", handlers.RestoreUser)
 		}
 
 		// Category routes (public)
 		v1.GET("/categories", handlers.GetCategories)
 		v1.GET("/categories/:id/products", handlers.GetProductsByCategory)
+		v1.GET("/categories/:id/attributes", handlers.GetCategoryAttributes)
 
 		// Product routes (public for read, protected for write)
 		products := v1.Group("/products")
 		{
-			products.GET("", handlers.GetProducts)                  // GET /api/v1/products?category_id=1&featured=true&search=phone
-			products.GET("/featured", handlers.GetFeaturedProducts) // GET /api/v1/products/featured
-			products.GET("/search", handlers.SearchProducts)        // GET /api/v1/products/search?q=phone
+			products.GET("", handlers.GetProducts)                                                // GET /api/v1/products?category_id=1&brand=Apple&attr.ram=8GB
+			products.GET("/featured", handlers.GetFeaturedProducts)                               // GET /api/v1/products/featured
+			products.GET("/search", middleware.OptionalAuthMiddleware(), handlers.SearchProducts) // GET /api/v1/products/search?q=phone
+			products.GET("/suggest", handlers.SuggestProducts)                                    // GET /api/v1/products/suggest?q=iph
 			products.GET("/:id", handlers.GetProductByID)
+			products.GET("/:id/variants", handlers.GetProductVariants)
+			products.GET("/:id/images", handlers.GetProductImages)
+			products.POST("/:id/notify-me", middleware.AuthMiddleware(), handlers.NotifyMe)
+			products.DELETE("/:id/notify-me", middleware.AuthMiddleware(), handlers.CancelNotifyMe)
 		}
 
+		// Bundle routes (public)
+		v1.GET("/bundles", handlers.GetBundles)
+		v1.GET("/bundles/:id", handlers.GetBundleByID)
+
+		// Flash sale routes (public)
+		v1.GET("/flash-sales", handlers.GetFlashSales)
+
 		// Cart routes (requires authentication)
 		cart := v1.Group("/cart")
 		cart.Use(middleware.AuthMiddleware())
 		{
-			cart.GET("", handlers.GetCart)               // GET /api/v1/cart
-			cart.POST("", handlers.AddToCart)            // POST /api/v1/cart
-			cart.PUT("/:id", handlers.UpdateCartItem)    // PUT /api/v1/cart/1
-			cart.DELETE("/:id", handlers.RemoveFromCart) // DELETE /api/v1/cart/1
-			cart.DELETE("", handlers.ClearCart)          // DELETE /api/v1/cart
+			cart.GET("", handlers.GetCart)                                    // GET /api/v1/cart
+			cart.POST("", handlers.AddToCart)                                 // POST /api/v1/cart
+			cart.PUT("/:id", handlers.UpdateCartItem)                         // PUT /api/v1/cart/1
+			cart.DELETE("/:id", handlers.RemoveFromCart)                      // DELETE /api/v1/cart/1
+			cart.DELETE("", handlers.ClearCart)                               // DELETE /api/v1/cart
+			cart.POST("/bundles", handlers.AddBundleToCart)                   // POST /api/v1/cart/bundles
+			cart.DELETE("/bundles/:bundle_id", handlers.RemoveBundleFromCart) // DELETE /api/v1/cart/bundles/1
+			cart.POST("/apply-coupon", handlers.ApplyCoupon)                  // POST /api/v1/cart/apply-coupon
+			cart.DELETE("/coupons/:code", handlers.RemoveCoupon)              // DELETE /api/v1/cart/coupons/SALE10
+			cart.GET("/summary", handlers.GetCartSummary)                     // GET /api/v1/cart/summary
 		}
 
 		// Purchase History routes (requires authentication)
@@ -205,6 +351,15 @@ func setupRoutes(router *gin.Engine) {
 			orders.GET("/:id", handlers.GetOrderByID)    // GET /api/v1/orders/:id
 		}
 
+		// Checkout routes (requires authentication)
+		checkout := v1.Group("/checkout")
+		checkout.Use(middleware.AuthMiddleware())
+		{
+			checkout.POST("/reservations", handlers.ReserveStock)             // POST /api/v1/checkout/reservations
+			checkout.GET("/reservations/:id", handlers.GetReservation)        // GET /api/v1/checkout/reservations/1
+			checkout.DELETE("/reservations/:id", handlers.ReleaseReservation) // DELETE /api/v1/checkout/reservations/1
+		}
+
 		// Wishlist routes (requires authentication)
 		// wishlist := v1.Group("/wishlist")
 		// {
@@ -61,6 +61,10 @@ func ConnectDatabase() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	if err := registerVersioning(DB); err != nil {
+		log.Fatal("Failed to register versioning callback:", err)
+	}
+
 	log.Println("Database connected successfully!")
 }
 
@@ -1,7 +1,9 @@
 package configs
 
 import (
+	"fmt"
 	"literally-backend/internal/models"
+	"literally-backend/pkg/utils"
 	"log"
 	"time"
 
@@ -14,13 +16,39 @@ func MigrateDatabase() {
 	// Auto migrate all models
 	err := DB.AutoMigrate(
 		&models.User{},
+		&models.UserSession{},
+		&models.UserAddress{},
 		&models.Admin{},
+		&models.ImpersonationLog{},
 		&models.Category{},
 		&models.Product{},
+		&models.ProductVariant{},
+		&models.ProductImage{},
+		&models.CategoryAttribute{},
+		&models.ProductAttributeValue{},
+		&models.SearchQueryLog{},
+		&models.ImportJob{},
+		&models.PriceHistory{},
+		&models.PriceSchedule{},
 		&models.Cart{},
 		&models.PaymentMethod{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.StockReservation{},
+		&models.StockReservationItem{},
+		&models.InventoryMovement{},
+		&models.Warehouse{},
+		&models.WarehouseStock{},
+		&models.AdminNotification{},
+		&models.StockSubscription{},
+		&models.Bundle{},
+		&models.BundleItem{},
+		&models.Coupon{},
+		&models.CouponTarget{},
+		&models.CouponRedemption{},
+		&models.CartCoupon{},
+		&models.FlashSale{},
+		&models.FlashSalePurchase{},
 		&models.InstallmentPlan{},
 		&models.InstallmentPayment{},
 		&models.Wishlist{},
@@ -53,12 +81,22 @@ func seedDefaultData() {
 	// Seed payment methods
 	seedPaymentMethods()
 
+	// Seed warehouses
+	seedWarehouses()
+
 	// Seed categories
 	seedCategories()
+	backfillCategorySlugs()
 
 	// Seed sample products
 	seedSampleProducts()
 
+	// Seed variants for sample products
+	seedProductVariants()
+
+	// Seed attributes and attribute values
+	seedProductAttributes()
+
 	// Seed purchase history
 	seedPurchaseHistory()
 
@@ -235,6 +273,24 @@ func seedPaymentMethods() {
 	}
 }
 
+// seedWarehouses adds a default warehouse in Hà Nội and one in Hồ Chí Minh
+func seedWarehouses() {
+	var count int64
+	DB.Model(&models.Warehouse{}).Count(&count)
+
+	if count == 0 {
+		warehouses := []models.Warehouse{
+			{Code: "HN", Name: "Kho Hà Nội", Province: "Hà Nội", Region: models.RegionNorth, Priority: 0, IsDefault: true},
+			{Code: "HCM", Name: "Kho Hồ Chí Minh", Province: "Hồ Chí Minh", Region: models.RegionSouth, Priority: 1},
+		}
+
+		for _, warehouse := range warehouses {
+			DB.Create(&warehouse)
+		}
+		log.Println("Warehouses seeded")
+	}
+}
+
 // seedCategories thêm categories mặc định
 func seedCategories() {
 	var count int64
@@ -242,22 +298,65 @@ func seedCategories() {
 
 	if count == 0 {
 		categories := []models.Category{
-			{Name: "Phones", Icon: "phone"},
-			{Name: "Laptops", Icon: "laptop"},
-			{Name: "Tablets", Icon: "tablet"},
-			{Name: "Smart Watches", Icon: "watch"},
-			{Name: "Headphones", Icon: "headphone"},
-			{Name: "Accessories", Icon: "accessory"},
+			{Name: "Phones", Slug: "phones", Icon: "phone", SortOrder: 1},
+			{Name: "Laptops", Slug: "laptops", Icon: "laptop", SortOrder: 2},
+			{Name: "Tablets", Slug: "tablets", Icon: "tablet", SortOrder: 3},
+			{Name: "Smart Watches", Slug: "smart-watches", Icon: "watch", SortOrder: 4},
+			{Name: "Headphones", Slug: "headphones", Icon: "headphone", SortOrder: 5},
+			{Name: "Accessories", Slug: "accessories", Icon: "accessory", SortOrder: 6},
 		}
 
 		for _, cat := range categories {
 			DB.Create(&cat)
 		}
+
+		// Subcategories of Accessories
+		var accessories models.Category
+		if err := DB.Where("slug = ?", "accessories").First(&accessories).Error; err == nil {
+			subcategories := []models.Category{
+				{Name: "Chargers & Cables", Slug: "chargers-cables", Icon: "charger", SortOrder: 1},
+				{Name: "Cases & Covers", Slug: "cases-covers", Icon: "case", SortOrder: 2},
+			}
+			for _, sub := range subcategories {
+				sub.ParentID = &accessories.ID
+				DB.Create(&sub)
+			}
+		}
 		log.Println("Categories seeded")
 	}
 }
 
 // seedSampleProducts thêm sample products
+// backfillCategorySlugs gives categories created before slugs existed a
+// unique slug derived from their name
+func backfillCategorySlugs() {
+	var categories []models.Category
+	DB.Unscoped().Where("slug IS NULL OR slug = ''").Order("id").Find(&categories)
+
+	for _, category := range categories {
+		base := utils.Slugify(category.Name)
+		if base == "" {
+			base = "category"
+		}
+
+		slug := base
+		for i := 2; ; i++ {
+			var count int64
+			DB.Unscoped().Model(&models.Category{}).Where("slug = ?", slug).Count(&count)
+			if count == 0 {
+				break
+			}
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+
+		DB.Unscoped().Model(&category).Update("slug", slug)
+	}
+
+	if len(categories) > 0 {
+		log.Printf("Backfilled slugs for %d categories", len(categories))
+	}
+}
+
 func seedSampleProducts() {
 	var count int64
 	DB.Model(&models.Product{}).Count(&count)
@@ -351,6 +450,116 @@ func seedSampleProducts() {
 	}
 }
 
+// seedProductVariants adds storage/color options to sample products. Variant
+// stock adds up to the product stock and the cheapest variant matches the
+// product price so the seeded totals stay consistent.
+func seedProductVariants() {
+	var count int64
+	DB.Model(&models.ProductVariant{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	variantsByProduct := map[string][]models.ProductVariant{
+		"iPhone 15 Pro": {
+			{SKU: "IP15P-128-NAT", Color: "Natural Titanium", Storage: "128GB", Price: 999.99, Stock: 20},
+			{SKU: "IP15P-256-BLK", Color: "Black Titanium", Storage: "256GB", Price: 1099.99, Stock: 20},
+			{SKU: "IP15P-512-BLU", Color: "Blue Titanium", Storage: "512GB", Price: 1299.99, Stock: 10},
+		},
+		"MacBook Pro M3": {
+			{SKU: "MBP-M3-18-512", Color: "Space Black", RAM: "18GB", Storage: "512GB", Price: 1999.99, Stock: 20},
+			{SKU: "MBP-M3-36-1T", Color: "Space Black", RAM: "36GB", Storage: "1TB", Price: 2499.99, Stock: 10},
+		},
+		"Samsung Galaxy S24 Ultra": {
+			{SKU: "S24U-12-256-GRY", Color: "Titanium Gray", RAM: "12GB", Storage: "256GB", Price: 1199.99, Stock: 25},
+			{SKU: "S24U-12-512-BLK", Color: "Titanium Black", RAM: "12GB", Storage: "512GB", Price: 1379.99, Stock: 15},
+		},
+	}
+
+	for name, variants := range variantsByProduct {
+		var product models.Product
+		if err := DB.Where("name = ?", name).First(&product).Error; err != nil {
+			continue
+		}
+		for _, variant := range variants {
+			variant.ProductID = product.ID
+			variant.IsAvailable = true
+			DB.Create(&variant)
+		}
+	}
+	log.Println("Sample product variants seeded")
+}
+
+// seedProductAttributes defines phone and laptop specifications and fills
+// them in for the sample products
+func seedProductAttributes() {
+	var count int64
+	DB.Model(&models.CategoryAttribute{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	attributesByCategory := map[string][]models.CategoryAttribute{
+		"phones": {
+			{Code: "ram", Name: "RAM", Type: models.AttributeTypeEnum, Options: []string{"6GB", "8GB", "12GB", "16GB"}, SortOrder: 1},
+			{Code: "screen_size", Name: "Screen size", Type: models.AttributeTypeNumber, Unit: "inch", SortOrder: 2},
+			{Code: "nfc", Name: "NFC", Type: models.AttributeTypeBool, SortOrder: 3},
+		},
+		"laptops": {
+			{Code: "ram", Name: "RAM", Type: models.AttributeTypeEnum, Options: []string{"8GB", "16GB", "18GB", "32GB", "36GB"}, SortOrder: 1},
+			{Code: "screen_size", Name: "Screen size", Type: models.AttributeTypeNumber, Unit: "inch", SortOrder: 2},
+		},
+	}
+
+	attributeIDs := make(map[string]uint)
+	for slug, attributes := range attributesByCategory {
+		var category models.Category
+		if err := DB.Where("slug = ?", slug).First(&category).Error; err != nil {
+			continue
+		}
+		for _, attribute := range attributes {
+			attribute.CategoryID = category.ID
+			attribute.IsFilterable = true
+			if err := DB.Create(&attribute).Error; err == nil {
+				attributeIDs[slug+"."+attribute.Code] = attribute.ID
+			}
+		}
+	}
+
+	text := func(s string) models.ProductAttributeValue { return models.ProductAttributeValue{ValueText: s} }
+	number := func(n float64) models.ProductAttributeValue { return models.ProductAttributeValue{ValueNumber: &n} }
+	flag := func(b bool) models.ProductAttributeValue { return models.ProductAttributeValue{ValueBool: &b} }
+
+	valuesByProduct := map[string]map[string]models.ProductAttributeValue{
+		"iPhone 15 Pro": {
+			"phones.ram": text("8GB"), "phones.screen_size": number(6.1), "phones.nfc": flag(true),
+		},
+		"Samsung Galaxy S24 Ultra": {
+			"phones.ram": text("12GB"), "phones.screen_size": number(6.8), "phones.nfc": flag(true),
+		},
+		"MacBook Pro M3": {
+			"laptops.ram": text("18GB"), "laptops.screen_size": number(14.2),
+		},
+	}
+
+	for name, values := range valuesByProduct {
+		var product models.Product
+		if err := DB.Where("name = ?", name).First(&product).Error; err != nil {
+			continue
+		}
+		for key, value := range values {
+			attributeID, ok := attributeIDs[key]
+			if !ok {
+				continue
+			}
+			value.ProductID = product.ID
+			value.AttributeID = attributeID
+			DB.Omit("Attribute").Create(&value)
+		}
+	}
+	log.Println("Sample product attributes seeded")
+}
+
 // seedAdmin creates default admin user
 func seedAdmin() {
 	var count int64
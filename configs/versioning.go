@@ -0,0 +1,47 @@
+package configs
+
+import (
+	"gorm.io/gorm"
+)
+
+// versionColumn is the optimistic concurrency column of versioned models
+const versionColumn = "version"
+
+// keepVersionSetting marks a statement that leaves the version alone
+const keepVersionSetting = "app:keep_version"
+
+// registerVersioning bumps the version of versioned models (those with a
+// Version field) on every update, so a client holding an older version can
+// be refused. Updates are made with Update and Updates with a map; an
+// explicit version in the map, or the KeepVersion scope, leaves it alone.
+func registerVersioning(db *gorm.DB) error {
+	return db.Callback().Update().Before("gorm:update").Register("app:bump_version", bumpVersion)
+}
+
+// KeepVersion is a scope for updates the system makes to fields clients do
+// not edit, such as the stock taken by orders, so that they do not refuse
+// edits made at the same time
+func KeepVersion(db *gorm.DB) *gorm.DB {
+	return db.Set(keepVersionSetting, true)
+}
+
+func bumpVersion(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	if keep, _ := db.Get(keepVersionSetting); keep == true {
+		return
+	}
+	if field := db.Statement.Schema.LookUpField(versionColumn); field == nil || field.DBName != versionColumn {
+		return
+	}
+
+	updates, ok := db.Statement.Dest.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if _, ok := updates[versionColumn]; ok {
+		return
+	}
+	updates[versionColumn] = gorm.Expr(versionColumn + " + 1")
+}
@@ -0,0 +1,53 @@
+package configs
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type versionedRow struct {
+	ID      uint
+	Stock   int
+	Version uint
+}
+
+func TestKeepVersion(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := registerVersioning(db); err != nil {
+		t.Fatalf("register versioning: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		scopes []func(*gorm.DB) *gorm.DB
+		want   bool
+	}{
+		{name: "edit", want: true},
+		{name: "kept", scopes: []func(*gorm.DB) *gorm.DB{KeepVersion}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := db.Model(&versionedRow{}).Scopes(tt.scopes...).
+				Where("id = ?", 1).
+				Updates(map[string]interface{}{"stock": 3})
+			if result.Error != nil {
+				t.Fatalf("update: %v", result.Error)
+			}
+			sql := result.Statement.SQL.String()
+			if got := strings.Contains(sql, `"version"`); got != tt.want {
+				t.Errorf("bumps version = %v, want %v: %s", got, tt.want, sql)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportProfileData godoc
+// @Summary Export personal data
+// @Description Download all personal data held about the authenticated user as JSON, or as a zip archive with format=zip
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Produce application/zip
+// @Security Bearer
+// @Param format query string false "Export format (json or zip, default: json)"
+// @Success 200 {object} map[string]interface{} "Personal data exported successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Unsupported format"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /profile/export [get]
+func ExportProfileData(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported export format",
+		})
+		return
+	}
+
+	export, err := services.ExportUserData(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"data":    export,
+			"message": "Personal data exported successfully",
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("user-%d-export-%s.zip", userID.(uint), export.ExportedAt.Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	if err := writeExportZip(c.Writer, export); err != nil {
+		c.Error(err)
+	}
+}
+
+// writeExportZip writes each section of the export as its own JSON file
+func writeExportZip(w http.ResponseWriter, export models.UserDataExport) error {
+	archive := zip.NewWriter(w)
+
+	sections := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"addresses.json", export.Addresses},
+		{"orders.json", export.Orders},
+		{"purchase_history.json", export.PurchaseHistory},
+		{"reviews.json", export.Reviews},
+		{"notifications.json", export.Notifications},
+		{"wishlist.json", export.Wishlist},
+		{"sessions.json", export.Sessions},
+	}
+
+	for _, section := range sections {
+		file, err := archive.Create(section.name)
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section.data); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// DeleteProfile godoc
+// @Summary Delete account
+// @Description Schedule deletion of the authenticated user's account. Personal data is anonymized after a cooling-off period; orders and financial records are kept.
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body models.DeleteAccountRequest true "Password confirmation"
+// @Success 200 {object} map[string]interface{} "Account deletion scheduled successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /profile [delete]
+func DeleteProfile(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	user, err := services.ScheduleAccountDeletion(userID.(uint), req.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"deletion_scheduled_at": user.DeletionScheduledAt,
+		},
+		"message": "Account deletion scheduled successfully",
+	})
+}
+
+// CancelProfileDeletion godoc
+// @Summary Cancel account deletion
+// @Description Cancel a pending account deletion during the cooling-off period
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{} "Account deletion cancelled successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - No deletion scheduled"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /profile/deletion/cancel [post]
+func CancelProfileDeletion(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	if err := services.CancelAccountDeletion(userID.(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account deletion cancelled successfully",
+	})
+}
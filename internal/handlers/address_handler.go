@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAddresses godoc
+// @Summary Get address book
+// @Description Get the authenticated user's saved shipping addresses
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{} "Addresses retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /profile/addresses [get]
+func GetAddresses(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	addresses := services.GetUserAddresses(userID.(uint))
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    addresses,
+		"message": "Addresses retrieved successfully",
+	})
+}
+
+// GetAddressByID godoc
+// @Summary Get address by ID
+// @Description Get a specific address from the authenticated user's address book
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Address ID"
+// @Success 200 {object} map[string]interface{} "Address retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid address ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Address not found"
+// @Router /profile/addresses/{id} [get]
+func GetAddressByID(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	addressID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid address ID",
+		})
+		return
+	}
+
+	address, err := services.GetUserAddressByID(userID.(uint), uint(addressID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    address,
+		"message": "Address retrieved successfully",
+	})
+}
+
+// CreateAddress godoc
+// @Summary Add address
+// @Description Add a shipping address to the authenticated user's address book
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param address body models.CreateAddressRequest true "Address data"
+// @Success 201 {object} map[string]interface{} "Address created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /profile/addresses [post]
+func CreateAddress(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.CreateAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	address, err := services.CreateUserAddress(userID.(uint), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    address,
+		"message": "Address created successfully",
+	})
+}
+
+// UpdateAddress godoc
+// @Summary Update address
+// @Description Update an address in the authenticated user's address book
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Address ID"
+// @Param address body models.UpdateAddressRequest true "Updated address data"
+// @Success 200 {object} map[string]interface{} "Address updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Address not found"
+// @Router /profile/addresses/{id} [put]
+func UpdateAddress(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	addressID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid address ID",
+		})
+		return
+	}
+
+	var req models.UpdateAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	address, err := services.UpdateUserAddress(userID.(uint), uint(addressID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    address,
+		"message": "Address updated successfully",
+	})
+}
+
+// SetDefaultAddress godoc
+// @Summary Set default address
+// @Description Mark an address as the authenticated user's default shipping address
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Address ID"
+// @Success 200 {object} map[string]interface{} "Default address updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid address ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Address not found"
+// @Router /profile/addresses/{id}/default [put]
+func SetDefaultAddress(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	addressID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid address ID",
+		})
+		return
+	}
+
+	address, err := services.SetDefaultUserAddress(userID.(uint), uint(addressID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    address,
+		"message": "Default address updated successfully",
+	})
+}
+
+// DeleteAddress godoc
+// @Summary Delete address
+// @Description Remove an address from the authenticated user's address book
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Address ID"
+// @Success 200 {object} map[string]interface{} "Address deleted successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid address ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Address not found"
+// @Router /profile/addresses/{id} [delete]
+func DeleteAddress(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	addressID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid address ID",
+		})
+		return
+	}
+
+	if err := services.DeleteUserAddress(userID.(uint), uint(addressID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Address deleted successfully",
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"literally-backend/internal/models"
 	"literally-backend/internal/services"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -109,3 +110,75 @@ func GetAdminProfile(c *gin.Context) {
 		"message": "Admin profile retrieved successfully",
 	})
 }
+
+// GetAdminNotifications godoc
+// @Summary Get admin notifications
+// @Description Get notifications shown to every admin, such as LOW_STOCK alerts for products whose stock fell to their reorder threshold, newest first
+// @Tags admin-notifications
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param type query string false "Filter by types, comma separated"
+// @Param is_read query string false "Filter by read state (true or false)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (created_at; default: -created_at)"
+// @Success 200 {object} map[string]interface{} "Notifications retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid sort or cursor"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/notifications [get]
+func GetAdminNotifications(c *gin.Context) {
+	params, ok := listParams(c, services.AdminNotificationListSpec)
+	if !ok {
+		return
+	}
+
+	notifications, page, err := services.GetAdminNotifications(params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       notifications,
+		"pagination": page,
+		"message":    "Notifications retrieved successfully",
+	})
+}
+
+// MarkAdminNotificationRead godoc
+// @Summary Mark admin notification as read
+// @Description Mark an admin notification as read
+// @Tags admin-notifications
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Notification ID"
+// @Success 200 {object} map[string]interface{} "Notification marked as read"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid notification ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Notification not found"
+// @Router /admin/notifications/{id}/read [put]
+func MarkAdminNotificationRead(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid notification ID",
+		})
+		return
+	}
+
+	notification, err := services.MarkAdminNotificationRead(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    notification,
+		"message": "Notification marked as read",
+	})
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCategoryAttributes godoc
+// @Summary Get category attributes
+// @Description Get the attribute definitions that apply to a category, including those inherited from parent categories
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 200 {object} map[string]interface{} "Attributes retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid category ID"
+// @Failure 404 {object} map[string]interface{} "Category not found"
+// @Router /categories/{id}/attributes [get]
+func GetCategoryAttributes(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	if _, exists := services.GetCategoryByID(uint(id)); !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Category not found",
+		})
+		return
+	}
+
+	attributes, err := services.GetCategoryAttributes(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    attributes,
+		"message": "Attributes retrieved successfully",
+	})
+}
+
+// CreateCategoryAttribute godoc
+// @Summary Create category attribute (admin)
+// @Description Define a typed attribute (ENUM, NUMBER or BOOL) for the products of a category and its subcategories
+// @Tags admin-categories
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Category ID"
+// @Param attribute body models.CreateAttributeRequest true "Attribute definition"
+// @Success 201 {object} map[string]interface{} "Attribute created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/categories/{id}/attributes [post]
+func CreateCategoryAttribute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	var req models.CreateAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	attribute, err := services.CreateCategoryAttribute(uint(id), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    attribute,
+		"message": "Attribute created successfully",
+	})
+}
+
+// UpdateCategoryAttribute godoc
+// @Summary Update category attribute (admin)
+// @Description Update the name, unit, options, filterability or sort order of a category attribute
+// @Tags admin-categories
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Category ID"
+// @Param attribute_id path int true "Attribute ID"
+// @Param attribute body models.UpdateAttributeRequest true "Attribute update data"
+// @Success 200 {object} map[string]interface{} "Attribute updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/categories/{id}/attributes/{attribute_id} [put]
+func UpdateCategoryAttribute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	attributeID, err := strconv.ParseUint(c.Param("attribute_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid attribute ID",
+		})
+		return
+	}
+
+	var req models.UpdateAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	attribute, err := services.UpdateCategoryAttribute(uint(id), uint(attributeID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    attribute,
+		"message": "Attribute updated successfully",
+	})
+}
+
+// DeleteCategoryAttribute godoc
+// @Summary Delete category attribute (admin)
+// @Description Delete a category attribute together with all product values for it
+// @Tags admin-categories
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Category ID"
+// @Param attribute_id path int true "Attribute ID"
+// @Success 200 {object} map[string]interface{} "Attribute deleted successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid ID"
+// @Failure 404 {object} map[string]interface{} "Attribute not found"
+// @Router /admin/categories/{id}/attributes/{attribute_id} [delete]
+func DeleteCategoryAttribute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	attributeID, err := strconv.ParseUint(c.Param("attribute_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid attribute ID",
+		})
+		return
+	}
+
+	if err := services.DeleteCategoryAttribute(uint(id), uint(attributeID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attribute deleted successfully",
+	})
+}
+
+// SetProductAttributes godoc
+// @Summary Set product attributes (admin)
+// @Description Set attribute values of a product by attribute code. Values must match the attribute type; null removes a value.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param attributes body models.SetProductAttributesRequest true "Attribute values by code"
+// @Success 200 {object} map[string]interface{} "Product attributes updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid value"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/products/{id}/attributes [put]
+func SetProductAttributes(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req models.SetProductAttributesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	values, err := services.SetProductAttributes(uint(id), req.Values)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    values,
+		"message": "Product attributes updated successfully",
+	})
+}
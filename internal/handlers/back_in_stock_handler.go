@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotifyMe godoc
+// @Summary Get notified when back in stock
+// @Description Ask for a BACK_IN_STOCK notification when an out-of-stock product, or the given variant, is back in stock. The subscription is cleared once the notification is sent; subscribing again returns the existing subscription.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param subscription body models.NotifyMeRequest false "Variant to wait for"
+// @Success 201 {object} map[string]interface{} "Subscribed to back-in-stock notification"
+// @Success 200 {object} map[string]interface{} "Already subscribed"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, unknown variant or product in stock"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Router /products/{id}/notify-me [post]
+func NotifyMe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	if _, found := services.GetProductByID(uint(id)); !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	var req models.NotifyMeRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	subscription, created, err := services.SubscribeBackInStock(userID.(uint), uint(id), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if !created {
+		c.JSON(http.StatusOK, gin.H{
+			"data":    subscription,
+			"message": "Already subscribed",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    subscription,
+		"message": "Subscribed to back-in-stock notification",
+	})
+}
+
+// CancelNotifyMe godoc
+// @Summary Cancel back-in-stock notification
+// @Description Cancel the authenticated user's back-in-stock subscription to a product, or to the variant given as variant_id
+// @Tags products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param variant_id query int false "Variant ID"
+// @Success 200 {object} map[string]interface{} "Back-in-stock notification cancelled"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Subscription not found"
+// @Router /products/{id}/notify-me [delete]
+func CancelNotifyMe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var variantID *uint
+	if value := c.Query("variant_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid variant ID",
+			})
+			return
+		}
+		id := uint(parsed)
+		variantID = &id
+	}
+
+	if err := services.UnsubscribeBackInStock(userID.(uint), uint(id), variantID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Back-in-stock notification cancelled",
+	})
+}
+
+// GetBackInStockDemand godoc
+// @Summary Get back-in-stock demand (admin)
+// @Description Get how many customers wait to be notified about each out-of-stock product and variant, most wanted first
+// @Tags admin-inventory
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{} "Back-in-stock demand retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/inventory/back-in-stock-demand [get]
+func GetBackInStockDemand(c *gin.Context) {
+	demand, err := services.GetBackInStockDemand()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve back-in-stock demand",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    demand,
+		"message": "Back-in-stock demand retrieved successfully",
+	})
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"errors"
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBundles godoc
+// @Summary Get bundles
+// @Description Get a page of active bundles with their items and prices
+// @Tags bundles
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (name, created_at; default: name)"
+// @Success 200 {object} map[string]interface{} "Bundles retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid list parameters"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /bundles [get]
+func GetBundles(c *gin.Context) {
+	listBundles(c, true)
+}
+
+// GetBundlesAdmin godoc
+// @Summary Get bundles (admin)
+// @Description Get a page of bundles, including inactive ones, with their items and prices
+// @Tags admin-bundles
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (name, created_at; default: name)"
+// @Param is_active query bool false "Filter by active state"
+// @Success 200 {object} map[string]interface{} "Bundles retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid list parameters"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/bundles [get]
+func GetBundlesAdmin(c *gin.Context) {
+	listBundles(c, false)
+}
+
+// listBundles answers a bundle list, only active bundles if activeOnly
+func listBundles(c *gin.Context, activeOnly bool) {
+	params, ok := listParams(c, services.BundleListSpec)
+	if !ok {
+		return
+	}
+
+	bundles, page, err := services.GetBundles(params, activeOnly)
+	if err != nil {
+		listError(c, err, "Failed to retrieve bundles")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       bundles,
+		"pagination": page,
+		"message":    "Bundles retrieved successfully",
+	})
+}
+
+// GetBundleByID godoc
+// @Summary Get bundle by ID
+// @Description Get an active bundle with its items, regular price, bundle price and savings
+// @Tags bundles
+// @Accept json
+// @Produce json
+// @Param id path int true "Bundle ID"
+// @Success 200 {object} map[string]interface{} "Bundle retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid bundle ID"
+// @Failure 404 {object} map[string]interface{} "Bundle not found"
+// @Router /bundles/{id} [get]
+func GetBundleByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid bundle ID",
+		})
+		return
+	}
+
+	bundle, err := services.GetBundleByID(uint(id), true)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrBundleNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    bundle,
+		"message": "Bundle retrieved successfully",
+	})
+}
+
+// CreateBundle godoc
+// @Summary Create bundle (admin)
+// @Description Create a bundle of at least two products or variants, sold for a fixed price or at a discount percent off the sum of its components. A fixed price takes precedence over the discount percent.
+// @Tags admin-bundles
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param bundle body models.BundleRequest true "Bundle data"
+// @Success 201 {object} map[string]interface{} "Bundle created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input or unknown product"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/bundles [post]
+func CreateBundle(c *gin.Context) {
+	var req models.BundleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	bundle, err := services.CreateBundle(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    bundle,
+		"message": "Bundle created successfully",
+	})
+}
+
+// ReplaceBundle godoc
+// @Summary Replace bundle (admin)
+// @Description Replace all fields and items of a bundle. When the items change, the bundle is removed from the carts holding it.
+// @Tags admin-bundles
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Bundle ID"
+// @Param bundle body models.BundleRequest true "Bundle data"
+// @Success 200 {object} map[string]interface{} "Bundle updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input or unknown product"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Bundle not found"
+// @Router /admin/bundles/{id} [put]
+func ReplaceBundle(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid bundle ID",
+		})
+		return
+	}
+
+	var req models.BundleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	bundle, err := services.ReplaceBundle(uint(id), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrBundleNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    bundle,
+		"message": "Bundle updated successfully",
+	})
+}
+
+// DeleteBundle godoc
+// @Summary Delete bundle (admin)
+// @Description Soft delete a bundle and remove it from the carts holding it. Orders placed with the bundle keep it.
+// @Tags admin-bundles
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Bundle ID"
+// @Success 200 {object} map[string]interface{} "Bundle deleted successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid bundle ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Bundle not found"
+// @Router /admin/bundles/{id} [delete]
+func DeleteBundle(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid bundle ID",
+		})
+		return
+	}
+
+	if err := services.DeleteBundle(uint(id)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrBundleNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bundle deleted successfully",
+	})
+}
+
+// AddBundleToCart godoc
+// @Summary Add bundle to cart
+// @Description Add a quantity of an active bundle to the authenticated user's cart as one line per component, linked by bundle_id. Bundle lines are changed and removed with their bundle only; the bundle discount is taken off the cart total and the order.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param bundle body models.AddBundleToCartRequest true "Bundle and quantity"
+// @Success 200 {object} map[string]interface{} "Bundle added to cart successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input or unavailable product"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Bundle not found"
+// @Failure 409 {object} map[string]interface{} "Insufficient stock"
+// @Router /cart/bundles [post]
+func AddBundleToCart(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.AddBundleToCartRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	lines, err := services.AddBundleToCart(userID.(uint), req)
+	if err != nil {
+		if stockError(c, err) {
+			return
+		}
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrBundleNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    lines,
+		"message": "Bundle added to cart successfully",
+	})
+}
+
+// RemoveBundleFromCart godoc
+// @Summary Remove bundle from cart
+// @Description Remove all lines of a bundle from the authenticated user's cart
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param bundle_id path int true "Bundle ID"
+// @Success 200 {object} map[string]interface{} "Bundle removed from cart successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid bundle ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Bundle not in cart"
+// @Router /cart/bundles/{bundle_id} [delete]
+func RemoveBundleFromCart(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	bundleID, err := strconv.ParseUint(c.Param("bundle_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid bundle ID",
+		})
+		return
+	}
+
+	if err := services.RemoveBundleFromCart(userID.(uint), uint(bundleID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bundle removed from cart successfully",
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"literally-backend/internal/models"
 	"literally-backend/internal/services"
 	"net/http"
@@ -61,6 +62,7 @@ func GetCart(c *gin.Context) {
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 409 {object} map[string]interface{} "Flash sale sold out or limit per customer reached"
 // @Router /cart [post]
 func AddToCart(c *gin.Context) {
 	// In a real app, extract user ID from JWT token
@@ -90,6 +92,9 @@ func AddToCart(c *gin.Context) {
 
 	cart, err := services.AddToCart(uint(userID), req)
 	if err != nil {
+		if stockError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -115,6 +120,7 @@ func AddToCart(c *gin.Context) {
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "Cart item not found"
+// @Failure 409 {object} map[string]interface{} "Cart item belongs to a bundle, flash sale sold out or limit per customer reached"
 // @Router /cart/{id} [put]
 func UpdateCartItem(c *gin.Context) {
 	// In a real app, extract user ID from JWT token
@@ -153,7 +159,14 @@ func UpdateCartItem(c *gin.Context) {
 
 	cart, err := services.UpdateCartItem(uint(userID), uint(cartID), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+		if stockError(c, err) {
+			return
+		}
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrBundleCartItem) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
 			"error": err.Error(),
 		})
 		return
@@ -177,6 +190,7 @@ func UpdateCartItem(c *gin.Context) {
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid cart item ID"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "Cart item not found"
+// @Failure 409 {object} map[string]interface{} "Cart item belongs to a bundle"
 // @Router /cart/{id} [delete]
 func RemoveFromCart(c *gin.Context) {
 	// In a real app, extract user ID from JWT token
@@ -207,7 +221,11 @@ func RemoveFromCart(c *gin.Context) {
 
 	err = services.RemoveFromCart(uint(userID), uint(cartID))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
+		status := http.StatusNotFound
+		if errors.Is(err, services.ErrBundleCartItem) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
 			"error": err.Error(),
 		})
 		return
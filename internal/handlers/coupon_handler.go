@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"errors"
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCoupons godoc
+// @Summary Get coupons (admin)
+// @Description Get a page of coupons with their restrictions and usage count
+// @Tags admin-coupons
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (code, created_at, used_count; default: -created_at)"
+// @Param type query string false "Filter by type, comma separated (PERCENT, FIXED, FREE_SHIPPING)"
+// @Param is_active query bool false "Filter by active state"
+// @Success 200 {object} map[string]interface{} "Coupons retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid list parameters"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/coupons [get]
+func GetCoupons(c *gin.Context) {
+	params, ok := listParams(c, services.CouponListSpec)
+	if !ok {
+		return
+	}
+
+	coupons, page, err := services.GetCoupons(params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve coupons")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       coupons,
+		"pagination": page,
+		"message":    "Coupons retrieved successfully",
+	})
+}
+
+// GetCouponByID godoc
+// @Summary Get coupon by ID (admin)
+// @Description Get a coupon with its restrictions and usage count
+// @Tags admin-coupons
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Coupon ID"
+// @Success 200 {object} map[string]interface{} "Coupon retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid coupon ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Coupon not found"
+// @Router /admin/coupons/{id} [get]
+func GetCouponByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid coupon ID",
+		})
+		return
+	}
+
+	coupon, err := services.GetCouponByID(uint(id))
+	if err != nil {
+		if couponError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve coupon",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    coupon,
+		"message": "Coupon retrieved successfully",
+	})
+}
+
+// CreateCoupon godoc
+// @Summary Create coupon (admin)
+// @Description Create a PERCENT, FIXED or FREE_SHIPPING coupon. Codes are case-insensitive. min_order_value is compared with the items total after bundle discounts; max_discount caps percent and free shipping coupons. Without product_ids and category_ids the coupon applies to every item, otherwise only to those products and to products in those categories and their subcategories. Coupons that are not stackable cannot be combined with other coupons.
+// @Tags admin-coupons
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param coupon body models.CouponRequest true "Coupon data"
+// @Success 201 {object} map[string]interface{} "Coupon created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input or code taken"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/coupons [post]
+func CreateCoupon(c *gin.Context) {
+	var req models.CouponRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	coupon, err := services.CreateCoupon(req)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    coupon,
+		"message": "Coupon created successfully",
+	})
+}
+
+// ReplaceCoupon godoc
+// @Summary Replace coupon (admin)
+// @Description Replace all fields and restrictions of a coupon. Its usage so far is kept.
+// @Tags admin-coupons
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Coupon ID"
+// @Param coupon body models.CouponRequest true "Coupon data"
+// @Success 200 {object} map[string]interface{} "Coupon updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input or code taken"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Coupon not found"
+// @Router /admin/coupons/{id} [put]
+func ReplaceCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid coupon ID",
+		})
+		return
+	}
+
+	var req models.CouponRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	coupon, err := services.ReplaceCoupon(uint(id), req)
+	if err != nil {
+		if couponError(c, err) {
+			return
+		}
+		badRequest(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    coupon,
+		"message": "Coupon updated successfully",
+	})
+}
+
+// DeleteCoupon godoc
+// @Summary Delete coupon (admin)
+// @Description Soft delete a coupon and remove it from the carts holding it. Orders that used it keep their redemptions.
+// @Tags admin-coupons
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Coupon ID"
+// @Success 200 {object} map[string]interface{} "Coupon deleted successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid coupon ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Coupon not found"
+// @Router /admin/coupons/{id} [delete]
+func DeleteCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid coupon ID",
+		})
+		return
+	}
+
+	if err := services.DeleteCoupon(uint(id)); err != nil {
+		if couponError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete coupon",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon deleted successfully",
+	})
+}
+
+// GetCouponRedemptions godoc
+// @Summary Get coupon redemptions (admin)
+// @Description Get a page of the orders that used a coupon with the discount each got. Redemptions of cancelled orders carry released_at and no longer count towards the usage limits.
+// @Tags admin-coupons
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Coupon ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (created_at, discount; default: -created_at)"
+// @Param user_id query int false "Filter by customer"
+// @Success 200 {object} map[string]interface{} "Coupon redemptions retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid coupon ID or list parameters"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Coupon not found"
+// @Router /admin/coupons/{id}/redemptions [get]
+func GetCouponRedemptions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid coupon ID",
+		})
+		return
+	}
+
+	params, ok := listParams(c, services.CouponRedemptionListSpec)
+	if !ok {
+		return
+	}
+
+	redemptions, page, err := services.GetCouponRedemptions(uint(id), params)
+	if err != nil {
+		if couponError(c, err) {
+			return
+		}
+		listError(c, err, "Failed to retrieve coupon redemptions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       redemptions,
+		"pagination": page,
+		"message":    "Coupon redemptions retrieved successfully",
+	})
+}
+
+// GetCouponUsageReport godoc
+// @Summary Get coupon usage report (admin)
+// @Description Get the uses, customers, discount given and order revenue of every coupon, most used first. Uses released by cancelled orders are counted apart.
+// @Tags admin-coupons
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{} "Coupon usage report retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/coupons/report [get]
+func GetCouponUsageReport(c *gin.Context) {
+	report, err := services.GetCouponUsageReport()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve coupon usage report",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    report,
+		"message": "Coupon usage report retrieved successfully",
+	})
+}
+
+// ApplyCoupon godoc
+// @Summary Apply coupon to cart
+// @Description Apply a coupon to the authenticated user's cart and get the discounted cart. The coupon must apply to the cart together with the coupons already applied. With preview the cart is priced with the coupon without applying it. Applied coupons are used when ordering without coupon_codes.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param coupon body models.ApplyCouponRequest true "Coupon code"
+// @Success 200 {object} map[string]interface{} "Coupon applied successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input or coupon not applicable"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Coupon not found"
+// @Failure 409 {object} map[string]interface{} "Coupon usage limit reached"
+// @Router /cart/apply-coupon [post]
+func ApplyCoupon(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.ApplyCouponRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	summary, err := services.ApplyCartCoupon(userID.(uint), req)
+	if err != nil {
+		if couponError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to apply coupon",
+		})
+		return
+	}
+
+	message := "Coupon applied successfully"
+	if req.Preview {
+		message = "Coupon preview calculated successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":    summary,
+		"message": message,
+	})
+}
+
+// RemoveCoupon godoc
+// @Summary Remove coupon from cart
+// @Description Remove a coupon from the authenticated user's cart
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param code path string true "Coupon code"
+// @Success 200 {object} map[string]interface{} "Coupon removed successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Coupon not found or not applied"
+// @Router /cart/coupons/{code} [delete]
+func RemoveCoupon(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	if err := services.RemoveCartCoupon(userID.(uint), c.Param("code")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon removed successfully",
+	})
+}
+
+// GetCartSummary godoc
+// @Summary Get cart summary
+// @Description Price the authenticated user's cart: subtotal, bundle and coupon discounts, shipping fee and total. Applied coupons that no longer apply, for example after the cart changed, are listed in rejected_coupons with the reason and not taken off.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{} "Cart summary retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /cart/summary [get]
+func GetCartSummary(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	summary, err := services.GetCartSummary(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve cart summary",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    summary,
+		"message": "Cart summary retrieved successfully",
+	})
+}
+
+// couponError answers 404 for unknown coupons, 400 for coupons that do not
+// apply and 409 for reached usage limits, returning true when err was one of
+// them
+func couponError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrCouponNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrCouponNotApplicable):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrCouponLimitReached):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		return false
+	}
+	return true
+}
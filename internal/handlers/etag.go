@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Versioned resources (products, categories, users and orders) carry their
+// version as the ETag, e.g. ETag: "3". Admin edits must name the version they
+// apply to, either in an If-Match header or in the version field of the body,
+// and are refused with 412 Precondition Failed once someone else changed the
+// resource. GET requests with a matching If-None-Match get 304 Not Modified,
+// except for products, whose stock changes with orders without a new version.
+
+// etag formats a resource version as an entity tag
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// setETag sends the version of a resource as its ETag
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", etag(version))
+}
+
+// notModified answers 304 and returns true when the If-None-Match header
+// already holds the current version. Otherwise it sets the ETag of the
+// response that follows.
+func notModified(c *gin.Context, version uint) bool {
+	setETag(c, version)
+
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag(version) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// editVersion returns the version an edit applies to, taken from the
+// If-Match header or else the version field of the body. An If-Match of *
+// returns 0, which applies the edit to any version. When neither is given it
+// answers 428 Precondition Required if required, and returns 0 otherwise.
+func editVersion(c *gin.Context, bodyVersion *uint, required bool) (uint, bool) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" {
+		if bodyVersion != nil {
+			return *bodyVersion, true
+		}
+		if required {
+			c.JSON(http.StatusPreconditionRequired, gin.H{
+				"error": "If-Match header or version field is required",
+			})
+			return 0, false
+		}
+		return 0, true
+	}
+
+	if ifMatch == "*" {
+		return 0, true
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	version, err := strconv.ParseUint(tag, 10, 32)
+	if err != nil || version == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid If-Match header, expected a single ETag",
+		})
+		return 0, false
+	}
+	return uint(version), true
+}
+
+// versionConflict answers 412 and returns true when err is a version conflict
+func versionConflict(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrVersionConflict) {
+		return false
+	}
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error": err.Error(),
+	})
+	return true
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"errors"
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetFlashSales godoc
+// @Summary Get flash sales
+// @Description Get a page of the running and upcoming flash sales with their products, sale prices, units left and status (SCHEDULED, LIVE or SOLD_OUT)
+// @Tags flash-sales
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (starts_at, ends_at, created_at; default: starts_at)"
+// @Param product_id query string false "Filter by product, comma separated"
+// @Success 200 {object} map[string]interface{} "Flash sales retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid list parameters"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /flash-sales [get]
+func GetFlashSales(c *gin.Context) {
+	params, ok := listParams(c, services.FlashSaleListSpec)
+	if !ok {
+		return
+	}
+
+	sales, page, err := services.GetFlashSales(params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve flash sales")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       sales,
+		"pagination": page,
+		"message":    "Flash sales retrieved successfully",
+	})
+}
+
+// GetFlashSalesAdmin godoc
+// @Summary Get flash sales (admin)
+// @Description Get a page of flash sales, past ones included, with their live sell-through: units sold and left, percentage sold, and the orders, customers and revenue of their purchases
+// @Tags admin-flash-sales
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (starts_at, ends_at, created_at; default: starts_at)"
+// @Param product_id query string false "Filter by product, comma separated"
+// @Success 200 {object} map[string]interface{} "Flash sales retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid list parameters"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/flash-sales [get]
+func GetFlashSalesAdmin(c *gin.Context) {
+	params, ok := listParams(c, services.FlashSaleListSpec)
+	if !ok {
+		return
+	}
+
+	sales, page, err := services.GetFlashSalesAdmin(params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve flash sales")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       sales,
+		"pagination": page,
+		"message":    "Flash sales retrieved successfully",
+	})
+}
+
+// GetFlashSaleSellThrough godoc
+// @Summary Get flash sale sell-through (admin)
+// @Description Get a flash sale with its live sell-through: units sold and left, percentage sold, and the orders, customers and revenue of its purchases. Purchases of cancelled orders are left out.
+// @Tags admin-flash-sales
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Flash sale ID"
+// @Success 200 {object} map[string]interface{} "Flash sale retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid flash sale ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Flash sale not found"
+// @Router /admin/flash-sales/{id} [get]
+func GetFlashSaleSellThrough(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid flash sale ID",
+		})
+		return
+	}
+
+	sale, err := services.GetFlashSaleSellThrough(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrFlashSaleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve flash sale",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    sale,
+		"message": "Flash sale retrieved successfully",
+	})
+}
+
+// CreateFlashSale godoc
+// @Summary Create flash sale (admin)
+// @Description Schedule a flash sale selling allocated_quantity units of a product, or of one of its variants, at sale_price between starts_at and ends_at, at most per_user_limit units per customer. The sale price must be below the regular price, the allocation within stock, and the window must not overlap another sale of the product or variant.
+// @Tags admin-flash-sales
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param sale body models.FlashSaleRequest true "Flash sale data"
+// @Success 201 {object} map[string]interface{} "Flash sale created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/flash-sales [post]
+func CreateFlashSale(c *gin.Context) {
+	var req models.FlashSaleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	sale, err := services.CreateFlashSale(req)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    sale,
+		"message": "Flash sale created successfully",
+	})
+}
+
+// ReplaceFlashSale godoc
+// @Summary Replace flash sale (admin)
+// @Description Replace all fields of a flash sale. The units sold so far are kept, so the allocation cannot go below them.
+// @Tags admin-flash-sales
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Flash sale ID"
+// @Param sale body models.FlashSaleRequest true "Flash sale data"
+// @Success 200 {object} map[string]interface{} "Flash sale updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Flash sale not found"
+// @Router /admin/flash-sales/{id} [put]
+func ReplaceFlashSale(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid flash sale ID",
+		})
+		return
+	}
+
+	var req models.FlashSaleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	sale, err := services.ReplaceFlashSale(uint(id), req)
+	if err != nil {
+		if errors.Is(err, services.ErrFlashSaleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		badRequest(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    sale,
+		"message": "Flash sale updated successfully",
+	})
+}
+
+// DeleteFlashSale godoc
+// @Summary Delete flash sale (admin)
+// @Description Soft delete a flash sale, ending it at once. Orders that bought in it keep their purchases.
+// @Tags admin-flash-sales
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Flash sale ID"
+// @Success 200 {object} map[string]interface{} "Flash sale deleted successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid flash sale ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Flash sale not found"
+// @Router /admin/flash-sales/{id} [delete]
+func DeleteFlashSale(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid flash sale ID",
+		})
+		return
+	}
+
+	if err := services.DeleteFlashSale(uint(id)); err != nil {
+		if errors.Is(err, services.ErrFlashSaleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete flash sale",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Flash sale deleted successfully",
+	})
+}
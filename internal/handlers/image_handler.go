@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImagesPerUpload limits how many gallery images one request may carry
+const maxImagesPerUpload = 10
+
+// GetProductImages godoc
+// @Summary Get product images
+// @Description Get the image gallery of a product in display order, with medium and thumbnail renditions
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} map[string]interface{} "Images retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid product ID"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Router /products/{id}/images [get]
+func GetProductImages(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	if _, found := services.GetProductByID(uint(id)); !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    services.GetProductImages(uint(id)),
+		"message": "Images retrieved successfully",
+	})
+}
+
+// UploadProductImages godoc
+// @Summary Upload product images (admin)
+// @Description Upload one or more JPEG, PNG or GIF images to the end of a product's gallery. Thumbnail and medium renditions are generated. The first image becomes primary when is_primary is true or the product has no primary image.
+// @Tags admin-products
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param images formData file true "Image files (repeat the field for several images)"
+// @Param is_primary formData bool false "Make the first uploaded image the primary image"
+// @Success 201 {object} map[string]interface{} "Images uploaded successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid image"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 413 {object} map[string]interface{} "Upload too large"
+// @Router /admin/products/{id}/images [post]
+func UploadProductImages(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxUploadSize()*maxImagesPerUpload+1<<20)
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "Upload is too large or not a multipart form",
+		})
+		return
+	}
+
+	files := form.File["images"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "At least one file is required in the images field",
+		})
+		return
+	}
+	if len(files) > maxImagesPerUpload {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Too many images in one upload, the limit is " + strconv.Itoa(maxImagesPerUpload),
+		})
+		return
+	}
+
+	primary, _ := strconv.ParseBool(c.PostForm("is_primary"))
+
+	images, err := services.AddProductImages(uint(id), files, primary)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    images,
+		"message": "Images uploaded successfully",
+	})
+}
+
+// ReorderProductImages godoc
+// @Summary Reorder product images (admin)
+// @Description Set the gallery order by listing every image ID of the product
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param order body models.ReorderProductImagesRequest true "Image IDs in the new order"
+// @Success 200 {object} map[string]interface{} "Images reordered successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/products/{id}/images/order [put]
+func ReorderProductImages(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req models.ReorderProductImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	images, err := services.ReorderProductImages(uint(id), req.ImageIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    images,
+		"message": "Images reordered successfully",
+	})
+}
+
+// SetPrimaryProductImage godoc
+// @Summary Set primary product image (admin)
+// @Description Make an image the primary image of its product; the product image_url follows it
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param image_id path int true "Image ID"
+// @Success 200 {object} map[string]interface{} "Primary image updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid ID"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Router /admin/products/{id}/images/{image_id}/primary [put]
+func SetPrimaryProductImage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	imageID, err := strconv.ParseUint(c.Param("image_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid image ID",
+		})
+		return
+	}
+
+	image, err := services.SetPrimaryProductImage(uint(id), uint(imageID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    image,
+		"message": "Primary image updated successfully",
+	})
+}
+
+// DeleteProductImage godoc
+// @Summary Delete product image (admin)
+// @Description Remove an image and its renditions from a product's gallery
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param image_id path int true "Image ID"
+// @Success 200 {object} map[string]interface{} "Image deleted successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid ID"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Router /admin/products/{id}/images/{image_id} [delete]
+func DeleteProductImage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	imageID, err := strconv.ParseUint(c.Param("image_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid image ID",
+		})
+		return
+	}
+
+	if err := services.DeleteProductImage(uint(id), uint(imageID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Image deleted successfully",
+	})
+}
+
+// UploadAvatar godoc
+// @Summary Upload profile photo
+// @Description Upload a JPEG, PNG or GIF avatar. photo is set to the medium rendition and photo_thumbnail to the thumbnail.
+// @Tags profile
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param avatar formData file true "Avatar image"
+// @Success 200 {object} map[string]interface{} "Avatar uploaded successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid image"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 413 {object} map[string]interface{} "Upload too large"
+// @Router /profile/avatar [post]
+func UploadAvatar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxUploadSize()+1<<20)
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A file is required in the avatar field and must not exceed the upload limit",
+		})
+		return
+	}
+
+	user, err := services.UploadUserAvatar(userID.(uint), file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user.ToResponse(),
+		"message": "Avatar uploaded successfully",
+	})
+}
+
+// DeleteAvatar godoc
+// @Summary Remove profile photo
+// @Description Remove the authenticated user's avatar and its stored files
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{} "Avatar removed successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /profile/avatar [delete]
+func DeleteAvatar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	user, err := services.DeleteUserAvatar(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user.ToResponse(),
+		"message": "Avatar removed successfully",
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonateUser godoc
+// @Summary Impersonate user (admin)
+// @Description Issue a short-lived user token so support can see what the customer sees. Tokens are read-only unless read_only is false. Every impersonation is audited.
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "User ID"
+// @Param request body models.ImpersonateUserRequest true "Impersonation reason and mode"
+// @Success 201 {object} map[string]interface{} "Impersonation token issued successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/users/{id}/impersonate [post]
+func ImpersonateUser(c *gin.Context) {
+	adminID, exists := c.Get("admin_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin not authenticated",
+		})
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	var req models.ImpersonateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	impersonation, err := services.ImpersonateUser(adminID.(uint), uint(userID), req, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    impersonation,
+		"message": "Impersonation token issued successfully",
+	})
+}
+
+// GetImpersonationLogs godoc
+// @Summary Get impersonation audit log (admin)
+// @Description Get the audit log of admin impersonations with optional admin and user filters
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param admin_id query int false "Filter by admin ID"
+// @Param user_id query int false "Filter by user ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (created_at, expires_at; default: -created_at)"
+// @Success 200 {object} map[string]interface{} "Impersonation log retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid sort or cursor"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/impersonations [get]
+func GetImpersonationLogs(c *gin.Context) {
+	adminID, _ := strconv.ParseUint(c.Query("admin_id"), 10, 32)
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32)
+
+	params, ok := listParams(c, services.ImpersonationLogListSpec)
+	if !ok {
+		return
+	}
+
+	logs, page, err := services.GetImpersonationLogs(uint(adminID), uint(userID), params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve impersonation log")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       logs,
+		"pagination": page,
+		"message":    "Impersonation log retrieved successfully",
+	})
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"fmt"
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportProducts godoc
+// @Summary Import products from CSV or XLSX (admin)
+// @Description Create and update products from a spreadsheet with the columns id, sku, name, description, price, stock, category, brand, image_url, is_featured and is_available. Rows match existing products by id, then sku, then name; blank cells keep the current value. Categories are names, slugs or paths like "Electronics > Phones". All rows are applied in one transaction, so a file with any invalid row changes nothing. Large files are processed in the background and answered with 202.
+// @Tags admin-products
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param file formData file true "CSV or XLSX file"
+// @Param dry_run formData bool false "Validate and report what would change without writing anything"
+// @Param async formData bool false "Process the file in the background even if it is small"
+// @Success 200 {object} map[string]interface{} "Import finished"
+// @Success 202 {object} map[string]interface{} "Import started in the background"
+// @Failure 400 {object} map[string]interface{} "Bad request - Unreadable file"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 413 {object} map[string]interface{} "Upload too large"
+// @Failure 422 {object} map[string]interface{} "Import failed validation"
+// @Router /admin/products/import [post]
+func ImportProducts(c *gin.Context) {
+	adminID, exists := c.Get("admin_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin not authenticated",
+		})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxImportSize()+1<<20)
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "Upload is too large or has no file field",
+		})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultPostForm("dry_run", c.Query("dry_run")))
+	async, _ := strconv.ParseBool(c.DefaultPostForm("async", c.Query("async")))
+
+	job, err := services.ImportProducts(adminID.(uint), file, dryRun, async)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	switch job.Status {
+	case models.ImportStatusPending:
+		c.JSON(http.StatusAccepted, gin.H{
+			"data":    job,
+			"message": "Import started, poll the job for progress",
+		})
+	case models.ImportStatusFailed:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"data":  job,
+			"error": job.Message,
+		})
+	default:
+		message := "Products imported successfully"
+		if job.DryRun {
+			message = "Dry run completed, no changes were applied"
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"data":    job,
+			"message": message,
+		})
+	}
+}
+
+// GetImportJob godoc
+// @Summary Get a product import job (admin)
+// @Description Get the status, progress counters and row errors of a product import
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param job_id path int true "Import job ID"
+// @Success 200 {object} map[string]interface{} "Import job retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid job ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Import job not found"
+// @Router /admin/products/import/{job_id} [get]
+func GetImportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("job_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid job ID",
+		})
+		return
+	}
+
+	job, err := services.GetImportJob(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    job,
+		"message": "Import job retrieved successfully",
+	})
+}
+
+// ExportProducts godoc
+// @Summary Export products to CSV or XLSX (admin)
+// @Description Download the products matching the usual product filters, including unavailable ones, in the columns read by the import
+// @Tags admin-products
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security Bearer
+// @Param format query string false "csv or xlsx (default: csv)"
+// @Param category_id query int false "Filter by category ID"
+// @Param include_descendants query bool false "Include products of subcategories"
+// @Param brand query string false "Comma separated brands"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param featured query bool false "Only featured products"
+// @Param search query string false "Search in name and description"
+// @Success 200 {file} file "Product export"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid filter or format"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/products/export [get]
+func ExportProducts(c *gin.Context) {
+	filter, err := productFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	filter.IncludeUnavailable = true
+
+	format := c.DefaultQuery("format", services.ExportFormatCSV)
+	contentType := "text/csv"
+	switch format {
+	case services.ExportFormatCSV:
+	case services.ExportFormatXLSX:
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported export format, use csv or xlsx",
+		})
+		return
+	}
+
+	fileName := fmt.Sprintf("products-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Status(http.StatusOK)
+
+	if err := services.ExportProducts(c.Writer, format, filter); err != nil {
+		// Once rows are streamed a failure can only cut the download short
+		if c.Writer.Written() {
+			c.Error(err)
+			return
+		}
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	}
+}
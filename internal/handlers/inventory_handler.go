@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"errors"
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReserveStock godoc
+// @Summary Start checkout by reserving stock
+// @Description Reserve the given items, or the cart when none are given, for the authenticated user until expires_at (STOCK_RESERVATION_TTL, 15 minutes by default). Place the order with the reservation_id before it expires; release it when payment fails. Stock is reserved in the warehouses nearest to address_id, or to the default address. Starting a new checkout releases the user's previous reservation.
+// @Tags checkout
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param reservation body models.CreateReservationRequest false "Items to reserve"
+// @Success 201 {object} map[string]interface{} "Stock reserved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, empty cart or unknown address"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Insufficient stock"
+// @Router /checkout/reservations [post]
+func ReserveStock(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.CreateReservationRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	reservation, err := services.ReserveStock(userID.(uint), req)
+	if err != nil {
+		if !stockError(c, err) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    reservation,
+		"message": "Stock reserved successfully",
+	})
+}
+
+// GetReservation godoc
+// @Summary Get stock reservation
+// @Description Get a stock reservation of the authenticated user with its status and expiry
+// @Tags checkout
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} map[string]interface{} "Reservation retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid reservation ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Reservation not found"
+// @Router /checkout/reservations/{id} [get]
+func GetReservation(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid reservation ID",
+		})
+		return
+	}
+
+	reservation, err := services.GetReservation(userID.(uint), uint(id))
+	if err != nil {
+		if !stockError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve reservation",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    reservation,
+		"message": "Reservation retrieved successfully",
+	})
+}
+
+// ReleaseReservation godoc
+// @Summary Release stock reservation
+// @Description Release an active stock reservation of the authenticated user, e.g. after a failed payment, putting its stock back on sale
+// @Tags checkout
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} map[string]interface{} "Reservation released successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid reservation ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Reservation not found"
+// @Failure 409 {object} map[string]interface{} "Reservation already expired, released or ordered"
+// @Router /checkout/reservations/{id} [delete]
+func ReleaseReservation(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid reservation ID",
+		})
+		return
+	}
+
+	reservation, err := services.ReleaseReservation(userID.(uint), uint(id))
+	if err != nil {
+		if !stockError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to release reservation",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    reservation,
+		"message": "Reservation released successfully",
+	})
+}
+
+// AdjustStock godoc
+// @Summary Adjust product stock (admin)
+// @Description Add units to, or with a negative quantity take units out of, the stock of a product or one of its variants, recording the reason (ADJUSTMENT by default, or RECEIVING for delivered goods) and a note in the inventory ledger. variant_id is required for products with variants. warehouse_id picks the warehouse, the default warehouse otherwise. Stock cannot go below 0, in total or in the warehouse.
+// @Tags admin-inventory
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param adjustment body models.AdjustStockRequest true "Stock adjustment"
+// @Success 201 {object} map[string]interface{} "Stock adjusted successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, unknown variant or unknown warehouse"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Insufficient stock"
+// @Router /admin/products/{id}/stock-adjustments [post]
+func AdjustStock(c *gin.Context) {
+	adminID, exists := c.Get("admin_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req models.AdjustStockRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	movement, err := services.AdjustStock(adminID.(uint), uint(id), req)
+	if err != nil {
+		if !stockError(c, err) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    movement,
+		"message": "Stock adjusted successfully",
+	})
+}
+
+// GetInventoryMovements godoc
+// @Summary Get product stock movements (admin)
+// @Description Get every stock change of a product and its variants from the inventory ledger with the reason (SALE, CANCEL, RETURN, ADJUSTMENT, RECEIVING, RESERVED, RELEASED or OPENING), the change, the resulting balance, the order, reservation or import it belongs to and who made it
+// @Tags admin-inventory
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param variant_id query string false "Filter by variant IDs, comma separated"
+// @Param reason query string false "Filter by reasons, comma separated"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (created_at, quantity; default: -created_at)"
+// @Success 200 {object} map[string]interface{} "Stock movements retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid product ID, sort or cursor"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Router /admin/products/{id}/inventory-movements [get]
+func GetInventoryMovements(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	if _, found := services.GetProductByID(uint(id)); !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	params, ok := listParams(c, services.InventoryMovementListSpec)
+	if !ok {
+		return
+	}
+
+	movements, page, err := services.GetInventoryMovements(uint(id), params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve stock movements")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       movements,
+		"pagination": page,
+		"message":    "Stock movements retrieved successfully",
+	})
+}
+
+// GetStockReconciliation godoc
+// @Summary Reconcile stock with the inventory ledger (admin)
+// @Description Check the stock of every product without variants and of every variant against the sum of its inventory movements and the sum of its warehouse stock. Only mismatches are listed unless all is true; checked and mismatched count every product and variant.
+// @Tags admin-inventory
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param all query bool false "List matching products and variants too"
+// @Success 200 {object} map[string]interface{} "Stock reconciled successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/inventory/reconciliation [get]
+func GetStockReconciliation(c *gin.Context) {
+	report, err := services.GetStockReconciliation(c.Query("all") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reconcile stock",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    report,
+		"message": "Stock reconciled successfully",
+	})
+}
+
+// stockError answers 409 for stock shortages, full pre-orders, orders waiting
+// for pre-ordered stock, unusable reservations, sold out flash sales and
+// flash sale limits, and 404 for unknown reservations, returning true when
+// err was one of them
+func stockError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrInsufficientStock), errors.Is(err, services.ErrReservationInactive),
+		errors.Is(err, services.ErrPreorderCapReached), errors.Is(err, services.ErrAwaitingStock),
+		errors.Is(err, services.ErrFlashSaleSoldOut), errors.Is(err, services.ErrFlashSaleLimitReached):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrReservationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+// GetReorderSuggestions godoc
+// @Summary Get reorder suggestions (admin)
+// @Description Suggest how many units of each product to reorder so that stock covers its reorder threshold (LOW_STOCK_THRESHOLD when the product sets none) plus REORDER_COVER_DAYS days of sales, from the units sold over the last days days (REORDER_WINDOW_DAYS, 30 by default) excluding cancelled and returned orders. Only products that need reordering are listed, soonest to run out first.
+// @Tags admin-inventory
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param days query int false "Sales window in days (1-365)"
+// @Success 200 {object} map[string]interface{} "Reorder suggestions retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid days"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/inventory/reorder-suggestions [get]
+func GetReorderSuggestions(c *gin.Context) {
+	days := services.ReorderWindowDays()
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "days must be between 1 and 365",
+			})
+			return
+		}
+		days = parsed
+	}
+
+	report, err := services.GetReorderSuggestions(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to compute reorder suggestions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    report,
+		"message": "Reorder suggestions retrieved successfully",
+	})
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications godoc
+// @Summary Get notifications
+// @Description Get the authenticated user's notifications, such as BACK_IN_STOCK for products they asked to be notified about, newest first
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param type query string false "Filter by types, comma separated"
+// @Param is_read query string false "Filter by read state (true or false)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (created_at; default: -created_at)"
+// @Success 200 {object} map[string]interface{} "Notifications retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid sort or cursor"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /profile/notifications [get]
+func GetNotifications(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	params, ok := listParams(c, services.NotificationListSpec)
+	if !ok {
+		return
+	}
+
+	notifications, page, err := services.GetUserNotifications(userID.(uint), params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       notifications,
+		"pagination": page,
+		"message":    "Notifications retrieved successfully",
+	})
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark notification as read
+// @Description Mark one of the authenticated user's notifications as read
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Notification ID"
+// @Success 200 {object} map[string]interface{} "Notification marked as read"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid notification ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Notification not found"
+// @Router /profile/notifications/{id}/read [put]
+func MarkNotificationRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid notification ID",
+		})
+		return
+	}
+
+	notification, err := services.MarkNotificationRead(userID.(uint), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    notification,
+		"message": "Notification marked as read",
+	})
+}
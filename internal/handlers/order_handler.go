@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"literally-backend/internal/models"
 	"literally-backend/internal/services"
 	"net/http"
@@ -16,10 +17,13 @@ import (
 // @Accept json
 // @Produce json
 // @Security Bearer
-// @Param status query string false "Filter by order status (PENDING, CONFIRMED, SHIPPED, DELIVERED, CANCELLED)"
-// @Param limit query int false "Number of orders to return (default: 10)"
-// @Param offset query int false "Number of orders to skip (default: 0)"
+// @Param status query string false "Filter by order status, comma separated (PENDING, CONFIRMED, SHIPPED, DELIVERED, CANCELLED)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (created_at, total_amount, status; default: -created_at)"
 // @Success 200 {object} map[string]interface{} "Success response with orders and pagination"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid sort or cursor"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /orders [get]
@@ -33,43 +37,22 @@ func GetUserOrders(c *gin.Context) {
 		return
 	}
 
-	// Get query parameters
-	status := c.Query("status")
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
-	}
-
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	params, ok := listParams(c, services.OrderListSpec)
+	if !ok {
+		return
 	}
 
-	// Calculate page from offset
-	page := offset/limit + 1
-
 	// Get orders
-	orders, total, err := services.GetUserOrders(userID.(uint), page, limit, status)
+	orders, page, err := services.GetUserOrders(userID.(uint), params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve orders",
-		})
+		listError(c, err, "Failed to retrieve orders")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": gin.H{
-			"orders": orders,
-			"pagination": gin.H{
-				"total":  total,
-				"limit":  limit,
-				"offset": offset,
-			},
-		},
-		"message": "Orders retrieved successfully",
+		"data":       orders,
+		"pagination": page,
+		"message":    "Orders retrieved successfully",
 	})
 }
 
@@ -81,7 +64,9 @@ func GetUserOrders(c *gin.Context) {
 // @Produce json
 // @Security Bearer
 // @Param id path int true "Order ID"
+// @Param If-None-Match header string false "ETag from an earlier response"
 // @Success 200 {object} map[string]interface{} "Success response with order details"
+// @Success 304 {string} string "Not modified"
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid order ID"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "Order not found"
@@ -115,6 +100,10 @@ func GetOrderByID(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, order.Version) {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data":    order,
 		"message": "Order retrieved successfully",
@@ -123,15 +112,17 @@ func GetOrderByID(c *gin.Context) {
 
 // CreateOrder godoc
 // @Summary Create new order
-// @Description Create a new order from cart items or specific items for the authenticated user
+// @Description Create a new order for the authenticated user from specific items, or from the items of a stock reservation made at checkout. Bundles are ordered by bundle_id and quantity, expanded into one item per component, and their discount is spread across those items in proportion to price. Coupons named in coupon_codes, or without it those applied to the cart, are taken off and their use is recorded with the order; the total includes the SHIPPING_FEE less free shipping coupons. Items bought outside bundles during a flash sale get its sale price while units are left, up to its limit per customer. Stock is taken atomically, so concurrent orders cannot oversell. Items of pre-order products beyond stock are pre-ordered up to the product's pre-order cap: the order is PREORDERED, with the deposit due, until stock arrives for them, first come first served, and then becomes pending.
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Security Bearer
-// @Param order body object false "Order creation request (optional - if not provided, creates order from cart)"
+// @Param order body models.CreateOrderRequest true "Order creation request"
 // @Success 201 {object} map[string]interface{} "Success response with created order"
-// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input or no shipping address"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product, address, reservation or coupon not found"
+// @Failure 409 {object} map[string]interface{} "Insufficient stock, flash sale sold out, pre-order, coupon or flash sale limit reached, or reservation expired or released"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /orders [post]
 func CreateOrder(c *gin.Context) {
@@ -156,6 +147,9 @@ func CreateOrder(c *gin.Context) {
 	// Create order
 	order, err := services.CreateOrderFromRequest(userID.(uint), req)
 	if err != nil {
+		if stockError(c, err) || couponError(c, err) || orderError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to create order",
 		})
@@ -170,18 +164,30 @@ func CreateOrder(c *gin.Context) {
 
 // UpdateOrderStatus godoc
 // @Summary Update order status
-// @Description Update the status of an order (admin only)
+// @Description Update the status of an order (admin only). Moving an order to CANCELLED or RETURNED puts its items back in stock; moving it out of those statuses takes them out again. Cancelling an order releases its coupons and flash sale units; reopening it fails when a coupon reached its usage limit or a sale has no units left for it. Orders with pre-ordered items waiting for stock can only be PREORDERED or CANCELLED.
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param id path int true "Order ID"
-// @Param status body object true "Status update request"
+// @Param status body models.UpdateOrderStatusRequest true "Status update request"
+// @Param If-Match header string false "ETag of the version being edited; required unless version is in the body"
 // @Success 200 {object} map[string]interface{} "Success response"
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 409 {object} map[string]interface{} "Insufficient stock or flash sale units, or coupon usage limit reached, to reopen the order, or pre-ordered items waiting for stock"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
-// @Router /orders/{id}/status [put]
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
+// @Router /admin/orders/{id}/status [put]
 func UpdateOrderStatus(c *gin.Context) {
+	adminID, exists := c.Get("admin_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin not authenticated",
+		})
+		return
+	}
+
 	// Get order ID from URL parameter
 	orderIDStr := c.Param("id")
 	orderID, err := strconv.ParseUint(orderIDStr, 10, 32)
@@ -201,16 +207,34 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
+	version, ok := editVersion(c, req.Version, true)
+	if !ok {
+		return
+	}
+
 	// Update order status
-	err = services.UpdateOrderStatus(uint(orderID), req.Status)
+	err = services.UpdateOrderStatus(adminID.(uint), uint(orderID), version, req.Status)
 	if err != nil {
+		if versionConflict(c, err) || stockError(c, err) || couponError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to update order status",
 		})
 		return
 	}
 
+	order, err := services.GetOrderByIDAdmin(uint(orderID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve order",
+		})
+		return
+	}
+
+	setETag(c, order.Version)
 	c.JSON(http.StatusOK, gin.H{
+		"data":    order,
 		"message": "Order status updated successfully",
 	})
 }
@@ -260,49 +284,33 @@ func GetOrderStats(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security Bearer
-// @Param status query string false "Filter by order status (PENDING, CONFIRMED, SHIPPED, DELIVERED, CANCELLED)"
+// @Param status query string false "Filter by order status, comma separated (PENDING, CONFIRMED, SHIPPED, DELIVERED, CANCELLED)"
 // @Param page query int false "Page number (default: 1)"
-// @Param limit query int false "Number of orders to return (default: 10)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (created_at, total_amount, status; default: -created_at)"
 // @Success 200 {object} map[string]interface{} "Success response with orders and pagination"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid sort or cursor"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /admin/orders [get]
 func GetAllOrdersAdmin(c *gin.Context) {
-	// Get query parameters
-	status := c.Query("status")
-	pageStr := c.DefaultQuery("page", "1")
-	limitStr := c.DefaultQuery("limit", "10")
-
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page <= 0 {
-		page = 1
-	}
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
+	params, ok := listParams(c, services.OrderListSpec)
+	if !ok {
+		return
 	}
 
 	// Get orders
-	orders, total, err := services.GetAllOrders(page, limit, status)
+	orders, page, err := services.GetAllOrders(params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve orders",
-		})
+		listError(c, err, "Failed to retrieve orders")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": gin.H{
-			"orders": orders,
-			"pagination": gin.H{
-				"total":       total,
-				"page":        page,
-				"limit":       limit,
-				"total_pages": (total + int64(limit) - 1) / int64(limit),
-			},
-		},
-		"message": "Orders retrieved successfully",
+		"data":       orders,
+		"pagination": page,
+		"message":    "Orders retrieved successfully",
 	})
 }
 
@@ -314,7 +322,9 @@ func GetAllOrdersAdmin(c *gin.Context) {
 // @Produce json
 // @Security Bearer
 // @Param id path int true "Order ID"
+// @Param If-None-Match header string false "ETag from an earlier response"
 // @Success 200 {object} map[string]interface{} "Success response with order details"
+// @Success 304 {string} string "Not modified"
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid order ID"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "Order not found"
@@ -339,6 +349,10 @@ func GetOrderByIDAdmin(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, order.Version) {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data":    order,
 		"message": "Order retrieved successfully",
@@ -371,3 +385,21 @@ func GetAdminOrderStats(c *gin.Context) {
 		"message": "Admin order statistics retrieved successfully",
 	})
 }
+
+// orderError answers 400 for orders without a shipping address and 404 for
+// unknown products and addresses, returning true when err was one of them
+func orderError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrShippingAddressRequired):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrAddressNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"errors"
+	"literally-backend/pkg/pagination"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listParams parses the paging, sorting and filter parameters of a list
+// request. It answers 400 and returns false when they are invalid.
+func listParams(c *gin.Context, spec pagination.Spec) (pagination.Params, bool) {
+	params, err := pagination.Parse(c.Request.URL.Query(), spec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return params, false
+	}
+	return params, true
+}
+
+// listError answers a failed list query: 400 for a bad cursor, 500 otherwise
+func listError(c *gin.Context, err error, message string) {
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": message,
+	})
+}
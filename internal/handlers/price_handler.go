@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPriceHistory godoc
+// @Summary Get product price history (admin)
+// @Description Get every price change of a product and its variants with the previous price, the compare-at price and the source (MANUAL, IMPORT, VARIANT or SCHEDULE)
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param variant_id query string false "Filter by variant IDs, comma separated"
+// @Param source query string false "Filter by sources, comma separated"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (created_at, price; default: -created_at)"
+// @Success 200 {object} map[string]interface{} "Price history retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid product ID, sort or cursor"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Router /admin/products/{id}/price-history [get]
+func GetPriceHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	if _, found := services.GetProductByID(uint(id)); !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	params, ok := listParams(c, services.PriceHistoryListSpec)
+	if !ok {
+		return
+	}
+
+	history, page, err := services.GetPriceHistory(uint(id), params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve price history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       history,
+		"pagination": page,
+		"message":    "Price history retrieved successfully",
+	})
+}
+
+// GetPriceSchedules godoc
+// @Summary Get product price schedules (admin)
+// @Description Get the scheduled, running and past price changes of a product
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Success 200 {object} map[string]interface{} "Price schedules retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid product ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Router /admin/products/{id}/price-schedules [get]
+func GetPriceSchedules(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	schedules, err := services.GetPriceSchedules(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    schedules,
+		"message": "Price schedules retrieved successfully",
+	})
+}
+
+// CreatePriceSchedule godoc
+// @Summary Schedule a price change (admin)
+// @Description Schedule a new price, and optionally compare-at price, for a product or one of its variants from effective_from until effective_to. The previous prices are restored when the schedule ends; without effective_to the change is permanent. Schedules of the same product or variant may not overlap.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param schedule body models.CreatePriceScheduleRequest true "Price schedule"
+// @Success 201 {object} map[string]interface{} "Price change scheduled successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input or overlapping schedule"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/products/{id}/price-schedules [post]
+func CreatePriceSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req models.CreatePriceScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	schedule, err := services.CreatePriceSchedule(uint(id), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    schedule,
+		"message": "Price change scheduled successfully",
+	})
+}
+
+// CancelPriceSchedule godoc
+// @Summary Cancel a price schedule (admin)
+// @Description Cancel a pending or running price schedule. Cancelling a running schedule restores the previous prices.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param schedule_id path int true "Price schedule ID"
+// @Success 200 {object} map[string]interface{} "Price schedule cancelled successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Unknown schedule or schedule already ended"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/products/{id}/price-schedules/{schedule_id} [delete]
+func CancelPriceSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	scheduleID, err := strconv.ParseUint(c.Param("schedule_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid schedule ID",
+		})
+		return
+	}
+
+	schedule, err := services.CancelPriceSchedule(uint(id), uint(scheduleID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    schedule,
+		"message": "Price schedule cancelled successfully",
+	})
+}
@@ -1,92 +1,263 @@
 package handlers
 
 import (
+	"errors"
 	"literally-backend/internal/models"
 	"literally-backend/internal/services"
+	"literally-backend/pkg/pagination"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GetProducts godoc
 // @Summary Get products
-// @Description Get a list of products with optional filtering by category, featured status, or search query
+// @Description Get a list of products filtered by category, featured status, search query, brand, price range and attributes. The response includes facet counts over the matching products for filter sidebars.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param category_id query string false "Filter by category ID"
+// @Param include_descendants query bool false "Include products of subcategories when filtering by category"
 // @Param featured query string false "Filter featured products (true/false)"
 // @Param search query string false "Search products by name or description"
+// @Param brand query string false "Filter by brands, comma separated"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param attr.code query string false "Attribute filter by code, e.g. attr.ram=8GB,16GB, attr.nfc=true or attr.screen_size=6..7"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (name, price, rating, stock, created_at; default: -created_at)"
 // @Success 200 {object} map[string]interface{} "Products retrieved successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request - Invalid category ID"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid filter"
 // @Router /products [get]
 func GetProducts(c *gin.Context) {
-	// Check for query parameters
-	categoryID := c.Query("category_id")
-	featured := c.Query("featured")
-	search := c.Query("search")
-
-	var products []models.Product
-
-	if search != "" {
-		// Search products
-		products = services.SearchProducts(search)
-	} else if categoryID != "" {
-		// Get products by category
+	params, ok := listParams(c, services.ProductListSpec)
+	if !ok {
+		return
+	}
+
+	filter, err := productFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	products, facets, page, err := services.FilterProducts(filter, params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       products,
+		"facets":     facets,
+		"pagination": page,
+		"message":    "Products retrieved successfully",
+	})
+}
+
+// productFilterFromQuery reads the product filter parameters shared by the
+// product list and the export
+func productFilterFromQuery(c *gin.Context) (models.ProductFilter, error) {
+	filter := models.ProductFilter{
+		Featured:           c.Query("featured") == "true",
+		Search:             c.Query("search"),
+		IncludeDescendants: c.Query("include_descendants") == "true",
+		Attributes:         make(map[string]string),
+	}
+
+	if categoryID := c.Query("category_id"); categoryID != "" {
 		catID, err := strconv.ParseUint(categoryID, 10, 32)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid category ID",
-			})
-			return
+			return filter, errors.New("Invalid category ID")
 		}
-		products = services.GetProductsByCategory(uint(catID))
-	} else if featured == "true" {
-		// Get featured products
-		products = services.GetFeaturedProducts()
-	} else {
-		// Get all products
-		products = services.GetAllProducts()
+		filter.CategoryID = uint(catID)
+	}
+
+	if brand := c.Query("brand"); brand != "" {
+		filter.Brands = strings.Split(brand, ",")
+	}
+
+	var err error
+	if filter.MinPrice, err = priceQuery(c, "min_price"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = priceQuery(c, "max_price"); err != nil {
+		return filter, err
+	}
+
+	for key, values := range c.Request.URL.Query() {
+		if code, ok := strings.CutPrefix(key, "attr."); ok && code != "" && len(values) > 0 {
+			filter.Attributes[code] = values[0]
+		}
+	}
+	return filter, nil
+}
+
+// priceQuery parses an optional price query parameter
+func priceQuery(c *gin.Context, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || price < 0 {
+		return nil, errors.New("invalid " + name)
+	}
+	return &price, nil
+}
+
+// GetProductsAdmin godoc
+// @Summary Get all products (admin)
+// @Description Get all products including unavailable ones, optionally with soft-deleted products (admin only)
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param deleted query string false "Show deleted products (include, only)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (name, price, rating, stock, created_at; default: -created_at)"
+// @Success 200 {object} map[string]interface{} "Products retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid deleted filter"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/products [get]
+func GetProductsAdmin(c *gin.Context) {
+	params, ok := listParams(c, services.ProductListSpec)
+	if !ok {
+		return
+	}
+
+	products, page, err := services.GetAllProductsAdmin(c.Query("deleted"), params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":    products,
-		"message": "Products retrieved successfully",
+		"data":       products,
+		"pagination": page,
+		"message":    "Products retrieved successfully",
 	})
 }
 
 // GetFeaturedProducts godoc
 // @Summary Get featured products
-// @Description Get a list of featured products
+// @Description Get a page of featured products
 // @Tags products
 // @Accept json
 // @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (name, price, rating, stock, created_at; default: -created_at)"
 // @Success 200 {object} map[string]interface{} "Featured products retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid sort or cursor"
 // @Router /products/featured [get]
 func GetFeaturedProducts(c *gin.Context) {
-	products := services.GetFeaturedProducts()
+	params, ok := listParams(c, services.ProductListSpec)
+	if !ok {
+		return
+	}
+
+	products, page, err := services.GetFeaturedProducts(params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve featured products")
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":    products,
-		"message": "Featured products retrieved successfully",
+		"data":       products,
+		"pagination": page,
+		"message":    "Featured products retrieved successfully",
 	})
 }
 
 // GetCategories godoc
 // @Summary Get all categories
-// @Description Get a list of all product categories
+// @Description Get the category tree: top-level categories with subcategories nested in children. Use flat=true for a paged flat list.
 // @Tags categories
 // @Accept json
 // @Produce json
+// @Param flat query bool false "Return a paged flat list instead of the tree"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (name, sort_order, created_at; default: sort_order,name)"
 // @Success 200 {object} map[string]interface{} "Categories retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid sort or cursor"
 // @Router /categories [get]
 func GetCategories(c *gin.Context) {
-	categories := services.GetAllCategories()
+	if c.Query("flat") != "true" {
+		c.JSON(http.StatusOK, gin.H{
+			"data":    services.GetCategoryTree(),
+			"message": "Categories retrieved successfully",
+		})
+		return
+	}
+
+	params, ok := listParams(c, services.CategoryListSpec)
+	if !ok {
+		return
+	}
+
+	categories, page, err := services.ListCategories(params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve categories")
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":    categories,
-		"message": "Categories retrieved successfully",
+		"data":       categories,
+		"pagination": page,
+		"message":    "Categories retrieved successfully",
+	})
+}
+
+// GetCategoriesAdmin godoc
+// @Summary Get all categories (admin)
+// @Description Get all categories, optionally with soft-deleted categories (admin only)
+// @Tags admin-categories
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param deleted query string false "Show deleted categories (include, only)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (name, sort_order, created_at; default: sort_order,name)"
+// @Success 200 {object} map[string]interface{} "Categories retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid deleted filter"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/categories [get]
+func GetCategoriesAdmin(c *gin.Context) {
+	params, ok := listParams(c, services.CategoryListSpec)
+	if !ok {
+		return
+	}
+
+	categories, page, err := services.GetAllCategoriesAdmin(c.Query("deleted"), params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       categories,
+		"pagination": page,
+		"message":    "Categories retrieved successfully",
 	})
 }
 
@@ -97,7 +268,9 @@ func GetCategories(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Category ID"
+// @Param If-None-Match header string false "ETag from an earlier response"
 // @Success 200 {object} map[string]interface{} "Category retrieved successfully"
+// @Success 304 {string} string "Not modified"
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid category ID"
 // @Failure 404 {object} map[string]interface{} "Category not found"
 // @Router /categories/{id} [get]
@@ -119,6 +292,10 @@ func GetCategoryByID(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, category.Version) {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data":    category,
 		"message": "Category retrieved successfully",
@@ -127,11 +304,16 @@ func GetCategoryByID(c *gin.Context) {
 
 // GetProductsByCategory godoc
 // @Summary Get products by category
-// @Description Get a list of products in a specific category
+// @Description Get a list of products in a specific category, optionally including its subcategories
 // @Tags categories
 // @Accept json
 // @Produce json
 // @Param id path int true "Category ID"
+// @Param include_descendants query bool false "Include products of all subcategories"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (name, price, rating, stock, created_at; default: -created_at)"
 // @Success 200 {object} map[string]interface{} "Products retrieved successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid category ID"
 // @Failure 404 {object} map[string]interface{} "Category not found"
@@ -155,23 +337,37 @@ func GetProductsByCategory(c *gin.Context) {
 		return
 	}
 
-	products := services.GetProductsByCategory(uint(categoryID))
+	params, ok := listParams(c, services.ProductListSpec)
+	if !ok {
+		return
+	}
+
+	includeDescendants := c.Query("include_descendants") == "true"
+	products, page, err := services.GetProductsByCategory(uint(categoryID), includeDescendants, params)
+	if err != nil {
+		listError(c, err, "Failed to retrieve products")
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":    products,
-		"message": "Products retrieved successfully",
+		"data":       products,
+		"pagination": page,
+		"message":    "Products retrieved successfully",
 	})
 }
 
 // SearchProducts godoc
 // @Summary Search products
-// @Description Search for products by name or description
+// @Description Full-text product search ranked by relevance (name > brand > description). Accents are ignored, typos are tolerated and matches are highlighted with <mark> tags. did_you_mean suggests a corrected query when nothing matched as typed.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param q query string true "Search query"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
 // @Success 200 {object} map[string]interface{} "Search results retrieved successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request - Search query is required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /products/search [get]
 func SearchProducts(c *gin.Context) {
 	query := c.Query("q")
@@ -182,17 +378,38 @@ func SearchProducts(c *gin.Context) {
 		return
 	}
 
-	products := services.SearchProducts(query)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	search := models.SearchQuery{
+		Query: query,
+		Page:  page,
+		Limit: limit,
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		id := userID.(uint)
+		search.UserID = &id
+	}
+
+	result, err := services.SearchProducts(search)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to search products",
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":    products,
-		"message": "Search results retrieved successfully",
+		"data":         result.Hits,
+		"did_you_mean": result.DidYouMean,
+		"pagination":   pagination.NewOffsetPage(result.Page, result.Limit, result.Total),
+		"message":      "Search results retrieved successfully",
 	})
 }
 
 // GetProductByID godoc
 // @Summary Get product by ID
-// @Description Get a specific product by its ID
+// @Description Get a specific product by its ID. Responses carry the product version as ETag for edits. Stock and flash sales change without a new version, so the ETag is not answered with 304.
 // @Tags products
 // @Accept json
 // @Produce json
@@ -219,6 +436,9 @@ func GetProductByID(c *gin.Context) {
 		return
 	}
 
+	// Orders and flash sales change the response without a version bump, so
+	// the version only serves as the If-Match of an edit and never as a 304
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"data":    product,
 		"message": "Product retrieved successfully",
@@ -238,15 +458,20 @@ func GetProductByID(c *gin.Context) {
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Router /products [post]
 func CreateProduct(c *gin.Context) {
-	var req models.CreateProductRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
+	adminID, exists := c.Get("admin_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin not authenticated",
 		})
 		return
 	}
 
-	product, err := services.CreateProduct(req)
+	var req models.CreateProductRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	product, err := services.CreateProduct(adminID.(uint), req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -254,6 +479,7 @@ func CreateProduct(c *gin.Context) {
 		return
 	}
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusCreated, gin.H{
 		"data":    product,
 		"message": "Product created successfully",
@@ -261,20 +487,31 @@ func CreateProduct(c *gin.Context) {
 }
 
 // UpdateProduct godoc
-// @Summary Update product by ID
-// @Description Update a specific product by its ID (admin only)
+// @Summary Partially update product by ID
+// @Description Change only the fields present in the body (admin only). Zero values such as a stock of 0, is_featured false or is_available false are applied; absent or null fields are left alone.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param id path int true "Product ID"
-// @Param product body object true "Updated product data"
+// @Param product body models.UpdateProductRequest true "Fields to change"
+// @Param If-Match header string false "ETag of the version being edited; required unless version is in the body"
 // @Success 200 {object} map[string]interface{} "Product updated successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, with per-field errors in fields"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "Product not found"
-// @Router /products/{id} [put]
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
+// @Router /admin/products/{id} [patch]
 func UpdateProduct(c *gin.Context) {
+	adminID, exists := c.Get("admin_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin not authenticated",
+		})
+		return
+	}
+
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
@@ -285,21 +522,83 @@ func UpdateProduct(c *gin.Context) {
 	}
 
 	var req models.UpdateProductRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	version, ok := editVersion(c, req.Version, true)
+	if !ok {
+		return
+	}
+
+	product, err := services.UpdateProduct(adminID.(uint), uint(id), version, req)
+	if err != nil {
+		if !versionConflict(c, err) {
+			badRequest(c, err)
+		}
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"data":    product,
+		"message": "Product updated successfully",
+	})
+}
+
+// ReplaceProduct godoc
+// @Summary Replace product by ID
+// @Description Replace all editable fields of a product (admin only). name, price, stock, category_id and is_available are required; omitted optional fields are cleared.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param product body models.ReplaceProductRequest true "Complete product data"
+// @Param If-Match header string false "ETag of the version being edited; required unless version is in the body"
+// @Success 200 {object} map[string]interface{} "Product updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, with per-field errors in fields"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
+// @Router /admin/products/{id} [put]
+func ReplaceProduct(c *gin.Context) {
+	adminID, exists := c.Get("admin_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin not authenticated",
 		})
 		return
 	}
 
-	product, err := services.UpdateProduct(uint(id), req)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
+			"error": "Invalid product ID",
 		})
 		return
 	}
 
+	var req models.ReplaceProductRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	version, ok := editVersion(c, req.Version, true)
+	if !ok {
+		return
+	}
+
+	product, err := services.ReplaceProduct(adminID.(uint), uint(id), version, req)
+	if err != nil {
+		if !versionConflict(c, err) {
+			badRequest(c, err)
+		}
+		return
+	}
+
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"data":    product,
 		"message": "Product updated successfully",
@@ -314,10 +613,13 @@ func UpdateProduct(c *gin.Context) {
 // @Produce json
 // @Security Bearer
 // @Param id path int true "Product ID"
+// @Param If-Match header string true "ETag of the version being deleted"
 // @Success 200 {object} map[string]interface{} "Product deleted successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid product ID"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
 // @Router /products/{id} [delete]
 func DeleteProduct(c *gin.Context) {
 	idParam := c.Param("id")
@@ -329,8 +631,16 @@ func DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	err = services.DeleteProduct(uint(id))
+	version, ok := editVersion(c, nil, true)
+	if !ok {
+		return
+	}
+
+	err = services.DeleteProduct(uint(id), version)
 	if err != nil {
+		if versionConflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
 		})
@@ -342,6 +652,44 @@ func DeleteProduct(c *gin.Context) {
 	})
 }
 
+// RestoreProduct godoc
+// @Summary Restore deleted product
+// @Description Restore a soft-deleted product (admin only)
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Success 200 {object} map[string]interface{} "Product restored successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid product ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Deleted product not found"
+// @Router /admin/products/{id}/restore [post]
+func RestoreProduct(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	if err := services.RestoreProduct(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	product, _ := services.GetProductByID(uint(id))
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    product,
+		"message": "Product restored successfully",
+	})
+}
+
 // CreateCategory godoc
 // @Summary Create a new category
 // @Description Create a new product category (admin only)
@@ -356,10 +704,7 @@ func DeleteProduct(c *gin.Context) {
 // @Router /categories [post]
 func CreateCategory(c *gin.Context) {
 	var req models.CreateCategoryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -371,6 +716,7 @@ func CreateCategory(c *gin.Context) {
 		return
 	}
 
+	setETag(c, category.Version)
 	c.JSON(http.StatusCreated, gin.H{
 		"data":    category,
 		"message": "Category created successfully",
@@ -378,19 +724,22 @@ func CreateCategory(c *gin.Context) {
 }
 
 // UpdateCategory godoc
-// @Summary Update category by ID
-// @Description Update a specific category by its ID (admin only)
+// @Summary Partially update category by ID
+// @Description Change only the fields present in the body (admin only). An empty slug is derived from the name.
 // @Tags categories
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param id path int true "Category ID"
-// @Param category body models.UpdateCategoryRequest true "Updated category data"
+// @Param category body models.UpdateCategoryRequest true "Fields to change"
+// @Param If-Match header string false "ETag of the version being edited; required unless version is in the body"
 // @Success 200 {object} map[string]interface{} "Category updated successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, with per-field errors in fields"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "Category not found"
-// @Router /categories/{id} [put]
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
+// @Router /admin/categories/{id} [patch]
 func UpdateCategory(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
@@ -402,21 +751,75 @@ func UpdateCategory(c *gin.Context) {
 	}
 
 	var req models.UpdateCategoryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	version, ok := editVersion(c, req.Version, true)
+	if !ok {
 		return
 	}
 
-	category, err := services.UpdateCategory(uint(id), req)
+	category, err := services.UpdateCategory(uint(id), version, req)
+	if err != nil {
+		if !versionConflict(c, err) {
+			badRequest(c, err)
+		}
+		return
+	}
+
+	setETag(c, category.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"data":    category,
+		"message": "Category updated successfully",
+	})
+}
+
+// ReplaceCategory godoc
+// @Summary Replace category by ID
+// @Description Replace the name, slug, icon and sort order of a category (admin only); omitted optional fields are cleared and a missing slug is derived from the name. Use the move endpoint to change the parent.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Category ID"
+// @Param category body models.ReplaceCategoryRequest true "Complete category data"
+// @Param If-Match header string false "ETag of the version being edited; required unless version is in the body"
+// @Success 200 {object} map[string]interface{} "Category updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, with per-field errors in fields"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
+// @Router /admin/categories/{id} [put]
+func ReplaceCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
+			"error": "Invalid category ID",
 		})
 		return
 	}
 
+	var req models.ReplaceCategoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	version, ok := editVersion(c, req.Version, true)
+	if !ok {
+		return
+	}
+
+	category, err := services.ReplaceCategory(uint(id), version, req)
+	if err != nil {
+		if !versionConflict(c, err) {
+			badRequest(c, err)
+		}
+		return
+	}
+
+	setETag(c, category.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"data":    category,
 		"message": "Category updated successfully",
@@ -425,16 +828,20 @@ func UpdateCategory(c *gin.Context) {
 
 // DeleteCategory godoc
 // @Summary Delete category by ID
-// @Description Delete a specific category by its ID (admin only)
+// @Description Delete a specific category by its ID (admin only). Categories with products or subcategories are refused unless reassign=parent moves them to the parent category.
 // @Tags categories
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param id path int true "Category ID"
+// @Param reassign query string false "Set to parent to move products and subcategories to the parent category, dropping the product values of the category's attributes"
+// @Param If-Match header string true "ETag of the version being deleted"
 // @Success 200 {object} map[string]interface{} "Category deleted successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request - Invalid category ID"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid category ID, or products or subcategories that cannot be moved"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
 // @Router /categories/{id} [delete]
 func DeleteCategory(c *gin.Context) {
 	idParam := c.Param("id")
@@ -446,8 +853,24 @@ func DeleteCategory(c *gin.Context) {
 		return
 	}
 
-	err = services.DeleteCategory(uint(id))
+	reassign := c.Query("reassign")
+	if reassign != "" && reassign != "parent" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid reassign option, use parent",
+		})
+		return
+	}
+
+	version, ok := editVersion(c, nil, true)
+	if !ok {
+		return
+	}
+
+	err = services.DeleteCategory(uint(id), version, reassign == "parent")
 	if err != nil {
+		if versionConflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -458,3 +881,95 @@ func DeleteCategory(c *gin.Context) {
 		"message": "Category deleted successfully",
 	})
 }
+
+// MoveCategory godoc
+// @Summary Move category (admin)
+// @Description Move a category and its subcategories under another parent, or to the top level with a null parent_id
+// @Tags admin-categories
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Category ID"
+// @Param move body models.MoveCategoryRequest true "New parent and sort order"
+// @Param If-Match header string false "ETag of the version being edited; required unless version is in the body"
+// @Success 200 {object} map[string]interface{} "Category moved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input or cycle"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
+// @Router /admin/categories/{id}/move [put]
+func MoveCategory(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	var req models.MoveCategoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	version, ok := editVersion(c, req.Version, true)
+	if !ok {
+		return
+	}
+
+	category, err := services.MoveCategory(uint(id), version, req)
+	if err != nil {
+		if versionConflict(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	setETag(c, category.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"data":    category,
+		"message": "Category moved successfully",
+	})
+}
+
+// RestoreCategory godoc
+// @Summary Restore deleted category
+// @Description Restore a soft-deleted category (admin only)
+// @Tags admin-categories
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Category ID"
+// @Success 200 {object} map[string]interface{} "Category restored successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid category ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Deleted category not found"
+// @Router /admin/categories/{id}/restore [post]
+func RestoreCategory(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	if err := services.RestoreCategory(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	category, _ := services.GetCategoryByID(uint(id))
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    category,
+		"message": "Category restored successfully",
+	})
+}
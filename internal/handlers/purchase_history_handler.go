@@ -18,7 +18,9 @@ import (
 // @Produce json
 // @Security Bearer
 // @Param page query int false "Page number (default: 1)"
-// @Param limit query int false "Items per page (default: 10)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (purchase_date, total_price, product_name; default: -purchase_date)"
 // @Param status query string false "Filter by order status"
 // @Param start_date query string false "Start date filter (YYYY-MM-DD)"
 // @Param end_date query string false "End date filter (YYYY-MM-DD)"
@@ -51,12 +53,9 @@ func GetUserPurchaseHistory(c *gin.Context) {
 		return
 	}
 
-	// Set default values for pagination
-	if filter.Page <= 0 {
-		filter.Page = 1
-	}
-	if filter.Limit <= 0 {
-		filter.Limit = 10
+	params, ok := listParams(c, services.PurchaseHistoryListSpec)
+	if !ok {
+		return
 	}
 
 	// Parse date filters if provided
@@ -72,29 +71,16 @@ func GetUserPurchaseHistory(c *gin.Context) {
 		}
 	}
 
-	purchases, total, err := services.GetUserPurchaseHistory(uint(userID), filter)
+	purchases, page, err := services.GetUserPurchaseHistory(uint(userID), filter, params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		listError(c, err, "Failed to retrieve purchase history")
 		return
 	}
 
-	// Calculate pagination info
-	totalPages := 0
-	if filter.Limit > 0 {
-		totalPages = int((total + int64(filter.Limit) - 1) / int64(filter.Limit))
-	}
-
 	c.JSON(http.StatusOK, gin.H{
-		"data": purchases,
-		"pagination": gin.H{
-			"current_page": filter.Page,
-			"total_pages":  totalPages,
-			"total_items":  total,
-			"per_page":     filter.Limit,
-		},
-		"message": "Purchase history retrieved successfully",
+		"data":       purchases,
+		"pagination": page,
+		"message":    "Purchase history retrieved successfully",
 	})
 }
 
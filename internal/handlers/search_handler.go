@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SuggestProducts godoc
+// @Summary Autocomplete suggestions
+// @Description Suggest products, brands, categories and earlier searches whose names start with the typed prefix, most popular first. Prefixes shorter than 2 characters return empty lists.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param q query string true "Typed prefix"
+// @Param limit query int false "Maximum product suggestions (default: 8, max: 20)"
+// @Success 200 {object} map[string]interface{} "Suggestions retrieved successfully"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /products/suggest [get]
+func SuggestProducts(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	suggestions, err := services.SuggestProducts(c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve suggestions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    suggestions,
+		"message": "Suggestions retrieved successfully",
+	})
+}
+
+// GetSearchQueryStats godoc
+// @Summary Top search queries (admin)
+// @Description Most frequent product searches with their average number of results
+// @Tags admin-search
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param days query int false "Look back this many days (default: 30)"
+// @Param limit query int false "Maximum queries (default: 50)"
+// @Success 200 {object} map[string]interface{} "Search queries retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/search/queries [get]
+func GetSearchQueryStats(c *gin.Context) {
+	searchQueryStats(c, false)
+}
+
+// GetZeroResultSearches godoc
+// @Summary Zero-result searches (admin)
+// @Description Most frequent product searches that found nothing, to spot missing products or synonyms
+// @Tags admin-search
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param days query int false "Look back this many days (default: 30)"
+// @Param limit query int false "Maximum queries (default: 50)"
+// @Success 200 {object} map[string]interface{} "Search queries retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/search/zero-results [get]
+func GetZeroResultSearches(c *gin.Context) {
+	searchQueryStats(c, true)
+}
+
+func searchQueryStats(c *gin.Context, zeroResultsOnly bool) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	stats, err := services.GetSearchQueryStats(days, limit, zeroResultsOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve search queries",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    stats,
+		"message": "Search queries retrieved successfully",
+	})
+}
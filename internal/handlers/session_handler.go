@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMySessions godoc
+// @Summary Get active sessions
+// @Description Get the authenticated user's active login sessions
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{} "Sessions retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /profile/sessions [get]
+func GetMySessions(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	sessions, err := services.GetUserSessions(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve sessions",
+		})
+		return
+	}
+
+	currentTokenID := c.GetString("token_id")
+	sessionResponses := []models.UserSessionResponse{}
+	for _, session := range sessions {
+		sessionResponses = append(sessionResponses, session.ToResponse(currentTokenID))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    sessionResponses,
+		"message": "Sessions retrieved successfully",
+	})
+}
+
+// RevokeMySession godoc
+// @Summary Revoke a session
+// @Description Sign out one of the authenticated user's sessions
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Session ID"
+// @Success 200 {object} map[string]interface{} "Session revoked successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid session ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Session not found"
+// @Router /profile/sessions/{id} [delete]
+func RevokeMySession(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid session ID",
+		})
+		return
+	}
+
+	if err := services.RevokeSession(userID.(uint), uint(sessionID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeMyOtherSessions godoc
+// @Summary Revoke all other sessions
+// @Description Sign out every session of the authenticated user except the current one
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{} "Other sessions revoked successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /profile/sessions [delete]
+func RevokeMyOtherSessions(c *gin.Context) {
+	// Extract user ID from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	revoked, err := services.RevokeOtherSessions(userID.(uint), c.GetString("token_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"revoked": revoked,
+		},
+		"message": "Other sessions revoked successfully",
+	})
+}
+
+// GetUserSessionsAdmin godoc
+// @Summary Get user sessions (admin)
+// @Description Get the active login sessions of a specific user (admin only)
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "Sessions retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid user ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/sessions [get]
+func GetUserSessionsAdmin(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	if _, found := services.GetUserByID(uint(userID)); !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+		return
+	}
+
+	sessions, err := services.GetUserSessions(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve sessions",
+		})
+		return
+	}
+
+	sessionResponses := []models.UserSessionResponse{}
+	for _, session := range sessions {
+		sessionResponses = append(sessionResponses, session.ToResponse(""))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    sessionResponses,
+		"message": "Sessions retrieved successfully",
+	})
+}
+
+// TerminateUserSessionAdmin godoc
+// @Summary Terminate a user session (admin)
+// @Description Terminate one login session of a specific user (admin only)
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "User ID"
+// @Param session_id path int true "Session ID"
+// @Success 200 {object} map[string]interface{} "Session terminated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Session not found"
+// @Router /admin/users/{id}/sessions/{session_id} [delete]
+func TerminateUserSessionAdmin(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("session_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid session ID",
+		})
+		return
+	}
+
+	if err := services.RevokeSession(uint(userID), uint(sessionID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session terminated successfully",
+	})
+}
+
+// TerminateUserSessionsAdmin godoc
+// @Summary Terminate all user sessions (admin)
+// @Description Terminate every login session of a specific user (admin only)
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "Sessions terminated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid user ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/users/{id}/sessions [delete]
+func TerminateUserSessionsAdmin(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	revoked, err := services.RevokeAllSessions(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to terminate sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"revoked": revoked,
+		},
+		"message": "Sessions terminated successfully",
+	})
+}
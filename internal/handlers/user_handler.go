@@ -28,7 +28,7 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := services.Register(req)
+	authResponse, err := services.Register(req, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -62,7 +62,7 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := services.Login(req)
+	authResponse, err := services.Login(req, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": err.Error(),
@@ -76,6 +76,14 @@ func Login(c *gin.Context) {
 	})
 }
 
+// clientInfo extracts the client details used to describe a login session
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
 // GetProfile godoc
 // @Summary Get user profile
 // @Description Get the authenticated user's profile information
@@ -83,7 +91,9 @@ func Login(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security Bearer
+// @Param If-None-Match header string false "ETag from an earlier response"
 // @Success 200 {object} map[string]interface{} "Profile retrieved successfully"
+// @Success 304 {string} string "Not modified"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "User not found"
 // @Router /profile [get]
@@ -105,6 +115,10 @@ func GetProfile(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, user.Version) {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data":    user.ToResponse(),
 		"message": "Profile retrieved successfully",
@@ -112,18 +126,20 @@ func GetProfile(c *gin.Context) {
 }
 
 // UpdateProfile godoc
-// @Summary Update user profile
-// @Description Update the authenticated user's profile information
+// @Summary Partially update user profile
+// @Description Change only the fields of the authenticated user's profile present in the body. Empty strings clear optional fields.
 // @Tags profile
 // @Accept json
 // @Produce json
 // @Security Bearer
-// @Param profile body object true "Updated profile data"
+// @Param profile body models.UpdateUserRequest true "Fields to change"
+// @Param If-Match header string false "ETag of the profile version being edited"
 // @Success 200 {object} map[string]interface{} "Profile updated successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, with per-field errors in fields"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "User not found"
-// @Router /profile [put]
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Router /profile [patch]
 func UpdateProfile(c *gin.Context) {
 	// Extract user ID from JWT token
 	userID, exists := c.Get("user_id")
@@ -135,21 +151,75 @@ func UpdateProfile(c *gin.Context) {
 	}
 
 	var req models.UpdateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// Profile edits are checked only when the client sends a version
+	version, ok := editVersion(c, req.Version, false)
+	if !ok {
 		return
 	}
 
-	user, err := services.UpdateUser(userID.(uint), req)
+	user, err := services.UpdateUser(userID.(uint), version, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
+		if !versionConflict(c, err) {
+			badRequest(c, err)
+		}
+		return
+	}
+
+	setETag(c, user.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user.ToResponse(),
+		"message": "Profile updated successfully",
+	})
+}
+
+// ReplaceProfile godoc
+// @Summary Replace user profile
+// @Description Replace the authenticated user's profile. name, email and phone_number are required; omitted optional fields are cleared. The photo is kept.
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param profile body models.ReplaceUserRequest true "Complete profile data"
+// @Param If-Match header string false "ETag of the profile version being edited"
+// @Success 200 {object} map[string]interface{} "Profile updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, with per-field errors in fields"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Router /profile [put]
+func ReplaceProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
 		})
 		return
 	}
 
+	var req models.ReplaceUserRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// Profile edits are checked only when the client sends a version
+	version, ok := editVersion(c, req.Version, false)
+	if !ok {
+		return
+	}
+
+	user, err := services.ReplaceUser(userID.(uint), version, req)
+	if err != nil {
+		if !versionConflict(c, err) {
+			badRequest(c, err)
+		}
+		return
+	}
+
+	setETag(c, user.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"data":    user.ToResponse(),
 		"message": "Profile updated successfully",
@@ -198,25 +268,45 @@ func RefreshToken(c *gin.Context) {
 
 // GetUsers godoc
 // @Summary Get all users
-// @Description Get a list of all users (admin only)
+// @Description Get a page of users (admin only)
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security Bearer
+// @Param deleted query string false "Show deleted users (include, only)"
+// @Param status query string false "Filter by status, comma separated"
+// @Param gender query string false "Filter by gender, comma separated"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor; pass an empty cursor for the first page"
+// @Param sort query string false "Sort fields, comma separated, - for descending (name, email, created_at; default: -created_at)"
 // @Success 200 {object} map[string]interface{} "Users retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid deleted filter"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Router /users [get]
 func GetUsers(c *gin.Context) {
-	users := services.GetAllUsers()
+	params, ok := listParams(c, services.UserListSpec)
+	if !ok {
+		return
+	}
+
+	users, page, err := services.GetAllUsersAdmin(c.Query("deleted"), params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-	var userResponses []models.UserResponse
+	userResponses := []models.UserResponse{}
 	for _, user := range users {
 		userResponses = append(userResponses, user.ToResponse())
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":    userResponses,
-		"message": "Users retrieved successfully",
+		"data":       userResponses,
+		"pagination": page,
+		"message":    "Users retrieved successfully",
 	})
 }
 
@@ -228,7 +318,9 @@ func GetUsers(c *gin.Context) {
 // @Produce json
 // @Security Bearer
 // @Param id path int true "User ID"
+// @Param If-None-Match header string false "ETag from an earlier response"
 // @Success 200 {object} map[string]interface{} "User retrieved successfully"
+// @Success 304 {string} string "Not modified"
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid user ID"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "User not found"
@@ -251,6 +343,10 @@ func GetUserByID(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, user.Version) {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data":    user.ToResponse(),
 		"message": "User retrieved successfully",
@@ -286,6 +382,7 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
+	setETag(c, user.Version)
 	c.JSON(http.StatusCreated, gin.H{
 		"data":    user.ToResponse(),
 		"message": "User created successfully",
@@ -293,19 +390,22 @@ func CreateUser(c *gin.Context) {
 }
 
 // UpdateUser godoc
-// @Summary Update user by ID
-// @Description Update a specific user by their ID (admin only)
+// @Summary Partially update user by ID
+// @Description Change only the fields of a user present in the body (admin only). Empty strings clear optional fields.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param id path int true "User ID"
-// @Param user body object true "Updated user data"
+// @Param user body models.UpdateUserRequest true "Fields to change"
+// @Param If-Match header string false "ETag of the version being edited; required unless version is in the body"
 // @Success 200 {object} map[string]interface{} "User updated successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, with per-field errors in fields"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "User not found"
-// @Router /users/{id} [put]
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
+// @Router /users/{id} [patch]
 func UpdateUser(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
@@ -317,21 +417,75 @@ func UpdateUser(c *gin.Context) {
 	}
 
 	var req models.UpdateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	version, ok := editVersion(c, req.Version, true)
+	if !ok {
+		return
+	}
+
+	user, err := services.UpdateUser(uint(id), version, req)
+	if err != nil {
+		if !versionConflict(c, err) {
+			badRequest(c, err)
+		}
 		return
 	}
 
-	user, err := services.UpdateUser(uint(id), req)
+	setETag(c, user.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user.ToResponse(),
+		"message": "User updated successfully",
+	})
+}
+
+// ReplaceUser godoc
+// @Summary Replace user by ID
+// @Description Replace the editable fields of a user (admin only). name, email and phone_number are required; omitted optional fields are cleared. The photo is kept.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "User ID"
+// @Param user body models.ReplaceUserRequest true "Complete user data"
+// @Param If-Match header string false "ETag of the version being edited; required unless version is in the body"
+// @Success 200 {object} map[string]interface{} "User updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input, with per-field errors in fields"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
+// @Router /users/{id} [put]
+func ReplaceUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
+			"error": "Invalid user ID",
 		})
 		return
 	}
 
+	var req models.ReplaceUserRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	version, ok := editVersion(c, req.Version, true)
+	if !ok {
+		return
+	}
+
+	user, err := services.ReplaceUser(uint(id), version, req)
+	if err != nil {
+		if !versionConflict(c, err) {
+			badRequest(c, err)
+		}
+		return
+	}
+
+	setETag(c, user.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"data":    user.ToResponse(),
 		"message": "User updated successfully",
@@ -346,10 +500,13 @@ func UpdateUser(c *gin.Context) {
 // @Produce json
 // @Security Bearer
 // @Param id path int true "User ID"
+// @Param If-Match header string true "ETag of the version being deleted"
 // @Success 200 {object} map[string]interface{} "User deleted successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid user ID"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
 // @Router /users/{id} [delete]
 func DeleteUser(c *gin.Context) {
 	idParam := c.Param("id")
@@ -361,8 +518,16 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err = services.DeleteUser(uint(id))
+	version, ok := editVersion(c, nil, true)
+	if !ok {
+		return
+	}
+
+	err = services.DeleteUser(uint(id), version)
 	if err != nil {
+		if versionConflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
 		})
@@ -374,6 +539,44 @@ func DeleteUser(c *gin.Context) {
 	})
 }
 
+// RestoreUser godoc
+// @Summary Restore deleted user
+// @Description Restore a soft-deleted user (admin only)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "User restored successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid user ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Deleted user not found"
+// @Router /users/{id}/restore [post]
+func RestoreUser(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	if err := services.RestoreUser(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	user, _ := services.GetUserByID(uint(id))
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user.ToResponse(),
+		"message": "User restored successfully",
+	})
+}
+
 // UpdateUserStatus godoc
 // @Summary Update user status
 // @Description Update user status (ACTIVE/INACTIVE/SUSPENDED) by ID (admin only)
@@ -383,10 +586,13 @@ func DeleteUser(c *gin.Context) {
 // @Security Bearer
 // @Param id path int true "User ID"
 // @Param status body models.UpdateUserStatusRequest true "User status update data"
+// @Param If-Match header string false "ETag of the version being edited; required unless version is in the body"
 // @Success 200 {object} map[string]interface{} "User status updated successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 412 {object} map[string]interface{} "Precondition failed - Modified since retrieved"
+// @Failure 428 {object} map[string]interface{} "Precondition required - Missing If-Match"
 // @Router /users/{id}/status [put]
 func UpdateUserStatus(c *gin.Context) {
 	idParam := c.Param("id")
@@ -406,15 +612,27 @@ func UpdateUserStatus(c *gin.Context) {
 		return
 	}
 
-	err = services.UpdateUserStatus(uint(id), req.Status)
+	version, ok := editVersion(c, req.Version, true)
+	if !ok {
+		return
+	}
+
+	err = services.UpdateUserStatus(uint(id), version, req.Status)
 	if err != nil {
+		if versionConflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
+	user, _ := services.GetUserByID(uint(id))
+
+	setETag(c, user.Version)
 	c.JSON(http.StatusOK, gin.H{
+		"data":    user.ToResponse(),
 		"message": "User status updated successfully",
 	})
 }
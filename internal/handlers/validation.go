@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"literally-backend/internal/models"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report validation errors by JSON field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// bindJSON binds the request body into req. It answers 400 with field-level
+// errors and returns false when the body is invalid.
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		badRequest(c, err)
+		return false
+	}
+	return true
+}
+
+// badRequest answers 400 for err, listing the invalid fields when err is a
+// validation error:
+//
+//	{"error": "Validation failed", "fields": {"price": "must be at least 0"}}
+func badRequest(c *gin.Context, err error) {
+	if fields := fieldErrors(err); len(fields) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Validation failed",
+			"fields": fields,
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": err.Error(),
+	})
+}
+
+// fieldErrors extracts per-field messages from binding and service errors
+func fieldErrors(err error) models.FieldErrors {
+	var fields models.FieldErrors
+	if errors.As(err, &fields) {
+		return fields
+	}
+
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		fields = make(models.FieldErrors, len(invalid))
+		for _, fe := range invalid {
+			fields[fe.Field()] = validationMessage(fe)
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return models.FieldErrors{typeErr.Field: "must be " + jsonTypeName(typeErr.Type)}
+	}
+
+	return nil
+}
+
+// validationMessage describes a failed validation rule
+func validationMessage(fe validator.FieldError) string {
+	text := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min":
+		if text && fe.Param() == "1" {
+			return "must not be empty"
+		}
+		if text {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if text {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	default:
+		return "is invalid (" + fe.Tag() + ")"
+	}
+}
+
+// jsonTypeName names the JSON type a Go type is decoded from
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProductVariants godoc
+// @Summary Get product variants
+// @Description Get all variants (color, RAM/storage combinations) of a product with their own price and stock
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} map[string]interface{} "Variants retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid product ID"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Router /products/{id}/variants [get]
+func GetProductVariants(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	if _, found := services.GetProductByID(uint(id)); !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    services.GetProductVariants(uint(id)),
+		"message": "Variants retrieved successfully",
+	})
+}
+
+// CreateProductVariant godoc
+// @Summary Create product variant (admin)
+// @Description Add a variant to a product. The product price becomes the lowest variant price and its stock the sum of variant stock.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param variant body models.CreateVariantRequest true "Variant creation data"
+// @Success 201 {object} map[string]interface{} "Variant created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/products/{id}/variants [post]
+func CreateProductVariant(c *gin.Context) {
+	adminID, exists := c.Get("admin_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req models.CreateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	variant, err := services.CreateProductVariant(adminID.(uint), uint(id), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    variant,
+		"message": "Variant created successfully",
+	})
+}
+
+// UpdateProductVariant godoc
+// @Summary Update product variant (admin)
+// @Description Update the options, price, stock or availability of a product variant
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param variant_id path int true "Variant ID"
+// @Param variant body models.UpdateVariantRequest true "Variant update data"
+// @Success 200 {object} map[string]interface{} "Variant updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/products/{id}/variants/{variant_id} [put]
+func UpdateProductVariant(c *gin.Context) {
+	adminID, exists := c.Get("admin_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	variantID, err := strconv.ParseUint(c.Param("variant_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid variant ID",
+		})
+		return
+	}
+
+	var req models.UpdateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	variant, err := services.UpdateProductVariant(adminID.(uint), uint(id), uint(variantID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    variant,
+		"message": "Variant updated successfully",
+	})
+}
+
+// DeleteProductVariant godoc
+// @Summary Delete product variant (admin)
+// @Description Delete a product variant. Existing orders keep referencing it.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Param variant_id path int true "Variant ID"
+// @Success 200 {object} map[string]interface{} "Variant deleted successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid ID"
+// @Failure 404 {object} map[string]interface{} "Variant not found"
+// @Router /admin/products/{id}/variants/{variant_id} [delete]
+func DeleteProductVariant(c *gin.Context) {
+	adminID, exists := c.Get("admin_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Admin not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	variantID, err := strconv.ParseUint(c.Param("variant_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid variant ID",
+		})
+		return
+	}
+
+	if err := services.DeleteProductVariant(adminID.(uint), uint(id), uint(variantID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Variant deleted successfully",
+	})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"literally-backend/internal/models"
+	"literally-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWarehouses godoc
+// @Summary Get warehouses (admin)
+// @Description Get all warehouses, the default warehouse first
+// @Tags admin-warehouses
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{} "Warehouses retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/warehouses [get]
+func GetWarehouses(c *gin.Context) {
+	warehouses, err := services.GetWarehouses()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve warehouses",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    warehouses,
+		"message": "Warehouses retrieved successfully",
+	})
+}
+
+// CreateWarehouse godoc
+// @Summary Create warehouse (admin)
+// @Description Add a warehouse in a province and region (NORTH, CENTRAL or SOUTH). Orders are fulfilled from the warehouses nearest to the shipping province, lowest priority first at the same distance. The first warehouse, or one created with is_default, becomes the default warehouse, which receives stock not placed anywhere else.
+// @Tags admin-warehouses
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param warehouse body models.CreateWarehouseRequest true "Warehouse data"
+// @Success 201 {object} map[string]interface{} "Warehouse created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input or code taken"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /admin/warehouses [post]
+func CreateWarehouse(c *gin.Context) {
+	var req models.CreateWarehouseRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	warehouse, err := services.CreateWarehouse(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    warehouse,
+		"message": "Warehouse created successfully",
+	})
+}
+
+// UpdateWarehouse godoc
+// @Summary Update warehouse (admin)
+// @Description Change the fields of a warehouse present in the body. Setting is_default makes the warehouse the default one; the default cannot be unset directly.
+// @Tags admin-warehouses
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Warehouse ID"
+// @Param warehouse body models.UpdateWarehouseRequest true "Fields to change"
+// @Success 200 {object} map[string]interface{} "Warehouse updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Warehouse not found"
+// @Router /admin/warehouses/{id} [patch]
+func UpdateWarehouse(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid warehouse ID",
+		})
+		return
+	}
+
+	var req models.UpdateWarehouseRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	warehouse, err := services.UpdateWarehouse(uint(id), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrWarehouseNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    warehouse,
+		"message": "Warehouse updated successfully",
+	})
+}
+
+// GetProductWarehouseStock godoc
+// @Summary Get product stock by warehouse (admin)
+// @Description Get the stock a product, or each of its variants, holds in each warehouse
+// @Tags admin-warehouses
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Product ID"
+// @Success 200 {object} map[string]interface{} "Warehouse stock retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request - Invalid product ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Router /admin/products/{id}/warehouse-stock [get]
+func GetProductWarehouseStock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	if _, found := services.GetProductByID(uint(id)); !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	stocks, err := services.GetProductWarehouseStock(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve warehouse stock",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    stocks,
+		"message": "Warehouse stock retrieved successfully",
+	})
+}
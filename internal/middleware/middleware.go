@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"errors"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
 	"log"
 	"net/http"
 	"os"
@@ -16,6 +18,9 @@ import (
 type JWTClaims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
+	// Set on tokens issued to an admin impersonating the user
+	ImpersonatedBy *uint `json:"impersonated_by,omitempty"`
+	ReadOnly       bool  `json:"read_only,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -53,6 +58,37 @@ func validateToken(tokenString string) (*JWTClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// sessionTouchInterval limits how often a session's last-seen time is written
+const sessionTouchInterval = time.Minute
+
+// validateSession checks that the session behind a user token is still active
+// and refreshes its last-seen time
+func validateSession(claims *JWTClaims) error {
+	if claims.ID == "" {
+		return errors.New("token is not bound to a session")
+	}
+
+	var session models.UserSession
+	if err := configs.DB.Where("token_id = ? AND user_id = ?", claims.ID, claims.UserID).First(&session).Error; err != nil {
+		return errors.New("session not found")
+	}
+
+	if !session.IsActive() {
+		return errors.New("session has been revoked or expired")
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		configs.DB.Model(&session).Update("last_seen_at", time.Now())
+	}
+
+	return nil
+}
+
+// isReadOnlyMethod reports whether an HTTP method does not modify data
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
 // LoggingMiddleware logs HTTP requests
 func LoggingMiddleware() gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
@@ -73,8 +109,9 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-Match, If-None-Match")
+		c.Header("Access-Control-Expose-Headers", "ETag")
+		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -85,6 +122,16 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
+// StaticCacheMiddleware sets cache headers for uploaded files. Upload keys
+// are random and never overwritten, so files can be cached indefinitely.
+func StaticCacheMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Next()
+	}
+}
+
 // AuthMiddleware validates JWT tokens
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -127,9 +174,31 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Validate session
+		if err := validateSession(claims); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Session is no longer valid",
+			})
+			c.Abort()
+			return
+		}
+
+		// Read-only impersonation tokens may not modify data
+		if claims.ReadOnly && !isReadOnlyMethod(c.Request.Method) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Read-only impersonation session cannot modify data",
+			})
+			c.Abort()
+			return
+		}
+
 		// Store user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Set("token_id", claims.ID)
+		if claims.ImpersonatedBy != nil {
+			c.Set("impersonated_by", *claims.ImpersonatedBy)
+		}
 
 		c.Next()
 	}
@@ -158,16 +227,29 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Validate token
+		// Validate token and session
 		claims, err := validateToken(tokenString)
-		if err != nil {
+		if err != nil || validateSession(claims) != nil {
 			c.Next()
 			return
 		}
 
+		// Read-only impersonation tokens may not modify data
+		if claims.ReadOnly && !isReadOnlyMethod(c.Request.Method) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Read-only impersonation session cannot modify data",
+			})
+			c.Abort()
+			return
+		}
+
 		// Store user info in context if token is valid
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Set("token_id", claims.ID)
+		if claims.ImpersonatedBy != nil {
+			c.Set("impersonated_by", *claims.ImpersonatedBy)
+		}
 
 		c.Next()
 	}
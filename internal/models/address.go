@@ -0,0 +1,82 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// UserAddress represents an entry in a user's address book
+type UserAddress struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        uint      `json:"user_id" gorm:"not null;index"`
+	RecipientName string    `json:"recipient_name" gorm:"not null"`
+	PhoneNumber   string    `json:"phone_number" gorm:"not null"`
+	Province      string    `json:"province" gorm:"not null"`
+	District      string    `json:"district" gorm:"not null"`
+	Ward          string    `json:"ward"`
+	Street        string    `json:"street" gorm:"not null"`
+	IsDefault     bool      `json:"is_default" gorm:"default:false"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// AddressSnapshot is a copy of a structured address stored on orders and
+// purchase history so later address book edits do not rewrite history
+type AddressSnapshot struct {
+	RecipientName string `json:"recipient_name,omitempty"`
+	PhoneNumber   string `json:"phone_number,omitempty"`
+	Province      string `json:"province,omitempty"`
+	District      string `json:"district,omitempty"`
+	Ward          string `json:"ward,omitempty"`
+	Street        string `json:"street,omitempty"`
+}
+
+// CreateAddressRequest represents the request body for creating an address
+type CreateAddressRequest struct {
+	RecipientName string `json:"recipient_name" binding:"required"`
+	PhoneNumber   string `json:"phone_number" binding:"required"`
+	Province      string `json:"province" binding:"required"`
+	District      string `json:"district" binding:"required"`
+	Ward          string `json:"ward"`
+	Street        string `json:"street" binding:"required"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+// UpdateAddressRequest represents the request body for updating an address
+type UpdateAddressRequest struct {
+	RecipientName string `json:"recipient_name,omitempty"`
+	PhoneNumber   string `json:"phone_number,omitempty"`
+	Province      string `json:"province,omitempty"`
+	District      string `json:"district,omitempty"`
+	Ward          string `json:"ward,omitempty"`
+	Street        string `json:"street,omitempty"`
+}
+
+// Snapshot copies the address into an AddressSnapshot
+func (a *UserAddress) Snapshot() AddressSnapshot {
+	return AddressSnapshot{
+		RecipientName: a.RecipientName,
+		PhoneNumber:   a.PhoneNumber,
+		Province:      a.Province,
+		District:      a.District,
+		Ward:          a.Ward,
+		Street:        a.Street,
+	}
+}
+
+// FullAddress returns the address formatted on a single line
+func (a *UserAddress) FullAddress() string {
+	snapshot := a.Snapshot()
+	return snapshot.FullAddress()
+}
+
+// FullAddress returns the snapshot formatted on a single line
+func (s AddressSnapshot) FullAddress() string {
+	var parts []string
+	for _, part := range []string{s.Street, s.Ward, s.District, s.Province} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -38,3 +38,20 @@ type AdminLoginResponse struct {
 	Token string        `json:"token"`
 	Admin AdminResponse `json:"admin"`
 }
+
+// Admin notification types
+const (
+	AdminNotificationLowStock = "LOW_STOCK"
+)
+
+// AdminNotification is a notification shown to every admin, such as a
+// product running low on stock
+type AdminNotification struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Type      string    `json:"type" gorm:"not null;index"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	ProductID *uint     `json:"product_id,omitempty" gorm:"index"`
+	IsRead    bool      `json:"is_read" gorm:"default:false"`
+	CreatedAt time.Time `json:"created_at"`
+}
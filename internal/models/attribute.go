@@ -0,0 +1,115 @@
+package models
+
+import "time"
+
+// Attribute types
+const (
+	AttributeTypeEnum   = "ENUM"
+	AttributeTypeNumber = "NUMBER"
+	AttributeTypeBool   = "BOOL"
+)
+
+// CategoryAttribute defines a typed specification (e.g. RAM, screen size) for
+// the products of a category. Subcategories inherit their ancestors' attributes.
+// Code is the key used in filters and facets.
+type CategoryAttribute struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	CategoryID   uint      `json:"category_id" gorm:"not null;uniqueIndex:idx_category_attribute_code"`
+	Code         string    `json:"code" gorm:"not null;uniqueIndex:idx_category_attribute_code"`
+	Name         string    `json:"name" gorm:"not null"`
+	Type         string    `json:"type" gorm:"not null"`
+	Unit         string    `json:"unit,omitempty"`
+	Options      []string  `json:"options,omitempty" gorm:"serializer:json"`
+	IsFilterable bool      `json:"is_filterable" gorm:"default:true"`
+	SortOrder    int       `json:"sort_order" gorm:"default:0"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ProductAttributeValue holds a product's value for one attribute; only the
+// column matching the attribute type is set
+type ProductAttributeValue struct {
+	ID          uint     `json:"-" gorm:"primaryKey"`
+	ProductID   uint     `json:"-" gorm:"not null;uniqueIndex:idx_product_attribute"`
+	AttributeID uint     `json:"attribute_id" gorm:"not null;uniqueIndex:idx_product_attribute"`
+	ValueText   string   `json:"value_text,omitempty"`
+	ValueNumber *float64 `json:"value_number,omitempty"`
+	ValueBool   *bool    `json:"value_bool,omitempty"`
+
+	// Relationships
+	Attribute CategoryAttribute `json:"attribute" gorm:"foreignKey:AttributeID"`
+}
+
+// CreateAttributeRequest represents the request body for defining a category attribute
+type CreateAttributeRequest struct {
+	Code         string   `json:"code"`
+	Name         string   `json:"name" binding:"required"`
+	Type         string   `json:"type" binding:"required,oneof=ENUM NUMBER BOOL"`
+	Unit         string   `json:"unit"`
+	Options      []string `json:"options"`
+	IsFilterable *bool    `json:"is_filterable"`
+	SortOrder    int      `json:"sort_order"`
+}
+
+// UpdateAttributeRequest represents the request body for updating a category attribute.
+// Code and type cannot change once products use the attribute.
+type UpdateAttributeRequest struct {
+	Name         string   `json:"name,omitempty"`
+	Unit         string   `json:"unit,omitempty"`
+	Options      []string `json:"options,omitempty"`
+	IsFilterable *bool    `json:"is_filterable,omitempty"`
+	SortOrder    *int     `json:"sort_order,omitempty"`
+}
+
+// SetProductAttributesRequest sets attribute values by code, e.g.
+// {"values": {"ram": "8GB", "screen_size": 6.1, "nfc": true}}. A null value
+// removes the attribute from the product.
+type SetProductAttributesRequest struct {
+	Values map[string]interface{} `json:"values" binding:"required"`
+}
+
+// ProductFilter narrows the product listing
+type ProductFilter struct {
+	CategoryID         uint
+	IncludeDescendants bool
+	Featured           bool
+	Search             string
+	Brands             []string
+	MinPrice           *float64
+	MaxPrice           *float64
+	IncludeUnavailable bool // admin listings and exports
+
+	// Attribute filters keyed by code: "8GB,16GB" for enums, "true" for
+	// booleans, "6..7", "6.." or "6.1" for numbers
+	Attributes map[string]string
+}
+
+// FacetValue is one filter option with the number of matching products
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// RangeFacet is the value range of a numeric filter
+type RangeFacet struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// AttributeFacet summarizes one attribute over the result set: value counts
+// for enum and bool attributes, the value range for number attributes
+type AttributeFacet struct {
+	Code   string       `json:"code"`
+	Name   string       `json:"name"`
+	Type   string       `json:"type"`
+	Unit   string       `json:"unit,omitempty"`
+	Values []FacetValue `json:"values,omitempty"`
+	Range  *RangeFacet  `json:"range,omitempty"`
+}
+
+// ProductFacets are the filter sidebar counts for a product listing
+type ProductFacets struct {
+	Brands     []FacetValue     `json:"brands"`
+	Price      *RangeFacet      `json:"price,omitempty"`
+	Attributes []AttributeFacet `json:"attributes"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Bundle sells several products together, such as a phone with a case and a
+// charger, for a fixed Price or at DiscountPercent off the sum of its
+// components. In carts and orders a bundle is expanded into one line per
+// component, linked by the bundle ID, and the bundle discount is allocated
+// across those lines in proportion to their price.
+type Bundle struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	Name            string    `json:"name" gorm:"not null"`
+	Description     string    `json:"description"`
+	ImageUrl        string    `json:"image_url"`
+	Price           *float64  `json:"price"`
+	DiscountPercent float64   `json:"discount_percent" gorm:"default:0"`
+	IsActive        bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Sum of the component prices, the price the bundle sells for and the
+	// difference, filled in by the bundle service
+	RegularPrice float64 `json:"regular_price" gorm:"-"`
+	BundlePrice  float64 `json:"bundle_price" gorm:"-"`
+	Savings      float64 `json:"savings" gorm:"-"`
+
+	// Relationships
+	Items []BundleItem `json:"items,omitempty" gorm:"foreignKey:BundleID"`
+}
+
+// BundleItem is a quantity of a product, or of one of its variants, in a
+// bundle
+type BundleItem struct {
+	ID        uint  `json:"id" gorm:"primaryKey"`
+	BundleID  uint  `json:"bundle_id" gorm:"not null;index"`
+	ProductID uint  `json:"product_id" gorm:"not null"`
+	VariantID *uint `json:"variant_id,omitempty"`
+	Quantity  int   `json:"quantity" gorm:"not null"`
+
+	// Relationships
+	Product Product         `json:"product" gorm:"foreignKey:ProductID"`
+	Variant *ProductVariant `json:"variant,omitempty" gorm:"foreignKey:VariantID"`
+}
+
+// BundleRequest creates a bundle or replaces all its fields and items. A
+// fixed price takes precedence over the discount percent.
+type BundleRequest struct {
+	Name            string              `json:"name" binding:"required"`
+	Description     string              `json:"description"`
+	ImageUrl        string              `json:"image_url"`
+	Price           *float64            `json:"price" binding:"omitnil,gt=0"`
+	DiscountPercent float64             `json:"discount_percent" binding:"min=0,lt=100"`
+	IsActive        *bool               `json:"is_active"`
+	Items           []BundleItemRequest `json:"items" binding:"required,min=2,dive"`
+}
+
+// BundleItemRequest is one component of a bundle. VariantID is required for
+// products that have variants.
+type BundleItemRequest struct {
+	ProductID uint  `json:"product_id" binding:"required"`
+	VariantID *uint `json:"variant_id"`
+	Quantity  int   `json:"quantity" binding:"required,min=1"`
+}
+
+// AddBundleToCartRequest adds a bundle to the cart, one line per component
+type AddBundleToCartRequest struct {
+	BundleID uint `json:"bundle_id" binding:"required"`
+	Quantity int  `json:"quantity" binding:"required,min=1"`
+}
+
+// OrderBundleRequest orders a quantity of a bundle
+type OrderBundleRequest struct {
+	BundleID uint `json:"bundle_id" binding:"required"`
+	Quantity int  `json:"quantity" binding:"required,min=1"`
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Coupon types. PERCENT takes Value percent off the eligible items, FIXED
+// takes Value off them, and FREE_SHIPPING waives the shipping fee.
+const (
+	CouponPercent      = "PERCENT"
+	CouponFixed        = "FIXED"
+	CouponFreeShipping = "FREE_SHIPPING"
+)
+
+// Coupon is a code customers apply to their cart or order for a discount.
+// MinOrderValue is compared with the items total after bundle discounts, and
+// MaxDiscount caps the discount of percent and free shipping coupons. Without
+// product or category restrictions a coupon applies to every item; with them,
+// only to the listed products and to products in the listed categories or
+// their subcategories. A coupon that is not Stackable cannot be used together
+// with other coupons.
+type Coupon struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	Code          string     `json:"code" gorm:"uniqueIndex;not null"`
+	Description   string     `json:"description"`
+	Type          string     `json:"type" gorm:"not null"`
+	Value         float64    `json:"value" gorm:"default:0"`
+	MinOrderValue float64    `json:"min_order_value" gorm:"default:0"`
+	MaxDiscount   *float64   `json:"max_discount,omitempty"`
+	StartsAt      *time.Time `json:"starts_at,omitempty"`
+	EndsAt        *time.Time `json:"ends_at,omitempty"`
+	UsageLimit    *int       `json:"usage_limit,omitempty"`
+	PerUserLimit  *int       `json:"per_user_limit,omitempty"`
+	UsedCount     int        `json:"used_count" gorm:"not null;default:0"`
+	Stackable     bool       `json:"stackable" gorm:"default:false"`
+	IsActive      bool       `json:"is_active" gorm:"default:true"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Products and categories the coupon is restricted to, filled in by the
+	// coupon service from its targets
+	ProductIDs  []uint `json:"product_ids" gorm:"-"`
+	CategoryIDs []uint `json:"category_ids" gorm:"-"`
+
+	// Relationships
+	Targets []CouponTarget `json:"-" gorm:"foreignKey:CouponID"`
+}
+
+// CouponTarget restricts a coupon to a product or to a category
+type CouponTarget struct {
+	ID         uint  `json:"id" gorm:"primaryKey"`
+	CouponID   uint  `json:"coupon_id" gorm:"not null;index"`
+	ProductID  *uint `json:"product_id,omitempty"`
+	CategoryID *uint `json:"category_id,omitempty"`
+}
+
+// CouponRedemption records the use of a coupon by an order. Redemptions of
+// cancelled orders are released and no longer count towards the usage
+// limits.
+type CouponRedemption struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	CouponID   uint       `json:"coupon_id" gorm:"not null;index"`
+	OrderID    uint       `json:"order_id" gorm:"not null;index"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Code       string     `json:"code" gorm:"not null"`
+	Discount   float64    `json:"discount" gorm:"type:decimal(10,2);not null"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CartCoupon is a coupon applied to a user's cart, used when the user
+// orders without naming coupons
+type CartCoupon struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_cart_coupon"`
+	CouponID  uint      `json:"coupon_id" gorm:"not null;uniqueIndex:idx_cart_coupon"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CouponRequest creates a coupon or replaces all its fields. Value is the
+// percent for PERCENT coupons and the amount for FIXED ones.
+type CouponRequest struct {
+	Code          string     `json:"code" binding:"required,max=50"`
+	Description   string     `json:"description"`
+	Type          string     `json:"type" binding:"required,oneof=PERCENT FIXED FREE_SHIPPING"`
+	Value         float64    `json:"value" binding:"min=0"`
+	MinOrderValue float64    `json:"min_order_value" binding:"min=0"`
+	MaxDiscount   *float64   `json:"max_discount" binding:"omitnil,gt=0"`
+	StartsAt      *time.Time `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	UsageLimit    *int       `json:"usage_limit" binding:"omitnil,min=1"`
+	PerUserLimit  *int       `json:"per_user_limit" binding:"omitnil,min=1"`
+	Stackable     bool       `json:"stackable"`
+	IsActive      *bool      `json:"is_active"`
+	ProductIDs    []uint     `json:"product_ids"`
+	CategoryIDs   []uint     `json:"category_ids"`
+}
+
+// ApplyCouponRequest applies a coupon to the cart. With Preview the
+// discounted cart is returned without applying the coupon.
+type ApplyCouponRequest struct {
+	Code    string `json:"code" binding:"required"`
+	Preview bool   `json:"preview"`
+}
+
+// AppliedCoupon is a coupon taken off a cart or order and its discount
+type AppliedCoupon struct {
+	Code     string  `json:"code"`
+	Type     string  `json:"type"`
+	Discount float64 `json:"discount"`
+}
+
+// RejectedCoupon is a coupon applied to a cart that no longer applies to it
+type RejectedCoupon struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// CartSummary is the priced cart: the items total before discounts, the
+// bundle and coupon discounts, the shipping fee and what is left to pay
+type CartSummary struct {
+	Subtotal         float64          `json:"subtotal"`
+	BundleDiscount   float64          `json:"bundle_discount"`
+	CouponDiscount   float64          `json:"coupon_discount"`
+	ShippingFee      float64          `json:"shipping_fee"`
+	ShippingDiscount float64          `json:"shipping_discount"`
+	Total            float64          `json:"total"`
+	Coupons          []AppliedCoupon  `json:"coupons"`
+	RejectedCoupons  []RejectedCoupon `json:"rejected_coupons,omitempty"`
+}
+
+// CouponUsage sums the redemptions of a coupon for the usage report
+type CouponUsage struct {
+	CouponID      uint    `json:"coupon_id"`
+	Code          string  `json:"code"`
+	Type          string  `json:"type"`
+	IsActive      bool    `json:"is_active"`
+	UsageLimit    *int    `json:"usage_limit,omitempty"`
+	Uses          int     `json:"uses"`
+	ReleasedUses  int     `json:"released_uses"`
+	Customers     int     `json:"customers"`
+	TotalDiscount float64 `json:"total_discount"`
+	OrderRevenue  float64 `json:"order_revenue"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Flash sale states, derived from the sale window and the units sold
+const (
+	FlashSaleScheduled = "SCHEDULED"
+	FlashSaleLive      = "LIVE"
+	FlashSaleSoldOut   = "SOLD_OUT"
+	FlashSaleEnded     = "ENDED"
+)
+
+// FlashSale sells AllocatedQuantity units of a product, or of one of its
+// variants, at SalePrice between StartsAt and EndsAt, at most PerUserLimit
+// units per customer. Units are claimed when orders are placed; once all are
+// sold the sale is sold out and the regular price applies again. Items sold
+// in bundles keep the bundle price.
+type FlashSale struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	ProductID         uint      `json:"product_id" gorm:"not null;index"`
+	VariantID         *uint     `json:"variant_id,omitempty" gorm:"index"`
+	SalePrice         float64   `json:"sale_price" gorm:"not null"`
+	AllocatedQuantity int       `json:"allocated_quantity" gorm:"not null"`
+	SoldQuantity      int       `json:"sold_quantity" gorm:"not null;default:0"`
+	PerUserLimit      *int      `json:"per_user_limit,omitempty"`
+	StartsAt          time.Time `json:"starts_at" gorm:"not null;index"`
+	EndsAt            time.Time `json:"ends_at" gorm:"not null;index"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// State, units left and percentage of the allocation sold, filled in by
+	// the flash sale service
+	Status      string  `json:"status" gorm:"-"`
+	Remaining   int     `json:"remaining" gorm:"-"`
+	SellThrough float64 `json:"sell_through" gorm:"-"`
+
+	// Relationships
+	Product Product         `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	Variant *ProductVariant `json:"variant,omitempty" gorm:"foreignKey:VariantID"`
+}
+
+// FlashSalePurchase records the units of a flash sale bought by an order.
+// Purchases of cancelled orders are released and their units go back to the
+// sale.
+type FlashSalePurchase struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	FlashSaleID uint       `json:"flash_sale_id" gorm:"not null;index"`
+	OrderID     uint       `json:"order_id" gorm:"not null;index"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Quantity    int        `json:"quantity" gorm:"not null"`
+	Price       float64    `json:"price" gorm:"not null"`
+	ReleasedAt  *time.Time `json:"released_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// FlashSaleOffer is a running flash sale as shown on products and cart
+// items
+type FlashSaleOffer struct {
+	ID           uint      `json:"id"`
+	VariantID    *uint     `json:"variant_id,omitempty"`
+	SalePrice    float64   `json:"sale_price"`
+	RegularPrice float64   `json:"regular_price"`
+	PerUserLimit *int      `json:"per_user_limit,omitempty"`
+	Remaining    int       `json:"remaining"`
+	SoldOut      bool      `json:"sold_out"`
+	EndsAt       time.Time `json:"ends_at"`
+}
+
+// FlashSaleRequest creates a flash sale or replaces all its fields.
+// VariantID is required for products that have variants.
+type FlashSaleRequest struct {
+	ProductID         uint      `json:"product_id" binding:"required"`
+	VariantID         *uint     `json:"variant_id"`
+	SalePrice         float64   `json:"sale_price" binding:"required,gt=0"`
+	AllocatedQuantity int       `json:"allocated_quantity" binding:"required,min=1"`
+	PerUserLimit      *int      `json:"per_user_limit" binding:"omitnil,min=1"`
+	StartsAt          time.Time `json:"starts_at" binding:"required"`
+	EndsAt            time.Time `json:"ends_at" binding:"required,gtfield=StartsAt"`
+}
+
+// FlashSaleSellThrough is a flash sale with the orders, customers and
+// revenue of its purchases, for admins following it live
+type FlashSaleSellThrough struct {
+	FlashSale
+	Orders    int     `json:"orders"`
+	Customers int     `json:"customers"`
+	Revenue   float64 `json:"revenue"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ProductImage is an uploaded image in a product's gallery. Every upload is
+// stored with thumbnail and medium renditions next to the original.
+type ProductImage struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ProductID    uint      `json:"product_id" gorm:"not null;index"`
+	Position     int       `json:"position" gorm:"not null;default:0"`
+	IsPrimary    bool      `json:"is_primary" gorm:"default:false"`
+	StorageKey   string    `json:"-" gorm:"not null"`
+	URL          string    `json:"url"`
+	MediumURL    string    `json:"medium_url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ReorderProductImagesRequest lists every image of a product in its new order
+type ReorderProductImagesRequest struct {
+	ImageIDs []uint `json:"image_ids" binding:"required,min=1"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ImpersonationLog is the audit record of an admin impersonating a user
+type ImpersonationLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	AdminID   uint      `json:"admin_id" gorm:"not null;index"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	SessionID uint      `json:"session_id"`
+	ReadOnly  bool      `json:"read_only"`
+	Reason    string    `json:"reason"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ImpersonateUserRequest represents the request body for impersonating a user
+type ImpersonateUserRequest struct {
+	Reason   string `json:"reason" binding:"required"`
+	ReadOnly *bool  `json:"read_only"`
+}
+
+// ImpersonationResponse represents the token issued for an impersonation
+type ImpersonationResponse struct {
+	Token          string       `json:"token"`
+	User           UserResponse `json:"user"`
+	ImpersonatedBy uint         `json:"impersonated_by"`
+	ReadOnly       bool         `json:"read_only"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+}
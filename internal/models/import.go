@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Import job statuses
+const (
+	ImportStatusPending   = "PENDING"
+	ImportStatusRunning   = "RUNNING"
+	ImportStatusCompleted = "COMPLETED"
+	ImportStatusFailed    = "FAILED"
+)
+
+// ProductImportColumns are the spreadsheet columns understood by the product
+// import, in the order the export writes them. Only name, price and category
+// are required for new products; blank cells leave existing values alone.
+var ProductImportColumns = []string{
+	"id", "sku", "name", "description", "price", "stock", "category",
+	"brand", "image_url", "is_featured", "is_available",
+}
+
+// ImportRowError is a validation error for one spreadsheet row. Row numbers
+// match the spreadsheet, so the header is row 1.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportJob tracks a bulk product import. Dry runs validate every row and
+// report what would change without writing anything; real imports apply all
+// rows in one transaction, so a file with any invalid row changes nothing.
+type ImportJob struct {
+	ID            uint             `json:"id" gorm:"primaryKey"`
+	AdminID       uint             `json:"admin_id" gorm:"index"`
+	FileName      string           `json:"file_name"`
+	DryRun        bool             `json:"dry_run"`
+	Status        string           `json:"status" gorm:"default:PENDING"`
+	TotalRows     int              `json:"total_rows"`
+	ProcessedRows int              `json:"processed_rows"`
+	Created       int              `json:"created"`
+	Updated       int              `json:"updated"`
+	Errors        []ImportRowError `json:"errors" gorm:"serializer:json"`
+	Message       string           `json:"message,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	StartedAt     *time.Time       `json:"started_at,omitempty"`
+	FinishedAt    *time.Time       `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,180 @@
+package models
+
+import "time"
+
+// Stock reservation statuses. An ACTIVE reservation becomes COMMITTED when
+// an order is placed with it, RELEASED when the checkout is abandoned or
+// payment fails, and EXPIRED when it runs out of time.
+const (
+	ReservationActive    = "ACTIVE"
+	ReservationCommitted = "COMMITTED"
+	ReservationReleased  = "RELEASED"
+	ReservationExpired   = "EXPIRED"
+)
+
+// StockReservation holds stock for a checkout until ExpiresAt. The reserved
+// units are taken out of the product or variant stock when the reservation
+// is placed and put back when it is released or expires.
+type StockReservation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Status    string    `json:"status" gorm:"default:ACTIVE;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	OrderID   *uint     `json:"order_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Items []StockReservationItem `json:"items" gorm:"foreignKey:ReservationID"`
+}
+
+// StockReservationItem is one reserved line of a stock reservation, held in
+// one warehouse
+type StockReservationItem struct {
+	ID            uint  `json:"id" gorm:"primaryKey"`
+	ReservationID uint  `json:"reservation_id" gorm:"not null;index"`
+	ProductID     uint  `json:"product_id" gorm:"not null"`
+	VariantID     *uint `json:"variant_id,omitempty"`
+	WarehouseID   *uint `json:"warehouse_id,omitempty"`
+	Quantity      int   `json:"quantity"`
+
+	// Bundle the line is a component of, for lines reserved from the cart
+	BundleID *uint `json:"bundle_id,omitempty"`
+}
+
+// CreateReservationRequest reserves stock at the start of checkout. Without
+// items the contents of the cart are reserved. The stock is reserved in the
+// warehouses nearest to the address, or to the default address when none is
+// given.
+type CreateReservationRequest struct {
+	Items     []CreateOrderItemRequest `json:"items" binding:"dive"`
+	AddressID *uint                    `json:"address_id"`
+}
+
+// Inventory movement reasons. RESERVED and RELEASED move stock in and out of
+// checkout reservations; OPENING records the stock a product or variant
+// already had when the ledger started.
+const (
+	MovementSale       = "SALE"
+	MovementCancel     = "CANCEL"
+	MovementReturn     = "RETURN"
+	MovementAdjustment = "ADJUSTMENT"
+	MovementReceiving  = "RECEIVING"
+	MovementReserved   = "RESERVED"
+	MovementReleased   = "RELEASED"
+	MovementOpening    = "OPENING"
+)
+
+// Records an inventory movement can refer to
+const (
+	MovementRefOrder       = "ORDER"
+	MovementRefReservation = "RESERVATION"
+	MovementRefImport      = "IMPORT"
+)
+
+// InventoryMovement records one change to the stock of a product, or of one
+// of its variants when VariantID is set. Quantity is the change and Balance
+// the stock left after it, so the movements of a product or variant add up
+// to its stock. Changes made by a customer carry UserID, those made by an
+// admin AdminID; system changes such as expiring reservations carry neither.
+type InventoryMovement struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ProductID     uint      `json:"product_id" gorm:"not null;index:idx_inventory_movement_product"`
+	VariantID     *uint     `json:"variant_id,omitempty" gorm:"index"`
+	WarehouseID   *uint     `json:"warehouse_id,omitempty" gorm:"index"`
+	Reason        string    `json:"reason" gorm:"not null;index"`
+	Quantity      int       `json:"quantity"`
+	Balance       int       `json:"balance"`
+	ReferenceType string    `json:"reference_type,omitempty"`
+	ReferenceID   *uint     `json:"reference_id,omitempty"`
+	UserID        *uint     `json:"user_id,omitempty"`
+	AdminID       *uint     `json:"admin_id,omitempty"`
+	Note          string    `json:"note,omitempty"`
+	CreatedAt     time.Time `json:"created_at" gorm:"index:idx_inventory_movement_product"`
+}
+
+// AdjustStockRequest changes the stock of a product by hand. VariantID is
+// required for products that have variants, whose stock is kept per variant.
+// Quantity is added to the stock of the warehouse, the default warehouse
+// when none is given; a negative quantity takes units out.
+type AdjustStockRequest struct {
+	VariantID   *uint  `json:"variant_id"`
+	WarehouseID *uint  `json:"warehouse_id"`
+	Quantity    int    `json:"quantity" binding:"required"`
+	Reason      string `json:"reason" binding:"omitempty,oneof=ADJUSTMENT RECEIVING"`
+	Note        string `json:"note" binding:"required,max=500"`
+}
+
+// StockReconciliationLine compares the stock of a product without variants,
+// or of a variant, with the sum of its inventory movements and with the sum
+// of its warehouse stock
+type StockReconciliationLine struct {
+	ProductID      uint   `json:"product_id"`
+	VariantID      *uint  `json:"variant_id,omitempty"`
+	SKU            string `json:"sku"`
+	Name           string `json:"name"`
+	Stock          int    `json:"stock"`
+	LedgerStock    int    `json:"ledger_stock"`
+	Difference     int    `json:"difference"`
+	WarehouseStock int    `json:"warehouse_stock"`
+}
+
+// StockReconciliation is the result of checking stock against the ledger
+type StockReconciliation struct {
+	Checked    int                       `json:"checked"`
+	Mismatched int                       `json:"mismatched"`
+	Lines      []StockReconciliationLine `json:"lines"`
+}
+
+// ReorderSuggestion is how many units of a product to reorder so that stock
+// covers its sales velocity for the cover period on top of its reorder
+// threshold
+type ReorderSuggestion struct {
+	ProductID         uint     `json:"product_id"`
+	SKU               string   `json:"sku"`
+	Name              string   `json:"name"`
+	Stock             int      `json:"stock"`
+	ReorderThreshold  int      `json:"reorder_threshold"`
+	UnitsSold         int      `json:"units_sold"`
+	DailyVelocity     float64  `json:"daily_velocity"`
+	DaysOfStock       *float64 `json:"days_of_stock"`
+	SuggestedQuantity int      `json:"suggested_quantity"`
+}
+
+// ReorderReport lists the products to reorder, soonest to run out first
+type ReorderReport struct {
+	WindowDays  int                 `json:"window_days"`
+	CoverDays   int                 `json:"cover_days"`
+	Suggestions []ReorderSuggestion `json:"suggestions"`
+}
+
+// NotificationBackInStock is the type of the notification sent when a
+// product a customer subscribed to is back in stock
+const NotificationBackInStock = "BACK_IN_STOCK"
+
+// StockSubscription asks for a notification when an out-of-stock product,
+// or one of its variants, is back in stock. It is deleted once notified.
+type StockSubscription struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	ProductID uint      `json:"product_id" gorm:"not null;index"`
+	VariantID *uint     `json:"variant_id,omitempty" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotifyMeRequest subscribes to a product, or one of its variants, coming
+// back in stock
+type NotifyMeRequest struct {
+	VariantID *uint `json:"variant_id"`
+}
+
+// BackInStockDemand is the number of customers waiting for an out-of-stock
+// product or variant
+type BackInStockDemand struct {
+	ProductID         uint      `json:"product_id"`
+	VariantID         *uint     `json:"variant_id,omitempty"`
+	SKU               string    `json:"sku"`
+	Name              string    `json:"name"`
+	Stock             int       `json:"stock"`
+	Subscribers       int       `json:"subscribers"`
+	FirstSubscribedAt time.Time `json:"first_subscribed_at"`
+}
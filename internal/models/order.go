@@ -2,6 +2,14 @@ package models
 
 import "time"
 
+// OrderStatusPreordered is the status of orders holding pre-ordered items
+// that wait for stock
+const OrderStatusPreordered = "PREORDERED"
+
+// NotificationPreorderReady is the type of the notification sent when the
+// pre-ordered items of an order got their stock
+const NotificationPreorderReady = "PREORDER_READY"
+
 // Order represents an order in the system
 type Order struct {
 	ID              uint      `json:"id" gorm:"primaryKey"`
@@ -14,9 +22,29 @@ type Order struct {
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 
+	// Structured copy of the shipping address at the time of ordering
+	ShippingDetails AddressSnapshot `json:"shipping_details" gorm:"embedded;embeddedPrefix:shipping_"`
+
+	// Bumped on every update; sent as the ETag for optimistic concurrency
+	Version uint `json:"version" gorm:"not null;default:1"`
+
+	// Set for orders with pre-ordered items; the deposit is due at ordering
+	IsPreorder    bool    `json:"is_preorder" gorm:"default:false"`
+	DepositAmount float64 `json:"deposit_amount" gorm:"default:0"`
+
+	// Taken off the total for the bundles in the order
+	BundleDiscount float64 `json:"bundle_discount" gorm:"default:0"`
+
+	// Taken off the total for the coupons used by the order. The total
+	// includes the shipping fee less the shipping discount.
+	CouponDiscount   float64 `json:"coupon_discount" gorm:"default:0"`
+	ShippingFee      float64 `json:"shipping_fee" gorm:"default:0"`
+	ShippingDiscount float64 `json:"shipping_discount" gorm:"default:0"`
+
 	// Relationships
-	OrderItems []OrderItem `json:"order_items,omitempty" gorm:"foreignKey:OrderID"`
-	User       User        `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	OrderItems []OrderItem        `json:"order_items,omitempty" gorm:"foreignKey:OrderID"`
+	Coupons    []CouponRedemption `json:"coupons,omitempty" gorm:"foreignKey:OrderID"`
+	User       User               `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
 // OrderItem represents an item in an order
@@ -24,13 +52,34 @@ type OrderItem struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	OrderID   uint      `json:"order_id"`
 	ProductID uint      `json:"product_id"`
+	VariantID *uint     `json:"variant_id,omitempty"`
 	Quantity  int       `json:"quantity"`
 	Price     float64   `json:"price"`
 	CreatedAt time.Time `json:"created_at"`
 
+	// Warehouse the item ships from
+	WarehouseID *uint `json:"warehouse_id,omitempty" gorm:"index"`
+
+	// Pre-ordered items wait for stock until they are allocated, first come
+	// first served
+	IsPreorder    bool `json:"is_preorder" gorm:"default:false"`
+	AwaitingStock bool `json:"awaiting_stock" gorm:"default:false;index"`
+
+	// Bundle the item was sold in and the part of the bundle and coupon
+	// discounts allocated to it; a refund of the item is
+	// Price × Quantity − Discount
+	BundleID *uint   `json:"bundle_id,omitempty" gorm:"index"`
+	Discount float64 `json:"discount" gorm:"default:0"`
+
+	// Flash sale the item was bought in, at its sale price
+	FlashSaleID *uint `json:"flash_sale_id,omitempty" gorm:"index"`
+
 	// Relationships
-	Order   Order   `json:"order,omitempty" gorm:"foreignKey:OrderID"`
-	Product Product `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	Order     Order           `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+	Product   Product         `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	Variant   *ProductVariant `json:"variant,omitempty" gorm:"foreignKey:VariantID"`
+	Warehouse *Warehouse      `json:"warehouse,omitempty" gorm:"foreignKey:WarehouseID"`
+	Bundle    *Bundle         `json:"bundle,omitempty" gorm:"foreignKey:BundleID"`
 }
 
 // OrderWithItems represents order with its items
@@ -55,23 +104,37 @@ type PaymentMethod struct {
 	CreatedAt     time.Time `json:"created_at"`
 }
 
-// CreateOrderRequest represents request to create an order
+// CreateOrderRequest represents request to create an order.
+// AddressID refers to the user's address book; ShippingAddress is a free-text
+// fallback. When neither is given the user's default address is used.
+// With ReservationID the items of that stock reservation are ordered and
+// Items and Bundles are ignored. Without CouponCodes the coupons applied to
+// the cart are used; an empty list uses none.
 type CreateOrderRequest struct {
 	PaymentMethodID uint                     `json:"payment_method_id" binding:"required"`
 	IsInstallment   bool                     `json:"is_installment"`
-	ShippingAddress string                   `json:"shipping_address" binding:"required"`
-	Items           []CreateOrderItemRequest `json:"items" binding:"required,min=1"`
+	AddressID       *uint                    `json:"address_id"`
+	ShippingAddress string                   `json:"shipping_address"`
+	Items           []CreateOrderItemRequest `json:"items" binding:"required_without_all=ReservationID Bundles,dive"`
+	Bundles         []OrderBundleRequest     `json:"bundles" binding:"dive"`
+	ReservationID   *uint                    `json:"reservation_id"`
+	CouponCodes     []string                 `json:"coupon_codes"`
 }
 
-// CreateOrderItemRequest represents request to create an order item
+// CreateOrderItemRequest represents request to create an order item.
+// VariantID is required for products that have variants.
 type CreateOrderItemRequest struct {
-	ProductID uint `json:"product_id" binding:"required"`
-	Quantity  int  `json:"quantity" binding:"required,min=1"`
+	ProductID uint  `json:"product_id" binding:"required"`
+	VariantID *uint `json:"variant_id"`
+	Quantity  int   `json:"quantity" binding:"required,min=1"`
 }
 
 // UpdateOrderStatusRequest represents request to update order status
 type UpdateOrderStatusRequest struct {
 	Status string `json:"status" binding:"required"`
+
+	// Version being edited, when not sent as If-Match
+	Version *uint `json:"version" binding:"omitnil,min=1"`
 }
 
 // InstallmentPlan represents an installment plan
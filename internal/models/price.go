@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Price change sources recorded in the price history
+const (
+	PriceSourceManual   = "MANUAL"
+	PriceSourceImport   = "IMPORT"
+	PriceSourceVariant  = "VARIANT"
+	PriceSourceSchedule = "SCHEDULE"
+)
+
+// Price schedule statuses. A schedule without an end date is COMPLETED once
+// applied; one with an end date is ACTIVE until it ends and the previous
+// prices are restored.
+const (
+	PriceScheduleScheduled = "SCHEDULED"
+	PriceScheduleActive    = "ACTIVE"
+	PriceScheduleCompleted = "COMPLETED"
+	PriceScheduleEnded     = "ENDED"
+	PriceScheduleCancelled = "CANCELLED"
+)
+
+// PriceHistory records a price of a product, or of one of its variants when
+// VariantID is set. PreviousPrice is empty for the first price.
+type PriceHistory struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	ProductID      uint      `json:"product_id" gorm:"not null;index:idx_price_history_product"`
+	VariantID      *uint     `json:"variant_id,omitempty" gorm:"index"`
+	Price          float64   `json:"price"`
+	PreviousPrice  *float64  `json:"previous_price"`
+	CompareAtPrice *float64  `json:"compare_at_price"`
+	Source         string    `json:"source"`
+	ScheduleID     *uint     `json:"schedule_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at" gorm:"index:idx_price_history_product"`
+}
+
+// PriceSchedule is a price change applied by the scheduler between
+// EffectiveFrom and EffectiveTo. Without EffectiveTo the change is permanent.
+type PriceSchedule struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	ProductID      uint       `json:"product_id" gorm:"not null;index"`
+	VariantID      *uint      `json:"variant_id,omitempty" gorm:"index"`
+	Price          float64    `json:"price"`
+	CompareAtPrice *float64   `json:"compare_at_price"`
+	EffectiveFrom  time.Time  `json:"effective_from" gorm:"index"`
+	EffectiveTo    *time.Time `json:"effective_to"`
+	Status         string     `json:"status" gorm:"default:SCHEDULED;index"`
+
+	// Prices in effect when the schedule started, restored when it ends
+	PreviousPrice          *float64 `json:"previous_price,omitempty"`
+	PreviousCompareAtPrice *float64 `json:"previous_compare_at_price,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreatePriceScheduleRequest schedules a price change. VariantID is required
+// for products that have variants, whose prices are set per variant.
+type CreatePriceScheduleRequest struct {
+	VariantID      *uint      `json:"variant_id"`
+	Price          float64    `json:"price" binding:"required,gt=0"`
+	CompareAtPrice *float64   `json:"compare_at_price" binding:"omitempty,gt=0"`
+	EffectiveFrom  time.Time  `json:"effective_from" binding:"required"`
+	EffectiveTo    *time.Time `json:"effective_to"`
+}
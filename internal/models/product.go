@@ -1,10 +1,15 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Product represents a product in the system
 type Product struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
+	SKU         string    `json:"sku,omitempty" gorm:"index"`
 	Name        string    `json:"name" binding:"required"`
 	Description string    `json:"description"`
 	Price       float64   `json:"price" binding:"required,min=0"`
@@ -18,27 +23,107 @@ type Product struct {
 	IsAvailable bool      `json:"is_available" gorm:"default:true"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Bumped on every update; sent as the ETag for optimistic concurrency
+	Version uint `json:"version" gorm:"not null;default:1"`
+
+	// Original price shown struck through next to a lower price
+	CompareAtPrice *float64 `json:"compare_at_price"`
+
+	// Admins are alerted when stock falls to the reorder threshold or below;
+	// nil uses LOW_STOCK_THRESHOLD. LowStockAlertedAt is set while an alert
+	// is open and cleared when stock rises above the threshold again.
+	ReorderThreshold  *int       `json:"reorder_threshold"`
+	LowStockAlertedAt *time.Time `json:"low_stock_alerted_at,omitempty"`
+
+	// Pre-order mode: orders beyond stock are accepted, up to PreorderCap
+	// units waiting for stock, for a deposit of PreorderDeposit per unit
+	IsPreorder      bool       `json:"is_preorder" gorm:"default:false"`
+	ReleaseDate     *time.Time `json:"release_date"`
+	PreorderCap     int        `json:"preorder_cap" gorm:"default:0"`
+	PreorderDeposit float64    `json:"preorder_deposit" gorm:"default:0"`
+
+	// Price range across variants, filled in by the product service
+	MinPrice float64 `json:"min_price" gorm:"-"`
+	MaxPrice float64 `json:"max_price" gorm:"-"`
+
+	// Lowest price of the last 30 days, filled in from the price history
+	LowestPrice30Days float64 `json:"lowest_price_30_days" gorm:"-"`
+
+	// Flash sales running on the product or its variants, sold out ones
+	// included, filled in by the flash sale service
+	FlashSales []FlashSaleOffer `json:"flash_sales,omitempty" gorm:"-"`
+
+	// Relationships
+	Variants   []ProductVariant        `json:"variants,omitempty" gorm:"foreignKey:ProductID"`
+	Images     []ProductImage          `json:"images,omitempty" gorm:"foreignKey:ProductID"`
+	Attributes []ProductAttributeValue `json:"attributes,omitempty" gorm:"foreignKey:ProductID"`
 }
 
-// Category represents a product category
+// Category represents a product category. Categories form a tree through
+// ParentID; top-level categories have no parent.
 type Category struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Name      string    `json:"name" binding:"required"`
+	Slug      string    `json:"slug" gorm:"index"`
 	Icon      string    `json:"icon"`
+	ParentID  *uint     `json:"parent_id" gorm:"index"`
+	SortOrder int       `json:"sort_order" gorm:"default:0"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Bumped on every update; sent as the ETag for optimistic concurrency
+	Version uint `json:"version" gorm:"not null;default:1"`
+
+	// Subcategories, filled in when the tree is built
+	Children []Category `json:"children,omitempty" gorm:"-"`
 }
 
 // CreateCategoryRequest represents the request body for creating a category
 type CreateCategoryRequest struct {
-	Name string `json:"name" binding:"required"`
-	Icon string `json:"icon"`
+	Name      string `json:"name" binding:"required"`
+	Slug      string `json:"slug"`
+	Icon      string `json:"icon"`
+	ParentID  *uint  `json:"parent_id"`
+	SortOrder int    `json:"sort_order"`
 }
 
-// UpdateCategoryRequest represents the request body for updating a category
+// UpdateCategoryRequest is a partial update of a category (PATCH): only
+// fields present in the body change. An empty slug is derived from the name.
 type UpdateCategoryRequest struct {
-	Name string `json:"name,omitempty"`
-	Icon string `json:"icon,omitempty"`
+	Name      *string `json:"name" binding:"omitnil,min=1"`
+	Slug      *string `json:"slug"`
+	Icon      *string `json:"icon"`
+	SortOrder *int    `json:"sort_order"`
+
+	// Version being edited, when not sent as If-Match
+	Version *uint `json:"version" binding:"omitnil,min=1"`
+}
+
+// ReplaceCategoryRequest replaces the editable fields of a category (PUT);
+// omitted optional fields are cleared. The parent is changed by moving.
+type ReplaceCategoryRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Slug      string `json:"slug"`
+	Icon      string `json:"icon"`
+	SortOrder int    `json:"sort_order"`
+
+	// Version being edited, when not sent as If-Match
+	Version *uint `json:"version" binding:"omitnil,min=1"`
+}
+
+// MoveCategoryRequest moves a category, with its subtree, under a new parent.
+// A null parent_id makes it a top-level category.
+type MoveCategoryRequest struct {
+	ParentID  *uint `json:"parent_id"`
+	SortOrder *int  `json:"sort_order"`
+
+	// Version being edited, when not sent as If-Match
+	Version *uint `json:"version" binding:"omitnil,min=1"`
 }
 
 // ProductWithCategory represents product with category information
@@ -49,27 +134,81 @@ type ProductWithCategory struct {
 
 // CreateProductRequest represents the request body for creating a product
 type CreateProductRequest struct {
+	SKU         string  `json:"sku"`
 	Name        string  `json:"name" binding:"required"`
 	Description string  `json:"description"`
 	Price       float64 `json:"price" binding:"required,min=0"`
-	Stock       int     `json:"stock" binding:"required,min=0"`
+	Stock       int     `json:"stock" binding:"min=0"`
 	ImageUrl    string  `json:"image_url"`
 	CategoryID  uint    `json:"category_id"`
 	Brand       string  `json:"brand"`
 	IsFeatured  bool    `json:"is_featured"`
+
+	CompareAtPrice *float64 `json:"compare_at_price" binding:"omitempty,gt=0"`
+
+	ReorderThreshold *int `json:"reorder_threshold" binding:"omitnil,min=0"`
+
+	IsPreorder      bool       `json:"is_preorder"`
+	ReleaseDate     *time.Time `json:"release_date"`
+	PreorderCap     int        `json:"preorder_cap" binding:"min=0"`
+	PreorderDeposit float64    `json:"preorder_deposit" binding:"min=0"`
 }
 
-// UpdateProductRequest represents the request body for updating a product
+// UpdateProductRequest is a partial update of a product (PATCH): only
+// fields present in the body change, so zero values such as a stock of 0 or
+// is_featured false are applied. Empty strings clear optional fields.
 type UpdateProductRequest struct {
-	Name        string  `json:"name,omitempty"`
-	Description string  `json:"description,omitempty"`
-	Price       float64 `json:"price,omitempty"`
-	Stock       int     `json:"stock,omitempty"`
-	ImageUrl    string  `json:"image_url,omitempty"`
-	CategoryID  uint    `json:"category_id,omitempty"`
-	Brand       string  `json:"brand,omitempty"`
-	IsFeatured  bool    `json:"is_featured,omitempty"`
-	IsAvailable bool    `json:"is_available,omitempty"`
+	SKU         *string  `json:"sku"`
+	Name        *string  `json:"name" binding:"omitnil,min=1"`
+	Description *string  `json:"description"`
+	Price       *float64 `json:"price" binding:"omitnil,min=0"`
+	Stock       *int     `json:"stock" binding:"omitnil,min=0"`
+	ImageUrl    *string  `json:"image_url"`
+	CategoryID  *uint    `json:"category_id" binding:"omitnil,min=1"`
+	Brand       *string  `json:"brand"`
+	IsFeatured  *bool    `json:"is_featured"`
+	IsAvailable *bool    `json:"is_available"`
+
+	// Compare-at price; 0 removes it
+	CompareAtPrice *float64 `json:"compare_at_price" binding:"omitnil,min=0"`
+
+	ReorderThreshold *int `json:"reorder_threshold" binding:"omitnil,min=0"`
+
+	IsPreorder      *bool      `json:"is_preorder"`
+	ReleaseDate     *time.Time `json:"release_date"`
+	PreorderCap     *int       `json:"preorder_cap" binding:"omitnil,min=0"`
+	PreorderDeposit *float64   `json:"preorder_deposit" binding:"omitnil,min=0"`
+
+	// Version being edited, when not sent as If-Match
+	Version *uint `json:"version" binding:"omitnil,min=1"`
+}
+
+// ReplaceProductRequest replaces all editable fields of a product (PUT);
+// omitted optional fields are cleared
+type ReplaceProductRequest struct {
+	SKU         string   `json:"sku"`
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Price       *float64 `json:"price" binding:"required,min=0"`
+	Stock       *int     `json:"stock" binding:"required,min=0"`
+	ImageUrl    string   `json:"image_url"`
+	CategoryID  uint     `json:"category_id" binding:"required"`
+	Brand       string   `json:"brand"`
+	IsFeatured  bool     `json:"is_featured"`
+	IsAvailable *bool    `json:"is_available" binding:"required"`
+
+	CompareAtPrice *float64 `json:"compare_at_price" binding:"omitnil,gt=0"`
+
+	// Reorder threshold; omitted uses LOW_STOCK_THRESHOLD
+	ReorderThreshold *int `json:"reorder_threshold" binding:"omitnil,min=0"`
+
+	IsPreorder      bool       `json:"is_preorder"`
+	ReleaseDate     *time.Time `json:"release_date"`
+	PreorderCap     int        `json:"preorder_cap" binding:"min=0"`
+	PreorderDeposit float64    `json:"preorder_deposit" binding:"min=0"`
+
+	// Version being edited, when not sent as If-Match
+	Version *uint `json:"version" binding:"omitnil,min=1"`
 }
 
 // Cart represents a cart item
@@ -77,21 +216,32 @@ type Cart struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	UserID    uint      `json:"user_id"`
 	ProductID uint      `json:"product_id"`
+	VariantID *uint     `json:"variant_id,omitempty"`
 	Quantity  int       `json:"quantity" binding:"required,min=1"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Bundle the item is a component of; bundle items change with their bundle
+	BundleID *uint `json:"bundle_id,omitempty" gorm:"index"`
+
+	// Flash sale pricing the item, filled in by the cart service
+	FlashSale *FlashSaleOffer `json:"flash_sale,omitempty" gorm:"-"`
 }
 
 // CartWithProduct represents cart item with product details
 type CartWithProduct struct {
 	Cart
-	Product Product `json:"product"`
+	Product Product         `json:"product"`
+	Variant *ProductVariant `json:"variant,omitempty"`
+	Bundle  *Bundle         `json:"bundle,omitempty"`
 }
 
-// AddToCartRequest represents request to add item to cart
+// AddToCartRequest represents request to add item to cart.
+// VariantID is required for products that have variants.
 type AddToCartRequest struct {
-	ProductID uint `json:"product_id" binding:"required"`
-	Quantity  int  `json:"quantity" binding:"required,min=1"`
+	ProductID uint  `json:"product_id" binding:"required"`
+	VariantID *uint `json:"variant_id"`
+	Quantity  int   `json:"quantity" binding:"required,min=1"`
 }
 
 // UpdateCartRequest represents request to update cart item
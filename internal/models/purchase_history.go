@@ -8,7 +8,10 @@ type PurchaseHistory struct {
 	UserID            uint       `json:"user_id" gorm:"not null"`
 	OrderID           *uint      `json:"order_id,omitempty"`
 	ProductID         uint       `json:"product_id" gorm:"not null"`
+	VariantID         *uint      `json:"variant_id,omitempty"`
 	ProductName       string     `json:"product_name" gorm:"not null"`
+	VariantName       string     `json:"variant_name,omitempty"`
+	SKU               string     `json:"sku,omitempty"`
 	ProductImageURL   string     `json:"product_image_url,omitempty"`
 	Quantity          int        `json:"quantity" gorm:"not null"`
 	UnitPrice         float64    `json:"unit_price" gorm:"type:decimal(10,2);not null"`
@@ -25,6 +28,15 @@ type PurchaseHistory struct {
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
 
+	// Structured copy of the shipping address at the time of purchase
+	ShippingDetails AddressSnapshot `json:"shipping_details" gorm:"embedded;embeddedPrefix:shipping_"`
+
+	// Bundle the product was bought in and its share of the bundle discount,
+	// already taken off TotalPrice
+	BundleID   *uint   `json:"bundle_id,omitempty" gorm:"index"`
+	BundleName string  `json:"bundle_name,omitempty"`
+	Discount   float64 `json:"discount" gorm:"type:decimal(10,2);default:0"`
+
 	// Relationships
 	User    User    `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	Product Product `json:"product,omitempty" gorm:"foreignKey:ProductID"`
@@ -34,7 +46,10 @@ type PurchaseHistory struct {
 type PurchaseHistoryResponse struct {
 	ID                uint       `json:"id"`
 	ProductID         uint       `json:"product_id"`
+	VariantID         *uint      `json:"variant_id,omitempty"`
 	ProductName       string     `json:"product_name"`
+	VariantName       string     `json:"variant_name,omitempty"`
+	SKU               string     `json:"sku,omitempty"`
 	ProductImageURL   string     `json:"product_image_url"`
 	Quantity          int        `json:"quantity"`
 	UnitPrice         float64    `json:"unit_price"`
@@ -52,6 +67,14 @@ type PurchaseHistoryResponse struct {
 	DaysSincePurchase int        `json:"days_since_purchase"`
 	CanReview         bool       `json:"can_review"`
 	CanReorder        bool       `json:"can_reorder"`
+
+	// Structured shipping address, present for purchases made from the address book
+	ShippingDetails *AddressSnapshot `json:"shipping_details,omitempty"`
+
+	// Bundle the product was bought in, for grouping its components
+	BundleID   *uint   `json:"bundle_id,omitempty"`
+	BundleName string  `json:"bundle_name,omitempty"`
+	Discount   float64 `json:"discount"`
 }
 
 // PurchaseHistoryFilter represents filters for purchase history queries
@@ -62,8 +85,6 @@ type PurchaseHistoryFilter struct {
 	StartDate     *time.Time `json:"start_date,omitempty" form:"start_date"`
 	EndDate       *time.Time `json:"end_date,omitempty" form:"end_date"`
 	ProductName   string     `json:"product_name,omitempty" form:"product_name"`
-	Page          int        `json:"page" form:"page"`
-	Limit         int        `json:"limit" form:"limit"`
 }
 
 // ToResponse converts PurchaseHistory to PurchaseHistoryResponse
@@ -73,10 +94,19 @@ func (ph *PurchaseHistory) ToResponse() PurchaseHistoryResponse {
 	canReview := ph.OrderStatus == "DELIVERED"
 	canReorder := ph.OrderStatus == "DELIVERED" || ph.OrderStatus == "CANCELLED"
 
+	var shippingDetails *AddressSnapshot
+	if ph.ShippingDetails != (AddressSnapshot{}) {
+		details := ph.ShippingDetails
+		shippingDetails = &details
+	}
+
 	return PurchaseHistoryResponse{
 		ID:                ph.ID,
 		ProductID:         ph.ProductID,
+		VariantID:         ph.VariantID,
 		ProductName:       ph.ProductName,
+		VariantName:       ph.VariantName,
+		SKU:               ph.SKU,
 		ProductImageURL:   ph.ProductImageURL,
 		Quantity:          ph.Quantity,
 		UnitPrice:         ph.UnitPrice,
@@ -94,6 +124,10 @@ func (ph *PurchaseHistory) ToResponse() PurchaseHistoryResponse {
 		DaysSincePurchase: daysSince,
 		CanReview:         canReview,
 		CanReorder:        canReorder,
+		ShippingDetails:   shippingDetails,
+		BundleID:          ph.BundleID,
+		BundleName:        ph.BundleName,
+		Discount:          ph.Discount,
 	}
 }
 
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// SearchQuery is a product search request
+type SearchQuery struct {
+	Query  string
+	Page   int
+	Limit  int
+	UserID *uint // signed-in user, recorded in the search log
+}
+
+// SearchHighlights holds matched fragments with matches wrapped in <mark> tags
+type SearchHighlights struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SearchHit is a product matching a search with its relevance score
+type SearchHit struct {
+	Product    Product          `json:"product"`
+	Score      float64          `json:"score"`
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+// SearchResult is one page of search hits, best match first
+type SearchResult struct {
+	Hits       []SearchHit `json:"results"`
+	Total      int64       `json:"total"`
+	Page       int         `json:"page"`
+	Limit      int         `json:"limit"`
+	DidYouMean string      `json:"did_you_mean,omitempty"`
+}
+
+// ProductSuggestion is a product offered while the user types
+type ProductSuggestion struct {
+	ID            uint    `json:"id"`
+	Name          string  `json:"name"`
+	Brand         string  `json:"brand,omitempty"`
+	ImageUrl      string  `json:"image_url,omitempty"`
+	Price         float64 `json:"price"`
+	PurchaseCount int64   `json:"purchase_count"`
+}
+
+// BrandSuggestion is a brand with the number of available products
+type BrandSuggestion struct {
+	Name     string `json:"name"`
+	Products int64  `json:"products"`
+}
+
+// CategorySuggestion is a category whose name matches the typed prefix
+type CategorySuggestion struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// Suggestions are the autocomplete results for a prefix, most popular first
+type Suggestions struct {
+	Products   []ProductSuggestion  `json:"products"`
+	Brands     []BrandSuggestion    `json:"brands"`
+	Categories []CategorySuggestion `json:"categories"`
+	Queries    []string             `json:"queries"`
+}
+
+// SearchQueryLog records a search so merchandisers can see what shoppers
+// look for and which searches find nothing
+type SearchQueryLog struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Query       string    `json:"query" gorm:"not null;index"`
+	ResultCount int64     `json:"result_count"`
+	UserID      *uint     `json:"user_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+// SearchQueryStat aggregates logged searches for one query
+type SearchQueryStat struct {
+	Query          string    `json:"query"`
+	Searches       int64     `json:"searches"`
+	AvgResults     float64   `json:"avg_results"`
+	LastSearchedAt time.Time `json:"last_searched_at"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// UserSession represents a login session bound to a JWT token (jti)
+type UserSession struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	TokenID    string     `json:"-" gorm:"column:token_id;uniqueIndex;not null"`
+	Device     string     `json:"device"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Admin ID when the session was opened by an admin impersonating the user
+	ImpersonatedBy *uint `json:"impersonated_by,omitempty"`
+}
+
+// UserSessionResponse represents session data returned to client
+type UserSessionResponse struct {
+	ID         uint       `json:"id"`
+	Device     string     `json:"device"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	IsCurrent  bool       `json:"is_current"`
+
+	ImpersonatedBy *uint `json:"impersonated_by,omitempty"`
+}
+
+// ClientInfo describes the client a session is created from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// IsActive reports whether the session can still be used
+func (s *UserSession) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// ToResponse converts UserSession to UserSessionResponse
+func (s *UserSession) ToResponse(currentTokenID string) UserSessionResponse {
+	return UserSessionResponse{
+		ID:         s.ID,
+		Device:     s.Device,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		RevokedAt:  s.RevokedAt,
+		CreatedAt:  s.CreatedAt,
+		IsCurrent:  s.TokenID == currentTokenID,
+
+		ImpersonatedBy: s.ImpersonatedBy,
+	}
+}
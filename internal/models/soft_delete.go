@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Deleted filter values accepted by admin list endpoints
+const (
+	DeletedFilterExclude = ""        // only live records (default)
+	DeletedFilterInclude = "include" // live and soft-deleted records
+	DeletedFilterOnly    = "only"    // only soft-deleted records
+)
+
+// deletedAtPtr converts a gorm.DeletedAt into a nullable timestamp for responses
+func deletedAtPtr(deletedAt gorm.DeletedAt) *time.Time {
+	if !deletedAt.Valid {
+		return nil
+	}
+	return &deletedAt.Time
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // User represents a user in the system
 type User struct {
@@ -18,6 +22,19 @@ type User struct {
 	Status       string     `json:"status" gorm:"default:ACTIVE"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Uploaded avatar; photo holds the medium rendition
+	PhotoThumbnail string `json:"photo_thumbnail,omitempty"`
+	PhotoKey       string `json:"-"`
+
+	// Account deletion (cooling-off period before personal data is anonymized)
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	AnonymizedAt        *time.Time `json:"anonymized_at,omitempty"`
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Bumped on every update; sent as the ETag for optimistic concurrency
+	Version uint `json:"version" gorm:"not null;default:1"`
 }
 
 // UserResponse represents user data returned to client (without password)
@@ -34,6 +51,11 @@ type UserResponse struct {
 	Status      string     `json:"status"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+
+	PhotoThumbnail      string     `json:"photo_thumbnail,omitempty"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	DeletedAt           *time.Time `json:"deleted_at,omitempty"`
+	Version             uint       `json:"version"`
 }
 
 // LoginRequest represents the request body for user login
@@ -58,16 +80,36 @@ type CreateUserRequest struct {
 	Password    string `json:"password" binding:"required,min=6"`
 }
 
-// UpdateUserRequest represents the request body for updating a user
+// UpdateUserRequest is a partial update of a user or profile (PATCH): only
+// fields present in the body change. Empty strings clear optional fields.
 type UpdateUserRequest struct {
-	Name        string     `json:"name,omitempty"`
-	Email       string     `json:"email,omitempty"`
-	PhoneNumber string     `json:"phone_number,omitempty"`
-	Photo       string     `json:"photo,omitempty"`
-	FullName    string     `json:"full_name,omitempty"`
-	DateOfBirth *time.Time `json:"date_of_birth,omitempty"`
-	Address     string     `json:"address,omitempty"`
-	Gender      string     `json:"gender,omitempty"`
+	Name        *string    `json:"name" binding:"omitnil,min=1"`
+	Email       *string    `json:"email" binding:"omitnil,email"`
+	PhoneNumber *string    `json:"phone_number" binding:"omitnil,min=1"`
+	Photo       *string    `json:"photo"`
+	FullName    *string    `json:"full_name"`
+	DateOfBirth *time.Time `json:"date_of_birth"`
+	Address     *string    `json:"address"`
+	Gender      *string    `json:"gender"`
+
+	// Version being edited, when not sent as If-Match
+	Version *uint `json:"version" binding:"omitnil,min=1"`
+}
+
+// ReplaceUserRequest replaces the editable fields of a user or profile (PUT);
+// omitted optional fields are cleared. The photo is managed through the
+// avatar endpoints and kept.
+type ReplaceUserRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Email       string     `json:"email" binding:"required,email"`
+	PhoneNumber string     `json:"phone_number" binding:"required"`
+	FullName    string     `json:"full_name"`
+	DateOfBirth *time.Time `json:"date_of_birth"`
+	Address     string     `json:"address"`
+	Gender      string     `json:"gender"`
+
+	// Version being edited, when not sent as If-Match
+	Version *uint `json:"version" binding:"omitnil,min=1"`
 }
 
 // AuthResponse represents authentication response
@@ -91,10 +133,36 @@ func (u *User) ToResponse() UserResponse {
 		Status:      u.Status,
 		CreatedAt:   u.CreatedAt,
 		UpdatedAt:   u.UpdatedAt,
+
+		PhotoThumbnail:      u.PhotoThumbnail,
+		DeletionScheduledAt: u.DeletionScheduledAt,
+		DeletedAt:           deletedAtPtr(u.DeletedAt),
+		Version:             u.Version,
 	}
 }
 
 // UpdateUserStatusRequest represents the request body for updating user status
 type UpdateUserStatusRequest struct {
 	Status string `json:"status" binding:"required,oneof=ACTIVE INACTIVE SUSPENDED"`
+
+	// Version being edited, when not sent as If-Match
+	Version *uint `json:"version" binding:"omitnil,min=1"`
+}
+
+// DeleteAccountRequest represents the request body for deleting one's own account
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// UserDataExport bundles all personal data held about a user
+type UserDataExport struct {
+	ExportedAt      time.Time             `json:"exported_at"`
+	Profile         UserResponse          `json:"profile"`
+	Addresses       []UserAddress         `json:"addresses"`
+	Orders          []Order               `json:"orders"`
+	PurchaseHistory []PurchaseHistory     `json:"purchase_history"`
+	Reviews         []Review              `json:"reviews"`
+	Notifications   []Notification        `json:"notifications"`
+	Wishlist        []Wishlist            `json:"wishlist"`
+	Sessions        []UserSessionResponse `json:"sessions"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// FieldErrors maps request fields, by their JSON names, to what is wrong
+// with them. Services return it for checks that need the database, such as
+// uniqueness, so clients get the same shape as for binding errors.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field + ": " + e[field]
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProductVariant represents a purchasable SKU of a product, e.g. one
+// color and RAM/storage combination of a phone model
+type ProductVariant struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ProductID   uint      `json:"product_id" gorm:"not null;index"`
+	SKU         string    `json:"sku" gorm:"uniqueIndex;not null"`
+	Color       string    `json:"color,omitempty"`
+	RAM         string    `json:"ram,omitempty"`
+	Storage     string    `json:"storage,omitempty"`
+	Price       float64   `json:"price"`
+	Stock       int       `json:"stock"`
+	ImageUrl    string    `json:"image_url,omitempty"`
+	IsAvailable bool      `json:"is_available" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Original price shown struck through next to a lower price
+	CompareAtPrice *float64 `json:"compare_at_price"`
+}
+
+// CreateVariantRequest represents the request body for creating a product variant
+type CreateVariantRequest struct {
+	SKU      string  `json:"sku" binding:"required"`
+	Color    string  `json:"color"`
+	RAM      string  `json:"ram"`
+	Storage  string  `json:"storage"`
+	Price    float64 `json:"price" binding:"required,min=0"`
+	Stock    int     `json:"stock" binding:"min=0"`
+	ImageUrl string  `json:"image_url"`
+
+	CompareAtPrice *float64 `json:"compare_at_price" binding:"omitempty,gt=0"`
+}
+
+// UpdateVariantRequest represents the request body for updating a product variant
+type UpdateVariantRequest struct {
+	SKU         string   `json:"sku,omitempty"`
+	Color       string   `json:"color,omitempty"`
+	RAM         string   `json:"ram,omitempty"`
+	Storage     string   `json:"storage,omitempty"`
+	Price       *float64 `json:"price,omitempty" binding:"omitempty,min=0"`
+	Stock       *int     `json:"stock,omitempty" binding:"omitempty,min=0"`
+	ImageUrl    string   `json:"image_url,omitempty"`
+	IsAvailable *bool    `json:"is_available,omitempty"`
+
+	// Compare-at price; 0 removes it
+	CompareAtPrice *float64 `json:"compare_at_price,omitempty" binding:"omitempty,min=0"`
+}
+
+// Label returns the option values of the variant, e.g. "12/256GB, Black"
+func (v *ProductVariant) Label() string {
+	var parts []string
+	switch {
+	case v.RAM != "" && v.Storage != "":
+		parts = append(parts, v.RAM+"/"+v.Storage)
+	case v.RAM != "":
+		parts = append(parts, v.RAM)
+	case v.Storage != "":
+		parts = append(parts, v.Storage)
+	}
+	if v.Color != "" {
+		parts = append(parts, v.Color)
+	}
+	return strings.Join(parts, ", ")
+}
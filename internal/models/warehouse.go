@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Regions of Vietnam, used to find the warehouse nearest to a shipping
+// address
+const (
+	RegionNorth   = "NORTH"
+	RegionCentral = "CENTRAL"
+	RegionSouth   = "SOUTH"
+)
+
+// Fulfillment strategies. NEAREST takes every order line from the warehouses
+// nearest to the shipping province, splitting lines when a warehouse runs
+// short. SINGLE ships the whole order from the nearest warehouse that holds
+// all of it and falls back to NEAREST when none does.
+const (
+	FulfillmentNearest = "NEAREST"
+	FulfillmentSingle  = "SINGLE"
+)
+
+// Warehouse is a place stock is held and shipped from. Warehouses at the
+// same distance from a shipping province are used in Priority order, lowest
+// first. Stock that is not placed in a warehouse, such as that of new
+// products, goes to the default warehouse.
+type Warehouse struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Code      string    `json:"code" gorm:"uniqueIndex;not null"`
+	Name      string    `json:"name" gorm:"not null"`
+	Province  string    `json:"province" gorm:"not null"`
+	Region    string    `json:"region" gorm:"not null"`
+	Priority  int       `json:"priority" gorm:"default:0"`
+	IsDefault bool      `json:"is_default" gorm:"default:false"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WarehouseStock is the stock of a product without variants, or of a
+// variant, held in one warehouse. The stock of a product or variant is the
+// sum over its warehouses.
+type WarehouseStock struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	WarehouseID uint      `json:"warehouse_id" gorm:"not null;uniqueIndex:idx_warehouse_stock_item"`
+	ProductID   uint      `json:"product_id" gorm:"not null;uniqueIndex:idx_warehouse_stock_item"`
+	VariantID   *uint     `json:"variant_id,omitempty" gorm:"uniqueIndex:idx_warehouse_stock_item"`
+	Stock       int       `json:"stock" gorm:"not null;default:0"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	Warehouse Warehouse `json:"warehouse" gorm:"foreignKey:WarehouseID"`
+}
+
+// CreateWarehouseRequest represents the request body for creating a warehouse
+type CreateWarehouseRequest struct {
+	Code      string `json:"code" binding:"required,max=20"`
+	Name      string `json:"name" binding:"required"`
+	Province  string `json:"province" binding:"required"`
+	Region    string `json:"region" binding:"required,oneof=NORTH CENTRAL SOUTH"`
+	Priority  int    `json:"priority"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// UpdateWarehouseRequest changes only the fields present in the body
+type UpdateWarehouseRequest struct {
+	Name      *string `json:"name" binding:"omitnil,min=1"`
+	Province  *string `json:"province" binding:"omitnil,min=1"`
+	Region    *string `json:"region" binding:"omitnil,oneof=NORTH CENTRAL SOUTH"`
+	Priority  *int    `json:"priority"`
+	IsDefault *bool   `json:"is_default"`
+}
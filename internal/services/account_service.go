@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/utils"
+	"log"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// accountDeletionGracePeriod is how long a user can cancel a deletion request
+func accountDeletionGracePeriod() time.Duration {
+	return time.Duration(envInt("ACCOUNT_DELETION_GRACE_DAYS", 14)) * 24 * time.Hour
+}
+
+// ExportUserData collects all personal data held about a user
+func ExportUserData(userID uint) (models.UserDataExport, error) {
+	user, found := GetUserByID(userID)
+	if !found {
+		return models.UserDataExport{}, errors.New("user not found")
+	}
+
+	export := models.UserDataExport{
+		ExportedAt: time.Now(),
+		Profile:    user.ToResponse(),
+	}
+
+	db := configs.DB
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&export.Addresses).Error; err != nil {
+		return models.UserDataExport{}, err
+	}
+	if err := db.Where("user_id = ?", userID).Preload("OrderItems").Order("created_at").Find(&export.Orders).Error; err != nil {
+		return models.UserDataExport{}, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("purchase_date").Find(&export.PurchaseHistory).Error; err != nil {
+		return models.UserDataExport{}, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&export.Reviews).Error; err != nil {
+		return models.UserDataExport{}, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&export.Notifications).Error; err != nil {
+		return models.UserDataExport{}, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("added_at").Find(&export.Wishlist).Error; err != nil {
+		return models.UserDataExport{}, err
+	}
+
+	var sessions []models.UserSession
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&sessions).Error; err != nil {
+		return models.UserDataExport{}, err
+	}
+	export.Sessions = []models.UserSessionResponse{}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, session.ToResponse(""))
+	}
+
+	return export, nil
+}
+
+// ScheduleAccountDeletion starts the cooling-off period before a user's account is anonymized
+func ScheduleAccountDeletion(userID uint, password string) (models.User, error) {
+	user, found := GetUserByID(userID)
+	if !found {
+		return models.User{}, errors.New("user not found")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return models.User{}, errors.New("invalid password")
+	}
+
+	if user.DeletionScheduledAt != nil {
+		return models.User{}, errors.New("account deletion is already scheduled")
+	}
+
+	scheduledAt := time.Now().Add(accountDeletionGracePeriod())
+	if err := configs.DB.Model(&user).Update("deletion_scheduled_at", scheduledAt).Error; err != nil {
+		return models.User{}, err
+	}
+	user.DeletionScheduledAt = &scheduledAt
+
+	return user, nil
+}
+
+// CancelAccountDeletion cancels a pending account deletion
+func CancelAccountDeletion(userID uint) error {
+	result := configs.DB.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL AND anonymized_at IS NULL", userID).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("no account deletion is scheduled")
+	}
+
+	return nil
+}
+
+// AnonymizeUser removes personal data from a user while keeping orders,
+// purchase history and transactions for financial records. It also applies
+// to soft-deleted users.
+func AnonymizeUser(userID uint) error {
+	var photoKey string
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Unscoped().First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("user not found")
+			}
+			return err
+		}
+
+		if user.AnonymizedAt != nil {
+			return nil
+		}
+		photoKey = user.PhotoKey
+
+		// Replace credentials with an unusable random password
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(utils.GenerateRandomString(32)), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Unscoped().Model(&user).Updates(map[string]interface{}{
+			"name":            "Deleted User",
+			"email":           fmt.Sprintf("deleted-%d@deleted.invalid", user.ID),
+			"phone_number":    fmt.Sprintf("deleted-%d", user.ID),
+			"password_hash":   string(hashedPassword),
+			"photo":           "",
+			"photo_thumbnail": "",
+			"photo_key":       "",
+			"full_name":       "",
+			"date_of_birth":   nil,
+			"address":         "",
+			"gender":          "",
+			"status":          "DELETED",
+			"anonymized_at":   now,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Remove data that only serves the live account
+		for _, model := range []interface{}{
+			&models.UserAddress{},
+			&models.Cart{},
+			&models.Wishlist{},
+			&models.Notification{},
+			&models.UserSession{},
+		} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	deleteImageBlobs(photoKey)
+	return nil
+}
+
+// ProcessDueAccountDeletions anonymizes accounts whose cooling-off period has ended
+// Accounts that fail are logged and retried on the next run.
+func ProcessDueAccountDeletions() error {
+	var userIDs []uint
+	if err := configs.DB.Model(&models.User{}).
+		Where("deletion_scheduled_at <= ? AND anonymized_at IS NULL", time.Now()).
+		Pluck("id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := AnonymizeUser(userID); err != nil {
+			log.Printf("Failed to anonymize user %d: %v", userID, err)
+			continue
+		}
+		log.Printf("Anonymized user %d after account deletion request", userID)
+	}
+
+	return nil
+}
+
+// StartAccountDeletionJob periodically finalizes scheduled account deletions
+func StartAccountDeletionJob() {
+	runPeriodically("account-deletion", envDuration("ACCOUNT_DELETION_JOB_INTERVAL", time.Hour), ProcessDueAccountDeletions)
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"errors"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrAddressNotFound is returned for unknown addresses and those of other
+// users
+var ErrAddressNotFound = errors.New("address not found")
+
+// GetUserAddresses returns a user's address book with the default address first
+func GetUserAddresses(userID uint) []models.UserAddress {
+	var addresses []models.UserAddress
+	configs.DB.Where("user_id = ?", userID).Order("is_default DESC, created_at DESC").Find(&addresses)
+	return addresses
+}
+
+// GetUserAddressByID returns an address belonging to a user
+func GetUserAddressByID(userID, addressID uint) (models.UserAddress, error) {
+	return findUserAddress(configs.DB, userID, addressID)
+}
+
+// GetDefaultUserAddress returns a user's default address
+func GetDefaultUserAddress(userID uint) (models.UserAddress, bool) {
+	var address models.UserAddress
+	if err := configs.DB.Where("user_id = ? AND is_default = ?", userID, true).First(&address).Error; err != nil {
+		return models.UserAddress{}, false
+	}
+	return address, true
+}
+
+// CreateUserAddress adds an address to a user's address book
+func CreateUserAddress(userID uint, req models.CreateAddressRequest) (models.UserAddress, error) {
+	address := models.UserAddress{
+		UserID:        userID,
+		RecipientName: req.RecipientName,
+		PhoneNumber:   req.PhoneNumber,
+		Province:      req.Province,
+		District:      req.District,
+		Ward:          req.Ward,
+		Street:        req.Street,
+	}
+
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		// The first address always becomes the default one
+		var count int64
+		if err := tx.Model(&models.UserAddress{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&address).Error; err != nil {
+			return err
+		}
+
+		if req.IsDefault || count == 0 {
+			return setDefaultAddress(tx, &address)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.UserAddress{}, err
+	}
+
+	return address, nil
+}
+
+// UpdateUserAddress updates an address in a user's address book
+func UpdateUserAddress(userID, addressID uint, req models.UpdateAddressRequest) (models.UserAddress, error) {
+	address, err := findUserAddress(configs.DB, userID, addressID)
+	if err != nil {
+		return models.UserAddress{}, err
+	}
+
+	// Update fields
+	updates := make(map[string]interface{})
+
+	if req.RecipientName != "" {
+		updates["recipient_name"] = req.RecipientName
+	}
+	if req.PhoneNumber != "" {
+		updates["phone_number"] = req.PhoneNumber
+	}
+	if req.Province != "" {
+		updates["province"] = req.Province
+	}
+	if req.District != "" {
+		updates["district"] = req.District
+	}
+	if req.Ward != "" {
+		updates["ward"] = req.Ward
+	}
+	if req.Street != "" {
+		updates["street"] = req.Street
+	}
+
+	err = configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&address).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&address, address.ID).Error; err != nil {
+			return err
+		}
+
+		// Keep the profile address in sync with the default address
+		if address.IsDefault {
+			return tx.Model(&models.User{}).Where("id = ?", userID).Update("address", address.FullAddress()).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return models.UserAddress{}, err
+	}
+
+	return address, nil
+}
+
+// SetDefaultUserAddress marks an address as the user's default address
+func SetDefaultUserAddress(userID, addressID uint) (models.UserAddress, error) {
+	var address models.UserAddress
+
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		address, err = findUserAddress(tx, userID, addressID)
+		if err != nil {
+			return err
+		}
+		return setDefaultAddress(tx, &address)
+	})
+	if err != nil {
+		return models.UserAddress{}, err
+	}
+
+	return address, nil
+}
+
+// DeleteUserAddress removes an address from a user's address book
+func DeleteUserAddress(userID, addressID uint) error {
+	return configs.DB.Transaction(func(tx *gorm.DB) error {
+		address, err := findUserAddress(tx, userID, addressID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&address).Error; err != nil {
+			return err
+		}
+
+		if !address.IsDefault {
+			return nil
+		}
+
+		// Promote the most recent remaining address to default
+		var next models.UserAddress
+		if err := tx.Where("user_id = ?", userID).Order("created_at DESC").First(&next).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		return setDefaultAddress(tx, &next)
+	})
+}
+
+// Helper functions
+
+func findUserAddress(db *gorm.DB, userID, addressID uint) (models.UserAddress, error) {
+	var address models.UserAddress
+	if err := db.Where("id = ? AND user_id = ?", addressID, userID).First(&address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.UserAddress{}, ErrAddressNotFound
+		}
+		return models.UserAddress{}, err
+	}
+	return address, nil
+}
+
+func setDefaultAddress(tx *gorm.DB, address *models.UserAddress) error {
+	if err := tx.Model(&models.UserAddress{}).
+		Where("user_id = ? AND id <> ?", address.UserID, address.ID).
+		Update("is_default", false).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(address).Update("is_default", true).Error; err != nil {
+		return err
+	}
+	address.IsDefault = true
+
+	return tx.Model(&models.User{}).Where("id = ?", address.UserID).Update("address", address.FullAddress()).Error
+}
@@ -0,0 +1,522 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
+	"literally-backend/pkg/utils"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// attributeValueExists matches products having a value for an attribute code;
+// the caller appends the condition on the value columns
+const attributeValueExists = `EXISTS (
+	SELECT 1 FROM product_attribute_values pav
+	JOIN category_attributes ca ON ca.id = pav.attribute_id
+	WHERE pav.product_id = products.id AND ca.code = ? AND `
+
+// GetCategoryAttributes returns the attributes that apply to a category,
+// including those inherited from its ancestors
+func GetCategoryAttributes(categoryID uint) ([]models.CategoryAttribute, error) {
+	categoryIDs, err := categoryAncestorIDs(configs.DB, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	var attributes []models.CategoryAttribute
+	if err := configs.DB.Where("category_id IN ?", categoryIDs).
+		Order("sort_order, name").
+		Find(&attributes).Error; err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+// CreateCategoryAttribute defines a new attribute on a category
+func CreateCategoryAttribute(categoryID uint, req models.CreateAttributeRequest) (models.CategoryAttribute, error) {
+	if !categoryExists(categoryID) {
+		return models.CategoryAttribute{}, errors.New("category not found")
+	}
+
+	code := req.Code
+	if code == "" {
+		code = req.Name
+	}
+	code = strings.ReplaceAll(utils.Slugify(code), "-", "_")
+	if code == "" {
+		return models.CategoryAttribute{}, errors.New("attribute code must contain letters or digits")
+	}
+
+	// Codes are filter keys, so one code must mean one type everywhere
+	var existing models.CategoryAttribute
+	if err := configs.DB.Where("code = ?", code).First(&existing).Error; err == nil {
+		if existing.CategoryID == categoryID {
+			return models.CategoryAttribute{}, errors.New("attribute with this code already exists in the category")
+		}
+		if existing.Type != req.Type {
+			return models.CategoryAttribute{}, fmt.Errorf("attribute code %s is already used with type %s", code, existing.Type)
+		}
+	}
+
+	if req.Type == models.AttributeTypeEnum && len(req.Options) == 0 {
+		return models.CategoryAttribute{}, errors.New("enum attributes need at least one option")
+	}
+
+	attribute := models.CategoryAttribute{
+		CategoryID:   categoryID,
+		Code:         code,
+		Name:         req.Name,
+		Type:         req.Type,
+		Unit:         req.Unit,
+		IsFilterable: req.IsFilterable == nil || *req.IsFilterable,
+		SortOrder:    req.SortOrder,
+	}
+	if req.Type == models.AttributeTypeEnum {
+		attribute.Options = req.Options
+	}
+
+	if err := configs.DB.Create(&attribute).Error; err != nil {
+		return models.CategoryAttribute{}, err
+	}
+
+	return attribute, nil
+}
+
+// UpdateCategoryAttribute updates the display settings or enum options of an attribute
+func UpdateCategoryAttribute(categoryID, attributeID uint, req models.UpdateAttributeRequest) (models.CategoryAttribute, error) {
+	var attribute models.CategoryAttribute
+	if err := configs.DB.Where("id = ? AND category_id = ?", attributeID, categoryID).First(&attribute).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.CategoryAttribute{}, errors.New("attribute not found")
+		}
+		return models.CategoryAttribute{}, err
+	}
+
+	if req.Name != "" {
+		attribute.Name = req.Name
+	}
+	if req.Unit != "" {
+		attribute.Unit = req.Unit
+	}
+	if req.IsFilterable != nil {
+		attribute.IsFilterable = *req.IsFilterable
+	}
+	if req.SortOrder != nil {
+		attribute.SortOrder = *req.SortOrder
+	}
+	if len(req.Options) > 0 {
+		if attribute.Type != models.AttributeTypeEnum {
+			return models.CategoryAttribute{}, errors.New("only enum attributes have options")
+		}
+
+		// Options still used by products cannot be dropped
+		var used []string
+		configs.DB.Model(&models.ProductAttributeValue{}).
+			Where("attribute_id = ? AND value_text NOT IN ?", attribute.ID, req.Options).
+			Distinct().Pluck("value_text", &used)
+		if len(used) > 0 {
+			return models.CategoryAttribute{}, fmt.Errorf("options still used by products: %s", strings.Join(used, ", "))
+		}
+		attribute.Options = req.Options
+	}
+
+	if err := configs.DB.Save(&attribute).Error; err != nil {
+		return models.CategoryAttribute{}, err
+	}
+
+	return attribute, nil
+}
+
+// DeleteCategoryAttribute removes an attribute and all product values for it
+func DeleteCategoryAttribute(categoryID, attributeID uint) error {
+	return configs.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND category_id = ?", attributeID, categoryID).Delete(&models.CategoryAttribute{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("attribute not found")
+		}
+
+		return tx.Where("attribute_id = ?", attributeID).Delete(&models.ProductAttributeValue{}).Error
+	})
+}
+
+// GetProductAttributes returns a product's attribute values
+func GetProductAttributes(productID uint) []models.ProductAttributeValue {
+	var values []models.ProductAttributeValue
+	configs.DB.Joins("Attribute").
+		Where("product_attribute_values.product_id = ?", productID).
+		Order(`"Attribute".sort_order, "Attribute".name`).
+		Find(&values)
+	return values
+}
+
+// SetProductAttributes sets attribute values of a product by attribute code.
+// Only attributes of the product's category or its ancestors are accepted and
+// each value must match the attribute type.
+func SetProductAttributes(productID uint, values map[string]interface{}) ([]models.ProductAttributeValue, error) {
+	product, found := GetProductByID(productID)
+	if !found {
+		return nil, errors.New("product not found")
+	}
+
+	attributes, err := GetCategoryAttributes(product.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]models.CategoryAttribute, len(attributes))
+	for _, attribute := range attributes {
+		byCode[attribute.Code] = attribute
+	}
+
+	err = configs.DB.Transaction(func(tx *gorm.DB) error {
+		for code, raw := range values {
+			attribute, ok := byCode[code]
+			if !ok {
+				return fmt.Errorf("attribute %s does not apply to this product's category", code)
+			}
+
+			if raw == nil {
+				if err := tx.Where("product_id = ? AND attribute_id = ?", productID, attribute.ID).
+					Delete(&models.ProductAttributeValue{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			value, err := attributeValue(attribute, raw)
+			if err != nil {
+				return err
+			}
+			value.ProductID = productID
+
+			var existing models.ProductAttributeValue
+			err = tx.Where("product_id = ? AND attribute_id = ?", productID, attribute.ID).First(&existing).Error
+			if err == nil {
+				value.ID = existing.ID
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			if err := tx.Omit("Attribute").Save(&value).Error; err != nil {
+				return err
+			}
+		}
+		return touchProduct(tx, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetProductAttributes(productID), nil
+}
+
+// attributeValue converts a JSON value to a typed attribute value
+func attributeValue(attribute models.CategoryAttribute, raw interface{}) (models.ProductAttributeValue, error) {
+	value := models.ProductAttributeValue{AttributeID: attribute.ID}
+
+	switch attribute.Type {
+	case models.AttributeTypeEnum:
+		text, ok := raw.(string)
+		if !ok || !slices.Contains(attribute.Options, text) {
+			return value, fmt.Errorf("%s must be one of: %s", attribute.Code, strings.Join(attribute.Options, ", "))
+		}
+		value.ValueText = text
+	case models.AttributeTypeNumber:
+		number, ok := raw.(float64)
+		if !ok {
+			return value, fmt.Errorf("%s must be a number", attribute.Code)
+		}
+		value.ValueNumber = &number
+	case models.AttributeTypeBool:
+		flag, ok := raw.(bool)
+		if !ok {
+			return value, fmt.Errorf("%s must be true or false", attribute.Code)
+		}
+		value.ValueBool = &flag
+	}
+
+	return value, nil
+}
+
+// pruneProductAttributes removes values for attributes that no longer apply
+// after a product moved to another category
+func pruneProductAttributes(db *gorm.DB, productID, categoryID uint) error {
+	categoryIDs, err := categoryAncestorIDs(db, categoryID)
+	if err != nil {
+		return err
+	}
+
+	return db.Where("product_id = ?", productID).
+		Where("attribute_id NOT IN (?)", db.Model(&models.CategoryAttribute{}).Select("id").Where("category_id IN ?", categoryIDs)).
+		Delete(&models.ProductAttributeValue{}).Error
+}
+
+// categoryAncestorIDs returns the ID of a category followed by the IDs of its ancestors
+func categoryAncestorIDs(db *gorm.DB, id uint) ([]uint, error) {
+	var ids []uint
+	err := db.Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id, c.parent_id FROM categories c
+			JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT id FROM ancestors`, id).Scan(&ids).Error
+	return ids, err
+}
+
+// FilterProducts returns one page of available products matching the filter
+// together with facet counts over all matching products
+func FilterProducts(filter models.ProductFilter, params pagination.Params) ([]models.Product, models.ProductFacets, pagination.Page, error) {
+	query, err := filterProductsQuery(configs.DB, filter)
+	if err != nil {
+		return nil, models.ProductFacets{}, pagination.Page{}, err
+	}
+
+	products, page, err := findProducts(query.Session(&gorm.Session{}), params)
+	if err != nil {
+		return nil, models.ProductFacets{}, page, err
+	}
+
+	// Facets describe every match, not only the current page
+	var matches []models.Product
+	if err := query.Select("id, brand, price").Find(&matches).Error; err != nil {
+		return nil, models.ProductFacets{}, page, err
+	}
+	attachPrices(matches)
+
+	facets, err := productFacets(matches)
+	if err != nil {
+		return nil, models.ProductFacets{}, page, err
+	}
+
+	return products, facets, page, nil
+}
+
+// filterProductsQuery builds the product listing query for a filter
+func filterProductsQuery(db *gorm.DB, filter models.ProductFilter) (*gorm.DB, error) {
+	query := db.Model(&models.Product{})
+	if !filter.IncludeUnavailable {
+		query = query.Where("is_available = ?", true)
+	}
+
+	if filter.CategoryID > 0 {
+		categoryIDs := []uint{filter.CategoryID}
+		if filter.IncludeDescendants {
+			ids, err := categoryDescendantIDs(db, filter.CategoryID)
+			if err != nil {
+				return nil, err
+			}
+			if len(ids) > 0 {
+				categoryIDs = ids
+			}
+		}
+		query = query.Where("category_id IN ?", categoryIDs)
+	}
+	if filter.Featured {
+		query = query.Where("is_featured = ?", true)
+	}
+	if filter.Search != "" {
+		searchTerm := "%" + strings.ToLower(filter.Search) + "%"
+		query = query.Where("(LOWER(name) LIKE ? OR LOWER(description) LIKE ? OR LOWER(brand) LIKE ?)",
+			searchTerm, searchTerm, searchTerm)
+	}
+	if len(filter.Brands) > 0 {
+		query = query.Where("brand IN ?", filter.Brands)
+	}
+
+	// A product matches a price range when any of its variants does
+	if filter.MinPrice != nil {
+		query = query.Where(`COALESCE((SELECT MAX(v.price) FROM product_variants v
+			WHERE v.product_id = products.id AND v.deleted_at IS NULL), products.price) >= ?`, *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("products.price <= ?", *filter.MaxPrice)
+	}
+
+	if len(filter.Attributes) == 0 {
+		return query, nil
+	}
+
+	codes := make([]string, 0, len(filter.Attributes))
+	for code := range filter.Attributes {
+		codes = append(codes, code)
+	}
+	var attributes []models.CategoryAttribute
+	if err := db.Where("code IN ?", codes).Find(&attributes).Error; err != nil {
+		return nil, err
+	}
+	types := make(map[string]string, len(attributes))
+	for _, attribute := range attributes {
+		types[attribute.Code] = attribute.Type
+	}
+
+	for code, raw := range filter.Attributes {
+		switch types[code] {
+		case models.AttributeTypeEnum:
+			query = query.Where(attributeValueExists+"pav.value_text IN ?)", code, strings.Split(raw, ","))
+		case models.AttributeTypeBool:
+			flag, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, fmt.Errorf("attribute %s expects true or false", code)
+			}
+			query = query.Where(attributeValueExists+"pav.value_bool = ?)", code, flag)
+		case models.AttributeTypeNumber:
+			from, to, err := parseNumberRange(raw)
+			if err != nil {
+				return nil, fmt.Errorf("attribute %s: %v", code, err)
+			}
+			if from != nil {
+				query = query.Where(attributeValueExists+"pav.value_number >= ?)", code, *from)
+			}
+			if to != nil {
+				query = query.Where(attributeValueExists+"pav.value_number <= ?)", code, *to)
+			}
+		default:
+			return nil, fmt.Errorf("unknown attribute %s", code)
+		}
+	}
+
+	return query, nil
+}
+
+// parseNumberRange parses "6..7", "6..", "..7" or an exact value "6.1"
+func parseNumberRange(raw string) (*float64, *float64, error) {
+	parse := func(s string) (*float64, error) {
+		if s == "" {
+			return nil, nil
+		}
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.New("expected a number or a range like 6..7")
+		}
+		return &n, nil
+	}
+
+	lower, upper, isRange := strings.Cut(raw, "..")
+	if !isRange {
+		n, err := parse(raw)
+		if err != nil || n == nil {
+			return nil, nil, errors.New("expected a number or a range like 6..7")
+		}
+		return n, n, nil
+	}
+
+	from, err := parse(lower)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := parse(upper)
+	if err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// productFacets counts brands, the price range and filterable attribute
+// values over a result set
+func productFacets(products []models.Product) (models.ProductFacets, error) {
+	facets := models.ProductFacets{
+		Brands:     []models.FacetValue{},
+		Attributes: []models.AttributeFacet{},
+	}
+	if len(products) == 0 {
+		return facets, nil
+	}
+
+	productIDs := make([]uint, len(products))
+	brandCounts := make(map[string]int)
+	price := models.RangeFacet{Min: products[0].MinPrice, Max: products[0].MaxPrice}
+	for i, product := range products {
+		productIDs[i] = product.ID
+		if product.Brand != "" {
+			brandCounts[product.Brand]++
+		}
+		price.Min = min(price.Min, product.MinPrice)
+		price.Max = max(price.Max, product.MaxPrice)
+	}
+	facets.Brands = sortedFacetValues(brandCounts)
+	facets.Price = &price
+
+	type row struct {
+		ProductID   uint
+		Code        string
+		Name        string
+		Type        string
+		Unit        string
+		SortOrder   int
+		ValueText   string
+		ValueNumber *float64
+		ValueBool   *bool
+	}
+	var rows []row
+	if err := configs.DB.Table("product_attribute_values pav").
+		Select("pav.product_id, ca.code, ca.name, ca.type, ca.unit, ca.sort_order, pav.value_text, pav.value_number, pav.value_bool").
+		Joins("JOIN category_attributes ca ON ca.id = pav.attribute_id").
+		Where("pav.product_id IN ? AND ca.is_filterable = ?", productIDs, true).
+		Order("ca.sort_order, ca.code").
+		Scan(&rows).Error; err != nil {
+		return facets, err
+	}
+
+	// Attributes are merged by code across categories
+	var order []string
+	byCode := make(map[string]*models.AttributeFacet)
+	counts := make(map[string]map[string]int)
+	for _, r := range rows {
+		facet, ok := byCode[r.Code]
+		if !ok {
+			facet = &models.AttributeFacet{Code: r.Code, Name: r.Name, Type: r.Type, Unit: r.Unit}
+			byCode[r.Code] = facet
+			counts[r.Code] = make(map[string]int)
+			order = append(order, r.Code)
+		}
+
+		switch {
+		case r.ValueNumber != nil:
+			if facet.Range == nil {
+				facet.Range = &models.RangeFacet{Min: *r.ValueNumber, Max: *r.ValueNumber}
+			}
+			facet.Range.Min = min(facet.Range.Min, *r.ValueNumber)
+			facet.Range.Max = max(facet.Range.Max, *r.ValueNumber)
+		case r.ValueBool != nil:
+			counts[r.Code][strconv.FormatBool(*r.ValueBool)]++
+		default:
+			counts[r.Code][r.ValueText]++
+		}
+	}
+
+	for _, code := range order {
+		facet := byCode[code]
+		if len(counts[code]) > 0 {
+			facet.Values = sortedFacetValues(counts[code])
+		}
+		facets.Attributes = append(facets.Attributes, *facet)
+	}
+
+	return facets, nil
+}
+
+// sortedFacetValues orders facet values by count, then value
+func sortedFacetValues(counts map[string]int) []models.FacetValue {
+	values := make([]models.FacetValue, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, models.FacetValue{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	return values
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 "errors"
+"literally-backend/configs"
 "literally-backend/internal/models"
 "os"
 "time"
@@ -13,6 +14,9 @@ import (
 type JWTClaims struct {
 UserID uint   `json:"user_id"`
 Email  string `json:"email"`
+// Set on tokens issued to an admin impersonating the user
+ImpersonatedBy *uint `json:"impersonated_by,omitempty"`
+ReadOnly       bool  `json:"read_only,omitempty"`
 jwt.RegisteredClaims
 }
 
@@ -32,13 +36,14 @@ secretKey: []byte(secret),
 }
 }
 
-// GenerateToken generates a real JWT token
-func (s *AuthService) GenerateToken(user models.User) (string, error) {
+// GenerateToken generates a real JWT token bound to a session
+func (s *AuthService) GenerateToken(user models.User, session models.UserSession) (string, error) {
 claims := JWTClaims{
 UserID: user.ID,
 Email:  user.Email,
 RegisteredClaims: jwt.RegisteredClaims{
-ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+ID:        session.TokenID,
+ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
 IssuedAt:  jwt.NewNumericDate(time.Now()),
 NotBefore: jwt.NewNumericDate(time.Now()),
 Issuer:    "literally-backend",
@@ -70,7 +75,7 @@ return claims, nil
 return nil, errors.New("invalid token")
 }
 
-// RefreshToken generates a new token with extended expiry
+// RefreshToken generates a new token with extended expiry for the same session
 func (s *AuthService) RefreshToken(tokenString string) (string, error) {
 claims, err := s.ValidateToken(tokenString)
 if err != nil {
@@ -82,18 +87,38 @@ if !found {
 return "", errors.New("user not found")
 }
 
-return s.GenerateToken(user)
+var session models.UserSession
+if err := configs.DB.Where("token_id = ? AND user_id = ?", claims.ID, user.ID).First(&session).Error; err != nil || !session.IsActive() {
+return "", errors.New("session is no longer valid")
 }
 
-// Login authenticates user and returns auth response with real JWT
-func Login(req models.LoginRequest) (models.AuthResponse, error) {
+// Impersonation tokens are short-lived by design
+if session.ImpersonatedBy != nil {
+return "", errors.New("impersonation tokens cannot be refreshed")
+}
+
+session.ExpiresAt = time.Now().Add(sessionTTL)
+if err := configs.DB.Model(&session).Update("expires_at", session.ExpiresAt).Error; err != nil {
+return "", err
+}
+
+return s.GenerateToken(user, session)
+}
+
+// Login authenticates user, opens a session and returns auth response with real JWT
+func Login(req models.LoginRequest, client models.ClientInfo) (models.AuthResponse, error) {
 user, err := LoginUser(req)
 if err != nil {
 return models.AuthResponse{}, err
 }
 
+session, err := CreateSession(user.ID, client)
+if err != nil {
+return models.AuthResponse{}, err
+}
+
 authService := NewAuthService()
-token, err := authService.GenerateToken(user)
+token, err := authService.GenerateToken(user, session)
 if err != nil {
 return models.AuthResponse{}, err
 }
@@ -104,15 +129,20 @@ Token: token,
 }, nil
 }
 
-// Register creates new user and returns auth response with real JWT
-func Register(req models.RegisterRequest) (models.AuthResponse, error) {
+// Register creates new user, opens a session and returns auth response with real JWT
+func Register(req models.RegisterRequest, client models.ClientInfo) (models.AuthResponse, error) {
 user, err := RegisterUser(req)
 if err != nil {
 return models.AuthResponse{}, err
 }
 
+session, err := CreateSession(user.ID, client)
+if err != nil {
+return models.AuthResponse{}, err
+}
+
 authService := NewAuthService()
-token, err := authService.GenerateToken(user)
+token, err := authService.GenerateToken(user, session)
 if err != nil {
 return models.AuthResponse{}, err
 }
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SubscribeBackInStock asks for a notification when an out-of-stock product,
+// or one of its variants, is back in stock. Subscribing twice returns the
+// existing subscription; created reports whether a new one was made.
+func SubscribeBackInStock(userID, productID uint, req models.NotifyMeRequest) (subscription models.StockSubscription, created bool, err error) {
+	var product models.Product
+	if err := configs.DB.First(&product, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.StockSubscription{}, false, errors.New("product not found")
+		}
+		return models.StockSubscription{}, false, err
+	}
+
+	inStock := product.IsAvailable && product.Stock > 0
+	if req.VariantID != nil {
+		variant, err := findProductVariant(configs.DB, productID, *req.VariantID)
+		if err != nil {
+			return models.StockSubscription{}, false, err
+		}
+		inStock = variant.IsAvailable && variant.Stock > 0
+	}
+	if inStock {
+		return models.StockSubscription{}, false, errors.New("product is in stock")
+	}
+
+	err = configs.DB.Where("user_id = ? AND product_id = ?", userID, productID).
+		Scopes(forVariant(req.VariantID)).
+		First(&subscription).Error
+	if err == nil {
+		return subscription, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.StockSubscription{}, false, err
+	}
+
+	subscription = models.StockSubscription{UserID: userID, ProductID: productID, VariantID: req.VariantID}
+	if err := configs.DB.Create(&subscription).Error; err != nil {
+		return models.StockSubscription{}, false, err
+	}
+	return subscription, true, nil
+}
+
+// UnsubscribeBackInStock cancels the user's back-in-stock subscription to a
+// product or one of its variants
+func UnsubscribeBackInStock(userID, productID uint, variantID *uint) error {
+	result := configs.DB.Where("user_id = ? AND product_id = ?", userID, productID).
+		Scopes(forVariant(variantID)).
+		Delete(&models.StockSubscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("subscription not found")
+	}
+	return nil
+}
+
+// GetBackInStockDemand returns how many customers wait for each out-of-stock
+// product and variant, most wanted first
+func GetBackInStockDemand() ([]models.BackInStockDemand, error) {
+	demand := []models.BackInStockDemand{}
+	err := configs.DB.Model(&models.StockSubscription{}).
+		Select("stock_subscriptions.product_id, stock_subscriptions.variant_id, " +
+			"COALESCE(product_variants.sku, products.sku) AS sku, products.name, " +
+			"COALESCE(product_variants.stock, products.stock) AS stock, " +
+			"COUNT(*) AS subscribers, MIN(stock_subscriptions.created_at) AS first_subscribed_at").
+		Joins("JOIN products ON products.id = stock_subscriptions.product_id AND products.deleted_at IS NULL").
+		Joins("LEFT JOIN product_variants ON product_variants.id = stock_subscriptions.variant_id").
+		Group("stock_subscriptions.product_id, stock_subscriptions.variant_id, products.id, product_variants.id").
+		Order("subscribers DESC, stock_subscriptions.product_id, stock_subscriptions.variant_id NULLS FIRST").
+		Scan(&demand).Error
+	return demand, err
+}
+
+// notifyBackInStock notifies the customers waiting for a product, or for one
+// of its variants, that it is back in stock and clears their subscriptions.
+// Subscriptions to the product without a variant fire for any variant.
+func notifyBackInStock(tx *gorm.DB, productID uint, variantID *uint) error {
+	query := tx.Where("product_id = ?", productID)
+	if variantID == nil {
+		query = query.Where("variant_id IS NULL")
+	} else {
+		query = query.Where("variant_id IS NULL OR variant_id = ?", *variantID)
+	}
+
+	var subscriptions []models.StockSubscription
+	if err := query.Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	var product models.Product
+	if err := tx.Select("id", "name").First(&product, productID).Error; err != nil {
+		return err
+	}
+	name := product.Name
+	if variantID != nil {
+		var variant models.ProductVariant
+		if err := tx.First(&variant, *variantID).Error; err != nil {
+			return err
+		}
+		if label := variant.Label(); label != "" {
+			name += " (" + label + ")"
+		}
+	}
+
+	notifications := make([]models.Notification, len(subscriptions))
+	ids := make([]uint, len(subscriptions))
+	for i, subscription := range subscriptions {
+		notifications[i] = models.Notification{
+			UserID:  subscription.UserID,
+			Title:   "Back in stock",
+			Message: name + " is back in stock",
+			Type:    models.NotificationBackInStock,
+		}
+		ids[i] = subscription.ID
+	}
+
+	if err := tx.CreateInBatches(&notifications, 500).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.StockSubscription{}, ids).Error
+}
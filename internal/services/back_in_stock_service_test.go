@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"literally-backend/internal/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// backInStockNotified reports whether each user got one back-in-stock
+// notification
+func backInStockNotified(t *testing.T, db *gorm.DB, users []models.User) []bool {
+	t.Helper()
+
+	notified := make([]bool, len(users))
+	for i, user := range users {
+		var count int64
+		if err := db.Model(&models.Notification{}).
+			Where("user_id = ? AND type = ?", user.ID, models.NotificationBackInStock).
+			Count(&count).Error; err != nil {
+			t.Fatalf("count notifications: %v", err)
+		}
+		if count > 1 {
+			t.Errorf("user %s notified %d times", user.Name, count)
+		}
+		notified[i] = count == 1
+	}
+	return notified
+}
+
+func TestRestockNotifiesEverySubscriberOnce(t *testing.T) {
+	db := openTestDB(t)
+
+	product := createStockedProduct(t, db, 0)
+	users := []models.User{
+		createTestUser(t, db, "First"),
+		createTestUser(t, db, "Second"),
+		createTestUser(t, db, "Third"),
+	}
+	for _, user := range users {
+		if _, created, err := SubscribeBackInStock(user.ID, product.ID, models.NotifyMeRequest{}); err != nil || !created {
+			t.Fatalf("subscribe: created %v, error %v", created, err)
+		}
+	}
+	if _, created, err := SubscribeBackInStock(users[0].ID, product.ID, models.NotifyMeRequest{}); err != nil || created {
+		t.Errorf("subscribing twice: created %v, error %v, want the existing subscription", created, err)
+	}
+
+	if _, err := AdjustStock(0, product.ID, models.AdjustStockRequest{Quantity: 2, Reason: models.MovementReceiving, Note: "Restock"}); err != nil {
+		t.Fatalf("restock: %v", err)
+	}
+
+	if got := fmt.Sprint(backInStockNotified(t, db, users)); got != "[true true true]" {
+		t.Errorf("notified %s, want every subscriber", got)
+	}
+	var left int64
+	db.Model(&models.StockSubscription{}).Where("product_id = ?", product.ID).Count(&left)
+	if left != 0 {
+		t.Errorf("%d subscriptions left after the restock, want 0", left)
+	}
+
+	// Stock added to a product in stock notifies no one
+	if _, err := AdjustStock(0, product.ID, models.AdjustStockRequest{Quantity: 1, Reason: models.MovementReceiving, Note: "Restock"}); err != nil {
+		t.Fatalf("restock again: %v", err)
+	}
+	backInStockNotified(t, db, users)
+	if _, _, err := SubscribeBackInStock(users[0].ID, product.ID, models.NotifyMeRequest{}); err == nil {
+		t.Error("subscribing to a product in stock succeeded, want an error")
+	}
+}
+
+func TestVariantRestockNotifiesItsSubscribers(t *testing.T) {
+	db := openTestDB(t)
+
+	product := createStockedProduct(t, db, 0)
+	var variants []models.ProductVariant
+	for i := 0; i < 2; i++ {
+		variant, err := CreateProductVariant(0, product.ID, models.CreateVariantRequest{
+			SKU:   fmt.Sprintf("VAR-%d-%d", time.Now().UnixNano(), i),
+			Price: 100,
+		})
+		if err != nil {
+			t.Fatalf("create variant: %v", err)
+		}
+		variants = append(variants, variant)
+	}
+
+	anyVariant := createTestUser(t, db, "Any variant")
+	restocked := createTestUser(t, db, "Restocked variant")
+	other := createTestUser(t, db, "Other variant")
+	subscriptions := map[uint]*uint{
+		anyVariant.ID: nil,
+		restocked.ID:  &variants[0].ID,
+		other.ID:      &variants[1].ID,
+	}
+	for userID, variantID := range subscriptions {
+		if _, _, err := SubscribeBackInStock(userID, product.ID, models.NotifyMeRequest{VariantID: variantID}); err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+	}
+
+	if _, err := AdjustStock(0, product.ID, models.AdjustStockRequest{VariantID: &variants[0].ID, Quantity: 1, Reason: models.MovementReceiving, Note: "Restock"}); err != nil {
+		t.Fatalf("restock variant: %v", err)
+	}
+
+	got := fmt.Sprint(backInStockNotified(t, db, []models.User{anyVariant, restocked, other}))
+	if got != "[true true false]" {
+		t.Errorf("notified %s, want the product and restocked variant subscribers only", got)
+	}
+	var left []models.StockSubscription
+	db.Where("product_id = ?", product.ID).Find(&left)
+	if len(left) != 1 || left[0].UserID != other.ID {
+		t.Errorf("subscriptions left %+v, want only the other variant's", left)
+	}
+}
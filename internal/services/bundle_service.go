@@ -0,0 +1,436 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBundleNotFound is returned for unknown, deleted and, when ordering,
+	// inactive bundles
+	ErrBundleNotFound = errors.New("bundle not found")
+
+	// ErrBundleCartItem is returned when a cart item of a bundle is changed
+	// or removed on its own
+	ErrBundleCartItem = errors.New("bundle items can only be changed with their bundle")
+)
+
+// BundleListSpec lists the sorts and filters available on bundle listings
+var BundleListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	Filters: map[string]string{
+		"is_active": "is_active",
+	},
+	DefaultSort: "name",
+}
+
+// GetBundles returns one page of bundles with their items and prices, only
+// active ones when activeOnly is set
+func GetBundles(params pagination.Params, activeOnly bool) ([]models.Bundle, pagination.Page, error) {
+	query := configs.DB
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+
+	bundles := []models.Bundle{}
+	page, err := pagination.Find(query, params, &bundles, preloadBundleItems)
+	if err != nil {
+		return nil, page, err
+	}
+	for i := range bundles {
+		priceBundle(&bundles[i])
+	}
+	return bundles, page, nil
+}
+
+// GetBundleByID returns a bundle with its items and prices, only an active
+// one when activeOnly is set
+func GetBundleByID(id uint, activeOnly bool) (models.Bundle, error) {
+	query := configs.DB.Scopes(preloadBundleItems)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var bundle models.Bundle
+	if err := query.First(&bundle, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Bundle{}, ErrBundleNotFound
+		}
+		return models.Bundle{}, err
+	}
+	priceBundle(&bundle)
+	return bundle, nil
+}
+
+// CreateBundle creates a bundle with its items
+func CreateBundle(req models.BundleRequest) (models.Bundle, error) {
+	items, err := bundleItems(req.Items)
+	if err != nil {
+		return models.Bundle{}, err
+	}
+
+	bundle := models.Bundle{
+		Name:            req.Name,
+		Description:     req.Description,
+		ImageUrl:        req.ImageUrl,
+		Price:           req.Price,
+		DiscountPercent: req.DiscountPercent,
+		IsActive:        true,
+		Items:           items,
+	}
+	if req.IsActive != nil {
+		bundle.IsActive = *req.IsActive
+	}
+
+	err = configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&bundle).Error; err != nil {
+			return err
+		}
+		// is_active has a database default of true, so false is written
+		// explicitly
+		if !bundle.IsActive {
+			return tx.Model(&bundle).Update("is_active", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Bundle{}, err
+	}
+
+	return GetBundleByID(bundle.ID, false)
+}
+
+// ReplaceBundle replaces the fields and items of a bundle. Carts holding the
+// bundle lose it when its components change, since their lines no longer
+// make up whole bundles.
+func ReplaceBundle(id uint, req models.BundleRequest) (models.Bundle, error) {
+	var current models.Bundle
+	if err := configs.DB.Preload("Items").First(&current, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Bundle{}, ErrBundleNotFound
+		}
+		return models.Bundle{}, err
+	}
+
+	items, err := bundleItems(req.Items)
+	if err != nil {
+		return models.Bundle{}, err
+	}
+	for i := range items {
+		items[i].BundleID = id
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	err = configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Bundle{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"name":             req.Name,
+			"description":      req.Description,
+			"image_url":        req.ImageUrl,
+			"price":            req.Price,
+			"discount_percent": req.DiscountPercent,
+			"is_active":        isActive,
+		}).Error; err != nil {
+			return err
+		}
+		if sameBundleItems(current.Items, items) {
+			return nil
+		}
+		if err := tx.Where("bundle_id = ?", id).Delete(&models.BundleItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+		return tx.Where("bundle_id = ?", id).Delete(&models.Cart{}).Error
+	})
+	if err != nil {
+		return models.Bundle{}, err
+	}
+
+	return GetBundleByID(id, false)
+}
+
+// DeleteBundle soft-deletes a bundle and takes it out of the carts holding it.
+// Orders keep referring to it.
+func DeleteBundle(id uint) error {
+	return configs.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Bundle{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBundleNotFound
+		}
+		return tx.Where("bundle_id = ?", id).Delete(&models.Cart{}).Error
+	})
+}
+
+// AddBundleToCart adds a quantity of an active bundle to the user's cart,
+// one line per component linked by the bundle ID. The stock of every
+// component is checked, counting the units of the bundle already in the cart.
+func AddBundleToCart(userID uint, req models.AddBundleToCartRequest) ([]models.Cart, error) {
+	bundle, err := GetBundleByID(req.BundleID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []models.Cart
+	err = configs.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range bundle.Items {
+			if !item.Product.IsAvailable {
+				return fmt.Errorf("product %s is not available", item.Product.Name)
+			}
+			variant, err := resolveLineVariant(tx, item.Product, item.VariantID)
+			if err != nil {
+				return err
+			}
+
+			var line models.Cart
+			err = tx.Where("user_id = ? AND product_id = ? AND bundle_id = ?", userID, item.ProductID, bundle.ID).
+				Scopes(forVariant(item.VariantID)).
+				First(&line).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			quantity := line.Quantity + item.Quantity*req.Quantity
+			if stock := lineStock(item.Product, variant); stock < quantity && !item.Product.IsPreorder {
+				return fmt.Errorf("%w for product %s. Available: %d, Requested: %d",
+					ErrInsufficientStock, lineName(item.Product, variant), stock, quantity)
+			}
+
+			if line.ID == 0 {
+				line = models.Cart{
+					UserID:    userID,
+					ProductID: item.ProductID,
+					VariantID: item.VariantID,
+					BundleID:  &bundle.ID,
+					Quantity:  quantity,
+				}
+				if err := tx.Create(&line).Error; err != nil {
+					return err
+				}
+			} else {
+				line.Quantity = quantity
+				if err := tx.Save(&line).Error; err != nil {
+					return err
+				}
+			}
+			lines = append(lines, line)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// RemoveBundleFromCart removes all lines of a bundle from the user's cart
+func RemoveBundleFromCart(userID, bundleID uint) error {
+	result := configs.DB.Where("user_id = ? AND bundle_id = ?", userID, bundleID).Delete(&models.Cart{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("bundle not in cart")
+	}
+	return nil
+}
+
+// preloadBundleItems loads the items of bundles with their products and
+// variants
+func preloadBundleItems(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Items.Product").Preload("Items.Variant")
+}
+
+// bundleItems checks the items of a bundle request: products must exist,
+// variants be named for products that have them, and no product or variant
+// be listed twice
+func bundleItems(requested []models.BundleItemRequest) ([]models.BundleItem, error) {
+	seen := make(map[stockKey]bool, len(requested))
+	items := make([]models.BundleItem, len(requested))
+	for i, item := range requested {
+		var product models.Product
+		if err := configs.DB.First(&product, item.ProductID).Error; err != nil {
+			return nil, fmt.Errorf("product not found: %d", item.ProductID)
+		}
+		if _, err := resolveLineVariant(configs.DB, product, item.VariantID); err != nil {
+			return nil, err
+		}
+
+		key := lineKey(stockLine{ProductID: item.ProductID, VariantID: item.VariantID})
+		if seen[key] {
+			return nil, fmt.Errorf("product %s is listed twice", product.Name)
+		}
+		seen[key] = true
+
+		items[i] = models.BundleItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity}
+	}
+	return items, nil
+}
+
+// sameBundleItems reports whether two lists of bundle items hold the same
+// quantities of the same products and variants
+func sameBundleItems(a, b []models.BundleItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	quantities := make(map[stockKey]int, len(a))
+	for _, item := range a {
+		quantities[lineKey(stockLine{ProductID: item.ProductID, VariantID: item.VariantID})] = item.Quantity
+	}
+	for _, item := range b {
+		if quantities[lineKey(stockLine{ProductID: item.ProductID, VariantID: item.VariantID})] != item.Quantity {
+			return false
+		}
+	}
+	return true
+}
+
+// priceBundle fills in the regular price of a bundle with loaded items, the
+// price it sells for and the savings
+func priceBundle(bundle *models.Bundle) {
+	var regular float64
+	for _, item := range bundle.Items {
+		regular += linePrice(item.Product, item.Variant) * float64(item.Quantity)
+	}
+	bundle.RegularPrice = regular
+	bundle.BundlePrice = regular - bundleDiscount(*bundle, regular, 1)
+	bundle.Savings = regular - bundle.BundlePrice
+}
+
+// bundleDiscount returns the discount on quantity bundles whose components
+// cost regular in total. A fixed bundle price above the regular price gives
+// no discount.
+func bundleDiscount(bundle models.Bundle, regular float64, quantity int) float64 {
+	discount := regular * bundle.DiscountPercent / 100
+	if bundle.Price != nil {
+		discount = regular - *bundle.Price*float64(quantity)
+	}
+	return roundMoney(math.Max(discount, 0))
+}
+
+// bundleStockLines expands ordered bundles into one stock line per component
+func bundleStockLines(db *gorm.DB, bundles []models.OrderBundleRequest) ([]stockLine, error) {
+	var lines []stockLine
+	for _, requested := range bundles {
+		var bundle models.Bundle
+		if err := db.Preload("Items").Where("is_active = ?", true).First(&bundle, requested.BundleID).Error; err != nil {
+			return nil, ErrBundleNotFound
+		}
+		for _, item := range bundle.Items {
+			lines = append(lines, stockLine{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				BundleID:  &bundle.ID,
+				Quantity:  item.Quantity * requested.Quantity,
+			})
+		}
+	}
+	return lines, nil
+}
+
+// allocateBundleDiscounts sets the discount of priced order items sold in
+// bundles. The items of each bundle must make up whole bundles of an active
+// bundle.
+func allocateBundleDiscounts(db *gorm.DB, items []models.OrderItem) error {
+	groups := make(map[uint][]int)
+	var order []uint
+	for i, item := range items {
+		if item.BundleID == nil {
+			continue
+		}
+		if _, ok := groups[*item.BundleID]; !ok {
+			order = append(order, *item.BundleID)
+		}
+		groups[*item.BundleID] = append(groups[*item.BundleID], i)
+	}
+
+	for _, bundleID := range order {
+		var bundle models.Bundle
+		if err := db.Preload("Items").Where("is_active = ?", true).First(&bundle, bundleID).Error; err != nil {
+			return fmt.Errorf("%w: %d", ErrBundleNotFound, bundleID)
+		}
+
+		if err := spreadBundleDiscount(bundle, items, groups[bundleID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spreadBundleDiscount sets the discount of the items at indexes, which make
+// up whole bundles, to their share of the bundle discount in proportion to
+// their price, the last item taking the rounding difference
+func spreadBundleDiscount(bundle models.Bundle, items []models.OrderItem, indexes []int) error {
+	quantity, err := bundleQuantity(bundle, items, indexes)
+	if err != nil {
+		return err
+	}
+
+	var regular float64
+	for _, i := range indexes {
+		regular += items[i].Price * float64(items[i].Quantity)
+	}
+	discount := bundleDiscount(bundle, regular, quantity)
+	if discount == 0 {
+		return nil
+	}
+
+	left := discount
+	for n, i := range indexes {
+		share := left
+		if n < len(indexes)-1 {
+			share = roundMoney(discount * items[i].Price * float64(items[i].Quantity) / regular)
+		}
+		items[i].Discount = roundMoney(share)
+		left -= share
+	}
+	return nil
+}
+
+// bundleQuantity returns how many whole bundles the items at indexes make
+// up, failing when they do not match the bundle's components
+func bundleQuantity(bundle models.Bundle, items []models.OrderItem, indexes []int) (int, error) {
+	units := make(map[stockKey]int)
+	for _, i := range indexes {
+		units[lineKey(stockLine{ProductID: items[i].ProductID, VariantID: items[i].VariantID})] += items[i].Quantity
+	}
+
+	quantity := 0
+	for _, component := range bundle.Items {
+		key := lineKey(stockLine{ProductID: component.ProductID, VariantID: component.VariantID})
+		have := units[key]
+		delete(units, key)
+		if have == 0 || have%component.Quantity != 0 || (quantity != 0 && have/component.Quantity != quantity) {
+			return 0, fmt.Errorf("items of bundle %s do not make up whole bundles", bundle.Name)
+		}
+		quantity = have / component.Quantity
+	}
+	if len(units) > 0 {
+		return 0, fmt.Errorf("items of bundle %s do not make up whole bundles", bundle.Name)
+	}
+	return quantity, nil
+}
+
+// roundMoney rounds an amount to two decimals
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"literally-backend/internal/models"
+	"testing"
+)
+
+// starterKit is a bundle of a phone variant, two cases and a charger
+func starterKit() models.Bundle {
+	variantID := uint(10)
+	return models.Bundle{
+		Name: "Starter kit",
+		Items: []models.BundleItem{
+			{ProductID: 1, VariantID: &variantID, Quantity: 1},
+			{ProductID: 2, Quantity: 2},
+			{ProductID: 3, Quantity: 1},
+		},
+	}
+}
+
+// starterKitItems returns the order items of quantity starter kits
+func starterKitItems(quantity int) []models.OrderItem {
+	variantID := uint(10)
+	return []models.OrderItem{
+		{ProductID: 1, VariantID: &variantID, Quantity: quantity, Price: 1000},
+		{ProductID: 2, Quantity: 2 * quantity, Price: 50},
+		{ProductID: 3, Quantity: quantity, Price: 100},
+	}
+}
+
+func TestBundleQuantity(t *testing.T) {
+	otherVariant := uint(11)
+	tests := []struct {
+		name    string
+		items   []models.OrderItem
+		want    int
+		wantErr bool
+	}{
+		{name: "one bundle", items: starterKitItems(1), want: 1},
+		{name: "three bundles", items: starterKitItems(3), want: 3},
+		{
+			name:  "components split over lines",
+			items: append(starterKitItems(1), starterKitItems(1)...),
+			want:  2,
+		},
+		{name: "missing component", items: starterKitItems(1)[:2], wantErr: true},
+		{
+			name:    "partial component",
+			items:   append(starterKitItems(1)[:1], models.OrderItem{ProductID: 2, Quantity: 1}, models.OrderItem{ProductID: 3, Quantity: 1}),
+			wantErr: true,
+		},
+		{
+			name:    "uneven components",
+			items:   append(starterKitItems(1)[:2], models.OrderItem{ProductID: 3, Quantity: 2}),
+			wantErr: true,
+		},
+		{
+			name:    "other variant",
+			items:   append([]models.OrderItem{{ProductID: 1, VariantID: &otherVariant, Quantity: 1}}, starterKitItems(1)[1:]...),
+			wantErr: true,
+		},
+		{
+			name:    "extra product",
+			items:   append(starterKitItems(1), models.OrderItem{ProductID: 4, Quantity: 1}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexes := make([]int, len(tt.items))
+			for i := range indexes {
+				indexes[i] = i
+			}
+			got, err := bundleQuantity(starterKit(), tt.items, indexes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %d bundles, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpreadBundleDiscount(t *testing.T) {
+	price := func(p float64) *float64 { return &p }
+	tests := []struct {
+		name      string
+		price     *float64
+		percent   float64
+		quantity  int
+		discounts []float64
+	}{
+		// 1200 regular: 1000 for the phone, 100 for the cases, 100 for the charger
+		{name: "percent", percent: 10, quantity: 1, discounts: []float64{100, 10, 10}},
+		{name: "fixed price", price: price(1100), quantity: 2, discounts: []float64{166.67, 16.67, 16.66}},
+		{name: "odd fixed price", price: price(1199.99), quantity: 3, discounts: []float64{0.03, 0, 0}},
+		{name: "fixed price above regular", price: price(1500), quantity: 1, discounts: []float64{0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := starterKit()
+			bundle.Price = tt.price
+			bundle.DiscountPercent = tt.percent
+			items := starterKitItems(tt.quantity)
+
+			if err := spreadBundleDiscount(bundle, items, []int{0, 1, 2}); err != nil {
+				t.Fatalf("spread: %v", err)
+			}
+
+			var total, want float64
+			for i, item := range items {
+				if item.Discount != tt.discounts[i] {
+					t.Errorf("item %d discount %v, want %v", i, item.Discount, tt.discounts[i])
+				}
+				total += item.Discount
+				want += tt.discounts[i]
+			}
+			if roundMoney(total) != roundMoney(want) {
+				t.Errorf("discounts sum to %v, want %v", total, want)
+			}
+		})
+	}
+}
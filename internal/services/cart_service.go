@@ -8,26 +8,58 @@ import (
 	"gorm.io/gorm"
 )
 
-// GetUserCart returns all cart items for a user with product details
+// GetUserCart returns all cart items for a user with product details. Items
+// of a bundle carry the bundle with its prices, and other items the flash
+// sale running on them.
 func GetUserCart(userID uint) []models.CartWithProduct {
 	var carts []models.Cart
-	configs.DB.Where("user_id = ?", userID).Find(&carts)
+	configs.DB.Where("user_id = ?", userID).Order("id").Find(&carts)
 
+	bundles := make(map[uint]*models.Bundle)
 	var cartWithProducts []models.CartWithProduct
 	for _, cart := range carts {
 		var product models.Product
-		if err := configs.DB.First(&product, cart.ProductID).Error; err == nil {
-			cartWithProducts = append(cartWithProducts, models.CartWithProduct{
-				Cart:    cart,
-				Product: product,
-			})
+		if err := configs.DB.First(&product, cart.ProductID).Error; err != nil {
+			continue
 		}
+
+		item := models.CartWithProduct{
+			Cart:    cart,
+			Product: product,
+		}
+		if cart.VariantID != nil {
+			var variant models.ProductVariant
+			if err := configs.DB.First(&variant, *cart.VariantID).Error; err != nil {
+				continue
+			}
+			item.Variant = &variant
+		}
+		if cart.BundleID == nil {
+			if sale, err := liveFlashSale(configs.DB, cart.ProductID, cart.VariantID); err == nil && sale != nil {
+				offer := flashSaleOffer(*sale, linePrice(product, item.Variant))
+				item.FlashSale = &offer
+			}
+		}
+		if cart.BundleID != nil {
+			bundle, ok := bundles[*cart.BundleID]
+			if !ok {
+				if found, err := GetBundleByID(*cart.BundleID, false); err == nil {
+					bundle = &found
+				}
+				bundles[*cart.BundleID] = bundle
+			}
+			item.Bundle = bundle
+		}
+
+		cartWithProducts = append(cartWithProducts, item)
 	}
 
 	return cartWithProducts
 }
 
-// AddToCart adds a product to user's cart
+// AddToCart adds a product to user's cart. During a flash sale the
+// quantity in the cart is checked against the units left and the per-user
+// limit, and the cart item carries the sale.
 func AddToCart(userID uint, req models.AddToCartRequest) (models.Cart, error) {
 	// Check if product exists and is available
 	var product models.Product
@@ -42,37 +74,63 @@ func AddToCart(userID uint, req models.AddToCartRequest) (models.Cart, error) {
 		return models.Cart{}, errors.New("product is not available")
 	}
 
-	// Check stock
-	if product.Stock < req.Quantity {
+	variant, err := resolveLineVariant(configs.DB, product, req.VariantID)
+	if err != nil {
+		return models.Cart{}, err
+	}
+
+	// Check stock; pre-order products are checked against their cap when
+	// ordered
+	stock := lineStock(product, variant)
+	if stock < req.Quantity && !product.IsPreorder {
 		return models.Cart{}, errors.New("insufficient stock")
 	}
 
 	// Check if item already exists in cart
 	var existingCart models.Cart
-	if err := configs.DB.Where("user_id = ? AND product_id = ?", userID, req.ProductID).First(&existingCart).Error; err == nil {
+	existingQuery := configs.DB.Where("user_id = ? AND product_id = ? AND bundle_id IS NULL", userID, req.ProductID)
+	if req.VariantID != nil {
+		existingQuery = existingQuery.Where("variant_id = ?", *req.VariantID)
+	} else {
+		existingQuery = existingQuery.Where("variant_id IS NULL")
+	}
+	if err := existingQuery.First(&existingCart).Error; err == nil {
 		// Update existing cart item
 		newQuantity := existingCart.Quantity + req.Quantity
-		if product.Stock < newQuantity {
+		if stock < newQuantity && !product.IsPreorder {
 			return models.Cart{}, errors.New("insufficient stock")
 		}
 
+		sale, err := cartFlashSale(configs.DB, userID, product, variant, newQuantity)
+		if err != nil {
+			return models.Cart{}, err
+		}
+
 		existingCart.Quantity = newQuantity
 		if err := configs.DB.Save(&existingCart).Error; err != nil {
 			return models.Cart{}, err
 		}
+		existingCart.FlashSale = sale
 		return existingCart, nil
 	}
 
+	sale, err := cartFlashSale(configs.DB, userID, product, variant, req.Quantity)
+	if err != nil {
+		return models.Cart{}, err
+	}
+
 	// Create new cart item
 	cart := models.Cart{
 		UserID:    userID,
 		ProductID: req.ProductID,
+		VariantID: req.VariantID,
 		Quantity:  req.Quantity,
 	}
 
 	if err := configs.DB.Create(&cart).Error; err != nil {
 		return models.Cart{}, err
 	}
+	cart.FlashSale = sale
 
 	return cart, nil
 }
@@ -88,6 +146,9 @@ func UpdateCartItem(userID uint, cartID uint, req models.UpdateCartRequest) (mod
 		}
 		return models.Cart{}, err
 	}
+	if cart.BundleID != nil {
+		return models.Cart{}, ErrBundleCartItem
+	}
 
 	// Check stock
 	var product models.Product
@@ -95,21 +156,42 @@ func UpdateCartItem(userID uint, cartID uint, req models.UpdateCartRequest) (mod
 		return models.Cart{}, errors.New("product not found")
 	}
 
-	if product.Stock < req.Quantity {
+	variant, err := resolveLineVariant(configs.DB, product, cart.VariantID)
+	if err != nil {
+		return models.Cart{}, err
+	}
+
+	if lineStock(product, variant) < req.Quantity && !product.IsPreorder {
 		return models.Cart{}, errors.New("insufficient stock")
 	}
+	sale, err := cartFlashSale(configs.DB, userID, product, variant, req.Quantity)
+	if err != nil {
+		return models.Cart{}, err
+	}
 
 	// Update quantity
 	cart.Quantity = req.Quantity
 	if err := configs.DB.Save(&cart).Error; err != nil {
 		return models.Cart{}, err
 	}
+	cart.FlashSale = sale
 
 	return cart, nil
 }
 
-// RemoveFromCart removes an item from user's cart
+// RemoveFromCart removes an item from user's cart. Items of a bundle are
+// removed with their bundle.
 func RemoveFromCart(userID uint, cartID uint) error {
+	var count int64
+	if err := configs.DB.Model(&models.Cart{}).
+		Where("id = ? AND user_id = ? AND bundle_id IS NOT NULL", cartID, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrBundleCartItem
+	}
+
 	result := configs.DB.Where("id = ? AND user_id = ?", cartID, userID).Delete(&models.Cart{})
 	if result.Error != nil {
 		return result.Error
@@ -122,23 +204,19 @@ func RemoveFromCart(userID uint, cartID uint) error {
 	return nil
 }
 
-// ClearUserCart removes all items from user's cart
+// ClearUserCart removes all items and coupons from user's cart
 func ClearUserCart(userID uint) error {
-	return configs.DB.Where("user_id = ?", userID).Delete(&models.Cart{}).Error
+	return configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Cart{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.CartCoupon{}).Error
+	})
 }
 
-// GetCartTotal calculates total amount for user's cart
+// GetCartTotal calculates total amount for user's cart, net of the bundle
+// and coupon discounts and with the shipping fee
 func GetCartTotal(userID uint) float64 {
-	var carts []models.Cart
-	configs.DB.Where("user_id = ?", userID).Find(&carts)
-
-	total := 0.0
-	for _, cart := range carts {
-		var product models.Product
-		if err := configs.DB.First(&product, cart.ProductID).Error; err == nil {
-			total += product.Price * float64(cart.Quantity)
-		}
-	}
-
-	return total
+	summary, _ := GetCartSummary(userID)
+	return summary.Total
 }
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// GetCategoryTree returns top-level categories with their subcategories
+// nested in children, each level ordered by sort order and name
+func GetCategoryTree() []models.Category {
+	return buildCategoryTree(GetAllCategories())
+}
+
+// MoveCategory moves a category and its subtree under a new parent, or to the
+// top level when parentID is nil. A category cannot be moved below itself.
+func MoveCategory(id, version uint, req models.MoveCategoryRequest) (models.Category, error) {
+	category, found := GetCategoryByID(id)
+	if !found {
+		return models.Category{}, errors.New("category not found")
+	}
+	if err := checkVersion(category.Version, version); err != nil {
+		return models.Category{}, err
+	}
+
+	if req.ParentID != nil {
+		if !categoryExists(*req.ParentID) {
+			return models.Category{}, errors.New("parent category not found")
+		}
+
+		subtree, err := categoryDescendantIDs(configs.DB, id)
+		if err != nil {
+			return models.Category{}, err
+		}
+		for _, descendantID := range subtree {
+			if descendantID == *req.ParentID {
+				return models.Category{}, errors.New("cannot move a category under itself or one of its subcategories")
+			}
+		}
+	}
+
+	if categoryNameTaken(category.Name, req.ParentID, id) {
+		return models.Category{}, errors.New("category with this name already exists under the new parent")
+	}
+
+	updates := map[string]interface{}{
+		"parent_id": req.ParentID,
+	}
+	if req.SortOrder != nil {
+		updates["sort_order"] = *req.SortOrder
+	}
+
+	result := configs.DB.Model(&category).Scopes(atVersion(version)).Updates(updates)
+	if err := versionedResult(result, version); err != nil {
+		return models.Category{}, err
+	}
+
+	category, _ = GetCategoryByID(id)
+	return category, nil
+}
+
+// categoryDescendantIDs returns the ID of a category followed by the IDs of
+// all its live descendants
+func categoryDescendantIDs(db *gorm.DB, id uint) ([]uint, error) {
+	var ids []uint
+	err := db.Raw(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = ? AND deleted_at IS NULL
+			UNION
+			SELECT c.id FROM categories c
+			JOIN subtree s ON c.parent_id = s.id
+			WHERE c.deleted_at IS NULL
+		)
+		SELECT id FROM subtree`, id).Scan(&ids).Error
+	return ids, err
+}
+
+// categoryNameTaken reports whether a sibling under parentID already uses name
+func categoryNameTaken(name string, parentID *uint, excludeID uint) bool {
+	query := configs.DB.Model(&models.Category{}).Where("name = ? AND id <> ?", name, excludeID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var count int64
+	query.Count(&count)
+	return count > 0
+}
+
+// uniqueCategorySlug turns the requested slug, or the name when none is
+// given, into a slug no other category uses by appending -2, -3, ...
+func uniqueCategorySlug(requested, name string, excludeID uint) (string, error) {
+	base := utils.Slugify(requested)
+	if base == "" {
+		base = utils.Slugify(name)
+	}
+	if base == "" {
+		return "", errors.New("category slug must contain letters or digits")
+	}
+
+	slug := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := configs.DB.Unscoped().Model(&models.Category{}).
+			Where("slug = ? AND id <> ?", slug, excludeID).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// categoryPathSeparator joins category names in paths like "Accessories > Cases"
+const categoryPathSeparator = " > "
+
+// categoryPaths returns the full path of every category, from its top-level
+// ancestor down. Ancestors missing from the list end the path.
+func categoryPaths(categories []models.Category) map[uint]string {
+	byID := make(map[uint]models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	paths := make(map[uint]string, len(categories))
+	var pathOf func(category models.Category, depth int) string
+	pathOf = func(category models.Category, depth int) string {
+		if path, ok := paths[category.ID]; ok {
+			return path
+		}
+		path := category.Name
+		// The depth guard protects against a corrupt parent cycle
+		if category.ParentID != nil && depth < len(categories) {
+			if parent, ok := byID[*category.ParentID]; ok {
+				path = pathOf(parent, depth+1) + categoryPathSeparator + category.Name
+			}
+		}
+		paths[category.ID] = path
+		return path
+	}
+	for _, category := range categories {
+		pathOf(category, 0)
+	}
+	return paths
+}
+
+// buildCategoryTree nests a flat category list. Categories whose parent is
+// not in the list (e.g. deleted) are treated as top-level.
+func buildCategoryTree(categories []models.Category) []models.Category {
+	byParent := make(map[uint][]models.Category)
+	present := make(map[uint]bool, len(categories))
+	for _, category := range categories {
+		present[category.ID] = true
+	}
+
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID != nil && present[*category.ParentID] {
+			byParent[*category.ParentID] = append(byParent[*category.ParentID], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	var attach func(nodes []models.Category) []models.Category
+	attach = func(nodes []models.Category) []models.Category {
+		for i := range nodes {
+			nodes[i].Children = attach(byParent[nodes[i].ID])
+		}
+		return nodes
+	}
+
+	return attach(roots)
+}
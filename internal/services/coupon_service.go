@@ -0,0 +1,852 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrCouponNotFound is returned for unknown and deleted coupon codes
+	ErrCouponNotFound = errors.New("coupon not found")
+
+	// ErrCouponNotApplicable is returned when a coupon cannot be used for a
+	// cart or order, such as an expired coupon or one below its minimum
+	// order value
+	ErrCouponNotApplicable = errors.New("coupon cannot be applied")
+
+	// ErrCouponLimitReached is returned when a coupon was used as many times
+	// as it may be, overall or by the user
+	ErrCouponLimitReached = errors.New("coupon usage limit reached")
+)
+
+// CouponListSpec lists the sorts and filters available on coupon listings
+var CouponListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"code":       "code",
+		"created_at": "created_at",
+		"used_count": "used_count",
+	},
+	Filters: map[string]string{
+		"type":      "type",
+		"is_active": "is_active",
+	},
+	DefaultSort: "-created_at",
+}
+
+// CouponRedemptionListSpec lists the sorts and filters available on the
+// redemptions of a coupon
+var CouponRedemptionListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"created_at": "created_at",
+		"discount":   "discount",
+	},
+	Filters: map[string]string{
+		"user_id": "user_id",
+	},
+	DefaultSort: "-created_at",
+}
+
+// GetCoupons returns one page of coupons with their restrictions
+func GetCoupons(params pagination.Params) ([]models.Coupon, pagination.Page, error) {
+	coupons := []models.Coupon{}
+	page, err := pagination.Find(configs.DB.Model(&models.Coupon{}), params, &coupons, preloadCouponTargets)
+	if err != nil {
+		return nil, page, err
+	}
+	for i := range coupons {
+		fillCouponTargets(&coupons[i])
+	}
+	return coupons, page, nil
+}
+
+// GetCouponByID returns a coupon with its restrictions
+func GetCouponByID(id uint) (models.Coupon, error) {
+	var coupon models.Coupon
+	if err := configs.DB.Scopes(preloadCouponTargets).First(&coupon, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Coupon{}, ErrCouponNotFound
+		}
+		return models.Coupon{}, err
+	}
+	fillCouponTargets(&coupon)
+	return coupon, nil
+}
+
+// CreateCoupon creates a coupon. Codes are stored in upper case.
+func CreateCoupon(req models.CouponRequest) (models.Coupon, error) {
+	if err := validateCoupon(req, 0); err != nil {
+		return models.Coupon{}, err
+	}
+
+	coupon := models.Coupon{}
+	setCouponFields(&coupon, req)
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&coupon).Error; err != nil {
+			return err
+		}
+		// Create skips the false is_active in favour of its default
+		if !coupon.IsActive {
+			if err := tx.Model(&coupon).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		return createCouponTargets(tx, coupon.ID, req)
+	})
+	if err != nil {
+		return models.Coupon{}, err
+	}
+
+	return GetCouponByID(coupon.ID)
+}
+
+// ReplaceCoupon replaces the fields and restrictions of a coupon. Its usage
+// so far is kept.
+func ReplaceCoupon(id uint, req models.CouponRequest) (models.Coupon, error) {
+	var coupon models.Coupon
+	if err := configs.DB.First(&coupon, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Coupon{}, ErrCouponNotFound
+		}
+		return models.Coupon{}, err
+	}
+	if err := validateCoupon(req, id); err != nil {
+		return models.Coupon{}, err
+	}
+
+	setCouponFields(&coupon, req)
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Coupon{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"code":            coupon.Code,
+			"description":     coupon.Description,
+			"type":            coupon.Type,
+			"value":           coupon.Value,
+			"min_order_value": coupon.MinOrderValue,
+			"max_discount":    coupon.MaxDiscount,
+			"starts_at":       coupon.StartsAt,
+			"ends_at":         coupon.EndsAt,
+			"usage_limit":     coupon.UsageLimit,
+			"per_user_limit":  coupon.PerUserLimit,
+			"stackable":       coupon.Stackable,
+			"is_active":       coupon.IsActive,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("coupon_id = ?", id).Delete(&models.CouponTarget{}).Error; err != nil {
+			return err
+		}
+		return createCouponTargets(tx, id, req)
+	})
+	if err != nil {
+		return models.Coupon{}, err
+	}
+
+	return GetCouponByID(id)
+}
+
+// DeleteCoupon soft deletes a coupon and removes it from carts. Orders that
+// used it keep their redemptions.
+func DeleteCoupon(id uint) error {
+	return configs.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Coupon{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCouponNotFound
+		}
+		return tx.Where("coupon_id = ?", id).Delete(&models.CartCoupon{}).Error
+	})
+}
+
+// GetCouponRedemptions returns one page of the orders that used a coupon,
+// including released ones
+func GetCouponRedemptions(id uint, params pagination.Params) ([]models.CouponRedemption, pagination.Page, error) {
+	if err := configs.DB.Unscoped().First(&models.Coupon{}, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pagination.Page{}, ErrCouponNotFound
+		}
+		return nil, pagination.Page{}, err
+	}
+
+	redemptions := []models.CouponRedemption{}
+	page, err := pagination.Find(configs.DB.Where("coupon_id = ?", id), params, &redemptions)
+	return redemptions, page, err
+}
+
+// GetCouponUsageReport sums the redemptions of every coupon, most used
+// first: the uses and customers counting towards the limits, the uses
+// released by cancelled orders, the discount given and the revenue of the
+// orders that used the coupon
+func GetCouponUsageReport() ([]models.CouponUsage, error) {
+	report := []models.CouponUsage{}
+	err := configs.DB.Model(&models.Coupon{}).
+		Select(`coupons.id AS coupon_id, coupons.code, coupons.type, coupons.is_active, coupons.usage_limit,
+			COUNT(coupon_redemptions.id) FILTER (WHERE coupon_redemptions.released_at IS NULL) AS uses,
+			COUNT(coupon_redemptions.id) FILTER (WHERE coupon_redemptions.released_at IS NOT NULL) AS released_uses,
+			COUNT(DISTINCT coupon_redemptions.user_id) FILTER (WHERE coupon_redemptions.released_at IS NULL) AS customers,
+			COALESCE(SUM(coupon_redemptions.discount) FILTER (WHERE coupon_redemptions.released_at IS NULL), 0) AS total_discount,
+			COALESCE(SUM(orders.total_amount) FILTER (WHERE coupon_redemptions.released_at IS NULL), 0) AS order_revenue`).
+		Joins("LEFT JOIN coupon_redemptions ON coupon_redemptions.coupon_id = coupons.id").
+		Joins("LEFT JOIN orders ON orders.id = coupon_redemptions.order_id").
+		Group("coupons.id").
+		Order("uses DESC, coupons.code").
+		Scan(&report).Error
+	return report, err
+}
+
+// ApplyCartCoupon applies a coupon to the user's cart and returns the
+// discounted cart. The coupon must apply to the cart as it is, together with
+// the coupons already applied. With req.Preview the cart is priced with the
+// coupon but the coupon is not kept.
+func ApplyCartCoupon(userID uint, req models.ApplyCouponRequest) (models.CartSummary, error) {
+	coupon, err := findCoupon(configs.DB, req.Code)
+	if err != nil {
+		return models.CartSummary{}, err
+	}
+
+	var applied []models.CartCoupon
+	if err := configs.DB.Where("user_id = ?", userID).Order("id").Find(&applied).Error; err != nil {
+		return models.CartSummary{}, err
+	}
+	couponIDs := make([]uint, 0, len(applied)+1)
+	for _, cartCoupon := range applied {
+		if cartCoupon.CouponID != coupon.ID {
+			couponIDs = append(couponIDs, cartCoupon.CouponID)
+		}
+	}
+	couponIDs = append(couponIDs, coupon.ID)
+
+	summary, rejected, err := priceCart(configs.DB, userID, couponIDs)
+	if err != nil {
+		return models.CartSummary{}, err
+	}
+	for _, rejection := range rejected {
+		if rejection.code == coupon.Code {
+			return models.CartSummary{}, rejection.err
+		}
+	}
+
+	if !req.Preview {
+		cartCoupon := models.CartCoupon{UserID: userID, CouponID: coupon.ID}
+		if err := configs.DB.Where(cartCoupon).FirstOrCreate(&cartCoupon).Error; err != nil {
+			return models.CartSummary{}, err
+		}
+	}
+	return summary, nil
+}
+
+// RemoveCartCoupon removes a coupon from the user's cart
+func RemoveCartCoupon(userID uint, code string) error {
+	coupon, err := findCoupon(configs.DB, code)
+	if err != nil {
+		return err
+	}
+
+	result := configs.DB.Where("user_id = ? AND coupon_id = ?", userID, coupon.ID).Delete(&models.CartCoupon{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("coupon not applied to cart")
+	}
+	return nil
+}
+
+// GetCartSummary prices the user's cart with its bundle discounts, the
+// coupons applied to it and the shipping fee. Applied coupons that no longer
+// apply are listed as rejected and not taken off.
+func GetCartSummary(userID uint) (models.CartSummary, error) {
+	couponIDs, err := cartCouponIDs(configs.DB, userID)
+	if err != nil {
+		return models.CartSummary{}, err
+	}
+	summary, _, err := priceCart(configs.DB, userID, couponIDs)
+	return summary, err
+}
+
+// priceCart prices the user's cart with the coupons with the given IDs, in
+// that order, and returns the coupons that did not apply
+func priceCart(db *gorm.DB, userID uint, couponIDs []uint) (models.CartSummary, []couponRejection, error) {
+	items, err := cartOrderItems(db, userID)
+	if err != nil {
+		return models.CartSummary{}, nil, err
+	}
+
+	summary := models.CartSummary{Coupons: []models.AppliedCoupon{}}
+	for _, item := range items {
+		summary.Subtotal += item.Price * float64(item.Quantity)
+		summary.BundleDiscount += item.Discount
+	}
+	if len(items) > 0 {
+		summary.ShippingFee = shippingFee()
+	}
+
+	var coupons []models.Coupon
+	if len(couponIDs) > 0 {
+		if err := db.Scopes(preloadCouponTargets).Where("id IN ?", couponIDs).Find(&coupons).Error; err != nil {
+			return models.CartSummary{}, nil, err
+		}
+		coupons = orderedCoupons(coupons, couponIDs)
+	}
+
+	pricing, err := applyCoupons(db, userID, coupons, items, summary.ShippingFee, true)
+	if err != nil {
+		return models.CartSummary{}, nil, err
+	}
+	summary.Coupons = append(summary.Coupons, pricing.applied...)
+	for _, rejection := range pricing.rejected {
+		summary.RejectedCoupons = append(summary.RejectedCoupons, models.RejectedCoupon{
+			Code:   rejection.code,
+			Reason: rejection.err.Error(),
+		})
+	}
+
+	summary.Subtotal = roundMoney(summary.Subtotal)
+	summary.BundleDiscount = roundMoney(summary.BundleDiscount)
+	summary.CouponDiscount = pricing.discount
+	summary.ShippingDiscount = pricing.shippingDiscount
+	summary.Total = roundMoney(summary.Subtotal - summary.BundleDiscount - summary.CouponDiscount +
+		summary.ShippingFee - summary.ShippingDiscount)
+	return summary, pricing.rejected, nil
+}
+
+// cartOrderItems prices the lines of the user's cart as order items, with
+// their share of the bundle discounts. Lines outside bundles get the sale
+// price of the flash sale running on them unless it is sold out. Bundles that
+// can no longer be ordered get no discount, and lines of products that are
+// gone are left out.
+func cartOrderItems(db *gorm.DB, userID uint) ([]models.OrderItem, error) {
+	var carts []models.Cart
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&carts).Error; err != nil {
+		return nil, err
+	}
+
+	var items []models.OrderItem
+	for _, cart := range carts {
+		var product models.Product
+		if err := db.First(&product, cart.ProductID).Error; err != nil {
+			continue
+		}
+
+		var variant *models.ProductVariant
+		if cart.VariantID != nil {
+			variant = &models.ProductVariant{}
+			if err := db.First(variant, *cart.VariantID).Error; err != nil {
+				continue
+			}
+		}
+
+		item := models.OrderItem{
+			ProductID: cart.ProductID,
+			VariantID: cart.VariantID,
+			BundleID:  cart.BundleID,
+			Quantity:  cart.Quantity,
+			Price:     linePrice(product, variant),
+		}
+		if cart.BundleID == nil {
+			sale, err := liveFlashSale(db, cart.ProductID, cart.VariantID)
+			if err != nil {
+				return nil, err
+			}
+			if sale != nil && sale.Remaining > 0 {
+				item.Price = sale.SalePrice
+				item.FlashSaleID = &sale.ID
+			}
+		}
+		items = append(items, item)
+	}
+
+	if allocateBundleDiscounts(db, items) != nil {
+		for i := range items {
+			items[i].Discount = 0
+		}
+	}
+	return items, nil
+}
+
+// cartCouponIDs returns the IDs of the coupons applied to the user's cart,
+// in the order they were applied
+func cartCouponIDs(db *gorm.DB, userID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Model(&models.CartCoupon{}).Where("user_id = ?", userID).Order("id").Pluck("coupon_id", &ids).Error
+	return ids, err
+}
+
+// orderCoupons returns the coupons an order uses, in the order they are
+// taken off: those named by codes or, when codes is nil, those applied to
+// the user's cart. The coupon rows are locked in ID order, like stock rows,
+// so their usage limits hold under concurrent orders.
+func orderCoupons(tx *gorm.DB, userID uint, codes []string) ([]models.Coupon, error) {
+	var ids []uint
+	if codes == nil {
+		cartIDs, err := cartCouponIDs(tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		ids = cartIDs
+	} else {
+		for _, code := range codes {
+			coupon, err := findCoupon(tx, code)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, coupon.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var coupons []models.Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).
+		Order("id").
+		Find(&coupons).Error; err != nil {
+		return nil, err
+	}
+	for i := range coupons {
+		if err := tx.Where("coupon_id = ?", coupons[i].ID).Order("id").Find(&coupons[i].Targets).Error; err != nil {
+			return nil, err
+		}
+	}
+	return orderedCoupons(coupons, ids), nil
+}
+
+// couponRejection is a coupon that did not apply and why
+type couponRejection struct {
+	code string
+	err  error
+}
+
+// couponPricing is the outcome of taking coupons off priced order items
+type couponPricing struct {
+	applied          []models.AppliedCoupon
+	redeemed         []models.Coupon
+	rejected         []couponRejection
+	discount         float64
+	shippingDiscount float64
+}
+
+// applyCoupons takes coupons off priced order items in the order given.
+// Percent and fixed coupons take their discount off the eligible items, net
+// of the bundle discount and of earlier coupons, and spread it across them
+// in proportion to that amount, adding each share to the item's Discount.
+// Free shipping coupons take the shipping fee off, up to their max
+// discount. With lenient set, coupons that do not apply are rejected and
+// left out; otherwise the first one that does not apply fails.
+func applyCoupons(db *gorm.DB, userID uint, coupons []models.Coupon, items []models.OrderItem, fee float64, lenient bool) (couponPricing, error) {
+	var pricing couponPricing
+
+	var subtotal float64
+	for _, item := range items {
+		subtotal += item.Price*float64(item.Quantity) - item.Discount
+	}
+
+	now := time.Now()
+	for i, coupon := range coupons {
+		err := checkCoupon(db, userID, coupons, i, subtotal, now)
+		var discount float64
+		if err == nil {
+			discount, err = takeCoupon(db, coupon, items, fee-pricing.shippingDiscount)
+		}
+		if err != nil {
+			if !lenient {
+				return couponPricing{}, err
+			}
+			pricing.rejected = append(pricing.rejected, couponRejection{code: coupon.Code, err: err})
+			continue
+		}
+
+		if coupon.Type == models.CouponFreeShipping {
+			pricing.shippingDiscount += discount
+		} else {
+			pricing.discount += discount
+		}
+		pricing.applied = append(pricing.applied, models.AppliedCoupon{Code: coupon.Code, Type: coupon.Type, Discount: discount})
+		pricing.redeemed = append(pricing.redeemed, coupon)
+	}
+
+	pricing.discount = roundMoney(pricing.discount)
+	pricing.shippingDiscount = roundMoney(pricing.shippingDiscount)
+	return pricing, nil
+}
+
+// checkCoupon checks that the coupon at index i of the coupons used
+// together can be used by the user for an order of subtotal: it is active
+// and within its validity window, it stacks when used with others, the
+// order reaches its minimum value and the usage limits are not reached
+func checkCoupon(db *gorm.DB, userID uint, coupons []models.Coupon, i int, subtotal float64, now time.Time) error {
+	coupon := coupons[i]
+	if !coupon.IsActive {
+		return fmt.Errorf("%w: %s is not active", ErrCouponNotApplicable, coupon.Code)
+	}
+	if coupon.StartsAt != nil && now.Before(*coupon.StartsAt) {
+		return fmt.Errorf("%w: %s is not valid yet", ErrCouponNotApplicable, coupon.Code)
+	}
+	if coupon.EndsAt != nil && !now.Before(*coupon.EndsAt) {
+		return fmt.Errorf("%w: %s has expired", ErrCouponNotApplicable, coupon.Code)
+	}
+	for j, other := range coupons {
+		if j == i {
+			continue
+		}
+		if other.ID == coupon.ID {
+			return fmt.Errorf("%w: %s is used twice", ErrCouponNotApplicable, coupon.Code)
+		}
+		if !coupon.Stackable || !other.Stackable {
+			return fmt.Errorf("%w: %s cannot be combined with %s", ErrCouponNotApplicable, coupon.Code, other.Code)
+		}
+	}
+	if subtotal < coupon.MinOrderValue {
+		return fmt.Errorf("%w: %s needs an order of at least %.2f", ErrCouponNotApplicable, coupon.Code, coupon.MinOrderValue)
+	}
+
+	if coupon.UsageLimit != nil && coupon.UsedCount >= *coupon.UsageLimit {
+		return fmt.Errorf("%w: %s", ErrCouponLimitReached, coupon.Code)
+	}
+	if coupon.PerUserLimit != nil {
+		var used int64
+		if err := db.Model(&models.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ? AND released_at IS NULL", coupon.ID, userID).
+			Count(&used).Error; err != nil {
+			return err
+		}
+		if used >= int64(*coupon.PerUserLimit) {
+			return fmt.Errorf("%w: %s was already used %d times", ErrCouponLimitReached, coupon.Code, used)
+		}
+	}
+	return nil
+}
+
+// takeCoupon takes a coupon off the items it applies to and returns its
+// discount; for free shipping coupons, the part of the shipping fee left
+// that it waives
+func takeCoupon(db *gorm.DB, coupon models.Coupon, items []models.OrderItem, fee float64) (float64, error) {
+	eligible, err := couponItems(db, coupon, items)
+	if err != nil {
+		return 0, err
+	}
+	if len(eligible) == 0 {
+		return 0, fmt.Errorf("%w: %s does not apply to any item", ErrCouponNotApplicable, coupon.Code)
+	}
+
+	if coupon.Type == models.CouponFreeShipping {
+		discount := fee
+		if coupon.MaxDiscount != nil {
+			discount = min(discount, *coupon.MaxDiscount)
+		}
+		return roundMoney(discount), nil
+	}
+
+	var base float64
+	for _, i := range eligible {
+		base += items[i].Price*float64(items[i].Quantity) - items[i].Discount
+	}
+
+	var discount float64
+	switch coupon.Type {
+	case models.CouponPercent:
+		discount = base * coupon.Value / 100
+		if coupon.MaxDiscount != nil {
+			discount = min(discount, *coupon.MaxDiscount)
+		}
+	case models.CouponFixed:
+		discount = min(coupon.Value, base)
+	}
+	discount = roundMoney(discount)
+	if discount == 0 {
+		return 0, nil
+	}
+
+	left := discount
+	for n, i := range eligible {
+		share := left
+		if n < len(eligible)-1 {
+			share = roundMoney(discount * (items[i].Price*float64(items[i].Quantity) - items[i].Discount) / base)
+		}
+		items[i].Discount = roundMoney(items[i].Discount + share)
+		left -= share
+	}
+	return discount, nil
+}
+
+// couponItems returns the indexes of the items a coupon applies to: all of
+// them for coupons without restrictions, else those of the listed products
+// and of products in the listed categories or their subcategories
+func couponItems(db *gorm.DB, coupon models.Coupon, items []models.OrderItem) ([]int, error) {
+	products := make(map[uint]bool)
+	categories := make(map[uint]bool)
+	for _, target := range coupon.Targets {
+		if target.ProductID != nil {
+			products[*target.ProductID] = true
+		}
+		if target.CategoryID != nil {
+			ids, err := categoryDescendantIDs(db, *target.CategoryID)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				categories[id] = true
+			}
+		}
+	}
+	restricted := len(coupon.Targets) > 0
+
+	var eligible []int
+	for i, item := range items {
+		if restricted && !products[item.ProductID] {
+			if len(categories) == 0 {
+				continue
+			}
+			var product models.Product
+			if err := db.Unscoped().Select("id", "category_id").First(&product, item.ProductID).Error; err != nil {
+				return nil, err
+			}
+			if !categories[product.CategoryID] {
+				continue
+			}
+		}
+		eligible = append(eligible, i)
+	}
+	return eligible, nil
+}
+
+// redeemCoupons records the use of the coupons taken off an order and
+// counts it towards their usage limits. The update only succeeds while the
+// global limit is not reached, as a last guard besides the row lock taken
+// when the coupons were loaded.
+func redeemCoupons(tx *gorm.DB, order models.Order, pricing couponPricing) error {
+	for i, coupon := range pricing.redeemed {
+		result := tx.Model(&models.Coupon{}).
+			Where("id = ? AND (usage_limit IS NULL OR used_count < usage_limit)", coupon.ID).
+			UpdateColumn("used_count", gorm.Expr("used_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrCouponLimitReached, coupon.Code)
+		}
+
+		if err := tx.Create(&models.CouponRedemption{
+			CouponID: coupon.ID,
+			OrderID:  order.ID,
+			UserID:   order.UserID,
+			Code:     coupon.Code,
+			Discount: pricing.applied[i].Discount,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveCouponUsage releases the coupons used by an order when it becomes
+// cancelled, so they no longer count towards their limits, and counts them
+// again when an admin moves it out of CANCELLED, failing when a coupon has
+// reached its usage limit since
+func moveCouponUsage(tx *gorm.DB, order models.Order, status string) error {
+	wasCancelled := strings.EqualFold(order.Status, "CANCELLED")
+	cancel := strings.EqualFold(status, "CANCELLED")
+	if wasCancelled == cancel {
+		return nil
+	}
+
+	var redemptions []models.CouponRedemption
+	query := tx.Where("order_id = ?", order.ID)
+	if cancel {
+		query = query.Where("released_at IS NULL")
+	} else {
+		query = query.Where("released_at IS NOT NULL")
+	}
+	if err := query.Order("coupon_id").Find(&redemptions).Error; err != nil {
+		return err
+	}
+
+	for _, redemption := range redemptions {
+		var releasedAt interface{}
+		coupons := tx.Model(&models.Coupon{}).Unscoped().Where("id = ?", redemption.CouponID)
+		change := gorm.Expr("used_count + 1")
+		if cancel {
+			releasedAt = time.Now()
+			change = gorm.Expr("GREATEST(used_count - 1, 0)")
+		} else {
+			coupons = coupons.Where("usage_limit IS NULL OR used_count < usage_limit")
+		}
+
+		result := coupons.UpdateColumn("used_count", change)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrCouponLimitReached, redemption.Code)
+		}
+		if err := tx.Model(&redemption).Update("released_at", releasedAt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findCoupon returns the live coupon with a code, in any case
+func findCoupon(db *gorm.DB, code string) (models.Coupon, error) {
+	var coupon models.Coupon
+	if err := db.Where("code = ?", normalizeCouponCode(code)).First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Coupon{}, fmt.Errorf("%w: %s", ErrCouponNotFound, code)
+		}
+		return models.Coupon{}, err
+	}
+	return coupon, nil
+}
+
+// orderedCoupons returns coupons in the order of ids
+func orderedCoupons(coupons []models.Coupon, ids []uint) []models.Coupon {
+	byID := make(map[uint]models.Coupon, len(coupons))
+	for _, coupon := range coupons {
+		byID[coupon.ID] = coupon
+	}
+	ordered := make([]models.Coupon, 0, len(coupons))
+	for _, id := range ids {
+		if coupon, ok := byID[id]; ok {
+			ordered = append(ordered, coupon)
+		}
+	}
+	return ordered
+}
+
+// validateCoupon checks the fields of a coupon request that binding cannot:
+// the value for the coupon type, the validity window, that the code is free
+// (excluding coupon excludeID, deleted coupons included) and that the
+// restricted products and categories exist
+func validateCoupon(req models.CouponRequest, excludeID uint) error {
+	errs := models.FieldErrors{}
+	switch req.Type {
+	case models.CouponPercent:
+		if req.Value <= 0 || req.Value > 100 {
+			errs["value"] = "must be between 0 and 100 for percent coupons"
+		}
+	case models.CouponFixed:
+		if req.Value <= 0 {
+			errs["value"] = "must be greater than 0 for fixed coupons"
+		}
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		errs["ends_at"] = "must be after starts_at"
+	}
+
+	var taken int64
+	if err := configs.DB.Unscoped().Model(&models.Coupon{}).
+		Where("code = ? AND id <> ?", normalizeCouponCode(req.Code), excludeID).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		errs["code"] = "is already taken"
+	}
+
+	if missing := missingIDs(&models.Product{}, req.ProductIDs); len(missing) > 0 {
+		errs["product_ids"] = fmt.Sprintf("unknown products: %v", missing)
+	}
+	if missing := missingIDs(&models.Category{}, req.CategoryIDs); len(missing) > 0 {
+		errs["category_ids"] = fmt.Sprintf("unknown categories: %v", missing)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// missingIDs returns the IDs among ids with no live row of model
+func missingIDs(model interface{}, ids []uint) []uint {
+	if len(ids) == 0 {
+		return nil
+	}
+	var found []uint
+	configs.DB.Model(model).Where("id IN ?", ids).Pluck("id", &found)
+
+	exists := make(map[uint]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	var missing []uint
+	for _, id := range ids {
+		if !exists[id] {
+			missing = append(missing, id)
+			exists[id] = true
+		}
+	}
+	return missing
+}
+
+// setCouponFields copies a coupon request onto a coupon
+func setCouponFields(coupon *models.Coupon, req models.CouponRequest) {
+	coupon.Code = normalizeCouponCode(req.Code)
+	coupon.Description = req.Description
+	coupon.Type = req.Type
+	coupon.Value = req.Value
+	coupon.MinOrderValue = req.MinOrderValue
+	coupon.MaxDiscount = req.MaxDiscount
+	coupon.StartsAt = req.StartsAt
+	coupon.EndsAt = req.EndsAt
+	coupon.UsageLimit = req.UsageLimit
+	coupon.PerUserLimit = req.PerUserLimit
+	coupon.Stackable = req.Stackable
+	coupon.IsActive = req.IsActive == nil || *req.IsActive
+	if req.Type == models.CouponFreeShipping {
+		coupon.Value = 0
+	}
+}
+
+// createCouponTargets stores the product and category restrictions of a
+// coupon request
+func createCouponTargets(tx *gorm.DB, couponID uint, req models.CouponRequest) error {
+	var targets []models.CouponTarget
+	for _, id := range req.ProductIDs {
+		productID := id
+		targets = append(targets, models.CouponTarget{CouponID: couponID, ProductID: &productID})
+	}
+	for _, id := range req.CategoryIDs {
+		categoryID := id
+		targets = append(targets, models.CouponTarget{CouponID: couponID, CategoryID: &categoryID})
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	return tx.Create(&targets).Error
+}
+
+// preloadCouponTargets loads the restrictions of coupons
+func preloadCouponTargets(db *gorm.DB) *gorm.DB {
+	return db.Preload("Targets", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
+
+// fillCouponTargets fills in the product and category IDs of a coupon from
+// its targets
+func fillCouponTargets(coupon *models.Coupon) {
+	coupon.ProductIDs = []uint{}
+	coupon.CategoryIDs = []uint{}
+	for _, target := range coupon.Targets {
+		if target.ProductID != nil {
+			coupon.ProductIDs = append(coupon.ProductIDs, *target.ProductID)
+		}
+		if target.CategoryID != nil {
+			coupon.CategoryIDs = append(coupon.CategoryIDs, *target.CategoryID)
+		}
+	}
+}
+
+// normalizeCouponCode trims a coupon code and puts it in upper case
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
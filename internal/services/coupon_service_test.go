@@ -0,0 +1,280 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/internal/models"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// couponCartItems returns a phone with a 20 bundle discount and two cases,
+// 380 after the bundle discount
+func couponCartItems() []models.OrderItem {
+	bundleID := uint(1)
+	return []models.OrderItem{
+		{ProductID: 1, BundleID: &bundleID, Quantity: 1, Price: 200, Discount: 20},
+		{ProductID: 2, Quantity: 2, Price: 100},
+	}
+}
+
+func TestTakeCoupon(t *testing.T) {
+	maxDiscount := func(amount float64) *float64 { return &amount }
+	productID := uint(2)
+	otherProductID := uint(3)
+
+	tests := []struct {
+		name      string
+		coupon    models.Coupon
+		fee       float64
+		want      float64
+		discounts []float64
+		wantErr   error
+	}{
+		{
+			name:      "percent spread by net amount",
+			coupon:    models.Coupon{Code: "TEN", Type: models.CouponPercent, Value: 10},
+			want:      38,
+			discounts: []float64{38, 20},
+		},
+		{
+			name:      "percent capped",
+			coupon:    models.Coupon{Code: "TEN", Type: models.CouponPercent, Value: 10, MaxDiscount: maxDiscount(19)},
+			want:      19,
+			discounts: []float64{29, 10},
+		},
+		{
+			name:      "fixed up to the items total",
+			coupon:    models.Coupon{Code: "BIG", Type: models.CouponFixed, Value: 500},
+			want:      380,
+			discounts: []float64{200, 200},
+		},
+		{
+			name: "restricted to a product",
+			coupon: models.Coupon{Code: "CASES", Type: models.CouponPercent, Value: 10,
+				Targets: []models.CouponTarget{{ProductID: &productID}}},
+			want:      20,
+			discounts: []float64{20, 20},
+		},
+		{
+			name: "restricted to a product not in the cart",
+			coupon: models.Coupon{Code: "OTHER", Type: models.CouponPercent, Value: 10,
+				Targets: []models.CouponTarget{{ProductID: &otherProductID}}},
+			discounts: []float64{20, 0},
+			wantErr:   ErrCouponNotApplicable,
+		},
+		{
+			name:      "free shipping capped",
+			coupon:    models.Coupon{Code: "SHIP", Type: models.CouponFreeShipping, MaxDiscount: maxDiscount(20)},
+			fee:       30,
+			want:      20,
+			discounts: []float64{20, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := couponCartItems()
+			got, err := takeCoupon(nil, tt.coupon, items, tt.fee)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("discount %v, want %v", got, tt.want)
+			}
+			for i, item := range items {
+				if item.Discount != tt.discounts[i] {
+					t.Errorf("item %d discount %v, want %v", i, item.Discount, tt.discounts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyCouponsStacking(t *testing.T) {
+	limit := 5
+	past := time.Now().Add(-time.Hour)
+	percent := models.Coupon{ID: 1, Code: "TEN", Type: models.CouponPercent, Value: 10, Stackable: true, IsActive: true}
+	fixed := models.Coupon{ID: 2, Code: "FIFTY", Type: models.CouponFixed, Value: 50, Stackable: true, IsActive: true}
+	exclusive := models.Coupon{ID: 3, Code: "SOLO", Type: models.CouponFixed, Value: 30, IsActive: true}
+	shipping := models.Coupon{ID: 4, Code: "SHIP", Type: models.CouponFreeShipping, Stackable: true, IsActive: true}
+
+	tests := []struct {
+		name             string
+		coupons          []models.Coupon
+		discount         float64
+		shippingDiscount float64
+		applied          []string
+		rejected         []string
+		strictErr        error
+	}{
+		{
+			name:             "stackable coupons apply one after another",
+			coupons:          []models.Coupon{percent, fixed, shipping},
+			discount:         88, // 10% of 380, then 50 off the 342 left
+			shippingDiscount: 30,
+			applied:          []string{"TEN", "FIFTY", "SHIP"},
+		},
+		{
+			name:     "exclusive coupon on its own",
+			coupons:  []models.Coupon{exclusive},
+			discount: 30,
+			applied:  []string{"SOLO"},
+		},
+		{
+			name:      "exclusive coupon with another",
+			coupons:   []models.Coupon{percent, exclusive},
+			rejected:  []string{"TEN", "SOLO"},
+			strictErr: ErrCouponNotApplicable,
+		},
+		{
+			name:      "same coupon twice",
+			coupons:   []models.Coupon{fixed, fixed},
+			rejected:  []string{"FIFTY", "FIFTY"},
+			strictErr: ErrCouponNotApplicable,
+		},
+		{
+			name: "minimum order not reached",
+			coupons: []models.Coupon{percent, func() models.Coupon {
+				c := fixed
+				c.MinOrderValue = 500
+				return c
+			}()},
+			discount:  38,
+			applied:   []string{"TEN"},
+			rejected:  []string{"FIFTY"},
+			strictErr: ErrCouponNotApplicable,
+		},
+		{
+			name: "usage limit reached",
+			coupons: []models.Coupon{func() models.Coupon {
+				c := percent
+				c.UsageLimit, c.UsedCount = &limit, limit
+				return c
+			}()},
+			rejected:  []string{"TEN"},
+			strictErr: ErrCouponLimitReached,
+		},
+		{
+			name: "expired",
+			coupons: []models.Coupon{func() models.Coupon {
+				c := percent
+				c.EndsAt = &past
+				return c
+			}()},
+			rejected:  []string{"TEN"},
+			strictErr: ErrCouponNotApplicable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pricing, err := applyCoupons(nil, 1, tt.coupons, couponCartItems(), 30, true)
+			if err != nil {
+				t.Fatalf("lenient: %v", err)
+			}
+			if pricing.discount != tt.discount || pricing.shippingDiscount != tt.shippingDiscount {
+				t.Errorf("discount %v and shipping discount %v, want %v and %v",
+					pricing.discount, pricing.shippingDiscount, tt.discount, tt.shippingDiscount)
+			}
+			if got := appliedCodes(pricing); !slices.Equal(got, tt.applied) {
+				t.Errorf("applied %v, want %v", got, tt.applied)
+			}
+			if got := rejectedCodes(pricing); !slices.Equal(got, tt.rejected) {
+				t.Errorf("rejected %v, want %v", got, tt.rejected)
+			}
+
+			_, err = applyCoupons(nil, 1, tt.coupons, couponCartItems(), 30, false)
+			if !errors.Is(err, tt.strictErr) {
+				t.Errorf("strict error %v, want %v", err, tt.strictErr)
+			}
+		})
+	}
+}
+
+func appliedCodes(pricing couponPricing) []string {
+	var codes []string
+	for _, applied := range pricing.applied {
+		codes = append(codes, applied.Code)
+	}
+	return codes
+}
+
+func rejectedCodes(pricing couponPricing) []string {
+	var codes []string
+	for _, rejected := range pricing.rejected {
+		codes = append(codes, rejected.code)
+	}
+	return codes
+}
+
+func TestCouponUsageLimitHoldsUnderConcurrentOrders(t *testing.T) {
+	db := openTestDB(t)
+
+	const usageLimit, buyers = 3, 20
+	product := createStockedProduct(t, db, buyers)
+
+	limit := usageLimit
+	coupon, err := CreateCoupon(models.CouponRequest{
+		Code:       fmt.Sprintf("TEN-%d", time.Now().UnixNano()),
+		Type:       models.CouponPercent,
+		Value:      10,
+		UsageLimit: &limit,
+	})
+	if err != nil {
+		t.Fatalf("create coupon: %v", err)
+	}
+
+	user := createTestUser(t, db, "Bargain hunter")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var discounted []uint
+	refused := 0
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, err := CreateOrderFromRequest(user.ID, models.CreateOrderRequest{
+				PaymentMethodID: 1,
+				ShippingAddress: "Ho Chi Minh City, Vietnam",
+				Items:           []models.CreateOrderItemRequest{{ProductID: product.ID, Quantity: 1}},
+				CouponCodes:     []string{coupon.Code},
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				if order.CouponDiscount != 10 || order.TotalAmount != 90 {
+					t.Errorf("order discount %v total %v, want 10 and 90", order.CouponDiscount, order.TotalAmount)
+				}
+				discounted = append(discounted, order.ID)
+			case errors.Is(err, ErrCouponLimitReached):
+				refused++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(discounted) != usageLimit || refused != buyers-usageLimit {
+		t.Fatalf("discounted %d and refused %d, want %d and %d", len(discounted), refused, usageLimit, buyers-usageLimit)
+	}
+
+	// Cancelling an order gives its use back
+	var order models.Order
+	db.First(&order, discounted[0])
+	if err := UpdateOrderStatus(0, order.ID, order.Version, "CANCELLED"); err != nil {
+		t.Fatalf("cancel order: %v", err)
+	}
+
+	var after models.Coupon
+	db.First(&after, coupon.ID)
+	if after.UsedCount != usageLimit-1 {
+		t.Errorf("coupon used %d times after a cancellation, want %d", after.UsedCount, usageLimit-1)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/spreadsheet"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// Export formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// exportBatchSize is the number of products loaded and written at a time
+const exportBatchSize = 500
+
+// rowWriter writes spreadsheet rows in one of the export formats
+type rowWriter interface {
+	WriteRow(values ...interface{}) error
+	Flush() error
+	Close() error
+}
+
+// ExportProducts streams the products matching filter to w in the columns
+// read by ImportProducts, so an export can be edited and imported again.
+// Products are written in batches and w is flushed after each one when it
+// supports flushing.
+func ExportProducts(w io.Writer, format string, filter models.ProductFilter) error {
+	query, err := filterProductsQuery(configs.DB, filter)
+	if err != nil {
+		return err
+	}
+
+	var categories []models.Category
+	if err := configs.DB.Unscoped().Find(&categories).Error; err != nil {
+		return err
+	}
+	paths := categoryPaths(categories)
+
+	var out rowWriter
+	switch format {
+	case ExportFormatCSV:
+		out = &csvRowWriter{w: csv.NewWriter(w)}
+	case ExportFormatXLSX:
+		if out, err = spreadsheet.NewXLSXWriter(w, "Products"); err != nil {
+			return err
+		}
+	default:
+		return errors.New("unsupported export format, use csv or xlsx")
+	}
+
+	header := make([]interface{}, len(models.ProductImportColumns))
+	for i, column := range models.ProductImportColumns {
+		header[i] = column
+	}
+	if err := out.WriteRow(header...); err != nil {
+		return err
+	}
+
+	var batch []models.Product
+	result := query.FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, p := range batch {
+			if err := out.WriteRow(p.ID, p.SKU, p.Name, p.Description, p.Price, p.Stock, paths[p.CategoryID],
+				p.Brand, p.ImageUrl, p.IsFeatured, p.IsAvailable); err != nil {
+				return err
+			}
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := w.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return out.Close()
+}
+
+// csvRowWriter adapts csv.Writer to rowWriter
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) WriteRow(values ...interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRowWriter) Close() error {
+	return c.Flush()
+}
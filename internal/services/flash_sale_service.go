@@ -0,0 +1,512 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrFlashSaleNotFound is returned for unknown and deleted flash sales
+	ErrFlashSaleNotFound = errors.New("flash sale not found")
+
+	// ErrFlashSaleSoldOut is returned when fewer units of a running flash
+	// sale are left than requested
+	ErrFlashSaleSoldOut = errors.New("not enough flash sale units left")
+
+	// ErrFlashSaleLimitReached is returned when a customer would buy more
+	// units of a flash sale than its per-user limit
+	ErrFlashSaleLimitReached = errors.New("flash sale limit per customer reached")
+)
+
+// FlashSaleListSpec lists the sorts and filters available on flash sale
+// listings
+var FlashSaleListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"starts_at":  "starts_at",
+		"ends_at":    "ends_at",
+		"created_at": "created_at",
+	},
+	Filters: map[string]string{
+		"product_id": "product_id",
+	},
+	DefaultSort: "starts_at",
+}
+
+// GetFlashSales returns one page of the running and upcoming flash sales
+// with their products, sold out ones included
+func GetFlashSales(params pagination.Params) ([]models.FlashSale, pagination.Page, error) {
+	sales := []models.FlashSale{}
+	page, err := pagination.Find(configs.DB.Where("ends_at > ?", time.Now()), params, &sales, preloadFlashSaleProduct)
+	if err != nil {
+		return nil, page, err
+	}
+	now := time.Now()
+	for i := range sales {
+		fillFlashSale(&sales[i], now)
+	}
+	return sales, page, nil
+}
+
+// GetFlashSalesAdmin returns one page of flash sales, past ones included,
+// with their sell-through
+func GetFlashSalesAdmin(params pagination.Params) ([]models.FlashSaleSellThrough, pagination.Page, error) {
+	sales := []models.FlashSale{}
+	page, err := pagination.Find(configs.DB.Model(&models.FlashSale{}), params, &sales, preloadFlashSaleProduct)
+	if err != nil {
+		return nil, page, err
+	}
+	report, err := flashSaleSellThrough(sales)
+	return report, page, err
+}
+
+// GetFlashSaleSellThrough returns a flash sale with its live sell-through:
+// the units sold and left, and the orders, customers and revenue of its
+// purchases
+func GetFlashSaleSellThrough(id uint) (models.FlashSaleSellThrough, error) {
+	var sale models.FlashSale
+	if err := configs.DB.Scopes(preloadFlashSaleProduct).First(&sale, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.FlashSaleSellThrough{}, ErrFlashSaleNotFound
+		}
+		return models.FlashSaleSellThrough{}, err
+	}
+	report, err := flashSaleSellThrough([]models.FlashSale{sale})
+	if err != nil {
+		return models.FlashSaleSellThrough{}, err
+	}
+	return report[0], nil
+}
+
+// CreateFlashSale schedules a flash sale
+func CreateFlashSale(req models.FlashSaleRequest) (models.FlashSaleSellThrough, error) {
+	if err := validateFlashSale(req, 0, 0); err != nil {
+		return models.FlashSaleSellThrough{}, err
+	}
+
+	sale := models.FlashSale{
+		ProductID:         req.ProductID,
+		VariantID:         req.VariantID,
+		SalePrice:         req.SalePrice,
+		AllocatedQuantity: req.AllocatedQuantity,
+		PerUserLimit:      req.PerUserLimit,
+		StartsAt:          req.StartsAt,
+		EndsAt:            req.EndsAt,
+	}
+	if err := configs.DB.Create(&sale).Error; err != nil {
+		return models.FlashSaleSellThrough{}, err
+	}
+	return GetFlashSaleSellThrough(sale.ID)
+}
+
+// ReplaceFlashSale replaces the fields of a flash sale. The units sold so
+// far are kept, so the allocation cannot go below them.
+func ReplaceFlashSale(id uint, req models.FlashSaleRequest) (models.FlashSaleSellThrough, error) {
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		var sale models.FlashSale
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sale, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFlashSaleNotFound
+			}
+			return err
+		}
+		if err := validateFlashSale(req, id, sale.SoldQuantity); err != nil {
+			return err
+		}
+
+		return tx.Model(&sale).Updates(map[string]interface{}{
+			"product_id":         req.ProductID,
+			"variant_id":         req.VariantID,
+			"sale_price":         req.SalePrice,
+			"allocated_quantity": req.AllocatedQuantity,
+			"per_user_limit":     req.PerUserLimit,
+			"starts_at":          req.StartsAt,
+			"ends_at":            req.EndsAt,
+		}).Error
+	})
+	if err != nil {
+		return models.FlashSaleSellThrough{}, err
+	}
+	return GetFlashSaleSellThrough(id)
+}
+
+// DeleteFlashSale soft deletes a flash sale, ending it at once. Orders that
+// bought in it keep their purchases.
+func DeleteFlashSale(id uint) error {
+	result := configs.DB.Delete(&models.FlashSale{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFlashSaleNotFound
+	}
+	return nil
+}
+
+// validateFlashSale checks a flash sale request against the database: the
+// product and variant exist, the sale price is below the regular price, the
+// units left to sell are in stock, the allocation covers the sold units and
+// no other sale of the product or variant overlaps the window
+func validateFlashSale(req models.FlashSaleRequest, excludeID uint, sold int) error {
+	var product models.Product
+	if err := configs.DB.First(&product, req.ProductID).Error; err != nil {
+		return models.FieldErrors{"product_id": "product not found"}
+	}
+	variant, err := resolveLineVariant(configs.DB, product, req.VariantID)
+	if err != nil {
+		return models.FieldErrors{"variant_id": err.Error()}
+	}
+
+	errs := models.FieldErrors{}
+	if req.SalePrice >= linePrice(product, variant) {
+		errs["sale_price"] = "must be lower than the regular price"
+	}
+	if req.AllocatedQuantity < sold {
+		errs["allocated_quantity"] = fmt.Sprintf("must be at least the %d units sold", sold)
+	} else if stock := lineStock(product, variant); req.AllocatedQuantity-sold > stock {
+		errs["allocated_quantity"] = fmt.Sprintf("must not exceed the %d units in stock", stock+sold)
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		errs["ends_at"] = "must be after starts_at"
+	}
+
+	var overlapping int64
+	if err := configs.DB.Model(&models.FlashSale{}).
+		Where("product_id = ? AND id <> ? AND starts_at < ? AND ends_at > ?", req.ProductID, excludeID, req.EndsAt, req.StartsAt).
+		Scopes(forVariant(req.VariantID)).
+		Count(&overlapping).Error; err != nil {
+		return err
+	}
+	if overlapping > 0 {
+		errs["starts_at"] = "overlaps another flash sale of the product"
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// flashSaleSellThrough adds the orders, customers and revenue of their
+// purchases to flash sales
+func flashSaleSellThrough(sales []models.FlashSale) ([]models.FlashSaleSellThrough, error) {
+	report := make([]models.FlashSaleSellThrough, len(sales))
+	if len(sales) == 0 {
+		return report, nil
+	}
+
+	ids := make([]uint, len(sales))
+	for i, sale := range sales {
+		ids[i] = sale.ID
+	}
+	var totals []struct {
+		FlashSaleID uint
+		Orders      int
+		Customers   int
+		Revenue     float64
+	}
+	if err := configs.DB.Model(&models.FlashSalePurchase{}).
+		Select("flash_sale_id, COUNT(DISTINCT order_id) AS orders, COUNT(DISTINCT user_id) AS customers, COALESCE(SUM(quantity * price), 0) AS revenue").
+		Where("flash_sale_id IN ? AND released_at IS NULL", ids).
+		Group("flash_sale_id").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range sales {
+		fillFlashSale(&sales[i], now)
+		report[i].FlashSale = sales[i]
+		for _, total := range totals {
+			if total.FlashSaleID == sales[i].ID {
+				report[i].Orders = total.Orders
+				report[i].Customers = total.Customers
+				report[i].Revenue = roundMoney(total.Revenue)
+			}
+		}
+	}
+	return report, nil
+}
+
+// fillFlashSale fills in the state, units left and sell-through of a flash
+// sale at now
+func fillFlashSale(sale *models.FlashSale, now time.Time) {
+	sale.Remaining = max(sale.AllocatedQuantity-sale.SoldQuantity, 0)
+	if sale.AllocatedQuantity > 0 {
+		sale.SellThrough = roundMoney(float64(sale.SoldQuantity) * 100 / float64(sale.AllocatedQuantity))
+	}
+	switch {
+	case now.Before(sale.StartsAt):
+		sale.Status = models.FlashSaleScheduled
+	case !now.Before(sale.EndsAt):
+		sale.Status = models.FlashSaleEnded
+	case sale.Remaining == 0:
+		sale.Status = models.FlashSaleSoldOut
+	default:
+		sale.Status = models.FlashSaleLive
+	}
+}
+
+// preloadFlashSaleProduct loads the product and variant of flash sales,
+// deleted ones included
+func preloadFlashSaleProduct(db *gorm.DB) *gorm.DB {
+	return db.Preload("Product", includeDeleted).Preload("Variant", includeDeleted)
+}
+
+// liveFlashSale returns the flash sale running now on a product, or on one
+// of its variants, or nil when there is none. Sold out sales are returned
+// too.
+func liveFlashSale(db *gorm.DB, productID uint, variantID *uint) (*models.FlashSale, error) {
+	now := time.Now()
+	var sale models.FlashSale
+	err := db.Where("product_id = ? AND starts_at <= ? AND ends_at > ?", productID, now, now).
+		Scopes(forVariant(variantID)).
+		First(&sale).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fillFlashSale(&sale, now)
+	return &sale, nil
+}
+
+// flashSaleOffer returns how a flash sale is shown on products and cart
+// items
+func flashSaleOffer(sale models.FlashSale, regularPrice float64) models.FlashSaleOffer {
+	return models.FlashSaleOffer{
+		ID:           sale.ID,
+		VariantID:    sale.VariantID,
+		SalePrice:    sale.SalePrice,
+		RegularPrice: regularPrice,
+		PerUserLimit: sale.PerUserLimit,
+		Remaining:    sale.Remaining,
+		SoldOut:      sale.Remaining == 0,
+		EndsAt:       sale.EndsAt,
+	}
+}
+
+// cartFlashSale returns the offer of the flash sale pricing quantity units
+// of a cart line, or nil when none runs or it is sold out. It fails when
+// fewer units are left than quantity or the user would go over the per-user
+// limit.
+func cartFlashSale(db *gorm.DB, userID uint, product models.Product, variant *models.ProductVariant, quantity int) (*models.FlashSaleOffer, error) {
+	var variantID *uint
+	if variant != nil {
+		variantID = &variant.ID
+	}
+	sale, err := liveFlashSale(db, product.ID, variantID)
+	if err != nil || sale == nil || sale.Remaining == 0 {
+		return nil, err
+	}
+	if err := checkFlashSaleQuantity(db, userID, *sale, quantity); err != nil {
+		return nil, err
+	}
+	offer := flashSaleOffer(*sale, linePrice(product, variant))
+	return &offer, nil
+}
+
+// checkFlashSaleQuantity checks that quantity units of a running flash sale
+// are left and that the user may buy them
+func checkFlashSaleQuantity(db *gorm.DB, userID uint, sale models.FlashSale, quantity int) error {
+	if remaining := sale.AllocatedQuantity - sale.SoldQuantity; quantity > remaining {
+		return fmt.Errorf("%w. Available: %d, Requested: %d", ErrFlashSaleSoldOut, max(remaining, 0), quantity)
+	}
+	if sale.PerUserLimit == nil {
+		return nil
+	}
+
+	var bought int
+	if err := db.Model(&models.FlashSalePurchase{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("flash_sale_id = ? AND user_id = ? AND released_at IS NULL", sale.ID, userID).
+		Scan(&bought).Error; err != nil {
+		return err
+	}
+	if bought+quantity > *sale.PerUserLimit {
+		return fmt.Errorf("%w. Limit: %d, Bought: %d, Requested: %d", ErrFlashSaleLimitReached, *sale.PerUserLimit, bought, quantity)
+	}
+	return nil
+}
+
+// flashSaleClaim is the units of a flash sale an order buys
+type flashSaleClaim struct {
+	sale     models.FlashSale
+	quantity int
+}
+
+// claimFlashSales claims the units of the running flash sales that order
+// lines buy, outside bundles. Sale rows are locked in ID order, like stock
+// rows, and the sold units only grow while they stay within the
+// allocation, so concurrent orders cannot sell more than allocated or go
+// over the per-user limit. Sold out sales are skipped and their lines pay
+// the regular price.
+func claimFlashSales(tx *gorm.DB, userID uint, lines []stockLine) ([]flashSaleClaim, error) {
+	quantities := make(map[orderItemKey]int)
+	var productIDs []uint
+	for _, line := range lines {
+		if line.BundleID != nil {
+			continue
+		}
+		quantities[itemKey(line.ProductID, line.VariantID, nil)] += line.Quantity
+		productIDs = append(productIDs, line.ProductID)
+	}
+	if len(productIDs) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var sales []models.FlashSale
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id IN ? AND starts_at <= ? AND ends_at > ?", productIDs, now, now).
+		Order("id").
+		Find(&sales).Error; err != nil {
+		return nil, err
+	}
+
+	var claims []flashSaleClaim
+	for _, sale := range sales {
+		quantity, ok := quantities[itemKey(sale.ProductID, sale.VariantID, nil)]
+		if !ok || sale.SoldQuantity >= sale.AllocatedQuantity {
+			continue
+		}
+		if err := checkFlashSaleQuantity(tx, userID, sale, quantity); err != nil {
+			return nil, err
+		}
+
+		result := tx.Model(&models.FlashSale{}).
+			Where("id = ? AND sold_quantity + ? <= allocated_quantity", sale.ID, quantity).
+			UpdateColumn("sold_quantity", gorm.Expr("sold_quantity + ?", quantity))
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, fmt.Errorf("%w. Requested: %d", ErrFlashSaleSoldOut, quantity)
+		}
+		claims = append(claims, flashSaleClaim{sale: sale, quantity: quantity})
+	}
+	return claims, nil
+}
+
+// claimedFlashSale returns the flash sale claimed for a product or variant
+// bought outside bundles, or nil
+func claimedFlashSale(claims []flashSaleClaim, productID uint, variantID, bundleID *uint) *models.FlashSale {
+	if bundleID != nil {
+		return nil
+	}
+	key := itemKey(productID, variantID, nil)
+	for i := range claims {
+		if itemKey(claims[i].sale.ProductID, claims[i].sale.VariantID, nil) == key {
+			return &claims[i].sale
+		}
+	}
+	return nil
+}
+
+// recordFlashSalePurchases records the flash sale units bought by an order
+func recordFlashSalePurchases(tx *gorm.DB, order models.Order, claims []flashSaleClaim) error {
+	for _, claim := range claims {
+		if err := tx.Create(&models.FlashSalePurchase{
+			FlashSaleID: claim.sale.ID,
+			OrderID:     order.ID,
+			UserID:      order.UserID,
+			Quantity:    claim.quantity,
+			Price:       claim.sale.SalePrice,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveFlashSaleUsage gives the flash sale units of an order back to their
+// sales when it becomes cancelled, and takes them again when an admin moves
+// it out of CANCELLED, failing when a sale no longer has enough units left
+func moveFlashSaleUsage(tx *gorm.DB, order models.Order, status string) error {
+	wasCancelled := strings.EqualFold(order.Status, "CANCELLED")
+	cancel := strings.EqualFold(status, "CANCELLED")
+	if wasCancelled == cancel {
+		return nil
+	}
+
+	var purchases []models.FlashSalePurchase
+	query := tx.Where("order_id = ?", order.ID)
+	if cancel {
+		query = query.Where("released_at IS NULL")
+	} else {
+		query = query.Where("released_at IS NOT NULL")
+	}
+	if err := query.Order("flash_sale_id").Find(&purchases).Error; err != nil {
+		return err
+	}
+
+	for _, purchase := range purchases {
+		var releasedAt interface{}
+		sales := tx.Model(&models.FlashSale{}).Unscoped().Where("id = ?", purchase.FlashSaleID)
+		change := gorm.Expr("sold_quantity + ?", purchase.Quantity)
+		if cancel {
+			releasedAt = time.Now()
+			change = gorm.Expr("GREATEST(sold_quantity - ?, 0)", purchase.Quantity)
+		} else {
+			sales = sales.Where("sold_quantity + ? <= allocated_quantity", purchase.Quantity)
+		}
+
+		result := sales.UpdateColumn("sold_quantity", change)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w to reopen the order. Requested: %d", ErrFlashSaleSoldOut, purchase.Quantity)
+		}
+		if err := tx.Model(&purchase).Update("released_at", releasedAt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachFlashSales fills in the flash sales running on products and their
+// variants, sold out ones included
+func attachFlashSales(products []models.Product) {
+	if len(products) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+
+	now := time.Now()
+	var sales []models.FlashSale
+	if err := configs.DB.Preload("Variant").
+		Where("product_id IN ? AND starts_at <= ? AND ends_at > ?", ids, now, now).
+		Order("id").
+		Find(&sales).Error; err != nil {
+		log.Printf("Failed to load flash sales: %v", err)
+		return
+	}
+
+	for _, sale := range sales {
+		fillFlashSale(&sale, now)
+		for i := range products {
+			if products[i].ID != sale.ProductID {
+				continue
+			}
+			regular := products[i].Price
+			if sale.Variant != nil {
+				regular = sale.Variant.Price
+			}
+			products[i].FlashSales = append(products[i].FlashSales, flashSaleOffer(sale, regular))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"literally-backend/internal/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFlashSaleAllocationHoldsUnderConcurrentOrders(t *testing.T) {
+	db := openTestDB(t)
+
+	const allocated, buyers = 3, 20
+	product := createStockedProduct(t, db, buyers)
+
+	sale, err := CreateFlashSale(models.FlashSaleRequest{
+		ProductID:         product.ID,
+		SalePrice:         60,
+		AllocatedQuantity: allocated,
+		StartsAt:          time.Now().Add(-time.Minute),
+		EndsAt:            time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("create flash sale: %v", err)
+	}
+
+	user := createTestUser(t, db, "Early bird")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var onSale []uint
+	regular := 0
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, err := CreateOrderFromRequest(user.ID, models.CreateOrderRequest{
+				PaymentMethodID: 1,
+				ShippingAddress: "Ho Chi Minh City, Vietnam",
+				Items:           []models.CreateOrderItemRequest{{ProductID: product.ID, Quantity: 1}},
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case order.TotalAmount == 60:
+				onSale = append(onSale, order.ID)
+			case order.TotalAmount == 100:
+				regular++
+			default:
+				t.Errorf("order total %v, want 60 or 100", order.TotalAmount)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(onSale) != allocated || regular != buyers-allocated {
+		t.Fatalf("sold %d at the sale price and %d at the regular price, want %d and %d", len(onSale), regular, allocated, buyers-allocated)
+	}
+
+	// Cancelling an order gives its units back to the sale
+	var order models.Order
+	db.First(&order, onSale[0])
+	if err := UpdateOrderStatus(0, order.ID, order.Version, "CANCELLED"); err != nil {
+		t.Fatalf("cancel order: %v", err)
+	}
+
+	report, err := GetFlashSaleSellThrough(sale.ID)
+	if err != nil {
+		t.Fatalf("sell-through: %v", err)
+	}
+	if report.SoldQuantity != allocated-1 || report.Orders != allocated-1 || report.Status != models.FlashSaleLive {
+		t.Errorf("sold %d in %d orders with status %s after a cancellation, want %d and LIVE", report.SoldQuantity, report.Orders, report.Status, allocated-1)
+	}
+}
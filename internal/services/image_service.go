@@ -0,0 +1,268 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"mime/multipart"
+
+	"gorm.io/gorm"
+)
+
+// GetProductImages returns the gallery of a product in display order
+func GetProductImages(productID uint) []models.ProductImage {
+	var images []models.ProductImage
+	configs.DB.Where("product_id = ?", productID).Order("position, id").Find(&images)
+	return images
+}
+
+// AddProductImages uploads images to the end of a product's gallery. The
+// first uploaded image becomes the primary image when primary is set or the
+// product has none yet.
+func AddProductImages(productID uint, files []*multipart.FileHeader, primary bool) ([]models.ProductImage, error) {
+	if _, found := GetProductByID(productID); !found {
+		return nil, errors.New("product not found")
+	}
+
+	var stored []StoredImage
+	for _, file := range files {
+		image, err := storeImage(fmt.Sprintf("products/%d", productID), file)
+		if err != nil {
+			for _, s := range stored {
+				deleteImageBlobs(s.Key)
+			}
+			return nil, fmt.Errorf("%s: %w", file.Filename, err)
+		}
+		stored = append(stored, image)
+	}
+
+	var images []models.ProductImage
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		var position int
+		if err := tx.Model(&models.ProductImage{}).
+			Where("product_id = ?", productID).
+			Select("COALESCE(MAX(position), -1) + 1").
+			Scan(&position).Error; err != nil {
+			return err
+		}
+
+		for i, s := range stored {
+			image := models.ProductImage{
+				ProductID:    productID,
+				Position:     position + i,
+				StorageKey:   s.Key,
+				URL:          s.URL,
+				MediumURL:    s.MediumURL,
+				ThumbnailURL: s.ThumbnailURL,
+				ContentType:  s.ContentType,
+				Size:         s.Size,
+				Width:        s.Width,
+				Height:       s.Height,
+			}
+			if err := tx.Create(&image).Error; err != nil {
+				return err
+			}
+			images = append(images, image)
+		}
+
+		var primaries int64
+		if err := tx.Model(&models.ProductImage{}).
+			Where("product_id = ? AND is_primary = ?", productID, true).
+			Count(&primaries).Error; err != nil {
+			return err
+		}
+		if primary || primaries == 0 {
+			if err := setPrimaryImage(tx, productID, &images[0]); err != nil {
+				return err
+			}
+		}
+
+		return touchProduct(tx, productID)
+	})
+	if err != nil {
+		for _, s := range stored {
+			deleteImageBlobs(s.Key)
+		}
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// ReorderProductImages sets the gallery order; imageIDs must list every image
+// of the product exactly once
+func ReorderProductImages(productID uint, imageIDs []uint) ([]models.ProductImage, error) {
+	current := GetProductImages(productID)
+	if len(current) != len(imageIDs) {
+		return nil, errors.New("image_ids must list every image of the product")
+	}
+
+	known := make(map[uint]bool, len(current))
+	for _, image := range current {
+		known[image.ID] = true
+	}
+	for _, id := range imageIDs {
+		if !known[id] {
+			return nil, fmt.Errorf("image %d does not belong to this product or is listed twice", id)
+		}
+		delete(known, id)
+	}
+
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		for position, id := range imageIDs {
+			if err := tx.Model(&models.ProductImage{}).
+				Where("id = ?", id).
+				Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return touchProduct(tx, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetProductImages(productID), nil
+}
+
+// SetPrimaryProductImage makes an image the primary image of its product
+func SetPrimaryProductImage(productID, imageID uint) (models.ProductImage, error) {
+	image, err := findProductImage(configs.DB, productID, imageID)
+	if err != nil {
+		return models.ProductImage{}, err
+	}
+
+	err = configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := setPrimaryImage(tx, productID, &image); err != nil {
+			return err
+		}
+		return touchProduct(tx, productID)
+	})
+	if err != nil {
+		return models.ProductImage{}, err
+	}
+
+	return image, nil
+}
+
+// DeleteProductImage removes an image from the gallery and storage. When the
+// primary image is removed the next image in order takes its place.
+func DeleteProductImage(productID, imageID uint) error {
+	image, err := findProductImage(configs.DB, productID, imageID)
+	if err != nil {
+		return err
+	}
+
+	err = configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		if err := touchProduct(tx, productID); err != nil {
+			return err
+		}
+
+		if !image.IsPrimary {
+			return nil
+		}
+
+		var next models.ProductImage
+		err := tx.Where("product_id = ?", productID).Order("position, id").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Model(&models.Product{}).
+				Where("id = ? AND image_url = ?", productID, image.URL).
+				Update("image_url", "").Error
+		}
+		if err != nil {
+			return err
+		}
+		return setPrimaryImage(tx, productID, &next)
+	})
+	if err != nil {
+		return err
+	}
+
+	deleteImageBlobs(image.StorageKey)
+	return nil
+}
+
+// UploadUserAvatar stores a new avatar for a user and removes the previous one
+func UploadUserAvatar(userID uint, file *multipart.FileHeader) (models.User, error) {
+	user, found := GetUserByID(userID)
+	if !found {
+		return models.User{}, errors.New("user not found")
+	}
+
+	image, err := storeImage(fmt.Sprintf("avatars/%d", userID), file)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if err := configs.DB.Model(&user).Updates(map[string]interface{}{
+		"photo":           image.MediumURL,
+		"photo_thumbnail": image.ThumbnailURL,
+		"photo_key":       image.Key,
+	}).Error; err != nil {
+		deleteImageBlobs(image.Key)
+		return models.User{}, err
+	}
+
+	oldKey := user.PhotoKey
+	if oldKey != "" && oldKey != image.Key {
+		deleteImageBlobs(oldKey)
+	}
+
+	user, _ = GetUserByID(userID)
+	return user, nil
+}
+
+// DeleteUserAvatar removes a user's photo
+func DeleteUserAvatar(userID uint) (models.User, error) {
+	user, found := GetUserByID(userID)
+	if !found {
+		return models.User{}, errors.New("user not found")
+	}
+
+	if err := configs.DB.Model(&user).Updates(map[string]interface{}{
+		"photo":           "",
+		"photo_thumbnail": "",
+		"photo_key":       "",
+	}).Error; err != nil {
+		return models.User{}, err
+	}
+
+	deleteImageBlobs(user.PhotoKey)
+
+	user, _ = GetUserByID(userID)
+	return user, nil
+}
+
+// findProductImage loads an image and checks it belongs to the product
+func findProductImage(db *gorm.DB, productID, imageID uint) (models.ProductImage, error) {
+	var image models.ProductImage
+	if err := db.Where("id = ? AND product_id = ?", imageID, productID).First(&image).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ProductImage{}, errors.New("image not found")
+		}
+		return models.ProductImage{}, err
+	}
+	return image, nil
+}
+
+// setPrimaryImage flags image as the product's only primary image and mirrors
+// its URL into products.image_url for clients that read the single URL
+func setPrimaryImage(tx *gorm.DB, productID uint, image *models.ProductImage) error {
+	if err := tx.Model(&models.ProductImage{}).
+		Where("product_id = ? AND id <> ?", productID, image.ID).
+		Update("is_primary", false).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(image).Update("is_primary", true).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Product{}).
+		Where("id = ?", productID).
+		Update("image_url", image.URL).Error
+}
@@ -0,0 +1,677 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/spreadsheet"
+	"log"
+	"math"
+	"mime/multipart"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Import limits
+const (
+	maxImportErrors    = 500
+	importProgressStep = 100
+)
+
+// MaxImportSize returns the maximum size of an import file in bytes
+func MaxImportSize() int64 {
+	return int64(envInt("IMPORT_MAX_SIZE_MB", 10)) << 20
+}
+
+// importRow is one non-empty spreadsheet row keyed by column name
+type importRow struct {
+	Number int
+	Values map[string]string
+}
+
+// plannedChange is what importing one row will do: create Product, or apply
+// Updates to the product with ProductID
+type plannedChange struct {
+	Row       int
+	ProductID uint
+	Product   models.Product
+	Updates   map[string]interface{}
+}
+
+// ImportProducts validates an uploaded CSV or XLSX file of products and,
+// unless dryRun is set, creates or updates them. Files with more rows than
+// IMPORT_SYNC_ROWS (default 200), or any file when async is set, are
+// processed in the background; poll GetImportJob for the outcome.
+func ImportProducts(adminID uint, file *multipart.FileHeader, dryRun, async bool) (models.ImportJob, error) {
+	if file.Size > MaxImportSize() {
+		return models.ImportJob{}, fmt.Errorf("file exceeds the maximum size of %d MB", MaxImportSize()>>20)
+	}
+
+	rows, err := readImportFile(file)
+	if err != nil {
+		return models.ImportJob{}, err
+	}
+
+	job := models.ImportJob{
+		AdminID:   adminID,
+		FileName:  filepath.Base(file.Filename),
+		DryRun:    dryRun,
+		Status:    models.ImportStatusPending,
+		TotalRows: len(rows),
+		Errors:    []models.ImportRowError{},
+	}
+	if err := configs.DB.Create(&job).Error; err != nil {
+		return models.ImportJob{}, err
+	}
+
+	if async || len(rows) > envInt("IMPORT_SYNC_ROWS", 200) {
+		background := job
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					finishImport(&background, models.ImportStatusFailed, fmt.Sprint("import crashed: ", r))
+				}
+			}()
+			runImport(&background, rows)
+		}()
+		return job, nil
+	}
+
+	runImport(&job, rows)
+	return job, nil
+}
+
+// GetImportJob returns an import job by ID
+func GetImportJob(id uint) (models.ImportJob, error) {
+	var job models.ImportJob
+	if err := configs.DB.First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return job, errors.New("import job not found")
+		}
+		return job, err
+	}
+	return job, nil
+}
+
+// runImport validates the rows of a job and applies them unless it is a dry run
+func runImport(job *models.ImportJob, rows []importRow) {
+	now := time.Now()
+	job.Status = models.ImportStatusRunning
+	job.StartedAt = &now
+	configs.DB.Model(job).Updates(map[string]interface{}{"status": job.Status, "started_at": now})
+
+	plan, rowErrors, err := planProductImport(rows)
+	if err != nil {
+		finishImport(job, models.ImportStatusFailed, err.Error())
+		return
+	}
+	job.Errors = rowErrors
+	if len(rowErrors) >= maxImportErrors {
+		job.Message = fmt.Sprintf("showing the first %d errors", maxImportErrors)
+	}
+	for _, change := range plan {
+		if change.ProductID == 0 {
+			job.Created++
+		} else {
+			job.Updated++
+		}
+	}
+
+	if job.DryRun {
+		job.ProcessedRows = job.TotalRows
+		finishImport(job, models.ImportStatusCompleted, job.Message)
+		return
+	}
+	if len(rowErrors) > 0 {
+		finishImport(job, models.ImportStatusFailed, "no changes were applied, fix the errors and import again")
+		return
+	}
+
+	productIDs, err := applyProductImport(job, plan)
+	if err != nil {
+		job.Created, job.Updated = 0, 0
+		finishImport(job, models.ImportStatusFailed, "no changes were applied: "+err.Error())
+		return
+	}
+	for _, id := range productIDs {
+		indexProduct(id)
+	}
+
+	job.ProcessedRows = job.TotalRows
+	finishImport(job, models.ImportStatusCompleted, "")
+}
+
+// finishImport stores the final state of a job
+func finishImport(job *models.ImportJob, status, message string) {
+	now := time.Now()
+	job.Status = status
+	job.Message = message
+	job.FinishedAt = &now
+	if err := configs.DB.Save(job).Error; err != nil {
+		log.Printf("Failed to save import job %d: %v", job.ID, err)
+	}
+}
+
+// applyProductImport writes a validated plan in one transaction and returns
+// the IDs of all created and updated products
+func applyProductImport(job *models.ImportJob, plan []plannedChange) ([]uint, error) {
+	var productIDs []uint
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		for i, change := range plan {
+			if change.ProductID == 0 {
+				if err := createImportedProduct(tx, job, &change.Product); err != nil {
+					return fmt.Errorf("row %d: %w", change.Row, err)
+				}
+				productIDs = append(productIDs, change.Product.ID)
+			} else {
+				entry := models.PriceHistory{ProductID: change.ProductID, Source: models.PriceSourceImport}
+				stockEntry := importMovement(job, change.ProductID, models.MovementAdjustment)
+				if err := trackProductPrice(tx, entry, func() error {
+					return trackStock(tx, stockEntry, func() error {
+						if err := tx.Model(&models.Product{}).Where("id = ?", change.ProductID).Updates(change.Updates).Error; err != nil {
+							return err
+						}
+						return syncProductFromVariants(tx, change.ProductID)
+					})
+				}); err != nil {
+					return fmt.Errorf("row %d: %w", change.Row, err)
+				}
+				if categoryID, ok := change.Updates["category_id"].(uint); ok {
+					if err := pruneProductAttributes(tx, change.ProductID, categoryID); err != nil {
+						return fmt.Errorf("row %d: %w", change.Row, err)
+					}
+				}
+				productIDs = append(productIDs, change.ProductID)
+			}
+
+			if (i+1)%importProgressStep == 0 {
+				configs.DB.Model(job).Update("processed_rows", i+1)
+			}
+		}
+		return nil
+	})
+	return productIDs, err
+}
+
+// createImportedProduct inserts a product and records its first price and
+// stock; is_available has a database default of true, so an explicit false
+// is written afterwards
+func createImportedProduct(tx *gorm.DB, job *models.ImportJob, product *models.Product) error {
+	available := product.IsAvailable
+	if err := tx.Create(product).Error; err != nil {
+		return err
+	}
+	entry := models.PriceHistory{ProductID: product.ID, Source: models.PriceSourceImport}
+	if err := recordInitialPrice(tx, entry, product.Price, product.CompareAtPrice); err != nil {
+		return err
+	}
+	if err := recordInitialStock(tx, importMovement(job, product.ID, models.MovementReceiving), product.Stock); err != nil {
+		return err
+	}
+	if !available {
+		return tx.Model(product).Update("is_available", false).Error
+	}
+	return nil
+}
+
+// importMovement is the inventory ledger entry of a stock change made by an
+// import job
+func importMovement(job *models.ImportJob, productID uint, reason string) models.InventoryMovement {
+	return models.InventoryMovement{
+		ProductID:     productID,
+		Reason:        reason,
+		ReferenceType: models.MovementRefImport,
+		ReferenceID:   &job.ID,
+		AdminID:       &job.AdminID,
+	}
+}
+
+// readImportFile parses a .csv or .xlsx upload into its non-empty data rows
+func readImportFile(file *multipart.FileHeader) ([]importRow, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cells [][]string
+	switch strings.ToLower(filepath.Ext(file.Filename)) {
+	case ".csv":
+		cells, err = readCSV(f)
+	case ".xlsx":
+		cells, err = spreadsheet.ReadXLSX(f, file.Size)
+	default:
+		return nil, errors.New("unsupported file type, upload a .csv or .xlsx file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cells) == 0 {
+		return nil, errors.New("the file is empty")
+	}
+
+	// Header names are matched loosely: "Image URL" is image_url
+	columns := make([]string, len(cells[0]))
+	for i, header := range cells[0] {
+		columns[i] = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(header)), " ", "_")
+	}
+	if !slices.Contains(columns, "id") && !slices.Contains(columns, "sku") && !slices.Contains(columns, "name") {
+		return nil, fmt.Errorf("the first row must be a header with the columns %s", strings.Join(models.ProductImportColumns, ", "))
+	}
+
+	var rows []importRow
+	for i, values := range cells[1:] {
+		row := importRow{Number: i + 2, Values: make(map[string]string)}
+		for j, value := range values {
+			value = strings.TrimSpace(value)
+			if j < len(columns) && value != "" && slices.Contains(models.ProductImportColumns, columns[j]) {
+				row.Values[columns[j]] = value
+			}
+		}
+		if len(row.Values) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("the file has no product rows")
+	}
+	return rows, nil
+}
+
+// readCSV reads a CSV file, skipping a UTF-8 byte order mark and accepting
+// semicolons as separator, as written by Excel in many locales
+func readCSV(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	if header, _ := br.Peek(1024); bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+
+	cells, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	return cells, nil
+}
+
+// planProductImport matches every row to an existing product or a new one
+// and validates it. Rows are matched by id, then sku, then name.
+func planProductImport(rows []importRow) ([]plannedChange, []models.ImportRowError, error) {
+	categories, err := newCategoryResolver()
+	if err != nil {
+		return nil, nil, err
+	}
+	existing, err := loadImportMatches(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var plan []plannedChange
+	rowErrors := []models.ImportRowError{}
+	seen := make(map[string]int)
+	skuRows := make(map[string]int)
+
+	for _, row := range rows {
+		if len(rowErrors) >= maxImportErrors {
+			break
+		}
+		fail := func(column, format string, args ...interface{}) {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row.Number, Column: column, Message: fmt.Sprintf(format, args...)})
+		}
+		values := row.Values
+
+		product, key, matchErr := existing.match(values)
+		if matchErr != "" {
+			fail("", "%s", matchErr)
+			continue
+		}
+		if first, ok := seen[key]; ok {
+			fail("", "duplicate of row %d", first)
+			continue
+		}
+		seen[key] = row.Number
+
+		fields, fieldErrors := parseImportFields(values, categories)
+		for _, fieldErr := range fieldErrors {
+			fail(fieldErr.Column, "%s", fieldErr.Message)
+		}
+		if len(fieldErrors) > 0 {
+			continue
+		}
+
+		if sku, ok := fields["sku"].(string); ok {
+			if first, ok := skuRows[sku]; ok {
+				fail("sku", "SKU %s is also used in row %d", sku, first)
+				continue
+			}
+			skuRows[sku] = row.Number
+
+			var productID uint
+			if product != nil {
+				productID = product.ID
+			}
+			if productSKUTaken(sku, productID) {
+				fail("sku", "SKU %s belongs to another product", sku)
+				continue
+			}
+		}
+
+		if product == nil {
+			newProduct, missing := newImportedProduct(fields)
+			if len(missing) > 0 {
+				fail(strings.Join(missing, ", "), "%s required for new products", strings.Join(missing, ", "))
+				continue
+			}
+			plan = append(plan, plannedChange{Row: row.Number, Product: newProduct})
+			continue
+		}
+
+		if updates := changedFields(*product, fields); len(updates) > 0 {
+			plan = append(plan, plannedChange{Row: row.Number, ProductID: product.ID, Updates: updates})
+		}
+	}
+
+	return plan, rowErrors, nil
+}
+
+// parseImportFields converts the cells of a row to product columns
+func parseImportFields(values map[string]string, categories categoryResolver) (map[string]interface{}, []models.ImportRowError) {
+	fields := make(map[string]interface{})
+	var fieldErrors []models.ImportRowError
+	fail := func(column, message string) {
+		fieldErrors = append(fieldErrors, models.ImportRowError{Column: column, Message: message})
+	}
+
+	for _, column := range []string{"sku", "name", "description", "brand", "image_url"} {
+		if value, ok := values[column]; ok {
+			fields[column] = value
+		}
+	}
+	if value, ok := values["price"]; ok {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+			fail("price", "price must be a number of at least 0")
+		} else {
+			fields["price"] = math.Round(price*100) / 100
+		}
+	}
+	if value, ok := values["stock"]; ok {
+		stock, err := strconv.Atoi(value)
+		if err != nil || stock < 0 {
+			fail("stock", "stock must be a whole number of at least 0")
+		} else {
+			fields["stock"] = stock
+		}
+	}
+	for _, column := range []string{"is_featured", "is_available"} {
+		if value, ok := values[column]; ok {
+			flag, err := parseImportBool(value)
+			if err != nil {
+				fail(column, column+" must be true or false")
+			} else {
+				fields[column] = flag
+			}
+		}
+	}
+	if value, ok := values["category"]; ok {
+		categoryID, err := categories.resolve(value)
+		if err != nil {
+			fail("category", err.Error())
+		} else {
+			fields["category_id"] = categoryID
+		}
+	}
+
+	return fields, fieldErrors
+}
+
+// newImportedProduct builds a new product from parsed fields and lists the
+// required columns that are missing
+func newImportedProduct(fields map[string]interface{}) (models.Product, []string) {
+	var missing []string
+	for _, column := range []string{"name", "price", "category_id"} {
+		if _, ok := fields[column]; !ok {
+			missing = append(missing, strings.TrimSuffix(column, "_id"))
+		}
+	}
+
+	product := models.Product{IsAvailable: true}
+	if len(missing) > 0 {
+		return product, missing
+	}
+
+	product.Name = fields["name"].(string)
+	product.Price = fields["price"].(float64)
+	product.CategoryID = fields["category_id"].(uint)
+	if v, ok := fields["sku"].(string); ok {
+		product.SKU = v
+	}
+	if v, ok := fields["description"].(string); ok {
+		product.Description = v
+	}
+	if v, ok := fields["brand"].(string); ok {
+		product.Brand = v
+	}
+	if v, ok := fields["image_url"].(string); ok {
+		product.ImageUrl = v
+	}
+	if v, ok := fields["stock"].(int); ok {
+		product.Stock = v
+	}
+	if v, ok := fields["is_featured"].(bool); ok {
+		product.IsFeatured = v
+	}
+	if v, ok := fields["is_available"].(bool); ok {
+		product.IsAvailable = v
+	}
+	return product, nil
+}
+
+// changedFields keeps the parsed fields that differ from the product, so
+// re-importing an unchanged export updates nothing
+func changedFields(product models.Product, fields map[string]interface{}) map[string]interface{} {
+	current := map[string]interface{}{
+		"sku":          product.SKU,
+		"name":         product.Name,
+		"description":  product.Description,
+		"brand":        product.Brand,
+		"image_url":    product.ImageUrl,
+		"price":        product.Price,
+		"stock":        product.Stock,
+		"is_featured":  product.IsFeatured,
+		"is_available": product.IsAvailable,
+		"category_id":  product.CategoryID,
+	}
+
+	updates := make(map[string]interface{})
+	for column, value := range fields {
+		if price, ok := value.(float64); ok {
+			if math.Abs(price-current[column].(float64)) >= 0.005 {
+				updates[column] = value
+			}
+			continue
+		}
+		if value != current[column] {
+			updates[column] = value
+		}
+	}
+	return updates
+}
+
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "y", "1":
+		return true, nil
+	case "false", "no", "n", "0":
+		return false, nil
+	}
+	return false, errors.New("not a boolean")
+}
+
+// importMatches holds the existing products referenced by an import
+type importMatches struct {
+	byID   map[uint]*models.Product
+	bySKU  map[string]*models.Product
+	byName map[string][]*models.Product
+}
+
+// loadImportMatches loads the products the rows refer to by id, sku or name
+func loadImportMatches(rows []importRow) (importMatches, error) {
+	matches := importMatches{
+		byID:   make(map[uint]*models.Product),
+		bySKU:  make(map[string]*models.Product),
+		byName: make(map[string][]*models.Product),
+	}
+
+	var ids []uint
+	var skus, names []string
+	for _, row := range rows {
+		if id, err := strconv.ParseUint(row.Values["id"], 10, 32); err == nil {
+			ids = append(ids, uint(id))
+		}
+		if sku := row.Values["sku"]; sku != "" {
+			skus = append(skus, sku)
+		}
+		if name := row.Values["name"]; name != "" {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+
+	var products []models.Product
+	for _, batch := range []struct {
+		condition string
+		values    interface{}
+		size      int
+	}{
+		{"id IN ?", ids, len(ids)},
+		{"sku IN ?", skus, len(skus)},
+		{"LOWER(name) IN ?", names, len(names)},
+	} {
+		if batch.size == 0 {
+			continue
+		}
+		var found []models.Product
+		if err := configs.DB.Where(batch.condition, batch.values).Find(&found).Error; err != nil {
+			return matches, err
+		}
+		products = append(products, found...)
+	}
+
+	for i := range products {
+		product := &products[i]
+		if matches.byID[product.ID] != nil {
+			continue
+		}
+		matches.byID[product.ID] = product
+		if product.SKU != "" {
+			matches.bySKU[product.SKU] = product
+		}
+		name := strings.ToLower(product.Name)
+		matches.byName[name] = append(matches.byName[name], product)
+	}
+	return matches, nil
+}
+
+// match finds the product a row refers to. It returns nil for a new product,
+// a key identifying the row's target for duplicate detection, and a message
+// when the row is ambiguous or points to a missing product.
+func (m importMatches) match(values map[string]string) (*models.Product, string, string) {
+	if raw, ok := values["id"]; ok {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return nil, "", "id must be a product ID"
+		}
+		product := m.byID[uint(id)]
+		if product == nil {
+			return nil, "", fmt.Sprintf("product %d not found", id)
+		}
+		return product, fmt.Sprint("id:", product.ID), ""
+	}
+
+	if sku, ok := values["sku"]; ok {
+		if product := m.bySKU[sku]; product != nil {
+			return product, fmt.Sprint("id:", product.ID), ""
+		}
+		return nil, "sku:" + sku, ""
+	}
+
+	name := strings.ToLower(values["name"])
+	switch products := m.byName[name]; len(products) {
+	case 0:
+		return nil, "name:" + name, ""
+	case 1:
+		return products[0], fmt.Sprint("id:", products[0].ID), ""
+	default:
+		return nil, "", fmt.Sprintf("%d products are named %q, add an id or sku column", len(products), values["name"])
+	}
+}
+
+// categoryResolver finds categories by name, slug or full path
+type categoryResolver struct {
+	byPath map[string]uint
+	byName map[string][]uint
+	paths  map[uint]string
+}
+
+func newCategoryResolver() (categoryResolver, error) {
+	var categories []models.Category
+	if err := configs.DB.Find(&categories).Error; err != nil {
+		return categoryResolver{}, err
+	}
+
+	resolver := categoryResolver{
+		byPath: make(map[string]uint),
+		byName: make(map[string][]uint),
+		paths:  categoryPaths(categories),
+	}
+	for _, category := range categories {
+		resolver.byPath[strings.ToLower(resolver.paths[category.ID])] = category.ID
+		resolver.byName[strings.ToLower(category.Name)] = append(resolver.byName[strings.ToLower(category.Name)], category.ID)
+		if category.Slug != "" {
+			resolver.byName[category.Slug] = append(resolver.byName[category.Slug], category.ID)
+		}
+	}
+	return resolver, nil
+}
+
+// resolve accepts a full path like "Accessories > Cases", a name or a slug;
+// names shared by several categories need the path
+func (r categoryResolver) resolve(value string) (uint, error) {
+	if id, ok := r.byPath[strings.ToLower(normalizeCategoryPath(value))]; ok {
+		return id, nil
+	}
+
+	ids := slices.Compact(slices.Sorted(slices.Values(r.byName[strings.ToLower(value)])))
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("unknown category %q", value)
+	case 1:
+		return ids[0], nil
+	default:
+		return 0, fmt.Errorf("category %q is ambiguous, use the full path like %q", value, r.paths[ids[0]])
+	}
+}
+
+// normalizeCategoryPath accepts ">" and "/" as separators with any spacing
+func normalizeCategoryPath(value string) string {
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == '>' || r == '/' })
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return strings.Join(parts, categoryPathSeparator)
+}
@@ -0,0 +1,817 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInsufficientStock is returned when a line asks for more units than
+	// are in stock
+	ErrInsufficientStock = errors.New("insufficient stock")
+
+	// ErrReservationNotFound is returned for unknown reservations and those
+	// of other users
+	ErrReservationNotFound = errors.New("reservation not found")
+
+	// ErrReservationInactive is returned for reservations that can no longer
+	// be used
+	ErrReservationInactive = errors.New("reservation has expired or was released")
+)
+
+// stockLine is a quantity of a product, or of one of its variants, taken out
+// of or put back into stock, in one warehouse when WarehouseID is set.
+// BundleID marks lines sold as a bundle component; it is carried along but
+// does not change which stock the line takes.
+type stockLine struct {
+	ProductID   uint
+	VariantID   *uint
+	WarehouseID *uint
+	BundleID    *uint
+	Quantity    int
+}
+
+// stockKey identifies the stock of a product or variant, in one warehouse
+// when warehouseID is set; IDs that are not set are 0
+type stockKey struct{ productID, variantID, warehouseID uint }
+
+// lineKey returns the stock key of a line
+func lineKey(line stockLine) stockKey {
+	key := stockKey{productID: line.ProductID}
+	if line.VariantID != nil {
+		key.variantID = *line.VariantID
+	}
+	if line.WarehouseID != nil {
+		key.warehouseID = *line.WarehouseID
+	}
+	return key
+}
+
+// InventoryMovementListSpec lists the sorts and filters available on the
+// movement history of a product
+var InventoryMovementListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"created_at": "created_at",
+		"quantity":   "quantity",
+	},
+	Filters: map[string]string{
+		"variant_id": "variant_id",
+		"reason":     "reason",
+	},
+	DefaultSort: "-created_at",
+}
+
+// reservationTTL is how long stock stays reserved for a checkout
+func reservationTTL() time.Duration {
+	return envDuration("STOCK_RESERVATION_TTL", 15*time.Minute)
+}
+
+// ReserveStock starts a checkout by reserving the requested items, or the
+// cart when none are given, until the reservation expires. The items are
+// reserved in the warehouses nearest to the shipping address. An active
+// reservation the user already holds is released first, so restarting a
+// checkout does not hold stock twice.
+func ReserveStock(userID uint, req models.CreateReservationRequest) (models.StockReservation, error) {
+	lines, err := checkoutStockLines(configs.DB, userID, req.Items)
+	if err != nil {
+		return models.StockReservation{}, err
+	}
+
+	var province string
+	if req.AddressID != nil {
+		address, err := findUserAddress(configs.DB, userID, *req.AddressID)
+		if err != nil {
+			return models.StockReservation{}, err
+		}
+		province = address.Province
+	} else if address, found := GetDefaultUserAddress(userID); found {
+		province = address.Province
+	}
+
+	// Released in their own transactions, so that every transaction takes
+	// stock locks in one ascending order
+	var previous []uint
+	if err := configs.DB.Model(&models.StockReservation{}).
+		Where("user_id = ? AND status = ?", userID, models.ReservationActive).
+		Pluck("id", &previous).Error; err != nil {
+		return models.StockReservation{}, err
+	}
+	for _, id := range previous {
+		if err := endReservation(id, models.ReservationReleased); err != nil {
+			return models.StockReservation{}, err
+		}
+	}
+
+	reservation := models.StockReservation{
+		UserID:    userID,
+		Status:    models.ReservationActive,
+		ExpiresAt: time.Now().Add(reservationTTL()),
+	}
+
+	err = configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&reservation).Error; err != nil {
+			return err
+		}
+		entry := models.InventoryMovement{
+			Reason:        models.MovementReserved,
+			ReferenceType: models.MovementRefReservation,
+			ReferenceID:   &reservation.ID,
+			UserID:        &userID,
+		}
+		allocated, _, err := takeStock(tx, lines, entry, province)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range allocated {
+			reservation.Items = append(reservation.Items, models.StockReservationItem{
+				ReservationID: reservation.ID,
+				ProductID:     line.ProductID,
+				VariantID:     line.VariantID,
+				WarehouseID:   line.WarehouseID,
+				BundleID:      line.BundleID,
+				Quantity:      line.Quantity,
+			})
+		}
+		return tx.Create(&reservation.Items).Error
+	})
+	if err != nil {
+		return models.StockReservation{}, err
+	}
+
+	return reservation, nil
+}
+
+// GetReservation returns a stock reservation of a user
+func GetReservation(userID, id uint) (models.StockReservation, error) {
+	var reservation models.StockReservation
+	if err := configs.DB.Preload("Items").
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&reservation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.StockReservation{}, ErrReservationNotFound
+		}
+		return models.StockReservation{}, err
+	}
+	return reservation, nil
+}
+
+// ReleaseReservation ends an active reservation of a user, for example after
+// a failed payment, and puts its stock back on sale
+func ReleaseReservation(userID, id uint) (models.StockReservation, error) {
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		reservation, err := lockReservation(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if reservation.Status != models.ReservationActive {
+			return ErrReservationInactive
+		}
+		return releaseReservation(tx, reservation, models.ReservationReleased)
+	})
+	if err != nil {
+		return models.StockReservation{}, err
+	}
+
+	return GetReservation(userID, id)
+}
+
+// ReleaseExpiredReservations puts the stock of reservations that ran out of
+// time back on sale
+func ReleaseExpiredReservations() error {
+	var ids []uint
+	if err := configs.DB.Model(&models.StockReservation{}).
+		Where("status = ? AND expires_at <= ?", models.ReservationActive, time.Now()).
+		Order("id").
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := endReservation(id, models.ReservationExpired); err != nil {
+			return err
+		}
+	}
+
+	if len(ids) > 0 {
+		log.Printf("Released %d expired stock reservations", len(ids))
+	}
+	return nil
+}
+
+// StartReservationExpiryJob periodically releases expired stock reservations
+func StartReservationExpiryJob() {
+	runPeriodically("stock-reservations", envDuration("STOCK_RESERVATION_SWEEP_INTERVAL", time.Minute), ReleaseExpiredReservations)
+}
+
+// GetInventoryMovements returns one page of the stock changes of a product and its variants
+func GetInventoryMovements(productID uint, params pagination.Params) ([]models.InventoryMovement, pagination.Page, error) {
+	movements := []models.InventoryMovement{}
+	page, err := pagination.Find(configs.DB.Where("product_id = ?", productID), params, &movements)
+	return movements, page, err
+}
+
+// AdjustStock changes the stock of a product, or of one of its variants, by
+// hand and records why. Stock cannot be taken below 0.
+func AdjustStock(adminID, productID uint, req models.AdjustStockRequest) (models.InventoryMovement, error) {
+	if req.Quantity == 0 {
+		return models.InventoryMovement{}, errors.New("quantity must not be zero")
+	}
+	if !productExists(productID) {
+		return models.InventoryMovement{}, errors.New("product not found")
+	}
+
+	var variantCount int64
+	configs.DB.Model(&models.ProductVariant{}).Where("product_id = ?", productID).Count(&variantCount)
+	if req.VariantID == nil && variantCount > 0 {
+		return models.InventoryMovement{}, errors.New("variant_id is required for products with variants")
+	}
+	if req.VariantID != nil {
+		if _, err := findProductVariant(configs.DB, productID, *req.VariantID); err != nil {
+			return models.InventoryMovement{}, err
+		}
+	}
+	if req.WarehouseID != nil {
+		if err := configs.DB.First(&models.Warehouse{}, *req.WarehouseID).Error; err != nil {
+			return models.InventoryMovement{}, ErrWarehouseNotFound
+		}
+	}
+
+	entry := models.InventoryMovement{Reason: req.Reason, AdminID: &adminID, Note: req.Note}
+	if entry.Reason == "" {
+		entry.Reason = models.MovementAdjustment
+	}
+	if entry.Reason == models.MovementReceiving && req.Quantity < 0 {
+		return models.InventoryMovement{}, errors.New("received quantity must be positive")
+	}
+
+	var movement models.InventoryMovement
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		line := stockLine{ProductID: productID, VariantID: req.VariantID, WarehouseID: req.WarehouseID, Quantity: req.Quantity}
+		if line.WarehouseID == nil {
+			warehouseID, err := lineWarehouse(tx, line)
+			if err != nil {
+				return err
+			}
+			line.WarehouseID = &warehouseID
+		}
+
+		var movements []models.InventoryMovement
+		var err error
+		if req.Quantity < 0 {
+			line.Quantity = -req.Quantity
+			_, movements, err = takeStock(tx, []stockLine{line}, entry, "")
+		} else {
+			movements, err = returnStock(tx, []stockLine{line}, entry)
+		}
+		if err != nil {
+			return err
+		}
+		if len(movements) == 0 {
+			return errors.New("stock adjustment recorded no movement")
+		}
+		movement = movements[0]
+		return nil
+	})
+	if err != nil {
+		return models.InventoryMovement{}, err
+	}
+
+	return movement, nil
+}
+
+// GetStockReconciliation checks the stock of every product without variants
+// and of every variant against the sum of its inventory movements and the
+// sum of its warehouse stock. Only mismatches are listed unless all is set.
+func GetStockReconciliation(all bool) (models.StockReconciliation, error) {
+	var products []models.StockReconciliationLine
+	if err := configs.DB.Model(&models.Product{}).
+		Select("products.id AS product_id, products.sku, products.name, products.stock, COALESCE(SUM(inventory_movements.quantity), 0) AS ledger_stock, " +
+			"(SELECT COALESCE(SUM(stock), 0) FROM warehouse_stocks WHERE warehouse_stocks.product_id = products.id AND warehouse_stocks.variant_id IS NULL) AS warehouse_stock").
+		Joins("LEFT JOIN inventory_movements ON inventory_movements.product_id = products.id AND inventory_movements.variant_id IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.deleted_at IS NULL)").
+		Group("products.id").
+		Order("products.id").
+		Scan(&products).Error; err != nil {
+		return models.StockReconciliation{}, err
+	}
+
+	var variants []models.StockReconciliationLine
+	if err := configs.DB.Model(&models.ProductVariant{}).
+		Select("product_variants.product_id, product_variants.id AS variant_id, product_variants.sku, products.name, product_variants.stock, COALESCE(SUM(inventory_movements.quantity), 0) AS ledger_stock, " +
+			"(SELECT COALESCE(SUM(stock), 0) FROM warehouse_stocks WHERE warehouse_stocks.variant_id = product_variants.id) AS warehouse_stock").
+		Joins("JOIN products ON products.id = product_variants.product_id AND products.deleted_at IS NULL").
+		Joins("LEFT JOIN inventory_movements ON inventory_movements.variant_id = product_variants.id").
+		Group("product_variants.id, products.id").
+		Order("product_variants.product_id, product_variants.id").
+		Scan(&variants).Error; err != nil {
+		return models.StockReconciliation{}, err
+	}
+
+	report := models.StockReconciliation{Lines: []models.StockReconciliationLine{}}
+	for _, line := range append(products, variants...) {
+		report.Checked++
+		line.Difference = line.Stock - line.LedgerStock
+		mismatched := line.Difference != 0 || line.Stock != line.WarehouseStock
+		if mismatched {
+			report.Mismatched++
+		}
+		if all || mismatched {
+			report.Lines = append(report.Lines, line)
+		}
+	}
+	return report, nil
+}
+
+// RecordOpeningBalances starts the inventory ledger of products without
+// variants and of variants that have stock but no movements yet, so that
+// stock from before the ledger existed reconciles. Deleted ones are included
+// in case they are restored.
+func RecordOpeningBalances() error {
+	var products []models.Product
+	if err := configs.DB.Unscoped().Select("id", "stock").
+		Where("stock <> 0").
+		Where("NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.deleted_at IS NULL)").
+		Where("NOT EXISTS (SELECT 1 FROM inventory_movements WHERE inventory_movements.product_id = products.id AND inventory_movements.variant_id IS NULL)").
+		Find(&products).Error; err != nil {
+		return err
+	}
+
+	var variants []models.ProductVariant
+	if err := configs.DB.Unscoped().Select("id", "product_id", "stock").
+		Where("stock <> 0").
+		Where("NOT EXISTS (SELECT 1 FROM inventory_movements WHERE inventory_movements.variant_id = product_variants.id)").
+		Find(&variants).Error; err != nil {
+		return err
+	}
+
+	var movements []models.InventoryMovement
+	for _, product := range products {
+		movements = append(movements, models.InventoryMovement{
+			ProductID: product.ID,
+			Reason:    models.MovementOpening,
+			Quantity:  product.Stock,
+			Balance:   product.Stock,
+		})
+	}
+	for _, variant := range variants {
+		variantID := variant.ID
+		movements = append(movements, models.InventoryMovement{
+			ProductID: variant.ProductID,
+			VariantID: &variantID,
+			Reason:    models.MovementOpening,
+			Quantity:  variant.Stock,
+			Balance:   variant.Stock,
+		})
+	}
+	if len(movements) == 0 {
+		return nil
+	}
+
+	if err := configs.DB.CreateInBatches(movements, 500).Error; err != nil {
+		return err
+	}
+	log.Printf("Recorded opening stock balances for %d products and variants", len(movements))
+	return nil
+}
+
+// endReservation releases a reservation with the given final status unless
+// it already ended
+func endReservation(id uint, status string) error {
+	return configs.DB.Transaction(func(tx *gorm.DB) error {
+		reservation, err := lockReservation(tx, 0, id)
+		if err != nil || reservation.Status != models.ReservationActive {
+			return err
+		}
+		return releaseReservation(tx, reservation, status)
+	})
+}
+
+// lockReservation locks a reservation row and loads its items. A userID of 0
+// matches any user.
+func lockReservation(tx *gorm.DB, userID, id uint) (models.StockReservation, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var reservation models.StockReservation
+	if err := query.First(&reservation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.StockReservation{}, ErrReservationNotFound
+		}
+		return models.StockReservation{}, err
+	}
+
+	err := tx.Where("reservation_id = ?", id).Order("id").Find(&reservation.Items).Error
+	return reservation, err
+}
+
+// lockActiveReservation locks a reservation a user is about to order and
+// checks that it still holds its stock
+func lockActiveReservation(tx *gorm.DB, userID, id uint) (models.StockReservation, error) {
+	reservation, err := lockReservation(tx, userID, id)
+	if err != nil {
+		return models.StockReservation{}, err
+	}
+	if reservation.Status != models.ReservationActive || !reservation.ExpiresAt.After(time.Now()) {
+		return models.StockReservation{}, ErrReservationInactive
+	}
+	return reservation, nil
+}
+
+// releaseReservation puts the stock of a locked reservation back and ends it
+// with status. Only a RELEASED reservation was ended by its user.
+func releaseReservation(tx *gorm.DB, reservation models.StockReservation, status string) error {
+	entry := models.InventoryMovement{
+		Reason:        models.MovementReleased,
+		ReferenceType: models.MovementRefReservation,
+		ReferenceID:   &reservation.ID,
+	}
+	if status == models.ReservationReleased {
+		entry.UserID = &reservation.UserID
+	} else {
+		entry.Note = "Reservation " + strings.ToLower(status)
+	}
+	if _, err := returnStock(tx, reservationStockLines(reservation), entry); err != nil {
+		return err
+	}
+	return tx.Model(&reservation).Update("status", status).Error
+}
+
+// commitReservation marks a locked reservation as ordered; its stock stays
+// taken
+func commitReservation(tx *gorm.DB, reservation models.StockReservation, orderID uint) error {
+	return tx.Model(&reservation).Updates(map[string]interface{}{
+		"status":   models.ReservationCommitted,
+		"order_id": orderID,
+	}).Error
+}
+
+// reservationStockLines returns the reserved lines of a reservation
+func reservationStockLines(reservation models.StockReservation) []stockLine {
+	lines := make([]stockLine, len(reservation.Items))
+	for i, item := range reservation.Items {
+		lines[i] = stockLine{ProductID: item.ProductID, VariantID: item.VariantID, WarehouseID: item.WarehouseID, BundleID: item.BundleID, Quantity: item.Quantity}
+	}
+	return lines
+}
+
+// checkoutStockLines validates the items of a checkout, or the user's cart
+// when there are none, and returns them as stock lines
+func checkoutStockLines(db *gorm.DB, userID uint, items []models.CreateOrderItemRequest) ([]stockLine, error) {
+	var lines []stockLine
+	if len(items) > 0 {
+		for _, item := range items {
+			lines = append(lines, stockLine{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+		}
+	} else {
+		var cartItems []models.Cart
+		if err := db.Where("user_id = ?", userID).Order("id").Find(&cartItems).Error; err != nil {
+			return nil, err
+		}
+		for _, item := range cartItems {
+			lines = append(lines, stockLine{ProductID: item.ProductID, VariantID: item.VariantID, BundleID: item.BundleID, Quantity: item.Quantity})
+		}
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	for _, line := range lines {
+		var product models.Product
+		if err := db.First(&product, line.ProductID).Error; err != nil {
+			return nil, productLookupError(err, line.ProductID)
+		}
+		if _, err := resolveLineVariant(db, product, line.VariantID); err != nil {
+			return nil, err
+		}
+	}
+
+	return lines, nil
+}
+
+// takeStock takes lines out of stock and records them in the inventory
+// ledger as entry, returning the lines, in their order, split by the
+// warehouse they were taken from, and the movements recorded. Lines naming
+// no warehouse are taken from the warehouses nearest to the shipping
+// province. Each decrement only applies while enough stock is left, so
+// concurrent checkouts cannot oversell, and rows are locked in ascending
+// product and variant order so they cannot deadlock. Products without
+// variants are marked unavailable when they run out, unless they take
+// pre-orders.
+func takeStock(tx *gorm.DB, lines []stockLine, entry models.InventoryMovement, province string) ([]stockLine, []models.InventoryMovement, error) {
+	// Lock the stock of every line first, so that warehouse levels cannot
+	// change while the warehouses are picked
+	if err := eachProductStock(lines, func(productID uint, lines []stockLine) error {
+		for _, line := range lines {
+			if _, err := stockBalance(tx, productID, line.VariantID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	allocated, err := allocateStock(tx, lines, province)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var movements []models.InventoryMovement
+	err = eachProductStock(allocated, func(productID uint, lines []stockLine) error {
+		hasVariants := false
+		for _, line := range lines {
+			var result *gorm.DB
+			if line.VariantID == nil {
+				result = tx.Model(&models.Product{}).Scopes(configs.KeepVersion).
+					Where("id = ? AND stock >= ?", productID, line.Quantity).
+					Updates(map[string]interface{}{
+						"stock":        gorm.Expr("stock - ?", line.Quantity),
+						"is_available": gorm.Expr("is_available AND (stock > ? OR is_preorder)", line.Quantity),
+					})
+			} else {
+				hasVariants = true
+				result = tx.Model(&models.ProductVariant{}).
+					Where("id = ? AND product_id = ? AND stock >= ?", *line.VariantID, productID, line.Quantity).
+					Update("stock", gorm.Expr("stock - ?", line.Quantity))
+			}
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return insufficientStock(tx, line)
+			}
+			if err := addWarehouseStock(tx, *line.WarehouseID, productID, line.VariantID, -line.Quantity); err != nil {
+				return err
+			}
+			movement, err := recordMovement(tx, entry, line, -line.Quantity)
+			if err != nil {
+				return err
+			}
+			movements = append(movements, movement)
+		}
+
+		if hasVariants {
+			return syncProductFromVariants(tx, productID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return allocated, movements, nil
+}
+
+// returnStock puts lines back into stock, in their warehouse or the default
+// one, in the same lock order as takeStock, and records them in the
+// inventory ledger as entry, returning the movements recorded. A product
+// without variants that had run out is available again.
+func returnStock(tx *gorm.DB, lines []stockLine, entry models.InventoryMovement) ([]models.InventoryMovement, error) {
+	var movements []models.InventoryMovement
+	err := eachProductStock(lines, func(productID uint, lines []stockLine) error {
+		hasVariants := false
+		for _, line := range lines {
+			if line.VariantID == nil {
+				if err := tx.Model(&models.Product{}).Scopes(configs.KeepVersion).
+					Where("id = ?", productID).
+					Updates(map[string]interface{}{
+						"stock":        gorm.Expr("stock + ?", line.Quantity),
+						"is_available": gorm.Expr("is_available OR stock = 0"),
+					}).Error; err != nil {
+					return err
+				}
+			} else {
+				hasVariants = true
+				if err := tx.Model(&models.ProductVariant{}).
+					Where("id = ? AND product_id = ?", *line.VariantID, productID).
+					Update("stock", gorm.Expr("stock + ?", line.Quantity)).Error; err != nil {
+					return err
+				}
+			}
+
+			warehouseID, err := lineWarehouse(tx, line)
+			if err != nil {
+				return err
+			}
+			line.WarehouseID = &warehouseID
+			if err := addWarehouseStock(tx, warehouseID, productID, line.VariantID, line.Quantity); err != nil {
+				return err
+			}
+			movement, err := recordMovement(tx, entry, line, line.Quantity)
+			if err != nil {
+				return err
+			}
+			movements = append(movements, movement)
+		}
+
+		if hasVariants {
+			return syncProductFromVariants(tx, productID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// eachProductStock merges lines for the same product, variant and warehouse,
+// sorts them by product, variant and warehouse ID and calls fn once per
+// product with its lines. Lines without a variant sort first.
+func eachProductStock(lines []stockLine, fn func(productID uint, lines []stockLine) error) error {
+	merged := make(map[stockKey]*stockLine)
+	var keys []stockKey
+	for _, line := range lines {
+		key := lineKey(line)
+		if existing, ok := merged[key]; ok {
+			existing.Quantity += line.Quantity
+			continue
+		}
+		copied := line
+		merged[key] = &copied
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].productID != keys[j].productID {
+			return keys[i].productID < keys[j].productID
+		}
+		if keys[i].variantID != keys[j].variantID {
+			return keys[i].variantID < keys[j].variantID
+		}
+		return keys[i].warehouseID < keys[j].warehouseID
+	})
+
+	for start := 0; start < len(keys); {
+		productID := keys[start].productID
+		var productLines []stockLine
+		end := start
+		for ; end < len(keys) && keys[end].productID == productID; end++ {
+			productLines = append(productLines, *merged[keys[end]])
+		}
+		if err := fn(productID, productLines); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// insufficientStock describes a line that could not be taken out of stock
+func insufficientStock(tx *gorm.DB, line stockLine) error {
+	var product models.Product
+	if err := tx.First(&product, line.ProductID).Error; err != nil {
+		return productLookupError(err, line.ProductID)
+	}
+
+	var variant *models.ProductVariant
+	if line.VariantID != nil {
+		found, err := findProductVariant(tx, line.ProductID, *line.VariantID)
+		if err != nil {
+			return err
+		}
+		variant = &found
+	}
+
+	return fmt.Errorf("%w for product %s. Available: %d, Requested: %d",
+		ErrInsufficientStock, lineName(product, variant), lineStock(product, variant), line.Quantity)
+}
+
+// recordMovement records in the inventory ledger a change of quantity units,
+// already applied, to the stock of a line's product or variant, and returns
+// the movement. Stock put back goes to pre-orders waiting for it, and
+// customers waiting for it are notified when it comes back in stock.
+func recordMovement(tx *gorm.DB, entry models.InventoryMovement, line stockLine, quantity int) (models.InventoryMovement, error) {
+	balance, err := stockBalance(tx, line.ProductID, line.VariantID)
+	if err != nil {
+		return models.InventoryMovement{}, err
+	}
+
+	entry.ProductID = line.ProductID
+	entry.VariantID = line.VariantID
+	entry.WarehouseID = line.WarehouseID
+	entry.Quantity = quantity
+	entry.Balance = balance
+	if err := tx.Create(&entry).Error; err != nil {
+		return models.InventoryMovement{}, err
+	}
+
+	if quantity > 0 {
+		if err := stockArrived(tx, line.ProductID, line.VariantID, balance-quantity <= 0); err != nil {
+			return models.InventoryMovement{}, err
+		}
+	}
+	return entry, nil
+}
+
+// trackStock runs update and records the change it made to the stock of the
+// product or variant named by entry in the inventory ledger. The change is
+// applied to the warehouse of entry, or the default warehouse. entry carries
+// the reason and actor; the quantity and balance are filled in.
+func trackStock(tx *gorm.DB, entry models.InventoryMovement, update func() error) error {
+	before, err := stockBalance(tx, entry.ProductID, entry.VariantID)
+	if err != nil {
+		return err
+	}
+
+	if err := update(); err != nil {
+		return err
+	}
+
+	after, err := stockBalance(tx, entry.ProductID, entry.VariantID)
+	if err != nil || after == before {
+		return err
+	}
+
+	return recordStockChange(tx, entry, after-before, after)
+}
+
+// recordInitialStock records the stock a new product or variant starts with
+// and places it in the warehouse of entry, or the default warehouse
+func recordInitialStock(tx *gorm.DB, entry models.InventoryMovement, stock int) error {
+	if stock == 0 {
+		return nil
+	}
+	return recordStockChange(tx, entry, stock, stock)
+}
+
+// recordStockChange applies a change of quantity units, already made to the
+// stock of the product or variant named by entry, to its warehouse stock and
+// records it in the inventory ledger ending at balance. Stock taken out
+// without naming a warehouse comes from the warehouses that hold it, in
+// priority order. Stock added goes to pre-orders waiting for it, and
+// customers waiting for the product or variant are notified when it comes
+// back in stock.
+func recordStockChange(tx *gorm.DB, entry models.InventoryMovement, quantity, balance int) error {
+	line := stockLine{ProductID: entry.ProductID, VariantID: entry.VariantID, WarehouseID: entry.WarehouseID, Quantity: quantity}
+	var parts []stockLine
+	if quantity < 0 && line.WarehouseID == nil {
+		line.Quantity = -quantity
+		allocated, err := allocateStock(tx, []stockLine{line}, "")
+		if err != nil {
+			return err
+		}
+		for _, part := range allocated {
+			part.Quantity = -part.Quantity
+			parts = append(parts, part)
+		}
+	} else {
+		warehouseID, err := lineWarehouse(tx, line)
+		if err != nil {
+			return err
+		}
+		line.WarehouseID = &warehouseID
+		parts = append(parts, line)
+	}
+
+	// Earlier parts end at the balance before the later ones were taken
+	for _, part := range parts {
+		balance -= part.Quantity
+	}
+	for _, part := range parts {
+		if err := addWarehouseStock(tx, *part.WarehouseID, part.ProductID, part.VariantID, part.Quantity); err != nil {
+			return err
+		}
+		balance += part.Quantity
+		movement := entry
+		movement.WarehouseID = part.WarehouseID
+		movement.Quantity = part.Quantity
+		movement.Balance = balance
+		if err := tx.Create(&movement).Error; err != nil {
+			return err
+		}
+	}
+
+	if quantity > 0 {
+		return stockArrived(tx, entry.ProductID, entry.VariantID, balance-quantity <= 0)
+	}
+	return nil
+}
+
+// stockBalance returns the stock of a product, or of a variant when variantID
+// is set, and locks its row until the transaction ends so that the stock
+// cannot change before the movement is recorded
+func stockBalance(tx *gorm.DB, productID uint, variantID *uint) (int, error) {
+	query := tx.Model(&models.Product{}).Where("id = ?", productID)
+	if variantID != nil {
+		query = tx.Model(&models.ProductVariant{}).Where("id = ? AND product_id = ?", *variantID, productID)
+	}
+
+	var stock int
+	err := query.Clauses(clause.Locking{Strength: "UPDATE"}).Select("stock").Scan(&stock).Error
+	return stock, err
+}
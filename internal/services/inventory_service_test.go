@@ -0,0 +1,291 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB connects to the PostgreSQL database in TEST_DATABASE_DSN, e.g.
+// "host=localhost user=postgres password=password dbname=literally_test",
+// and skips the test when it is not set. Row locks need a real database.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.UserAddress{},
+		&models.Category{},
+		&models.CategoryAttribute{},
+		&models.Product{},
+		&models.ProductAttributeValue{},
+		&models.ProductVariant{},
+		&models.PriceHistory{},
+		&models.Cart{},
+		&models.PaymentMethod{},
+		&models.Order{},
+		&models.OrderItem{},
+		&models.StockReservation{},
+		&models.StockReservationItem{},
+		&models.InventoryMovement{},
+		&models.Warehouse{},
+		&models.WarehouseStock{},
+		&models.StockSubscription{},
+		&models.Bundle{},
+		&models.BundleItem{},
+		&models.Coupon{},
+		&models.CouponTarget{},
+		&models.CouponRedemption{},
+		&models.CartCoupon{},
+		&models.FlashSale{},
+		&models.FlashSalePurchase{},
+		&models.Notification{},
+		&models.AdminNotification{},
+	); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// Stock is placed in the default warehouse
+	if err := db.Where(models.Warehouse{Code: "TEST"}).
+		Attrs(models.Warehouse{Name: "Test", Province: "Hà Nội", Region: models.RegionNorth, IsDefault: true}).
+		FirstOrCreate(&models.Warehouse{}).Error; err != nil {
+		t.Fatalf("create warehouse: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("pool: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(20)
+
+	configs.DB = db
+	InitOrderService()
+	return db
+}
+
+// createStockedProduct creates a product without variants holding stock
+// units, received in the inventory ledger and the default warehouse
+func createStockedProduct(t *testing.T, db *gorm.DB, stock int) models.Product {
+	t.Helper()
+
+	product := models.Product{Name: "Last units " + t.Name(), Price: 100, Stock: stock, IsAvailable: true}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	entry := models.InventoryMovement{ProductID: product.ID, Reason: models.MovementReceiving}
+	if err := recordInitialStock(db, entry, stock); err != nil {
+		t.Fatalf("record stock: %v", err)
+	}
+	return product
+}
+
+// createTestUser creates a customer with a unique email and phone number
+func createTestUser(t *testing.T, db *gorm.DB, name string) models.User {
+	t.Helper()
+
+	unique := time.Now().UnixNano()
+	user := models.User{
+		Name:         name,
+		Email:        fmt.Sprintf("user-%d@example.com", unique),
+		PhoneNumber:  fmt.Sprintf("%d", unique),
+		PasswordHash: "-",
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// ledgerStock returns the sum of the inventory movements of a product
+func ledgerStock(t *testing.T, db *gorm.DB, productID uint) int {
+	t.Helper()
+
+	var sum int
+	if err := db.Model(&models.InventoryMovement{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("product_id = ?", productID).
+		Scan(&sum).Error; err != nil {
+		t.Fatalf("sum movements: %v", err)
+	}
+	return sum
+}
+
+func TestConcurrentOrdersDoNotOversell(t *testing.T) {
+	db := openTestDB(t)
+
+	const stock, buyers = 5, 50
+	product := createStockedProduct(t, db, stock)
+
+	user := createTestUser(t, db, "Buyer")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sold, refused := 0, 0
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := CreateOrderFromRequest(user.ID, models.CreateOrderRequest{
+				PaymentMethodID: 1,
+				ShippingAddress: "Ho Chi Minh City, Vietnam",
+				Items:           []models.CreateOrderItemRequest{{ProductID: product.ID, Quantity: 1}},
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				sold++
+			case errors.Is(err, ErrInsufficientStock):
+				refused++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sold != stock || refused != buyers-stock {
+		t.Errorf("sold %d and refused %d, want %d and %d", sold, refused, stock, buyers-stock)
+	}
+
+	var after models.Product
+	db.First(&after, product.ID)
+	if after.Stock != 0 || after.IsAvailable {
+		t.Errorf("stock %d available %v after selling out, want 0 and false", after.Stock, after.IsAvailable)
+	}
+	if ledger := ledgerStock(t, db, product.ID); ledger != after.Stock {
+		t.Errorf("ledger sums to %d, want the stock of %d", ledger, after.Stock)
+	}
+}
+
+func TestExpiredReservationsReturnStock(t *testing.T) {
+	db := openTestDB(t)
+
+	const stock, shoppers = 3, 20
+	product := createStockedProduct(t, db, stock)
+
+	// Each shopper is a different user, so no reservation replaces another
+	base := uint(time.Now().UnixNano() % 1000000000)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var reserved []uint
+	for i := 0; i < shoppers; i++ {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			reservation, err := ReserveStock(userID, models.CreateReservationRequest{
+				Items: []models.CreateOrderItemRequest{{ProductID: product.ID, Quantity: 1}},
+			})
+			if err != nil && !errors.Is(err, ErrInsufficientStock) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil {
+				mu.Lock()
+				reserved = append(reserved, reservation.ID)
+				mu.Unlock()
+			}
+		}(base + uint(i))
+	}
+	wg.Wait()
+
+	if len(reserved) != stock {
+		t.Fatalf("%d reservations succeeded, want %d", len(reserved), stock)
+	}
+
+	db.Model(&models.StockReservation{}).Where("id IN ?", reserved).Update("expires_at", time.Now().Add(-time.Minute))
+	if err := ReleaseExpiredReservations(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+
+	var after models.Product
+	db.First(&after, product.ID)
+	if after.Stock != stock || !after.IsAvailable {
+		t.Errorf("stock %d available %v after expiry, want %d and true", after.Stock, after.IsAvailable, stock)
+	}
+	if ledger := ledgerStock(t, db, product.ID); ledger != after.Stock {
+		t.Errorf("ledger sums to %d, want the stock of %d", ledger, after.Stock)
+	}
+}
+
+func TestStockAdjustmentsReconcile(t *testing.T) {
+	db := openTestDB(t)
+
+	product := createStockedProduct(t, db, 10)
+
+	adjustments := []struct {
+		quantity int
+		reason   string
+		balance  int
+	}{
+		{quantity: -3, reason: models.MovementAdjustment, balance: 7},
+		{quantity: 5, reason: models.MovementReceiving, balance: 12},
+	}
+	for _, adjustment := range adjustments {
+		movement, err := AdjustStock(0, product.ID, models.AdjustStockRequest{
+			Quantity: adjustment.quantity,
+			Reason:   adjustment.reason,
+			Note:     "Stock count",
+		})
+		if err != nil {
+			t.Fatalf("adjust by %d: %v", adjustment.quantity, err)
+		}
+		if movement.Quantity != adjustment.quantity || movement.Balance != adjustment.balance || movement.Reason != adjustment.reason {
+			t.Errorf("movement %d to %d as %s, want %d to %d as %s", movement.Quantity, movement.Balance, movement.Reason,
+				adjustment.quantity, adjustment.balance, adjustment.reason)
+		}
+	}
+
+	if _, err := AdjustStock(0, product.ID, models.AdjustStockRequest{Quantity: -20, Note: "Stock count"}); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("taking more than the stock: error %v, want %v", err, ErrInsufficientStock)
+	}
+	if _, err := AdjustStock(0, product.ID, models.AdjustStockRequest{Note: "Stock count"}); err == nil {
+		t.Error("adjusting by 0 succeeded, want an error")
+	}
+
+	mismatch := func() (models.StockReconciliationLine, bool) {
+		report, err := GetStockReconciliation(false)
+		if err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		for _, line := range report.Lines {
+			if line.ProductID == product.ID {
+				return line, true
+			}
+		}
+		return models.StockReconciliationLine{}, false
+	}
+
+	if line, found := mismatch(); found {
+		t.Errorf("adjusted product listed as a mismatch: %+v", line)
+	}
+
+	// Stock changed outside the ledger shows up
+	if err := db.Model(&models.Product{}).Where("id = ?", product.ID).UpdateColumn("stock", 15).Error; err != nil {
+		t.Fatalf("change stock: %v", err)
+	}
+	line, found := mismatch()
+	if !found || line.Stock != 15 || line.LedgerStock != 12 || line.Difference != 3 || line.WarehouseStock != 12 {
+		t.Errorf("mismatch %+v found %v, want stock 15, ledger 12, difference 3 and warehouse stock 12", line, found)
+	}
+}
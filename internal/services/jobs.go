@@ -0,0 +1,44 @@
+package services
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// runPeriodically runs job immediately and then on every tick of interval
+// in a background goroutine, logging failures
+func runPeriodically(name string, interval time.Duration, job func() error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := job(); err != nil {
+				log.Printf("Background job %s failed: %v", name, err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// envDuration reads a duration (e.g. "1h") from the environment or returns the default
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
+// envInt reads an integer from the environment or returns the default
+func envInt(key string, fallback int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
+
+	"gorm.io/gorm"
+)
+
+// NotificationListSpec lists the sorts and filters available on a user's
+// notifications
+var NotificationListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"created_at": "created_at",
+	},
+	Filters: map[string]string{
+		"type":    "type",
+		"is_read": "is_read",
+	},
+	DefaultSort: "-created_at",
+}
+
+// GetUserNotifications returns one page of a user's notifications
+func GetUserNotifications(userID uint, params pagination.Params) ([]models.Notification, pagination.Page, error) {
+	notifications := []models.Notification{}
+	page, err := pagination.Find(configs.DB.Where("user_id = ?", userID), params, &notifications)
+	return notifications, page, err
+}
+
+// MarkNotificationRead marks one of a user's notifications as read
+func MarkNotificationRead(userID, id uint) (models.Notification, error) {
+	var notification models.Notification
+	if err := configs.DB.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Notification{}, errors.New("notification not found")
+		}
+		return models.Notification{}, err
+	}
+
+	if err := configs.DB.Model(&notification).Update("is_read", true).Error; err != nil {
+		return models.Notification{}, err
+	}
+	return notification, nil
+}
@@ -3,12 +3,15 @@ package services
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"literally-backend/configs"
 	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderService struct {
@@ -17,6 +20,23 @@ type OrderService struct {
 
 var orderService *OrderService
 
+// ErrShippingAddressRequired is returned for orders that give no shipping
+// address when the user has no default address either
+var ErrShippingAddressRequired = errors.New("shipping address is required")
+
+// OrderListSpec lists the sorts and filters available on order listings
+var OrderListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"created_at":   "created_at",
+		"total_amount": "total_amount",
+		"status":       "status",
+	},
+	Filters: map[string]string{
+		"status": "status",
+	},
+	DefaultSort: "-created_at",
+}
+
 func NewOrderService(db *gorm.DB) *OrderService {
 	return &OrderService{db: db}
 }
@@ -26,11 +46,11 @@ func InitOrderService() {
 }
 
 // Export functions for global use
-func GetUserOrders(userID uint, page, limit int, status string) ([]models.Order, int64, error) {
+func GetUserOrders(userID uint, params pagination.Params) ([]models.Order, pagination.Page, error) {
 	if orderService == nil {
 		InitOrderService()
 	}
-	return orderService.GetUserOrders(userID, page, limit, status)
+	return orderService.GetUserOrders(userID, params)
 }
 
 func GetOrderByID(orderID, userID uint) (*models.Order, error) {
@@ -40,11 +60,11 @@ func GetOrderByID(orderID, userID uint) (*models.Order, error) {
 	return orderService.GetOrderByID(orderID, userID)
 }
 
-func UpdateOrderStatus(orderID uint, status string) error {
+func UpdateOrderStatus(adminID, orderID, version uint, status string) error {
 	if orderService == nil {
 		InitOrderService()
 	}
-	return orderService.UpdateOrderStatus(orderID, status)
+	return orderService.UpdateOrderStatus(adminID, orderID, version, status)
 }
 
 func CreateOrder(userID uint, shippingAddress string) (*models.Order, error) {
@@ -68,37 +88,16 @@ func GetOrderStats(userID uint) (map[string]interface{}, error) {
 	return orderService.GetOrderStats(userID)
 }
 
-func (s *OrderService) GetUserOrders(userID uint, page, limit int, status string) ([]models.Order, int64, error) {
-	var orders []models.Order
-	var total int64
-
-	query := s.db.Where("user_id = ?", userID)
-	if status != "" {
-		query = query.Where("status = ?", status)
-	}
-
-	if err := query.Model(&models.Order{}).Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	offset := (page - 1) * limit
-	if err := query.Preload("OrderItems").
-		Preload("OrderItems.Product").
-		Order("created_at DESC").
-		Offset(offset).
-		Limit(limit).
-		Find(&orders).Error; err != nil {
-		return nil, 0, err
-	}
-
-	return orders, total, nil
+func (s *OrderService) GetUserOrders(userID uint, params pagination.Params) ([]models.Order, pagination.Page, error) {
+	orders := []models.Order{}
+	page, err := pagination.Find(s.db.Where("user_id = ?", userID), params, &orders, preloadOrderItems)
+	return orders, page, err
 }
 
 func (s *OrderService) GetOrderByID(orderID, userID uint) (*models.Order, error) {
 	var order models.Order
 	if err := s.db.Where("id = ? AND user_id = ?", orderID, userID).
-		Preload("OrderItems").
-		Preload("OrderItems.Product").
+		Scopes(preloadOrderItems).
 		First(&order).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("order not found")
@@ -108,23 +107,61 @@ func (s *OrderService) GetOrderByID(orderID, userID uint) (*models.Order, error)
 	return &order, nil
 }
 
-func (s *OrderService) UpdateOrderStatus(orderID uint, status string) error {
-	result := s.db.Model(&models.Order{}).
-		Where("id = ?", orderID).
-		Updates(map[string]interface{}{
-			"status":     status,
-			"updated_at": time.Now(),
-		})
+// UpdateOrderStatus moves an order to status at the given version.
+// Cancelling or returning an order puts its items back in stock, and
+// cancelling it releases its coupons and flash sale units and lets the
+// pre-orders queued behind it take the stock. Orders with pre-ordered items waiting
+// for stock can only be PREORDERED or CANCELLED.
+func (s *OrderService) UpdateOrderStatus(adminID, orderID, version uint, status string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "status", "version").
+			First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("order not found")
+			}
+			return err
+		}
+		if err := checkVersion(order.Version, version); err != nil {
+			return err
+		}
+		if err := checkAwaitingStock(tx, orderID, status); err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Order{}).
+			Where("id = ?", orderID).
+			Scopes(atVersion(version)).
+			Updates(map[string]interface{}{
+				"status":     status,
+				"updated_at": time.Now(),
+			})
 
-	if result.Error != nil {
-		return result.Error
-	}
+		if err := versionedResult(result, version); err != nil {
+			return err
+		}
 
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("order not found")
-	}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("order not found")
+		}
 
-	return nil
+		// Coupons and flash sales are locked before stock, as when orders
+		// are placed
+		if err := moveCouponUsage(tx, order, status); err != nil {
+			return err
+		}
+		if err := moveFlashSaleUsage(tx, order, status); err != nil {
+			return err
+		}
+		if err := moveOrderStock(tx, adminID, order, status); err != nil {
+			return err
+		}
+		if strings.EqualFold(status, "CANCELLED") {
+			return resumePreorders(tx, orderID)
+		}
+		return nil
+	})
 }
 
 func (s *OrderService) CreateOrder(userID uint, shippingAddress string) (*models.Order, error) {
@@ -147,23 +184,16 @@ func (s *OrderService) CreateOrder(userID uint, shippingAddress string) (*models
 		return nil, fmt.Errorf("cart is empty")
 	}
 
-	// Validate stock availability and calculate total amount
-	var totalAmount float64
-	for _, item := range cartItems {
-		var product models.Product
-		if err := tx.Where("id = ?", item.ProductID).First(&product).Error; err != nil {
-			tx.Rollback()
-			return nil, err
-		}
-
-		// Check if there's enough stock
-		if product.Stock < item.Quantity {
-			tx.Rollback()
-			return nil, fmt.Errorf("insufficient stock for product %s. Available: %d, Requested: %d",
-				product.Name, product.Stock, item.Quantity)
-		}
+	lines := make([]stockLine, len(cartItems))
+	for i, item := range cartItems {
+		lines[i] = stockLine{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity}
+	}
 
-		totalAmount += product.Price * float64(item.Quantity)
+	// Price the cart items
+	orderItems, totalAmount, err := priceOrderLines(tx, lines, nil)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	order := models.Order{
@@ -180,40 +210,21 @@ func (s *OrderService) CreateOrder(userID uint, shippingAddress string) (*models
 		return nil, err
 	}
 
-	// Create order items and update product stock
-	for _, cartItem := range cartItems {
-		var product models.Product
-		if err := tx.Where("id = ?", cartItem.ProductID).First(&product).Error; err != nil {
-			tx.Rollback()
-			return nil, err
-		}
+	// Take the items out of stock, failing instead of overselling
+	allocated, _, err := takeStock(tx, lines, saleMovement(order), "")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	orderItems = fulfillOrderItems(orderItems, allocated)
 
-		// Create order item
-		orderItem := models.OrderItem{
-			OrderID:   order.ID,
-			ProductID: cartItem.ProductID,
-			Quantity:  cartItem.Quantity,
-			Price:     product.Price,
-		}
+	// Create order items
+	for _, orderItem := range orderItems {
+		orderItem.OrderID = order.ID
 		if err := tx.Create(&orderItem).Error; err != nil {
 			tx.Rollback()
 			return nil, err
 		}
-
-		// Update product stock (decrease by purchased quantity)
-		newStock := product.Stock - cartItem.Quantity
-		if err := tx.Model(&product).Update("stock", newStock).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("failed to update stock for product %d: %v", cartItem.ProductID, err)
-		}
-
-		// If stock reaches 0, mark product as unavailable
-		if newStock == 0 {
-			if err := tx.Model(&product).Update("is_available", false).Error; err != nil {
-				tx.Rollback()
-				return nil, fmt.Errorf("failed to update availability for product %d: %v", cartItem.ProductID, err)
-			}
-		}
 	}
 
 	// Clear cart after successful order creation
@@ -227,8 +238,7 @@ func (s *OrderService) CreateOrder(userID uint, shippingAddress string) (*models
 	}
 
 	if err := s.db.Where("id = ?", order.ID).
-		Preload("OrderItems").
-		Preload("OrderItems.Product").
+		Scopes(preloadOrderItems).
 		First(&order).Error; err != nil {
 		return nil, err
 	}
@@ -286,35 +296,107 @@ func (s *OrderService) CreateOrderFromRequest(userID uint, req models.CreateOrde
 		}
 	}()
 
-	// Calculate total amount and validate stock availability
-	var totalAmount float64
-	for _, item := range req.Items {
-		var product models.Product
-		if err := tx.Where("id = ?", item.ProductID).First(&product).Error; err != nil {
+	// Order the reserved items, whose stock is already taken, or the
+	// requested ones
+	var reservation *models.StockReservation
+	var lines []stockLine
+	if req.ReservationID != nil {
+		reserved, err := lockActiveReservation(tx, userID, *req.ReservationID)
+		if err != nil {
 			tx.Rollback()
-			return nil, fmt.Errorf("product not found: %d", item.ProductID)
+			return nil, err
 		}
-
-		// Check if there's enough stock
-		if product.Stock < item.Quantity {
+		reservation = &reserved
+		lines = reservationStockLines(reserved)
+	} else {
+		for _, item := range req.Items {
+			lines = append(lines, stockLine{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+		}
+		bundled, err := bundleStockLines(tx, req.Bundles)
+		if err != nil {
 			tx.Rollback()
-			return nil, fmt.Errorf("insufficient stock for product %s. Available: %d, Requested: %d",
-				product.Name, product.Stock, item.Quantity)
+			return nil, err
 		}
+		lines = append(lines, bundled...)
+	}
+	if len(lines) == 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("order has no items")
+	}
+
+	// Lock the coupons until the order is stored so their usage limits
+	// hold, then claim the flash sale units the order buys
+	coupons, err := orderCoupons(tx, userID, req.CouponCodes)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	claims, err := claimFlashSales(tx, userID, lines)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
-		totalAmount += product.Price * float64(item.Quantity)
+	// Calculate total amount
+	orderItems, totalAmount, err := priceOrderLines(tx, lines, claims)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	var bundleDiscount float64
+	for _, item := range orderItems {
+		bundleDiscount += item.Discount
+	}
+
+	// Take the coupons off
+	fee := shippingFee()
+	pricing, err := applyCoupons(tx, userID, coupons, orderItems, fee, false)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	totalAmount = roundMoney(totalAmount - pricing.discount + fee - pricing.shippingDiscount)
+
+	// Resolve shipping address
+	shippingAddress, shippingDetails, err := resolveShippingAddress(tx, userID, req)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Lines of pre-order products that cannot be taken out of stock now
+	// wait for it, up to the product's pre-order cap. Reserved items are in
+	// stock already.
+	var preordered []stockLine
+	var deposit float64
+	if reservation == nil {
+		lines, preordered, deposit, err = splitPreorderLines(tx, lines)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 	}
 
 	// Create order
 	order := models.Order{
-		UserID:          userID,
-		TotalAmount:     totalAmount,
-		Status:          "pending",
-		PaymentMethodID: req.PaymentMethodID,
-		IsInstallment:   req.IsInstallment,
-		ShippingAddress: req.ShippingAddress,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+		UserID:           userID,
+		TotalAmount:      totalAmount,
+		Status:           "pending",
+		PaymentMethodID:  req.PaymentMethodID,
+		IsInstallment:    req.IsInstallment,
+		ShippingAddress:  shippingAddress,
+		ShippingDetails:  shippingDetails,
+		BundleDiscount:   roundMoney(bundleDiscount),
+		CouponDiscount:   pricing.discount,
+		ShippingFee:      fee,
+		ShippingDiscount: pricing.shippingDiscount,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+	if len(preordered) > 0 {
+		order.Status = models.OrderStatusPreordered
+		order.IsPreorder = true
+		order.DepositAmount = deposit
 	}
 
 	if err := tx.Create(&order).Error; err != nil {
@@ -322,39 +404,48 @@ func (s *OrderService) CreateOrderFromRequest(userID uint, req models.CreateOrde
 		return nil, err
 	}
 
-	// Create order items and update product stock
-	for _, reqItem := range req.Items {
-		var product models.Product
-		if err := tx.Where("id = ?", reqItem.ProductID).First(&product).Error; err != nil {
+	// Take the items out of stock from the warehouses nearest to the
+	// shipping address, failing instead of overselling. Reserved items were
+	// taken already.
+	if reservation == nil {
+		allocated, _, err := takeStock(tx, lines, saleMovement(order), shippingDetails.Province)
+		if err != nil {
 			tx.Rollback()
 			return nil, err
 		}
+		orderItems = append(fulfillOrderItems(orderItems, allocated), preorderItems(orderItems, preordered)...)
+	}
 
-		// Create order item
-		orderItem := models.OrderItem{
-			OrderID:   order.ID,
-			ProductID: reqItem.ProductID,
-			Quantity:  reqItem.Quantity,
-			Price:     product.Price,
-		}
+	// Create order items
+	for _, orderItem := range orderItems {
+		orderItem.OrderID = order.ID
 		if err := tx.Create(&orderItem).Error; err != nil {
 			tx.Rollback()
 			return nil, err
 		}
+	}
 
-		// Update product stock (decrease by purchased quantity)
-		newStock := product.Stock - reqItem.Quantity
-		if err := tx.Model(&product).Update("stock", newStock).Error; err != nil {
+	if reservation != nil {
+		if err := commitReservation(tx, *reservation, order.ID); err != nil {
 			tx.Rollback()
-			return nil, fmt.Errorf("failed to update stock for product %d: %v", reqItem.ProductID, err)
+			return nil, err
 		}
+	}
 
-		// If stock reaches 0, mark product as unavailable
-		if newStock == 0 {
-			if err := tx.Model(&product).Update("is_available", false).Error; err != nil {
-				tx.Rollback()
-				return nil, fmt.Errorf("failed to update availability for product %d: %v", reqItem.ProductID, err)
-			}
+	// Record the coupon and flash sale usage; coupons taken from the cart
+	// leave it
+	if err := redeemCoupons(tx, order, pricing); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := recordFlashSalePurchases(tx, order, claims); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if req.CouponCodes == nil {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.CartCoupon{}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
 		}
 	}
 
@@ -365,8 +456,7 @@ func (s *OrderService) CreateOrderFromRequest(userID uint, req models.CreateOrde
 
 	// Load complete order with items
 	if err := s.db.Where("id = ?", order.ID).
-		Preload("OrderItems").
-		Preload("OrderItems.Product").
+		Scopes(preloadOrderItems).
 		First(&order).Error; err != nil {
 		return nil, err
 	}
@@ -374,14 +464,205 @@ func (s *OrderService) CreateOrderFromRequest(userID uint, req models.CreateOrde
 	return &order, nil
 }
 
+// shippingFee returns the flat shipping fee charged per order,
+// SHIPPING_FEE (default 0)
+func shippingFee() float64 {
+	return float64(max(envInt("SHIPPING_FEE", 0), 0))
+}
+
+// saleMovement is the inventory ledger entry of the items sold with an order
+func saleMovement(order models.Order) models.InventoryMovement {
+	return models.InventoryMovement{
+		Reason:        models.MovementSale,
+		ReferenceType: models.MovementRefOrder,
+		ReferenceID:   &order.ID,
+		UserID:        &order.UserID,
+	}
+}
+
+// orderRestockReasons maps the statuses of orders whose items went back in
+// stock to the reason recorded in the inventory ledger
+var orderRestockReasons = map[string]string{
+	"CANCELLED": models.MovementCancel,
+	"RETURNED":  models.MovementReturn,
+}
+
+// moveOrderStock puts the items of an order back in stock when it becomes
+// cancelled or returned, and takes them out again when an admin moves it
+// out of those statuses
+func moveOrderStock(tx *gorm.DB, adminID uint, order models.Order, status string) error {
+	_, wasRestocked := orderRestockReasons[strings.ToUpper(order.Status)]
+	reason, restock := orderRestockReasons[strings.ToUpper(status)]
+	if wasRestocked == restock {
+		return nil
+	}
+
+	// Pre-ordered items waiting for stock never took any
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ? AND NOT awaiting_stock", order.ID).Find(&items).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	lines := make([]stockLine, len(items))
+	for i, item := range items {
+		lines[i] = stockLine{ProductID: item.ProductID, VariantID: item.VariantID, WarehouseID: item.WarehouseID, Quantity: item.Quantity}
+	}
+
+	entry := models.InventoryMovement{
+		ReferenceType: models.MovementRefOrder,
+		ReferenceID:   &order.ID,
+		AdminID:       &adminID,
+	}
+	if restock {
+		entry.Reason = reason
+		_, err := returnStock(tx, lines, entry)
+		return err
+	}
+	entry.Reason = models.MovementSale
+	_, _, err := takeStock(tx, lines, entry, "")
+	return err
+}
+
+// priceOrderLines checks that order lines can be bought and returns their
+// order items, without the order ID, and the order total. Items sold in
+// bundles get their share of the bundle discount, which the total is net of,
+// and items sold on their own the sale price of the flash sales claimed for
+// them.
+func priceOrderLines(tx *gorm.DB, lines []stockLine, claims []flashSaleClaim) ([]models.OrderItem, float64, error) {
+	orderItems := make([]models.OrderItem, 0, len(lines))
+	for _, line := range lines {
+		var product models.Product
+		if err := tx.Where("id = ?", line.ProductID).First(&product).Error; err != nil {
+			return nil, 0, productLookupError(err, line.ProductID)
+		}
+
+		variant, err := resolveLineVariant(tx, product, line.VariantID)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		item := models.OrderItem{
+			ProductID:   line.ProductID,
+			VariantID:   line.VariantID,
+			WarehouseID: line.WarehouseID,
+			BundleID:    line.BundleID,
+			Quantity:    line.Quantity,
+			Price:       linePrice(product, variant),
+		}
+		if sale := claimedFlashSale(claims, line.ProductID, line.VariantID, line.BundleID); sale != nil {
+			item.Price = sale.SalePrice
+			item.FlashSaleID = &sale.ID
+		}
+		orderItems = append(orderItems, item)
+	}
+
+	if err := allocateBundleDiscounts(tx, orderItems); err != nil {
+		return nil, 0, err
+	}
+
+	var totalAmount float64
+	for _, item := range orderItems {
+		totalAmount += item.Price*float64(item.Quantity) - item.Discount
+	}
+	return orderItems, roundMoney(totalAmount), nil
+}
+
+// orderItemKey matches order items with the stock lines taken for them
+type orderItemKey struct {
+	stock    stockKey
+	bundleID uint
+}
+
+// itemKey returns the order item key of a product or variant sold on its
+// own, or in a bundle when bundleID is set
+func itemKey(productID uint, variantID, bundleID *uint) orderItemKey {
+	key := orderItemKey{stock: lineKey(stockLine{ProductID: productID, VariantID: variantID})}
+	if bundleID != nil {
+		key.bundleID = *bundleID
+	}
+	return key
+}
+
+// fulfillOrderItems splits priced order items by the warehouses their stock
+// was taken from, spreading each item's discount over its parts by quantity.
+// Only items with allocated lines are returned.
+func fulfillOrderItems(items []models.OrderItem, allocated []stockLine) []models.OrderItem {
+	parts := make(map[orderItemKey][]stockLine)
+	for _, line := range allocated {
+		key := itemKey(line.ProductID, line.VariantID, line.BundleID)
+		parts[key] = append(parts[key], line)
+	}
+
+	var fulfilled []models.OrderItem
+	for _, item := range items {
+		key := itemKey(item.ProductID, item.VariantID, item.BundleID)
+		remaining, discount := item.Quantity, item.Discount
+		for remaining > 0 && len(parts[key]) > 0 {
+			part := &parts[key][0]
+			take := min(part.Quantity, remaining)
+
+			share := discount
+			if take < remaining {
+				share = roundMoney(item.Discount * float64(take) / float64(item.Quantity))
+			}
+
+			fulfilled = append(fulfilled, models.OrderItem{
+				ProductID:   item.ProductID,
+				VariantID:   item.VariantID,
+				WarehouseID: part.WarehouseID,
+				BundleID:    item.BundleID,
+				Quantity:    take,
+				Price:       item.Price,
+				Discount:    share,
+				FlashSaleID: item.FlashSaleID,
+			})
+
+			remaining -= take
+			discount -= share
+			part.Quantity -= take
+			if part.Quantity == 0 {
+				parts[key] = parts[key][1:]
+			}
+		}
+	}
+	return fulfilled
+}
+
+// resolveShippingAddress picks the shipping address for an order: the requested
+// address book entry, then free text, then the user's default address
+func resolveShippingAddress(tx *gorm.DB, userID uint, req models.CreateOrderRequest) (string, models.AddressSnapshot, error) {
+	if req.AddressID != nil {
+		address, err := findUserAddress(tx, userID, *req.AddressID)
+		if err != nil {
+			return "", models.AddressSnapshot{}, err
+		}
+		return address.FullAddress(), address.Snapshot(), nil
+	}
+
+	if req.ShippingAddress != "" {
+		return req.ShippingAddress, models.AddressSnapshot{}, nil
+	}
+
+	var address models.UserAddress
+	if err := tx.Where("user_id = ? AND is_default = ?", userID, true).First(&address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", models.AddressSnapshot{}, ErrShippingAddressRequired
+		}
+		return "", models.AddressSnapshot{}, err
+	}
+	return address.FullAddress(), address.Snapshot(), nil
+}
+
 // Admin Order Management Functions
 
-// GetAllOrders returns all orders for admin (with pagination and filtering)
-func GetAllOrders(page, limit int, status string) ([]models.Order, int64, error) {
+// GetAllOrders returns one page of all orders for admin
+func GetAllOrders(params pagination.Params) ([]models.Order, pagination.Page, error) {
 	if orderService == nil {
 		InitOrderService()
 	}
-	return orderService.GetAllOrders(page, limit, status)
+	return orderService.GetAllOrders(params)
 }
 
 // GetOrderByIDAdmin returns order by ID for admin (no user restriction)
@@ -400,37 +681,26 @@ func GetAdminOrderStats() (map[string]interface{}, error) {
 	return orderService.GetAdminOrderStats()
 }
 
-func (s *OrderService) GetAllOrders(page, limit int, status string) ([]models.Order, int64, error) {
-	var orders []models.Order
-	var total int64
-
-	query := s.db.Model(&models.Order{})
-	if status != "" {
-		query = query.Where("status = ?", status)
-	}
-
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	offset := (page - 1) * limit
-	if err := query.Preload("OrderItems").
-		Preload("OrderItems.Product").
-		Order("created_at DESC").
-		Offset(offset).
-		Limit(limit).
-		Find(&orders).Error; err != nil {
-		return nil, 0, err
-	}
+func (s *OrderService) GetAllOrders(params pagination.Params) ([]models.Order, pagination.Page, error) {
+	orders := []models.Order{}
+	page, err := pagination.Find(s.db, params, &orders, preloadOrderItems)
+	return orders, page, err
+}
 
-	return orders, total, nil
+// preloadOrderItems loads order items with their products, variants and
+// warehouses, including products and variants deleted from the catalog since
+func preloadOrderItems(db *gorm.DB) *gorm.DB {
+	return db.Preload("OrderItems").
+		Preload("Coupons").
+		Preload("OrderItems.Product", includeDeleted).
+		Preload("OrderItems.Variant", includeDeleted).
+		Preload("OrderItems.Warehouse")
 }
 
 func (s *OrderService) GetOrderByIDAdmin(orderID uint) (*models.Order, error) {
 	var order models.Order
 	if err := s.db.Where("id = ?", orderID).
-		Preload("OrderItems").
-		Preload("OrderItems.Product").
+		Scopes(preloadOrderItems).
 		First(&order).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("order not found")
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPreorderCapReached is returned when a pre-order would take the
+	// units waiting for stock beyond the product's pre-order cap
+	ErrPreorderCapReached = errors.New("pre-order limit reached")
+
+	// ErrAwaitingStock is returned when an order whose pre-ordered items wait
+	// for stock is moved to a status other than PREORDERED or CANCELLED
+	ErrAwaitingStock = errors.New("order has pre-ordered items waiting for stock")
+)
+
+// validatePreorder checks the pre-order settings of a product. Pre-order
+// products need a cap, and the deposit cannot exceed the price.
+func validatePreorder(isPreorder bool, preorderCap int, deposit, price float64) models.FieldErrors {
+	if !isPreorder {
+		return nil
+	}
+	errs := models.FieldErrors{}
+	if preorderCap < 1 {
+		errs["preorder_cap"] = "must be at least 1 for pre-order products"
+	}
+	if deposit > price {
+		errs["preorder_deposit"] = "must not be higher than the price"
+	}
+	return errs
+}
+
+// splitPreorderLines separates the order lines of pre-order products that
+// cannot be taken out of stock now, because stock is short or earlier
+// pre-orders still wait for it, from the lines that can. It returns the
+// deposit due for the pre-ordered lines, or ErrPreorderCapReached when they
+// would take a product beyond its cap. Product rows are locked in ascending
+// order, like takeStock does, so the cap holds under concurrent orders.
+func splitPreorderLines(tx *gorm.DB, lines []stockLine) (inStock, preordered []stockLine, deposit float64, err error) {
+	// Lines for the same product or variant, such as one on its own and one
+	// in a bundle, are decided together
+	preorder := make(map[stockKey]bool)
+	err = eachProductStock(lines, func(productID uint, merged []stockLine) error {
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "name", "is_preorder", "preorder_cap", "preorder_deposit").
+			First(&product, productID).Error; err != nil {
+			return productLookupError(err, productID)
+		}
+		if !product.IsPreorder {
+			return nil
+		}
+
+		waiting, err := awaitingUnits(tx, productID, nil, false)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range merged {
+			balance, err := stockBalance(tx, productID, line.VariantID)
+			if err != nil {
+				return err
+			}
+			queued, err := awaitingUnits(tx, productID, line.VariantID, true)
+			if err != nil {
+				return err
+			}
+			waits, err := preorderLine(product, line, balance, queued, waiting)
+			if err != nil {
+				return err
+			}
+			if !waits {
+				continue
+			}
+			waiting += line.Quantity
+			deposit += product.PreorderDeposit * float64(line.Quantity)
+			preorder[lineKey(line)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	for _, line := range lines {
+		if preorder[lineKey(line)] {
+			preordered = append(preordered, line)
+		} else {
+			inStock = append(inStock, line)
+		}
+	}
+	return inStock, preordered, deposit, nil
+}
+
+// preorderLine decides whether a line of a pre-order product is pre-ordered
+// rather than taken out of stock: when the balance of its product or variant
+// is short, or queued units of earlier pre-orders still wait for it. The
+// units waiting for the product must stay within its cap.
+func preorderLine(product models.Product, line stockLine, balance, queued, waiting int) (bool, error) {
+	if queued == 0 && balance >= line.Quantity {
+		return false, nil
+	}
+	if waiting+line.Quantity > product.PreorderCap {
+		return false, fmt.Errorf("%w for product %s. Available: %d, Requested: %d",
+			ErrPreorderCapReached, product.Name, max(product.PreorderCap-waiting, 0), line.Quantity)
+	}
+	return true, nil
+}
+
+// awaitingUnits returns the units of a product waiting for stock in
+// PREORDERED orders, only those of one variant, or of the product itself when
+// variantID is nil, if byVariant is set
+func awaitingUnits(tx *gorm.DB, productID uint, variantID *uint, byVariant bool) (int, error) {
+	query := tx.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("order_items.product_id = ? AND order_items.awaiting_stock AND orders.status = ?", productID, models.OrderStatusPreordered)
+	if byVariant {
+		if variantID == nil {
+			query = query.Where("order_items.variant_id IS NULL")
+		} else {
+			query = query.Where("order_items.variant_id = ?", *variantID)
+		}
+	}
+
+	var units int
+	err := query.Select("COALESCE(SUM(order_items.quantity), 0)").Scan(&units).Error
+	return units, err
+}
+
+// preorderItems returns the order items of pre-ordered lines, priced like
+// items, waiting for stock and without a warehouse
+func preorderItems(items []models.OrderItem, lines []stockLine) []models.OrderItem {
+	preordered := fulfillOrderItems(items, lines)
+	for i := range preordered {
+		preordered[i].IsPreorder = true
+		preordered[i].AwaitingStock = true
+	}
+	return preordered
+}
+
+// stockArrived runs after stock of a product or variant was added: the
+// stock goes to pre-orders waiting for it first, and if wasOut and some
+// stock is left a product without variants is available again, as in
+// returnStock, and customers waiting for it to be back in stock are notified
+func stockArrived(tx *gorm.DB, productID uint, variantID *uint, wasOut bool) error {
+	if err := allocatePreorders(tx, productID, variantID); err != nil {
+		return err
+	}
+	if !wasOut {
+		return nil
+	}
+
+	balance, err := stockBalance(tx, productID, variantID)
+	if err != nil || balance <= 0 {
+		return err
+	}
+	// Products with variants follow them in syncProductFromVariants
+	if variantID == nil {
+		if err := tx.Model(&models.Product{}).Scopes(configs.KeepVersion).Where("id = ?", productID).Update("is_available", true).Error; err != nil {
+			return err
+		}
+	}
+	return notifyBackInStock(tx, productID, variantID)
+}
+
+// allocatePreorders takes the stock of a product or variant for the items
+// waiting for it, first come first served, and stops at the first item that
+// does not fit so later orders cannot overtake it. Orders whose pre-ordered
+// items all got stock become pending and their customers are notified.
+//
+// The stock is locked before the orders here, while status changes lock the
+// order before its stock, so an order locked by a status change is skipped
+// instead of waited for, and allocation stops there to keep the queue order.
+func allocatePreorders(tx *gorm.DB, productID uint, variantID *uint) error {
+	var items []models.OrderItem
+	query := tx.Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("order_items.product_id = ? AND order_items.awaiting_stock AND orders.status = ?", productID, models.OrderStatusPreordered)
+	if variantID == nil {
+		query = query.Where("order_items.variant_id IS NULL")
+	} else {
+		query = query.Where("order_items.variant_id = ?", *variantID)
+	}
+	if err := query.Order("orders.created_at, orders.id, order_items.id").Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		balance, err := stockBalance(tx, productID, variantID)
+		if err != nil {
+			return err
+		}
+		if balance < item.Quantity {
+			return nil
+		}
+
+		var order models.Order
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).First(&order, item.OrderID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line := stockLine{ProductID: item.ProductID, VariantID: item.VariantID, BundleID: item.BundleID, Quantity: item.Quantity}
+		allocated, _, err := takeStock(tx, []stockLine{line}, saleMovement(order), order.ShippingDetails.Province)
+		if err != nil {
+			return err
+		}
+		if err := fillPreorderItem(tx, item, allocated); err != nil {
+			return err
+		}
+
+		if err := releasePreorder(tx, order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillPreorderItem records that a pre-ordered item got its stock, splitting
+// it by the warehouses the stock was taken from
+func fillPreorderItem(tx *gorm.DB, item models.OrderItem, allocated []stockLine) error {
+	for i, part := range fulfillOrderItems([]models.OrderItem{item}, allocated) {
+		if i == 0 {
+			if err := tx.Model(&item).Updates(map[string]interface{}{
+				"warehouse_id":   part.WarehouseID,
+				"quantity":       part.Quantity,
+				"discount":       part.Discount,
+				"awaiting_stock": false,
+			}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		part.OrderID = item.OrderID
+		part.IsPreorder = true
+		if err := tx.Create(&part).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releasePreorder moves a PREORDERED order to pending once none of its
+// items wait for stock, and notifies its customer
+func releasePreorder(tx *gorm.DB, order models.Order) error {
+	var waiting int64
+	if err := tx.Model(&models.OrderItem{}).
+		Where("order_id = ? AND awaiting_stock", order.ID).
+		Count(&waiting).Error; err != nil {
+		return err
+	}
+	if waiting > 0 {
+		return nil
+	}
+
+	if err := tx.Model(&order).Updates(map[string]interface{}{
+		"status":     "pending",
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.Notification{
+		UserID:  order.UserID,
+		Title:   "Pre-order ready",
+		Message: fmt.Sprintf("The pre-ordered items of order #%d are in stock and will be shipped soon", order.ID),
+		Type:    models.NotificationPreorderReady,
+	}).Error
+}
+
+// resumePreorders allocates stock to the pre-orders queued behind the items
+// of a cancelled order that were still waiting for it, as stock arriving
+// while the order was being cancelled skipped it and stopped there
+func resumePreorders(tx *gorm.DB, orderID uint) error {
+	var lines []stockLine
+	if err := tx.Model(&models.OrderItem{}).
+		Select("DISTINCT product_id, variant_id").
+		Where("order_id = ? AND awaiting_stock", orderID).
+		Order("product_id, variant_id").
+		Scan(&lines).Error; err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if err := allocatePreorders(tx, line.ProductID, line.VariantID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkAwaitingStock refuses to move an order whose pre-ordered items still
+// wait for stock to a status other than PREORDERED or CANCELLED
+func checkAwaitingStock(tx *gorm.DB, orderID uint, status string) error {
+	status = strings.ToUpper(status)
+	if status == models.OrderStatusPreordered || status == "CANCELLED" {
+		return nil
+	}
+
+	var waiting int64
+	if err := tx.Model(&models.OrderItem{}).
+		Where("order_id = ? AND awaiting_stock", orderID).
+		Count(&waiting).Error; err != nil {
+		return err
+	}
+	if waiting > 0 {
+		return ErrAwaitingStock
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"literally-backend/internal/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlRecorder is a logger keeping the SQL of the statements it sees
+type sqlRecorder struct {
+	logger.Interface
+	statements []string
+}
+
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
+
+// dryRunDB returns a PostgreSQL session that builds statements without
+// running them, and the recorder of their SQL
+func dryRunDB(t *testing.T) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               recorder,
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return db, recorder
+}
+
+func TestPreorderLine(t *testing.T) {
+	product := models.Product{Name: "Phone X", IsPreorder: true, PreorderCap: 10}
+
+	tests := []struct {
+		name     string
+		quantity int
+		balance  int
+		queued   int
+		waiting  int
+		want     bool
+		wantErr  error
+	}{
+		{name: "in stock", quantity: 2, balance: 5, want: false},
+		{name: "stock short", quantity: 6, balance: 5, want: true},
+		{name: "out of stock", quantity: 1, want: true},
+		{name: "earlier pre-orders queued", quantity: 1, balance: 5, queued: 3, waiting: 3, want: true},
+		{name: "fills the cap", quantity: 4, waiting: 6, want: true},
+		{name: "beyond the cap", quantity: 5, waiting: 6, wantErr: ErrPreorderCapReached},
+		{name: "in stock beyond the cap", quantity: 5, balance: 5, waiting: 8, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := stockLine{ProductID: 1, Quantity: tt.quantity}
+			got, err := preorderLine(product, line, tt.balance, tt.queued, tt.waiting)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("pre-ordered %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAwaitingUnitsQuery(t *testing.T) {
+	variantID := uint(7)
+	tests := []struct {
+		name      string
+		variantID *uint
+		byVariant bool
+		want      string
+		dontWant  string
+	}{
+		{name: "whole product", variantID: &variantID, dontWant: "variant_id"},
+		{name: "product itself", byVariant: true, want: "order_items.variant_id IS NULL"},
+		{name: "one variant", variantID: &variantID, byVariant: true, want: "order_items.variant_id = 7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := dryRunDB(t)
+			// Scanning is not supported without a database, building is
+			if _, err := awaitingUnits(db, 3, tt.variantID, tt.byVariant); err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+				t.Fatalf("awaiting units: %v", err)
+			}
+			if len(recorder.statements) != 1 {
+				t.Fatalf("ran %d statements, want 1", len(recorder.statements))
+			}
+
+			sql := recorder.statements[0]
+			for _, part := range []string{
+				"SUM(order_items.quantity)",
+				"JOIN orders ON orders.id = order_items.order_id",
+				"order_items.product_id = 3 AND order_items.awaiting_stock AND orders.status = 'PREORDERED'",
+				tt.want,
+			} {
+				if !strings.Contains(sql, part) {
+					t.Errorf("query %q lacks %q", sql, part)
+				}
+			}
+			if tt.dontWant != "" && strings.Contains(sql, tt.dontWant) {
+				t.Errorf("query %q filters on %q", sql, tt.dontWant)
+			}
+		})
+	}
+}
+
+func TestPreordersStopAtCapAndFillInOrder(t *testing.T) {
+	db := openTestDB(t)
+
+	const preorderCap, buyers = 3, 20
+	product := createStockedProduct(t, db, 0)
+	if err := db.Model(&product).Updates(map[string]interface{}{
+		"is_preorder":      true,
+		"preorder_cap":     preorderCap,
+		"preorder_deposit": 10,
+	}).Error; err != nil {
+		t.Fatalf("enable pre-order: %v", err)
+	}
+
+	user := createTestUser(t, db, "Early bird")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var preordered []uint
+	refused := 0
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, err := CreateOrderFromRequest(user.ID, models.CreateOrderRequest{
+				PaymentMethodID: 1,
+				ShippingAddress: "Ho Chi Minh City, Vietnam",
+				Items:           []models.CreateOrderItemRequest{{ProductID: product.ID, Quantity: 1}},
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				if order.Status != models.OrderStatusPreordered || order.DepositAmount != 10 {
+					t.Errorf("order status %s deposit %v, want %s and 10", order.Status, order.DepositAmount, models.OrderStatusPreordered)
+				}
+				preordered = append(preordered, order.ID)
+			case errors.Is(err, ErrPreorderCapReached):
+				refused++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(preordered) != preorderCap || refused != buyers-preorderCap {
+		t.Fatalf("pre-ordered %d and refused %d, want %d and %d", len(preordered), refused, preorderCap, buyers-preorderCap)
+	}
+
+	// Two units arrive: the two earliest pre-orders get them
+	if _, err := AdjustStock(0, product.ID, models.AdjustStockRequest{Quantity: 2, Reason: models.MovementReceiving, Note: "Launch delivery"}); err != nil {
+		t.Fatalf("receive stock: %v", err)
+	}
+
+	var orders []models.Order
+	db.Where("id IN ?", preordered).Order("created_at, id").Find(&orders)
+	for i, order := range orders {
+		want := "pending"
+		if i == len(orders)-1 {
+			want = models.OrderStatusPreordered
+		}
+		if order.Status != want {
+			t.Errorf("pre-order %d of %d is %s, want %s", i+1, len(orders), order.Status, want)
+		}
+	}
+
+	var after models.Product
+	db.First(&after, product.ID)
+	if after.Stock != 0 {
+		t.Errorf("stock %d after filling pre-orders, want 0", after.Stock)
+	}
+	if ledger := ledgerStock(t, db, product.ID); ledger != after.Stock {
+		t.Errorf("ledger sums to %d, want the stock of %d", ledger, after.Stock)
+	}
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lowestPriceWindow is the period covered by Product.LowestPrice30Days
+const lowestPriceWindow = 30 * 24 * time.Hour
+
+// PriceHistoryListSpec lists the sorts and filters available on price history
+var PriceHistoryListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"created_at": "created_at",
+		"price":      "price",
+	},
+	Filters: map[string]string{
+		"variant_id": "variant_id",
+		"source":     "source",
+	},
+	DefaultSort: "-created_at",
+}
+
+// GetPriceHistory returns one page of the price changes of a product and its variants
+func GetPriceHistory(productID uint, params pagination.Params) ([]models.PriceHistory, pagination.Page, error) {
+	history := []models.PriceHistory{}
+	page, err := pagination.Find(configs.DB.Where("product_id = ?", productID), params, &history)
+	return history, page, err
+}
+
+// GetPriceSchedules returns the price schedules of a product, latest start first
+func GetPriceSchedules(productID uint) ([]models.PriceSchedule, error) {
+	if !productExists(productID) {
+		return nil, errors.New("product not found")
+	}
+
+	schedules := []models.PriceSchedule{}
+	err := configs.DB.Where("product_id = ?", productID).Order("effective_from DESC, id DESC").Find(&schedules).Error
+	return schedules, err
+}
+
+// CreatePriceSchedule schedules a price change for a product or one of its
+// variants. Schedules for the same product or variant may not overlap. A
+// schedule that is already due is applied right away.
+func CreatePriceSchedule(productID uint, req models.CreatePriceScheduleRequest) (models.PriceSchedule, error) {
+	if !productExists(productID) {
+		return models.PriceSchedule{}, errors.New("product not found")
+	}
+
+	var variantCount int64
+	configs.DB.Model(&models.ProductVariant{}).Where("product_id = ?", productID).Count(&variantCount)
+	if req.VariantID == nil && variantCount > 0 {
+		return models.PriceSchedule{}, errors.New("variant_id is required for products with variants")
+	}
+	if req.VariantID != nil {
+		if _, err := findProductVariant(configs.DB, productID, *req.VariantID); err != nil {
+			return models.PriceSchedule{}, err
+		}
+	}
+
+	if req.EffectiveTo != nil {
+		if !req.EffectiveTo.After(req.EffectiveFrom) {
+			return models.PriceSchedule{}, errors.New("effective_to must be after effective_from")
+		}
+		if !req.EffectiveTo.After(time.Now()) {
+			return models.PriceSchedule{}, errors.New("effective_to must be in the future")
+		}
+	}
+	if err := validateCompareAtPrice(req.Price, req.CompareAtPrice); err != nil {
+		return models.PriceSchedule{}, err
+	}
+
+	overlapping := configs.DB.Model(&models.PriceSchedule{}).
+		Where("product_id = ? AND status IN ?", productID, []string{models.PriceScheduleScheduled, models.PriceScheduleActive}).
+		Where("effective_to IS NULL OR effective_to > ?", req.EffectiveFrom)
+	if req.EffectiveTo != nil {
+		overlapping = overlapping.Where("effective_from < ?", *req.EffectiveTo)
+	}
+	if req.VariantID != nil {
+		overlapping = overlapping.Where("variant_id = ?", *req.VariantID)
+	} else {
+		overlapping = overlapping.Where("variant_id IS NULL")
+	}
+	var conflicts int64
+	if err := overlapping.Count(&conflicts).Error; err != nil {
+		return models.PriceSchedule{}, err
+	}
+	if conflicts > 0 {
+		return models.PriceSchedule{}, errors.New("the schedule overlaps another price schedule of this product")
+	}
+
+	schedule := models.PriceSchedule{
+		ProductID:      productID,
+		VariantID:      req.VariantID,
+		Price:          req.Price,
+		CompareAtPrice: req.CompareAtPrice,
+		EffectiveFrom:  req.EffectiveFrom,
+		EffectiveTo:    req.EffectiveTo,
+		Status:         models.PriceScheduleScheduled,
+	}
+	if err := configs.DB.Create(&schedule).Error; err != nil {
+		return models.PriceSchedule{}, err
+	}
+
+	if !schedule.EffectiveFrom.After(time.Now()) {
+		if err := startPriceSchedule(schedule.ID, time.Now()); err != nil {
+			return models.PriceSchedule{}, err
+		}
+		configs.DB.First(&schedule, schedule.ID)
+	}
+
+	return schedule, nil
+}
+
+// CancelPriceSchedule cancels a price schedule. Cancelling a running schedule
+// restores the prices it replaced.
+func CancelPriceSchedule(productID, scheduleID uint) (models.PriceSchedule, error) {
+	var schedule models.PriceSchedule
+	if err := configs.DB.Where("id = ? AND product_id = ?", scheduleID, productID).First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return schedule, errors.New("price schedule not found")
+		}
+		return schedule, err
+	}
+
+	switch schedule.Status {
+	case models.PriceScheduleScheduled:
+		result := configs.DB.Model(&schedule).Where("status = ?", models.PriceScheduleScheduled).
+			Update("status", models.PriceScheduleCancelled)
+		if result.Error != nil {
+			return schedule, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// The scheduler started it in the meantime
+			return CancelPriceSchedule(productID, scheduleID)
+		}
+	case models.PriceScheduleActive:
+		if err := endPriceSchedule(schedule.ID, models.PriceScheduleCancelled); err != nil {
+			return schedule, err
+		}
+	default:
+		return schedule, errors.New("price schedule has already ended")
+	}
+
+	configs.DB.First(&schedule, schedule.ID)
+	return schedule, nil
+}
+
+// ApplyDuePriceSchedules ends running schedules whose end has passed and
+// starts those that became effective
+func ApplyDuePriceSchedules() error {
+	now := time.Now()
+
+	// End first, so back-to-back schedules restore before the next one applies
+	var ids []uint
+	if err := configs.DB.Model(&models.PriceSchedule{}).
+		Where("status = ? AND effective_to <= ?", models.PriceScheduleActive, now).
+		Order("effective_to, id").
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := endPriceSchedule(id, models.PriceScheduleEnded); err != nil {
+			return fmt.Errorf("end price schedule %d: %w", id, err)
+		}
+	}
+
+	ids = nil
+	if err := configs.DB.Model(&models.PriceSchedule{}).
+		Where("status = ? AND effective_from <= ?", models.PriceScheduleScheduled, now).
+		Order("effective_from, id").
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := startPriceSchedule(id, now); err != nil {
+			return fmt.Errorf("start price schedule %d: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// StartPriceScheduleJob periodically applies due price schedules
+func StartPriceScheduleJob() {
+	runPeriodically("price-schedules", envDuration("PRICE_SCHEDULE_INTERVAL", time.Minute), ApplyDuePriceSchedules)
+}
+
+// startPriceSchedule applies the prices of a schedule and remembers the ones
+// it replaces
+func startPriceSchedule(id uint, now time.Time) error {
+	var productID uint
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		schedule, err := lockPriceSchedule(tx, id)
+		if err != nil || schedule.Status != models.PriceScheduleScheduled {
+			return err
+		}
+		productID = schedule.ProductID
+
+		// The whole window passed while the scheduler was not running
+		if schedule.EffectiveTo != nil && !schedule.EffectiveTo.After(now) {
+			return tx.Model(&schedule).Update("status", models.PriceScheduleEnded).Error
+		}
+
+		previous, previousCompareAt, err := scheduledTargetPrice(tx, schedule)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The product or variant was deleted
+			return tx.Model(&schedule).Update("status", models.PriceScheduleCancelled).Error
+		}
+		if err != nil {
+			return err
+		}
+		if err := setScheduledPrice(tx, schedule, schedule.Price, schedule.CompareAtPrice); err != nil {
+			return err
+		}
+
+		status := models.PriceScheduleActive
+		if schedule.EffectiveTo == nil {
+			status = models.PriceScheduleCompleted
+		}
+		return tx.Model(&schedule).Updates(map[string]interface{}{
+			"status":                    status,
+			"previous_price":            previous,
+			"previous_compare_at_price": previousCompareAt,
+		}).Error
+	})
+	if err == nil && productID > 0 {
+		indexProduct(productID)
+	}
+	return err
+}
+
+// endPriceSchedule restores the prices replaced by a running schedule and
+// moves it to status. Prices changed by hand while the schedule ran are kept.
+func endPriceSchedule(id uint, status string) error {
+	var productID uint
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		schedule, err := lockPriceSchedule(tx, id)
+		if err != nil || schedule.Status != models.PriceScheduleActive {
+			return err
+		}
+		productID = schedule.ProductID
+
+		price, compareAt, err := scheduledTargetPrice(tx, schedule)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && schedule.PreviousPrice != nil && price == schedule.Price && samePrice(compareAt, schedule.CompareAtPrice) {
+			if err := setScheduledPrice(tx, schedule, *schedule.PreviousPrice, schedule.PreviousCompareAtPrice); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&schedule).Update("status", status).Error
+	})
+	if err == nil && productID > 0 {
+		indexProduct(productID)
+	}
+	return err
+}
+
+// lockPriceSchedule loads a schedule and locks it until the transaction ends,
+// so several servers never apply it twice
+func lockPriceSchedule(tx *gorm.DB, id uint) (models.PriceSchedule, error) {
+	var schedule models.PriceSchedule
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, id).Error
+	return schedule, err
+}
+
+// scheduledTargetPrice returns the current prices of the product or variant
+// a schedule applies to
+func scheduledTargetPrice(tx *gorm.DB, schedule models.PriceSchedule) (float64, *float64, error) {
+	if schedule.VariantID != nil {
+		var variant models.ProductVariant
+		err := tx.Where("id = ? AND product_id = ?", *schedule.VariantID, schedule.ProductID).First(&variant).Error
+		return variant.Price, variant.CompareAtPrice, err
+	}
+
+	var product models.Product
+	err := tx.First(&product, schedule.ProductID).Error
+	return product.Price, product.CompareAtPrice, err
+}
+
+// setScheduledPrice writes the prices of the product or variant a schedule
+// applies to and records the change
+func setScheduledPrice(tx *gorm.DB, schedule models.PriceSchedule, price float64, compareAt *float64) error {
+	entry := models.PriceHistory{
+		ProductID:  schedule.ProductID,
+		Source:     models.PriceSourceSchedule,
+		ScheduleID: &schedule.ID,
+	}
+	updates := map[string]interface{}{
+		"price":            price,
+		"compare_at_price": compareAt,
+	}
+
+	if schedule.VariantID == nil {
+		return trackProductPrice(tx, entry, func() error {
+			return tx.Model(&models.Product{}).Where("id = ?", schedule.ProductID).Updates(updates).Error
+		})
+	}
+
+	variant, err := findProductVariant(tx, schedule.ProductID, *schedule.VariantID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&variant).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	variantEntry := entry
+	variantEntry.VariantID = &variant.ID
+	if err := recordVariantPrice(tx, variantEntry, variant.Price, variant.CompareAtPrice, price, compareAt); err != nil {
+		return err
+	}
+
+	return trackProductPrice(tx, entry, func() error {
+		return syncProductFromVariants(tx, schedule.ProductID)
+	})
+}
+
+// trackProductPrice runs update and records the product's new price in the
+// price history when update changed its price or compare-at price. entry
+// names the product and the source of the change; the prices are filled in.
+func trackProductPrice(tx *gorm.DB, entry models.PriceHistory, update func() error) error {
+	var before models.Product
+	if err := tx.Select("id", "price", "compare_at_price").First(&before, entry.ProductID).Error; err != nil {
+		return err
+	}
+
+	if err := update(); err != nil {
+		return err
+	}
+
+	var after models.Product
+	if err := tx.Select("id", "price", "compare_at_price").First(&after, entry.ProductID).Error; err != nil {
+		return err
+	}
+	if before.Price == after.Price && samePrice(before.CompareAtPrice, after.CompareAtPrice) {
+		return nil
+	}
+
+	entry.Price = after.Price
+	entry.PreviousPrice = &before.Price
+	entry.CompareAtPrice = after.CompareAtPrice
+	return tx.Create(&entry).Error
+}
+
+// recordVariantPrice records a variant's price change in the price history
+func recordVariantPrice(tx *gorm.DB, entry models.PriceHistory, previous float64, previousCompareAt *float64, price float64, compareAt *float64) error {
+	if previous == price && samePrice(previousCompareAt, compareAt) {
+		return nil
+	}
+
+	entry.Price = price
+	entry.PreviousPrice = &previous
+	entry.CompareAtPrice = compareAt
+	return tx.Create(&entry).Error
+}
+
+// recordInitialPrice records the first price of a new product or variant
+func recordInitialPrice(tx *gorm.DB, entry models.PriceHistory, price float64, compareAt *float64) error {
+	entry.Price = price
+	entry.CompareAtPrice = compareAt
+	return tx.Create(&entry).Error
+}
+
+// attachPrices fills the computed price fields of products: the price range
+// across variants, the lowest price of the last 30 days and the running
+// flash sales
+func attachPrices(products []models.Product) {
+	attachPriceRanges(products)
+	attachLowestPrices(products)
+	attachFlashSales(products)
+}
+
+// attachLowestPrices fills LowestPrice30Days with the lowest price each
+// product had in the last 30 days, its current price included. Every change
+// inside the window contributes both the price it set and the one it replaced.
+func attachLowestPrices(products []models.Product) {
+	if len(products) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+		products[i].LowestPrice30Days = product.Price
+	}
+
+	var lows []struct {
+		ProductID   uint
+		LowestPrice float64
+	}
+	if err := configs.DB.Model(&models.PriceHistory{}).
+		Select("product_id, LEAST(MIN(price), MIN(previous_price)) AS lowest_price").
+		Where("product_id IN ? AND variant_id IS NULL AND created_at >= ?", ids, time.Now().Add(-lowestPriceWindow)).
+		Group("product_id").
+		Scan(&lows).Error; err != nil {
+		log.Printf("Failed to load lowest prices: %v", err)
+		return
+	}
+
+	byProduct := make(map[uint]float64, len(lows))
+	for _, low := range lows {
+		byProduct[low.ProductID] = low.LowestPrice
+	}
+	for i := range products {
+		if low, ok := byProduct[products[i].ID]; ok && low < products[i].LowestPrice30Days {
+			products[i].LowestPrice30Days = low
+		}
+	}
+}
+
+// validateCompareAtPrice checks that a compare-at price is above the price
+func validateCompareAtPrice(price float64, compareAt *float64) error {
+	if compareAt != nil && *compareAt <= price {
+		return errors.New("compare_at_price must be higher than the price")
+	}
+	return nil
+}
+
+// compareAtUpdate converts a compare-at price from an update request, where
+// 0 removes it, to a column value
+func compareAtUpdate(value float64) interface{} {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
+
+// samePrice reports whether two optional prices are equal
+func samePrice(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// productExists reports whether a product exists and is not deleted
+func productExists(id uint) bool {
+	var count int64
+	configs.DB.Model(&models.Product{}).Where("id = ?", id).Count(&count)
+	return count > 0
+}
@@ -2,66 +2,121 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"literally-backend/configs"
 	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
 	"strings"
 
 	"gorm.io/gorm"
 )
 
-// GetAllProducts returns all products
-func GetAllProducts() []models.Product {
-	var products []models.Product
-	configs.DB.Where("is_available = ?", true).Find(&products)
-	return products
+// ErrProductNotFound is returned for products that do not exist or are
+// deleted
+var ErrProductNotFound = errors.New("product not found")
+
+// productLookupError describes a failed lookup of a product: not found when
+// it does not exist, the database error otherwise
+func productLookupError(err error, productID uint) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+	}
+	return err
+}
+
+// ProductListSpec lists the sorts available on product listings
+var ProductListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"name":       "products.name",
+		"price":      "products.price",
+		"rating":     "products.rating",
+		"stock":      "products.stock",
+		"created_at": "products.created_at",
+	},
+	DefaultSort: "-created_at",
+	Key:         "products.id",
 }
 
-// GetFeaturedProducts returns featured products
-func GetFeaturedProducts() []models.Product {
-	var products []models.Product
-	configs.DB.Where("is_featured = ? AND is_available = ?", true, true).Find(&products)
-	return products
+// GetAllProducts returns one page of available products
+func GetAllProducts(params pagination.Params) ([]models.Product, pagination.Page, error) {
+	return findProducts(configs.DB.Where("is_available = ?", true), params)
 }
 
-// GetProductsByCategory returns products by category ID
-func GetProductsByCategory(categoryID uint) []models.Product {
-	var products []models.Product
-	configs.DB.Where("category_id = ? AND is_available = ?", categoryID, true).Find(&products)
-	return products
+// GetAllProductsAdmin returns one page of products for admin regardless of
+// availability, applying the "show deleted" filter
+func GetAllProductsAdmin(deleted string, params pagination.Params) ([]models.Product, pagination.Page, error) {
+	query, err := scopeDeleted(configs.DB, deleted)
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	return findProducts(query, params)
+}
+
+// GetFeaturedProducts returns one page of featured products
+func GetFeaturedProducts(params pagination.Params) ([]models.Product, pagination.Page, error) {
+	return findProducts(configs.DB.Where("is_featured = ? AND is_available = ?", true, true), params)
+}
+
+// GetProductsByCategory returns one page of products by category ID,
+// optionally including products of all its subcategories
+func GetProductsByCategory(categoryID uint, includeDescendants bool, params pagination.Params) ([]models.Product, pagination.Page, error) {
+	categoryIDs := []uint{categoryID}
+	if includeDescendants {
+		if ids, err := categoryDescendantIDs(configs.DB, categoryID); err == nil && len(ids) > 0 {
+			categoryIDs = ids
+		}
+	}
+
+	return findProducts(configs.DB.Where("category_id IN ? AND is_available = ?", categoryIDs, true), params)
+}
+
+// findProducts loads one page of a product query with price ranges
+func findProducts(query *gorm.DB, params pagination.Params) ([]models.Product, pagination.Page, error) {
+	products := []models.Product{}
+	page, err := pagination.Find(query, params, &products)
+	if err != nil {
+		return nil, page, err
+	}
+	attachPrices(products)
+	return products, page, nil
 }
 
-// GetProductByID returns a product by ID
+// GetProductByID returns a product by ID with its variants, images and attributes
 func GetProductByID(id uint) (models.Product, bool) {
 	var product models.Product
-	result := configs.DB.First(&product, id)
+	result := configs.DB.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("price, id")
+	}).Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).Preload("Attributes.Attribute").First(&product, id)
 	if result.Error != nil {
 		return models.Product{}, false
 	}
-	return product, true
-}
-
-// SearchProducts searches products by name, description, or brand
-func SearchProducts(query string) []models.Product {
-	var products []models.Product
-	searchTerm := "%" + strings.ToLower(query) + "%"
 
-	configs.DB.Where(
-		"(LOWER(name) LIKE ? OR LOWER(description) LIKE ? OR LOWER(brand) LIKE ?) AND is_available = ?",
-		searchTerm, searchTerm, searchTerm, true,
-	).Find(&products)
-
-	return products
+	products := []models.Product{product}
+	attachPrices(products)
+	return products[0], true
 }
 
-// CreateProduct creates a new product
-func CreateProduct(req models.CreateProductRequest) (models.Product, error) {
+// CreateProduct creates a new product, recording its stock as received
+func CreateProduct(adminID uint, req models.CreateProductRequest) (models.Product, error) {
 	// Validate category exists
 	if !categoryExists(req.CategoryID) {
 		return models.Product{}, errors.New("category not found")
 	}
+	if productSKUTaken(req.SKU, 0) {
+		return models.Product{}, errors.New("product with this SKU already exists")
+	}
+	if err := validateCompareAtPrice(req.Price, req.CompareAtPrice); err != nil {
+		return models.Product{}, err
+	}
+	if errs := validatePreorder(req.IsPreorder, req.PreorderCap, req.PreorderDeposit, req.Price); len(errs) > 0 {
+		return models.Product{}, errs
+	}
 
 	// Create new product
 	product := models.Product{
+		SKU:         req.SKU,
 		Name:        req.Name,
 		Description: req.Description,
 		Price:       req.Price,
@@ -73,17 +128,118 @@ func CreateProduct(req models.CreateProductRequest) (models.Product, error) {
 		IsAvailable: true,
 		Rating:      0,
 		ReviewCount: 0,
+
+		CompareAtPrice:   req.CompareAtPrice,
+		ReorderThreshold: req.ReorderThreshold,
+
+		IsPreorder:      req.IsPreorder,
+		ReleaseDate:     req.ReleaseDate,
+		PreorderCap:     req.PreorderCap,
+		PreorderDeposit: req.PreorderDeposit,
 	}
 
-	if err := configs.DB.Create(&product).Error; err != nil {
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		if err := recordInitialPrice(tx, models.PriceHistory{ProductID: product.ID, Source: models.PriceSourceManual}, product.Price, product.CompareAtPrice); err != nil {
+			return err
+		}
+		entry := models.InventoryMovement{ProductID: product.ID, Reason: models.MovementReceiving, AdminID: &adminID}
+		return recordInitialStock(tx, entry, product.Stock)
+	})
+	if err != nil {
 		return models.Product{}, err
 	}
 
+	indexProduct(product.ID)
 	return product, nil
 }
 
-// UpdateProduct updates an existing product
-func UpdateProduct(id uint, req models.UpdateProductRequest) (models.Product, error) {
+// UpdateProduct applies a partial update to a product, changing only the
+// fields present in the request
+func UpdateProduct(adminID, id, version uint, req models.UpdateProductRequest) (models.Product, error) {
+	updates := make(map[string]interface{})
+
+	if req.SKU != nil {
+		updates["sku"] = *req.SKU
+	}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Price != nil {
+		updates["price"] = *req.Price
+	}
+	if req.Stock != nil {
+		updates["stock"] = *req.Stock
+	}
+	if req.ImageUrl != nil {
+		updates["image_url"] = *req.ImageUrl
+	}
+	if req.CategoryID != nil {
+		updates["category_id"] = *req.CategoryID
+	}
+	if req.Brand != nil {
+		updates["brand"] = *req.Brand
+	}
+	if req.IsFeatured != nil {
+		updates["is_featured"] = *req.IsFeatured
+	}
+	if req.IsAvailable != nil {
+		updates["is_available"] = *req.IsAvailable
+	}
+	if req.CompareAtPrice != nil {
+		updates["compare_at_price"] = compareAtUpdate(*req.CompareAtPrice)
+	}
+	if req.ReorderThreshold != nil {
+		updates["reorder_threshold"] = *req.ReorderThreshold
+	}
+	if req.IsPreorder != nil {
+		updates["is_preorder"] = *req.IsPreorder
+	}
+	if req.ReleaseDate != nil {
+		updates["release_date"] = *req.ReleaseDate
+	}
+	if req.PreorderCap != nil {
+		updates["preorder_cap"] = *req.PreorderCap
+	}
+	if req.PreorderDeposit != nil {
+		updates["preorder_deposit"] = *req.PreorderDeposit
+	}
+
+	return saveProduct(adminID, id, version, updates)
+}
+
+// ReplaceProduct replaces all editable fields of a product, clearing the
+// optional fields missing from the request
+func ReplaceProduct(adminID, id, version uint, req models.ReplaceProductRequest) (models.Product, error) {
+	return saveProduct(adminID, id, version, map[string]interface{}{
+		"sku":               req.SKU,
+		"name":              req.Name,
+		"description":       req.Description,
+		"price":             *req.Price,
+		"stock":             *req.Stock,
+		"image_url":         req.ImageUrl,
+		"category_id":       req.CategoryID,
+		"brand":             req.Brand,
+		"is_featured":       req.IsFeatured,
+		"is_available":      *req.IsAvailable,
+		"compare_at_price":  req.CompareAtPrice,
+		"reorder_threshold": req.ReorderThreshold,
+		"is_preorder":       req.IsPreorder,
+		"release_date":      req.ReleaseDate,
+		"preorder_cap":      req.PreorderCap,
+		"preorder_deposit":  req.PreorderDeposit,
+	})
+}
+
+// saveProduct checks and writes column updates to a product at the given
+// version, recording a price change in the price history and a stock change
+// in the inventory ledger
+func saveProduct(adminID, id, version uint, updates map[string]interface{}) (models.Product, error) {
 	var product models.Product
 
 	// Find the product
@@ -93,73 +249,155 @@ func UpdateProduct(id uint, req models.UpdateProductRequest) (models.Product, er
 		}
 		return models.Product{}, err
 	}
-
-	// Validate category if provided
-	if req.CategoryID > 0 && !categoryExists(req.CategoryID) {
-		return models.Product{}, errors.New("category not found")
+	if err := checkVersion(product.Version, version); err != nil {
+		return models.Product{}, err
 	}
 
-	// Update fields
-	updates := make(map[string]interface{})
+	fieldErrors := models.FieldErrors{}
 
-	if req.Name != "" {
-		updates["name"] = req.Name
+	categoryID, categoryChanged := updates["category_id"].(uint)
+	categoryChanged = categoryChanged && categoryID != product.CategoryID
+	if categoryChanged && !categoryExists(categoryID) {
+		fieldErrors["category_id"] = "category not found"
 	}
-	if req.Description != "" {
-		updates["description"] = req.Description
+	if sku, ok := updates["sku"].(string); ok && productSKUTaken(sku, id) {
+		fieldErrors["sku"] = "product with this SKU already exists"
 	}
-	if req.Price > 0 {
-		updates["price"] = req.Price
+
+	price := product.Price
+	if value, ok := updates["price"].(float64); ok {
+		price = value
+	}
+	compareAt := product.CompareAtPrice
+	if value, ok := updates["compare_at_price"]; ok {
+		compareAt = nil
+		switch v := value.(type) {
+		case float64:
+			compareAt = &v
+		case *float64:
+			compareAt = v
+		}
 	}
-	if req.Stock >= 0 {
-		updates["stock"] = req.Stock
+	if err := validateCompareAtPrice(price, compareAt); err != nil {
+		fieldErrors["compare_at_price"] = err.Error()
 	}
-	if req.ImageUrl != "" {
-		updates["image_url"] = req.ImageUrl
+
+	isPreorder := product.IsPreorder
+	if value, ok := updates["is_preorder"].(bool); ok {
+		isPreorder = value
 	}
-	if req.CategoryID > 0 {
-		updates["category_id"] = req.CategoryID
+	preorderCap := product.PreorderCap
+	if value, ok := updates["preorder_cap"].(int); ok {
+		preorderCap = value
 	}
-	if req.Brand != "" {
-		updates["brand"] = req.Brand
+	deposit := product.PreorderDeposit
+	if value, ok := updates["preorder_deposit"].(float64); ok {
+		deposit = value
+	}
+	for field, message := range validatePreorder(isPreorder, preorderCap, deposit, price) {
+		fieldErrors[field] = message
+	}
+
+	if len(fieldErrors) > 0 {
+		return models.Product{}, fieldErrors
 	}
-	updates["is_featured"] = req.IsFeatured
-	updates["is_available"] = req.IsAvailable
 
-	// Update the product
-	if err := configs.DB.Model(&product).Updates(updates).Error; err != nil {
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		return trackProductPrice(tx, models.PriceHistory{ProductID: id, Source: models.PriceSourceManual}, func() error {
+			stockEntry := models.InventoryMovement{ProductID: id, Reason: models.MovementAdjustment, AdminID: &adminID}
+			if err := trackStock(tx, stockEntry, func() error {
+				result := tx.Model(&models.Product{}).Where("id = ?", id).Scopes(atVersion(version)).Updates(updates)
+				if err := versionedResult(result, version); err != nil {
+					return err
+				}
+				// Stock and price of products with variants are derived from the variants
+				return syncProductFromVariants(tx, id)
+			}); err != nil {
+				return err
+			}
+			// Attribute values of the old category no longer apply
+			if categoryChanged {
+				return pruneProductAttributes(tx, id, categoryID)
+			}
+			return nil
+		})
+	})
+	if err != nil {
 		return models.Product{}, err
 	}
 
 	// Fetch the updated product
 	configs.DB.First(&product, id)
 
+	indexProduct(id)
 	return product, nil
 }
 
-// DeleteProduct deletes a product by ID (soft delete - set is_available to false)
-func DeleteProduct(id uint) error {
-	result := configs.DB.Model(&models.Product{}).Where("id = ?", id).Update("is_available", false)
-	if result.Error != nil {
-		return result.Error
+// DeleteProduct soft-deletes a product by ID at the given version
+func DeleteProduct(id, version uint) error {
+	var product models.Product
+	if err := configs.DB.Select("id", "version").First(&product, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("product not found")
+		}
+		return err
+	}
+	if err := checkVersion(product.Version, version); err != nil {
+		return err
+	}
+
+	result := configs.DB.Scopes(atVersion(version)).Delete(&models.Product{}, id)
+	if err := versionedResult(result, version); err != nil {
+		return err
 	}
 
 	if result.RowsAffected == 0 {
 		return errors.New("product not found")
 	}
 
+	unindexProduct(id)
 	return nil
 }
 
 // Categories Management
 
-// GetAllCategories returns all categories
+// GetAllCategories returns all categories as a flat list ordered for display
 func GetAllCategories() []models.Category {
 	var categories []models.Category
-	configs.DB.Find(&categories)
+	configs.DB.Order("sort_order, name").Find(&categories)
 	return categories
 }
 
+// CategoryListSpec lists the sorts available on flat category listings
+var CategoryListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"name":       "name",
+		"sort_order": "sort_order",
+		"created_at": "created_at",
+	},
+	DefaultSort: "sort_order,name",
+}
+
+// ListCategories returns one page of the flat category list
+func ListCategories(params pagination.Params) ([]models.Category, pagination.Page, error) {
+	categories := []models.Category{}
+	page, err := pagination.Find(configs.DB, params, &categories)
+	return categories, page, err
+}
+
+// GetAllCategoriesAdmin returns one page of categories for admin, applying
+// the "show deleted" filter
+func GetAllCategoriesAdmin(deleted string, params pagination.Params) ([]models.Category, pagination.Page, error) {
+	query, err := scopeDeleted(configs.DB, deleted)
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	categories := []models.Category{}
+	page, err := pagination.Find(query, params, &categories)
+	return categories, page, err
+}
+
 // GetCategoryByID returns a category by ID
 func GetCategoryByID(id uint) (models.Category, bool) {
 	var category models.Category
@@ -172,16 +410,27 @@ func GetCategoryByID(id uint) (models.Category, bool) {
 
 // CreateCategory creates a new category
 func CreateCategory(req models.CreateCategoryRequest) (models.Category, error) {
-	// Check if category with same name already exists
-	var existingCategory models.Category
-	if err := configs.DB.Where("name = ?", req.Name).First(&existingCategory).Error; err == nil {
+	if req.ParentID != nil && !categoryExists(*req.ParentID) {
+		return models.Category{}, errors.New("parent category not found")
+	}
+
+	// Check if a sibling category with same name already exists
+	if categoryNameTaken(req.Name, req.ParentID, 0) {
 		return models.Category{}, errors.New("category with this name already exists")
 	}
 
+	slug, err := uniqueCategorySlug(req.Slug, req.Name, 0)
+	if err != nil {
+		return models.Category{}, err
+	}
+
 	// Create new category
 	category := models.Category{
-		Name: req.Name,
-		Icon: req.Icon,
+		Name:      req.Name,
+		Slug:      slug,
+		Icon:      req.Icon,
+		ParentID:  req.ParentID,
+		SortOrder: req.SortOrder,
 	}
 
 	if err := configs.DB.Create(&category).Error; err != nil {
@@ -191,8 +440,43 @@ func CreateCategory(req models.CreateCategoryRequest) (models.Category, error) {
 	return category, nil
 }
 
-// UpdateCategory updates an existing category
-func UpdateCategory(id uint, req models.UpdateCategoryRequest) (models.Category, error) {
+// UpdateCategory applies a partial update to a category, changing only the
+// fields present in the request
+func UpdateCategory(id, version uint, req models.UpdateCategoryRequest) (models.Category, error) {
+	updates := make(map[string]interface{})
+
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Slug != nil {
+		updates["slug"] = *req.Slug
+	}
+	if req.Icon != nil {
+		updates["icon"] = *req.Icon
+	}
+	if req.SortOrder != nil {
+		updates["sort_order"] = *req.SortOrder
+	}
+
+	return saveCategory(id, version, updates)
+}
+
+// ReplaceCategory replaces the editable fields of a category, clearing the
+// optional fields missing from the request. Without a slug one is derived
+// from the name.
+func ReplaceCategory(id, version uint, req models.ReplaceCategoryRequest) (models.Category, error) {
+	return saveCategory(id, version, map[string]interface{}{
+		"name":       req.Name,
+		"slug":       req.Slug,
+		"icon":       req.Icon,
+		"sort_order": req.SortOrder,
+	})
+}
+
+// saveCategory checks and writes column updates to a category at the given
+// version. A requested slug is made unique, and an empty one is derived from
+// the name.
+func saveCategory(id, version uint, updates map[string]interface{}) (models.Category, error) {
 	var category models.Category
 
 	// Find the category
@@ -202,27 +486,35 @@ func UpdateCategory(id uint, req models.UpdateCategoryRequest) (models.Category,
 		}
 		return models.Category{}, err
 	}
-
-	// Check if name is being updated and if it already exists
-	if req.Name != "" && req.Name != category.Name {
-		var existingCategory models.Category
-		if err := configs.DB.Where("name = ? AND id != ?", req.Name, id).First(&existingCategory).Error; err == nil {
-			return models.Category{}, errors.New("category with this name already exists")
-		}
+	if err := checkVersion(category.Version, version); err != nil {
+		return models.Category{}, err
 	}
 
-	// Update fields
-	updates := make(map[string]interface{})
+	name := category.Name
+	if value, ok := updates["name"].(string); ok {
+		name = value
+	}
 
-	if req.Name != "" {
-		updates["name"] = req.Name
+	// Check if name is being updated and if a sibling already uses it
+	if name != category.Name && categoryNameTaken(name, category.ParentID, id) {
+		return models.Category{}, models.FieldErrors{"name": "category with this name already exists"}
 	}
-	if req.Icon != "" {
-		updates["icon"] = req.Icon
+
+	if requested, ok := updates["slug"].(string); ok {
+		if requested == category.Slug {
+			delete(updates, "slug")
+		} else {
+			slug, err := uniqueCategorySlug(requested, name, id)
+			if err != nil {
+				return models.Category{}, models.FieldErrors{"slug": err.Error()}
+			}
+			updates["slug"] = slug
+		}
 	}
 
 	// Update the category
-	if err := configs.DB.Model(&category).Updates(updates).Error; err != nil {
+	result := configs.DB.Model(&category).Scopes(atVersion(version)).Updates(updates)
+	if err := versionedResult(result, version); err != nil {
 		return models.Category{}, err
 	}
 
@@ -232,8 +524,12 @@ func UpdateCategory(id uint, req models.UpdateCategoryRequest) (models.Category,
 	return category, nil
 }
 
-// DeleteCategory deletes a category by ID
-func DeleteCategory(id uint) error {
+// DeleteCategory soft-deletes a category by ID. Categories with products or
+// subcategories are refused unless reassignToParent is set, in which case
+// both move to the category's parent first; subcategories whose name is used
+// there are refused, and the values of attributes defined on the category
+// are dropped. Version is the category version the deletion applies to.
+func DeleteCategory(id, version uint, reassignToParent bool) error {
 	var category models.Category
 
 	// Find the category
@@ -243,20 +539,78 @@ func DeleteCategory(id uint) error {
 		}
 		return err
 	}
+	if err := checkVersion(category.Version, version); err != nil {
+		return err
+	}
 
-	// Check if there are products using this category
-	var productCount int64
+	// Check if there are products or subcategories using this category
+	var productCount, childCount int64
 	configs.DB.Model(&models.Product{}).Where("category_id = ?", id).Count(&productCount)
-	if productCount > 0 {
-		return errors.New("cannot delete category that has products associated with it")
-	}
+	configs.DB.Model(&models.Category{}).Where("parent_id = ?", id).Count(&childCount)
 
-	// Delete the category
-	if err := configs.DB.Delete(&category).Error; err != nil {
-		return err
+	if !reassignToParent {
+		if productCount > 0 {
+			return errors.New("cannot delete category that has products associated with it")
+		}
+		if childCount > 0 {
+			return errors.New("cannot delete category that has subcategories")
+		}
+	}
+	if productCount > 0 && category.ParentID == nil {
+		return errors.New("cannot reassign products of a top-level category, move them to another category first")
 	}
 
-	return nil
+	return configs.DB.Transaction(func(tx *gorm.DB) error {
+		// Subcategories cannot take the name of a category already there
+		if childCount > 0 {
+			siblings := tx.Model(&models.Category{}).Select("name").Where("id <> ?", id)
+			if category.ParentID == nil {
+				siblings = siblings.Where("parent_id IS NULL")
+			} else {
+				siblings = siblings.Where("parent_id = ?", *category.ParentID)
+			}
+
+			var clashes []string
+			if err := tx.Model(&models.Category{}).
+				Where("parent_id = ? AND name IN (?)", id, siblings).
+				Order("name").
+				Pluck("name", &clashes).Error; err != nil {
+				return err
+			}
+			if len(clashes) > 0 {
+				return fmt.Errorf("cannot move subcategories up, a category named %s already exists there", strings.Join(clashes, ", "))
+			}
+		}
+
+		// Attributes of the category no longer apply to the products moving
+		// up, nor to those of its subcategories
+		if productCount > 0 || childCount > 0 {
+			if err := tx.Where("attribute_id IN (?)", tx.Model(&models.CategoryAttribute{}).Select("id").Where("category_id = ?", id)).
+				Delete(&models.ProductAttributeValue{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if productCount > 0 {
+			if err := tx.Model(&models.Product{}).
+				Where("category_id = ?", id).
+				Update("category_id", *category.ParentID).Error; err != nil {
+				return err
+			}
+		}
+
+		// Subcategories move up one level, to the top level for a root category
+		if childCount > 0 {
+			if err := tx.Model(&models.Category{}).
+				Where("parent_id = ?", id).
+				Update("parent_id", category.ParentID).Error; err != nil {
+				return err
+			}
+		}
+
+		// Delete the category
+		return versionedResult(tx.Scopes(atVersion(version)).Delete(&category), version)
+	})
 }
 
 // Helper functions
@@ -265,3 +619,14 @@ func categoryExists(id uint) bool {
 	_, exists := GetCategoryByID(id)
 	return exists
 }
+
+// productSKUTaken reports whether another product, deleted ones included,
+// already uses a SKU. Products without a SKU never conflict.
+func productSKUTaken(sku string, excludeID uint) bool {
+	if sku == "" {
+		return false
+	}
+	var count int64
+	configs.DB.Unscoped().Model(&models.Product{}).Where("sku = ? AND id <> ?", sku, excludeID).Count(&count)
+	return count > 0
+}
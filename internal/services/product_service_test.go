@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"literally-backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestDeleteCategoryReassignsToParent(t *testing.T) {
+	db := openTestDB(t)
+
+	unique := time.Now().UnixNano()
+	category := func(name string, parentID *uint) models.Category {
+		t.Helper()
+		created, err := CreateCategory(models.CreateCategoryRequest{Name: fmt.Sprintf("%s %d", name, unique), ParentID: parentID})
+		if err != nil {
+			t.Fatalf("create category %s: %v", name, err)
+		}
+		return created
+	}
+	attribute := func(categoryID uint, code string) models.CategoryAttribute {
+		t.Helper()
+		created, err := CreateCategoryAttribute(categoryID, models.CreateAttributeRequest{
+			Code: fmt.Sprintf("%s_%d", code, unique),
+			Name: code,
+			Type: models.AttributeTypeBool,
+		})
+		if err != nil {
+			t.Fatalf("create attribute %s: %v", code, err)
+		}
+		return created
+	}
+	product := func(categoryID uint, values map[string]interface{}) models.Product {
+		t.Helper()
+		created := models.Product{Name: fmt.Sprintf("Product %d", unique), Price: 100, CategoryID: categoryID, IsAvailable: true}
+		if err := db.Create(&created).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+		if _, err := SetProductAttributes(created.ID, values); err != nil {
+			t.Fatalf("set attributes: %v", err)
+		}
+		return created
+	}
+	codes := func(productID uint) string {
+		t.Helper()
+		var codes []string
+		if err := db.Model(&models.ProductAttributeValue{}).
+			Joins("JOIN category_attributes ON category_attributes.id = product_attribute_values.attribute_id").
+			Where("product_attribute_values.product_id = ?", productID).
+			Order("category_attributes.code").
+			Pluck("category_attributes.code", &codes).Error; err != nil {
+			t.Fatalf("list attribute values: %v", err)
+		}
+		return fmt.Sprint(codes)
+	}
+
+	phones := category("Phones", nil)
+	apple := category("Apple", &phones.ID)
+	cases := category("Cases", &apple.ID)
+	dualSim := attribute(phones.ID, "dual_sim")
+	faceID := attribute(apple.ID, "face_id")
+	magsafe := attribute(cases.ID, "magsafe")
+
+	phone := product(apple.ID, map[string]interface{}{dualSim.Code: true, faceID.Code: true})
+	phoneCase := product(cases.ID, map[string]interface{}{faceID.Code: false, magsafe.Code: true})
+
+	deleteApple := func() error {
+		t.Helper()
+		var current models.Category
+		db.First(&current, apple.ID)
+		return DeleteCategory(apple.ID, current.Version, true)
+	}
+
+	// A subcategory cannot move next to a category of the same name
+	clash := category("Cases", &phones.ID)
+	if err := deleteApple(); err == nil {
+		t.Fatal("deleting with a name clash succeeded, want an error")
+	}
+	want := fmt.Sprint([]string{dualSim.Code, faceID.Code})
+	if got := codes(phone.ID); got != want {
+		t.Errorf("after the refused delete the phone has %s, want %s", got, want)
+	}
+
+	db.First(&clash, clash.ID)
+	if err := DeleteCategory(clash.ID, clash.Version, false); err != nil {
+		t.Fatalf("delete clashing category: %v", err)
+	}
+	if err := deleteApple(); err != nil {
+		t.Fatalf("delete category: %v", err)
+	}
+
+	db.First(&phone, phone.ID)
+	db.First(&cases, cases.ID)
+	if phone.CategoryID != phones.ID || cases.ParentID == nil || *cases.ParentID != phones.ID {
+		t.Errorf("phone in category %d and cases under %v, want both under %d", phone.CategoryID, cases.ParentID, phones.ID)
+	}
+	if got, want := codes(phone.ID), fmt.Sprint([]string{dualSim.Code}); got != want {
+		t.Errorf("phone attributes %s, want %s", got, want)
+	}
+	if got, want := codes(phoneCase.ID), fmt.Sprint([]string{magsafe.Code}); got != want {
+		t.Errorf("case attributes %s, want %s", got, want)
+	}
+}
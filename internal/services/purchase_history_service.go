@@ -4,24 +4,24 @@ import (
 	"errors"
 	"literally-backend/configs"
 	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-// GetUserPurchaseHistory retrieves purchase history for a specific user
-func GetUserPurchaseHistory(userID uint, filter models.PurchaseHistoryFilter) ([]models.PurchaseHistoryResponse, int64, error) {
-	// Set default values for pagination
-	if filter.Page <= 0 {
-		filter.Page = 1
-	}
-	if filter.Limit <= 0 {
-		filter.Limit = 10
-	}
-
-	var purchases []models.PurchaseHistory
-	var total int64
+// PurchaseHistoryListSpec lists the sorts available on purchase history listings
+var PurchaseHistoryListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"purchase_date": "purchase_date",
+		"total_price":   "total_price",
+		"product_name":  "product_name",
+	},
+	DefaultSort: "-purchase_date",
+}
 
+// GetUserPurchaseHistory retrieves one page of purchase history for a specific user
+func GetUserPurchaseHistory(userID uint, filter models.PurchaseHistoryFilter, params pagination.Params) ([]models.PurchaseHistoryResponse, pagination.Page, error) {
 	// Build query
 	query := configs.DB.Where("user_id = ?", userID)
 
@@ -50,38 +50,21 @@ func GetUserPurchaseHistory(userID uint, filter models.PurchaseHistoryFilter) ([
 		query = query.Where("product_name ILIKE ?", "%"+filter.ProductName+"%")
 	}
 
-	// Count total records
-	if err := query.Model(&models.PurchaseHistory{}).Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// Set pagination defaults
-	if filter.Page <= 0 {
-		filter.Page = 1
-	}
-	if filter.Limit <= 0 {
-		filter.Limit = 10
-	}
-
-	offset := (filter.Page - 1) * filter.Limit
-
-	// Execute query with pagination
-	if err := query.
-		Preload("Product").
-		Order("purchase_date DESC").
-		Offset(offset).
-		Limit(filter.Limit).
-		Find(&purchases).Error; err != nil {
-		return nil, 0, err
+	var purchases []models.PurchaseHistory
+	page, err := pagination.Find(query, params, &purchases, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Product", includeDeleted)
+	})
+	if err != nil {
+		return nil, page, err
 	}
 
 	// Convert to response format
-	var responses []models.PurchaseHistoryResponse
+	responses := []models.PurchaseHistoryResponse{}
 	for _, purchase := range purchases {
 		responses = append(responses, purchase.ToResponse())
 	}
 
-	return responses, total, nil
+	return responses, page, nil
 }
 
 // GetPurchaseHistoryByID retrieves a specific purchase history record
@@ -89,7 +72,7 @@ func GetPurchaseHistoryByID(userID, purchaseID uint) (models.PurchaseHistoryResp
 	var purchase models.PurchaseHistory
 
 	if err := configs.DB.
-		Preload("Product").
+		Preload("Product", includeDeleted).
 		Where("id = ? AND user_id = ?", purchaseID, userID).
 		First(&purchase).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -161,7 +144,7 @@ func GetRecentPurchases(userID uint, limit int) ([]models.PurchaseHistoryRespons
 	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
 
 	if err := configs.DB.
-		Preload("Product").
+		Preload("Product", includeDeleted).
 		Where("user_id = ? AND purchase_date >= ?", userID, thirtyDaysAgo).
 		Order("purchase_date DESC").
 		Limit(limit).
@@ -185,7 +168,7 @@ func CreatePurchaseHistory(req models.PurchaseHistory) (models.PurchaseHistory,
 
 	// Load relationships
 	if err := configs.DB.
-		Preload("Product").
+		Preload("Product", includeDeleted).
 		First(&req, req.ID).Error; err != nil {
 		return models.PurchaseHistory{}, err
 	}
@@ -215,7 +198,7 @@ func GetPurchaseHistoryByOrder(orderID uint) ([]models.PurchaseHistory, error) {
 	var purchases []models.PurchaseHistory
 
 	if err := configs.DB.
-		Preload("Product").
+		Preload("Product", includeDeleted).
 		Where("order_id = ?", orderID).
 		Find(&purchases).Error; err != nil {
 		return nil, err
@@ -246,12 +229,8 @@ func GetPopularProducts(limit int) ([]models.Product, error) {
 	var products []models.Product
 
 	// Get products ordered by purchase frequency
-	if err := configs.DB.
-		Table("products").
-		Select("products.*, COUNT(purchase_history.product_id) as purchase_count").
-		Joins("LEFT JOIN purchase_history ON products.id = purchase_history.product_id").
+	if err := withPurchaseCounts(configs.DB.Table("products")).
 		Where("products.is_available = ?", true).
-		Group("products.id").
 		Order("purchase_count DESC").
 		Limit(limit).
 		Find(&products).Error; err != nil {
@@ -261,13 +240,22 @@ func GetPopularProducts(limit int) ([]models.Product, error) {
 	return products, nil
 }
 
+// withPurchaseCounts adds the number of purchases of each product to a
+// products query as purchase_count
+func withPurchaseCounts(db *gorm.DB) *gorm.DB {
+	return db.
+		Select("products.*, COUNT(purchase_histories.product_id) AS purchase_count").
+		Joins("LEFT JOIN purchase_histories ON products.id = purchase_histories.product_id").
+		Group("products.id")
+}
+
 // GetPurchaseHistoryByDateRange retrieves purchase history within a date range
 func GetPurchaseHistoryByDateRange(userID uint, startDate, endDate time.Time) ([]models.PurchaseHistoryResponse, float64, error) {
 	var purchases []models.PurchaseHistory
 	var totalAmount float64
 
 	if err := configs.DB.
-		Preload("Product").
+		Preload("Product", includeDeleted).
 		Where("user_id = ? AND purchase_date BETWEEN ? AND ?", userID, startDate, endDate).
 		Order("purchase_date DESC").
 		Find(&purchases).Error; err != nil {
@@ -292,7 +280,7 @@ func SearchUserPurchaseHistory(userID uint, searchTerm string, limit int) ([]mod
 	}
 
 	if err := configs.DB.
-		Preload("Product").
+		Preload("Product", includeDeleted).
 		Where("user_id = ? AND (product_name ILIKE ? OR tracking_number ILIKE ?)",
 			userID, "%"+searchTerm+"%", "%"+searchTerm+"%").
 		Order("purchase_date DESC").
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"html"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"log"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Search paging defaults
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchEngine runs product searches. The default engine uses PostgreSQL
+// full-text search; another engine can be installed with SetSearchEngine.
+type SearchEngine interface {
+	Search(query models.SearchQuery) (models.SearchResult, error)
+
+	// Suggest returns autocomplete suggestions for a normalized prefix
+	Suggest(prefix string, limit int) (models.Suggestions, error)
+
+	// IndexProduct and RemoveProduct keep an external index in sync with the
+	// catalog. Engines that read the products table directly ignore them.
+	IndexProduct(product models.Product) error
+	RemoveProduct(productID uint) error
+}
+
+// searchEngine starts as the LIKE based engine until InitSearchEngine runs
+var searchEngine SearchEngine = basicSearchEngine{}
+
+// SetSearchEngine replaces the search engine used by SearchProducts
+func SetSearchEngine(engine SearchEngine) {
+	searchEngine = engine
+}
+
+// InitSearchEngine sets up PostgreSQL full-text search. If the database
+// lacks the unaccent or pg_trgm extensions, substring search stays in use.
+func InitSearchEngine() {
+	engine, err := NewPostgresSearchEngine(configs.DB)
+	if err != nil {
+		log.Printf("Full-text search unavailable, using basic search: %v", err)
+		return
+	}
+	SetSearchEngine(engine)
+	log.Println("Full-text search initialized")
+}
+
+// SearchProducts searches available products, best match first
+func SearchProducts(query models.SearchQuery) (models.SearchResult, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultSearchLimit
+	}
+	if query.Limit > maxSearchLimit {
+		query.Limit = maxSearchLimit
+	}
+	query.Query = strings.TrimSpace(query.Query)
+
+	result, err := searchEngine.Search(query)
+	if err != nil {
+		return result, err
+	}
+
+	// Only first pages are logged so paging through results counts once
+	if query.Page == 1 {
+		recordSearch(query, result.Total)
+	}
+	return result, nil
+}
+
+// indexProduct pushes a product to the search engine. Errors are logged only;
+// the catalog stays the source of truth.
+func indexProduct(productID uint) {
+	product, found := GetProductByID(productID)
+	if !found {
+		return
+	}
+	if err := searchEngine.IndexProduct(product); err != nil {
+		log.Printf("Failed to index product %d: %v", productID, err)
+	}
+}
+
+// unindexProduct removes a product from the search engine
+func unindexProduct(productID uint) {
+	if err := searchEngine.RemoveProduct(productID); err != nil {
+		log.Printf("Failed to remove product %d from search index: %v", productID, err)
+	}
+}
+
+// PostgresSearchEngine searches a weighted tsvector column on products
+// (name > brand > description) and falls back to trigram similarity on the
+// name for typos. Accents are ignored on both sides through unaccent.
+type PostgresSearchEngine struct {
+	db *gorm.DB
+}
+
+// fuzzyThreshold is the minimum word similarity for a typo match
+const fuzzyThreshold = 0.3
+
+// postgresSearchSetup creates the extensions, the accent-insensitive text
+// search configuration, the generated search column and its indexes. Every
+// statement is idempotent.
+var postgresSearchSetup = []string{
+	`CREATE EXTENSION IF NOT EXISTS unaccent`,
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'simple_unaccent') THEN
+			CREATE TEXT SEARCH CONFIGURATION simple_unaccent (COPY = simple);
+			ALTER TEXT SEARCH CONFIGURATION simple_unaccent
+				ALTER MAPPING FOR hword, hword_part, word WITH unaccent, simple;
+		END IF;
+	END $$`,
+	`CREATE OR REPLACE FUNCTION immutable_unaccent(text) RETURNS text AS
+		$$ SELECT public.unaccent('public.unaccent', $1) $$
+		LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT`,
+	`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple_unaccent', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('simple_unaccent', coalesce(brand, '')), 'B') ||
+		setweight(to_tsvector('simple_unaccent', coalesce(description, '')), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products
+		USING GIN (immutable_unaccent(lower(name)) gin_trgm_ops)`,
+}
+
+// NewPostgresSearchEngine prepares the database for full-text search
+func NewPostgresSearchEngine(db *gorm.DB) (*PostgresSearchEngine, error) {
+	for _, statement := range postgresSearchSetup {
+		if err := db.Exec(statement).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &PostgresSearchEngine{db: db}, nil
+}
+
+// Search implements SearchEngine
+func (e *PostgresSearchEngine) Search(query models.SearchQuery) (models.SearchResult, error) {
+	result := models.SearchResult{Hits: []models.SearchHit{}, Page: query.Page, Limit: query.Limit}
+
+	tokens := searchTokens(query.Query)
+	if len(tokens) == 0 {
+		return result, nil
+	}
+
+	// Every word must match, the last one may be a prefix of a longer word
+	prefixed := make([]string, len(tokens))
+	for i, token := range tokens {
+		prefixed[i] = token + ":*"
+	}
+	args := map[string]interface{}{
+		"tsquery": strings.Join(prefixed, " & "),
+		"text":    strings.Join(tokens, " "),
+		"fuzzy":   fuzzyThreshold,
+	}
+
+	base := e.db.Table("products").
+		Where("products.deleted_at IS NULL AND products.is_available = ?", true).
+		Where(`(products.search_vector @@ to_tsquery('simple_unaccent', @tsquery)
+			OR word_similarity(immutable_unaccent(@text), immutable_unaccent(lower(products.name))) >= @fuzzy)`, args)
+
+	if err := base.Session(&gorm.Session{}).Count(&result.Total).Error; err != nil {
+		return result, err
+	}
+
+	type row struct {
+		models.Product `gorm:"embedded"`
+		Score          float64
+		Exact          bool
+		NameHighlight  string
+		DescHighlight  string
+	}
+	var rows []row
+	if result.Total > 0 {
+		if err := base.Session(&gorm.Session{}).
+			Select(`products.*,
+				ts_rank('{0.1, 0.2, 0.4, 1.0}', products.search_vector, to_tsquery('simple_unaccent', @tsquery), 32)
+					+ 0.5 * word_similarity(immutable_unaccent(@text), immutable_unaccent(lower(products.name))) AS score,
+				products.search_vector @@ to_tsquery('simple_unaccent', @tsquery) AS exact,
+				ts_headline('simple_unaccent', products.name, to_tsquery('simple_unaccent', @tsquery),
+					'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS name_highlight,
+				ts_headline('simple_unaccent', coalesce(products.description, ''), to_tsquery('simple_unaccent', @tsquery),
+					'StartSel=<mark>, StopSel=</mark>, MaxWords=25, MinWords=8, MaxFragments=2, FragmentDelimiter=" … "') AS desc_highlight`, args).
+			Order("score DESC, products.rating DESC, products.id").
+			Offset((query.Page - 1) * query.Limit).
+			Limit(query.Limit).
+			Scan(&rows).Error; err != nil {
+			return result, err
+		}
+	}
+
+	products := make([]models.Product, len(rows))
+	exactMatch := false
+	for i, r := range rows {
+		products[i] = r.Product
+		exactMatch = exactMatch || r.Exact
+	}
+	attachPrices(products)
+
+	for i, r := range rows {
+		hit := models.SearchHit{Product: products[i], Score: r.Score}
+		if strings.Contains(r.NameHighlight, "<mark>") {
+			hit.Highlights.Name = safeHighlight(r.NameHighlight)
+		}
+		if strings.Contains(r.DescHighlight, "<mark>") {
+			hit.Highlights.Description = safeHighlight(r.DescHighlight)
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+
+	// Offer a correction when nothing matched the words as typed
+	if !exactMatch && query.Page == 1 {
+		suggestion, err := e.didYouMean(tokens)
+		if err != nil {
+			return result, err
+		}
+		result.DidYouMean = suggestion
+	}
+
+	return result, nil
+}
+
+// didYouMean replaces each unknown word with the most similar word indexed
+// for the catalog; it returns "" when no word could be improved. Indexed
+// words are unaccented and normalized by the text search configuration, so
+// a word typed correctly is compared in both forms and kept.
+func (e *PostgresSearchEngine) didYouMean(tokens []string) (string, error) {
+	corrected := make([]string, len(tokens))
+	changed := false
+
+	for i, token := range tokens {
+		var match struct {
+			Word       string
+			Folded     string
+			Normalized string
+		}
+		err := e.db.Raw(`
+			SELECT
+				COALESCE((
+					SELECT word FROM ts_stat('SELECT search_vector FROM products WHERE deleted_at IS NULL AND is_available')
+					WHERE similarity(word, t.folded) >= ?
+					ORDER BY similarity(word, t.folded) DESC, nentry DESC
+					LIMIT 1
+				), '') AS word,
+				t.folded,
+				COALESCE((SELECT lexeme FROM unnest(to_tsvector('simple_unaccent', t.token)) LIMIT 1), t.folded) AS normalized
+			FROM (SELECT CAST(? AS text) AS token, immutable_unaccent(lower(?)) AS folded) AS t`,
+			fuzzyThreshold, token, token).Scan(&match).Error
+		if err != nil {
+			return "", err
+		}
+
+		corrected[i] = token
+		if match.Word != "" && match.Word != match.Folded && match.Word != match.Normalized {
+			corrected[i] = match.Word
+			changed = true
+		}
+	}
+
+	if !changed {
+		return "", nil
+	}
+	return strings.Join(corrected, " "), nil
+}
+
+// Suggest implements SearchEngine
+func (e *PostgresSearchEngine) Suggest(prefix string, limit int) (models.Suggestions, error) {
+	return suggest(e.db, prefix, limit, func(expr string) string {
+		return "immutable_unaccent(lower(" + expr + "))"
+	})
+}
+
+// IndexProduct implements SearchEngine; the generated column keeps itself current
+func (e *PostgresSearchEngine) IndexProduct(product models.Product) error {
+	return nil
+}
+
+// RemoveProduct implements SearchEngine; deleted products are filtered by the query
+func (e *PostgresSearchEngine) RemoveProduct(productID uint) error {
+	return nil
+}
+
+// basicSearchEngine is a substring search used when full-text search is not
+// available in the database
+type basicSearchEngine struct{}
+
+// Search implements SearchEngine
+func (basicSearchEngine) Search(query models.SearchQuery) (models.SearchResult, error) {
+	result := models.SearchResult{Hits: []models.SearchHit{}, Page: query.Page, Limit: query.Limit}
+	if query.Query == "" {
+		return result, nil
+	}
+
+	searchTerm := "%" + strings.ToLower(query.Query) + "%"
+	base := configs.DB.Model(&models.Product{}).Where(
+		"(LOWER(name) LIKE ? OR LOWER(description) LIKE ? OR LOWER(brand) LIKE ?) AND is_available = ?",
+		searchTerm, searchTerm, searchTerm, true,
+	)
+
+	if err := base.Session(&gorm.Session{}).Count(&result.Total).Error; err != nil {
+		return result, err
+	}
+
+	// Name matches first
+	var products []models.Product
+	if err := base.Session(&gorm.Session{}).
+		Order(gorm.Expr("CASE WHEN LOWER(name) LIKE ? THEN 0 ELSE 1 END, rating DESC, id", searchTerm)).
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&products).Error; err != nil {
+		return result, err
+	}
+	attachPrices(products)
+
+	for _, product := range products {
+		result.Hits = append(result.Hits, models.SearchHit{Product: product})
+	}
+	return result, nil
+}
+
+// Suggest implements SearchEngine
+func (basicSearchEngine) Suggest(prefix string, limit int) (models.Suggestions, error) {
+	return suggest(configs.DB, prefix, limit, func(expr string) string {
+		return "lower(" + expr + ")"
+	})
+}
+
+// IndexProduct implements SearchEngine
+func (basicSearchEngine) IndexProduct(product models.Product) error {
+	return nil
+}
+
+// RemoveProduct implements SearchEngine
+func (basicSearchEngine) RemoveProduct(productID uint) error {
+	return nil
+}
+
+// searchTokens splits a query into lowercase words, dropping characters that
+// have a meaning in tsquery syntax
+func searchTokens(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// safeHighlight escapes product text but keeps the <mark> tags added by ts_headline
+func safeHighlight(fragment string) string {
+	return strings.NewReplacer("&lt;mark&gt;", "<mark>", "&lt;/mark&gt;", "</mark>").
+		Replace(html.EscapeString(fragment))
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/utils"
+	"strings"
+	"time"
+)
+
+// sessionTTL matches the lifetime of user JWT tokens
+const sessionTTL = 24 * time.Hour
+
+// CreateSession records a new login session and returns it with a fresh token ID
+func CreateSession(userID uint, client models.ClientInfo) (models.UserSession, error) {
+	now := time.Now()
+	session := models.UserSession{
+		UserID:     userID,
+		TokenID:    utils.GenerateRandomString(32),
+		Device:     describeDevice(client.UserAgent),
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(sessionTTL),
+	}
+
+	if err := configs.DB.Create(&session).Error; err != nil {
+		return models.UserSession{}, err
+	}
+
+	return session, nil
+}
+
+// GetUserSessions returns the active sessions of a user, most recently used first
+func GetUserSessions(userID uint) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	if err := configs.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes one session belonging to a user
+func RevokeSession(userID, sessionID uint) error {
+	result := configs.DB.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("session not found")
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions revokes all sessions of a user except the one with the given token ID
+func RevokeOtherSessions(userID uint, currentTokenID string) (int64, error) {
+	result := configs.DB.Model(&models.UserSession{}).
+		Where("user_id = ? AND token_id <> ? AND revoked_at IS NULL", userID, currentTokenID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// RevokeAllSessions revokes every session of a user
+func RevokeAllSessions(userID uint) (int64, error) {
+	result := configs.DB.Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// describeDevice builds a short human-readable device label from a user agent
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/"), strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "okhttp"), strings.Contains(ua, "dart"), strings.Contains(ua, "cfnetwork"):
+		browser = "Mobile app"
+	case strings.Contains(ua, "postman"), strings.Contains(ua, "curl"):
+		browser = "API client"
+	}
+
+	platform := "Unknown OS"
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ios"):
+		platform = "iOS"
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os"), strings.Contains(ua, "macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	return browser + " on " + platform
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// includeDeleted is a preload scope that keeps soft-deleted records, so order
+// and purchase history still show products that were removed from the catalog
+func includeDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// scopeDeleted applies an admin "show deleted" filter to a query
+func scopeDeleted(db *gorm.DB, filter string) (*gorm.DB, error) {
+	switch filter {
+	case models.DeletedFilterExclude:
+		return db, nil
+	case models.DeletedFilterInclude:
+		return db.Unscoped(), nil
+	case models.DeletedFilterOnly:
+		return db.Unscoped().Where("deleted_at IS NOT NULL"), nil
+	default:
+		return nil, errors.New("invalid deleted filter, expected include or only")
+	}
+}
+
+// restoreRecord clears deleted_at on a soft-deleted record
+func restoreRecord(model interface{}, id uint, notFound string) error {
+	result := configs.DB.Unscoped().Model(model).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New(notFound)
+	}
+
+	return nil
+}
+
+// RestoreUser restores a soft-deleted user
+func RestoreUser(id uint) error {
+	return restoreRecord(&models.User{}, id, "deleted user not found")
+}
+
+// RestoreProduct restores a soft-deleted product
+func RestoreProduct(id uint) error {
+	var product models.Product
+	if err := configs.DB.Unscoped().First(&product, id).Error; err == nil && !categoryExists(product.CategoryID) {
+		return errors.New("product category is deleted, restore the category first")
+	}
+	if err := restoreRecord(&models.Product{}, id, "deleted product not found"); err != nil {
+		return err
+	}
+
+	indexProduct(id)
+	return nil
+}
+
+// RestoreCategory restores a soft-deleted category
+func RestoreCategory(id uint) error {
+	var category models.Category
+	if err := configs.DB.Unscoped().First(&category, id).Error; err == nil &&
+		category.ParentID != nil && !categoryExists(*category.ParentID) {
+		return errors.New("parent category is deleted, restore the parent first")
+	}
+	return restoreRecord(&models.Category{}, id, "deleted category not found")
+}
+
+// softDeleteRetention is how long soft-deleted records are kept before purging
+func softDeleteRetention() time.Duration {
+	return time.Duration(envInt("SOFT_DELETE_RETENTION_DAYS", 30)) * 24 * time.Hour
+}
+
+// PurgeSoftDeleted hard-deletes records that were soft-deleted longer ago than
+// the retention period. Records still referenced by orders are kept: users are
+// anonymized instead and products stay so order history can show them, as do
+// products that bundles are made of. Purged products take their variants and
+// other dependent rows with them.
+func PurgeSoftDeleted() error {
+	cutoff := time.Now().Add(-softDeleteRetention())
+	db := configs.DB.Unscoped()
+
+	// Products not referenced by any order, purchase or bundle, with their
+	// gallery, variants, attribute values and flash sales
+	var productIDs []uint
+	if err := db.Model(&models.Product{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM order_items WHERE order_items.product_id = products.id)").
+		Where("NOT EXISTS (SELECT 1 FROM purchase_histories WHERE purchase_histories.product_id = products.id)").
+		Where("NOT EXISTS (SELECT 1 FROM bundle_items WHERE bundle_items.product_id = products.id)").
+		Pluck("id", &productIDs).Error; err != nil {
+		return fmt.Errorf("find products to purge: %w", err)
+	}
+
+	var imageKeys []string
+	if len(productIDs) > 0 {
+		if err := db.Model(&models.ProductImage{}).
+			Where("product_id IN ?", productIDs).
+			Pluck("storage_key", &imageKeys).Error; err != nil {
+			return fmt.Errorf("find product images to purge: %w", err)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			dependents := []interface{}{
+				&models.ProductImage{},
+				&models.ProductAttributeValue{},
+				&models.FlashSale{},
+				&models.ProductVariant{},
+			}
+			for _, dependent := range dependents {
+				if err := tx.Where("product_id IN ?", productIDs).Delete(dependent).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Where("id IN ?", productIDs).Delete(&models.Product{}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("purge products: %w", err)
+		}
+	}
+
+	for _, key := range imageKeys {
+		deleteImageBlobs(key)
+	}
+
+	// Categories no longer used by any product or subcategory, deleted or not
+	categories := db.Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM products WHERE products.category_id = categories.id)").
+		Where("NOT EXISTS (SELECT 1 FROM categories AS children WHERE children.parent_id = categories.id)").
+		Delete(&models.Category{})
+	if categories.Error != nil {
+		return fmt.Errorf("purge categories: %w", categories.Error)
+	}
+
+	// Expired users lose their personal data first; those with orders or
+	// purchases keep the anonymized row for financial records
+	var userIDs []uint
+	if err := db.Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND anonymized_at IS NULL", cutoff).
+		Pluck("id", &userIDs).Error; err != nil {
+		return fmt.Errorf("find users to anonymize: %w", err)
+	}
+	for _, userID := range userIDs {
+		if err := AnonymizeUser(userID); err != nil {
+			return fmt.Errorf("anonymize user %d: %w", userID, err)
+		}
+	}
+
+	users := db.Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM orders WHERE orders.user_id = users.id)").
+		Where("NOT EXISTS (SELECT 1 FROM purchase_histories WHERE purchase_histories.user_id = users.id)").
+		Delete(&models.User{})
+	if users.Error != nil {
+		return fmt.Errorf("purge users: %w", users.Error)
+	}
+
+	if int64(len(productIDs))+categories.RowsAffected+users.RowsAffected > 0 || len(userIDs) > 0 {
+		log.Printf("Purged %d products, %d categories, %d users; anonymized %d users",
+			len(productIDs), categories.RowsAffected, users.RowsAffected, len(userIDs))
+	}
+
+	return nil
+}
+
+// StartSoftDeletePurgeJob periodically purges expired soft-deleted records
+func StartSoftDeletePurgeJob() {
+	runPeriodically("soft-delete-purge", envDuration("SOFT_DELETE_PURGE_INTERVAL", 24*time.Hour), PurgeSoftDeleted)
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AdminNotificationListSpec lists the sorts and filters available on admin
+// notifications
+var AdminNotificationListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"created_at": "created_at",
+	},
+	Filters: map[string]string{
+		"type":    "type",
+		"is_read": "is_read",
+	},
+	DefaultSort: "-created_at",
+}
+
+// webhookClient sends low-stock alerts to LOW_STOCK_WEBHOOK_URL
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// lowStockThreshold is the reorder threshold of products that set none
+func lowStockThreshold() int {
+	return envInt("LOW_STOCK_THRESHOLD", 5)
+}
+
+// ReorderWindowDays is the default number of days of sales reorder
+// suggestions are based on
+func ReorderWindowDays() int {
+	if days := envInt("REORDER_WINDOW_DAYS", 30); days > 0 {
+		return days
+	}
+	return 30
+}
+
+// GetAdminNotifications returns one page of admin notifications
+func GetAdminNotifications(params pagination.Params) ([]models.AdminNotification, pagination.Page, error) {
+	notifications := []models.AdminNotification{}
+	page, err := pagination.Find(configs.DB, params, &notifications)
+	return notifications, page, err
+}
+
+// MarkAdminNotificationRead marks an admin notification as read
+func MarkAdminNotificationRead(id uint) (models.AdminNotification, error) {
+	var notification models.AdminNotification
+	if err := configs.DB.First(&notification, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.AdminNotification{}, errors.New("notification not found")
+		}
+		return models.AdminNotification{}, err
+	}
+
+	if err := configs.DB.Model(&notification).Update("is_read", true).Error; err != nil {
+		return models.AdminNotification{}, err
+	}
+	return notification, nil
+}
+
+// CheckLowStock alerts admins once about every product whose stock fell to
+// its reorder threshold or below, and closes the alerts of products
+// restocked above it so that they are alerted again next time
+func CheckLowStock() error {
+	threshold := lowStockThreshold()
+
+	if err := configs.DB.Model(&models.Product{}).Scopes(configs.KeepVersion).
+		Where("low_stock_alerted_at IS NOT NULL AND stock > COALESCE(reorder_threshold, ?)", threshold).
+		Update("low_stock_alerted_at", nil).Error; err != nil {
+		return err
+	}
+
+	var products []models.Product
+	if err := configs.DB.
+		Where("low_stock_alerted_at IS NULL AND stock <= COALESCE(reorder_threshold, ?)", threshold).
+		Order("id").
+		Find(&products).Error; err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		if err := alertLowStock(product, threshold); err != nil {
+			return fmt.Errorf("alert low stock of product %d: %w", product.ID, err)
+		}
+	}
+	return nil
+}
+
+// StartLowStockJob periodically checks for products running low on stock
+func StartLowStockJob() {
+	runPeriodically("low-stock", envDuration("LOW_STOCK_CHECK_INTERVAL", 15*time.Minute), CheckLowStock)
+}
+
+// alertLowStock opens the low-stock alert of a product, notifies admins and
+// calls the webhook. The alert is only opened once, even when several
+// servers check at the same time.
+func alertLowStock(product models.Product, threshold int) error {
+	if product.ReorderThreshold != nil {
+		threshold = *product.ReorderThreshold
+	}
+
+	message := fmt.Sprintf("%s has %d units left (reorder threshold %d)", product.Name, product.Stock, threshold)
+	if product.Stock == 0 {
+		message = fmt.Sprintf("%s is out of stock (reorder threshold %d)", product.Name, threshold)
+	}
+
+	opened := false
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Product{}).Scopes(configs.KeepVersion).
+			Where("id = ? AND low_stock_alerted_at IS NULL", product.ID).
+			Update("low_stock_alerted_at", time.Now())
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		opened = true
+
+		return tx.Create(&models.AdminNotification{
+			Type:      models.AdminNotificationLowStock,
+			Title:     "Low stock",
+			Message:   message,
+			ProductID: &product.ID,
+		}).Error
+	})
+	if err != nil || !opened {
+		return err
+	}
+
+	sendLowStockWebhook(product, threshold)
+	return nil
+}
+
+// sendLowStockWebhook posts a low-stock alert to LOW_STOCK_WEBHOOK_URL when
+// it is set. Failures are logged; the notification is already saved.
+func sendLowStockWebhook(product models.Product, threshold int) {
+	url := os.Getenv("LOW_STOCK_WEBHOOK_URL")
+	if url == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"event":             "product.low_stock",
+		"product_id":        product.ID,
+		"sku":               product.SKU,
+		"name":              product.Name,
+		"stock":             product.Stock,
+		"reorder_threshold": threshold,
+		"occurred_at":       time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to encode low-stock webhook: %v", err)
+		return
+	}
+
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Low-stock webhook for product %d failed: %v", product.ID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("Low-stock webhook for product %d answered %s", product.ID, resp.Status)
+	}
+}
+
+// GetReorderSuggestions suggests reorder quantities from the units sold in
+// the last windowDays days, skipping cancelled and returned orders. A
+// product needs reordering when its stock does not cover its reorder
+// threshold plus REORDER_COVER_DAYS days of sales.
+func GetReorderSuggestions(windowDays int) (models.ReorderReport, error) {
+	threshold := lowStockThreshold()
+	coverDays := envInt("REORDER_COVER_DAYS", 30)
+	since := time.Now().AddDate(0, 0, -windowDays)
+
+	var rows []models.ReorderSuggestion
+	if err := configs.DB.Model(&models.Product{}).
+		Select("products.id AS product_id, products.sku, products.name, products.stock, COALESCE(products.reorder_threshold, ?) AS reorder_threshold, COALESCE(SUM(sold.quantity), 0) AS units_sold", threshold).
+		Joins("LEFT JOIN (SELECT order_items.product_id, order_items.quantity FROM order_items JOIN orders ON orders.id = order_items.order_id "+
+			"WHERE orders.created_at >= ? AND UPPER(orders.status) NOT IN ?) AS sold ON sold.product_id = products.id",
+			since, []string{"CANCELLED", "RETURNED"}).
+		Group("products.id").
+		Order("products.id").
+		Scan(&rows).Error; err != nil {
+		return models.ReorderReport{}, err
+	}
+
+	report := models.ReorderReport{WindowDays: windowDays, CoverDays: coverDays, Suggestions: []models.ReorderSuggestion{}}
+	for _, row := range rows {
+		row.DailyVelocity = float64(row.UnitsSold) / float64(windowDays)
+		if row.DailyVelocity > 0 {
+			days := float64(row.Stock) / row.DailyVelocity
+			row.DaysOfStock = &days
+		}
+
+		target := int(math.Ceil(row.DailyVelocity*float64(coverDays))) + row.ReorderThreshold
+		row.SuggestedQuantity = target - row.Stock
+		if row.SuggestedQuantity > 0 {
+			report.Suggestions = append(report.Suggestions, row)
+		}
+	}
+
+	// Soonest to run out first; products that do not sell come last
+	sort.SliceStable(report.Suggestions, func(i, j int) bool {
+		a, b := report.Suggestions[i].DaysOfStock, report.Suggestions[j].DaysOfStock
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a < *b
+	})
+	return report, nil
+}
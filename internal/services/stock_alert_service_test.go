@@ -0,0 +1,71 @@
+package services
+
+import (
+	"encoding/json"
+	"literally-backend/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestLowStockAlertOpensOnceAndClearsOnRestock(t *testing.T) {
+	db := openTestDB(t)
+
+	product := createStockedProduct(t, db, 5)
+	if err := db.Model(&product).Update("reorder_threshold", 5).Error; err != nil {
+		t.Fatalf("set threshold: %v", err)
+	}
+
+	var mu sync.Mutex
+	webhooks := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert struct {
+			ProductID uint `json:"product_id"`
+		}
+		if json.NewDecoder(r.Body).Decode(&alert) == nil && alert.ProductID == product.ID {
+			mu.Lock()
+			webhooks++
+			mu.Unlock()
+		}
+	}))
+	defer server.Close()
+	t.Setenv("LOW_STOCK_WEBHOOK_URL", server.URL)
+
+	check := func(step string, wantOpen bool, wantAlerts int) {
+		t.Helper()
+
+		if err := CheckLowStock(); err != nil {
+			t.Fatalf("%s: check: %v", step, err)
+		}
+
+		var after models.Product
+		db.First(&after, product.ID)
+		if open := after.LowStockAlertedAt != nil; open != wantOpen {
+			t.Errorf("%s: alert open %v, want %v", step, open, wantOpen)
+		}
+
+		var alerts int64
+		db.Model(&models.AdminNotification{}).
+			Where("product_id = ? AND type = ?", product.ID, models.AdminNotificationLowStock).
+			Count(&alerts)
+		mu.Lock()
+		defer mu.Unlock()
+		if int(alerts) != wantAlerts || webhooks != wantAlerts {
+			t.Errorf("%s: %d notifications and %d webhooks, want %d", step, alerts, webhooks, wantAlerts)
+		}
+	}
+
+	check("at the threshold", true, 1)
+	check("checked again", true, 1)
+
+	if _, err := AdjustStock(0, product.ID, models.AdjustStockRequest{Quantity: 10, Reason: models.MovementReceiving, Note: "Restock"}); err != nil {
+		t.Fatalf("restock: %v", err)
+	}
+	check("restocked", false, 1)
+
+	if _, err := AdjustStock(0, product.ID, models.AdjustStockRequest{Quantity: -12, Note: "Damaged"}); err != nil {
+		t.Fatalf("take stock: %v", err)
+	}
+	check("low again", true, 2)
+}
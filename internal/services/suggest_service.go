@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Autocomplete limits
+const (
+	minSuggestLength   = 2
+	defaultSuggestSize = 8
+	maxSuggestSize     = 20
+	sideSuggestSize    = 5
+)
+
+// SuggestProducts returns autocomplete suggestions for a typed prefix. Each
+// lookup shares a deadline (SEARCH_SUGGEST_TIMEOUT, default 300ms) and
+// suggestions that miss it are left out rather than delaying the response.
+func SuggestProducts(prefix string, limit int) (models.Suggestions, error) {
+	prefix = normalizeSearchQuery(prefix)
+	if limit <= 0 {
+		limit = defaultSuggestSize
+	}
+	if limit > maxSuggestSize {
+		limit = maxSuggestSize
+	}
+	if len([]rune(prefix)) < minSuggestLength {
+		return emptySuggestions(), nil
+	}
+
+	return searchEngine.Suggest(prefix, limit)
+}
+
+// LogSearchQuery records a search and its number of results
+func LogSearchQuery(query string, resultCount int64, userID *uint) error {
+	query = normalizeSearchQuery(query)
+	if query == "" {
+		return nil
+	}
+
+	return configs.DB.Create(&models.SearchQueryLog{
+		Query:       query,
+		ResultCount: resultCount,
+		UserID:      userID,
+	}).Error
+}
+
+// GetSearchQueryStats returns the most frequent searches of the last days.
+// With zeroResultsOnly only searches that found nothing are counted.
+func GetSearchQueryStats(days, limit int, zeroResultsOnly bool) ([]models.SearchQueryStat, error) {
+	if days <= 0 {
+		days = 30
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := configs.DB.Model(&models.SearchQueryLog{}).
+		Select("query, COUNT(*) AS searches, AVG(result_count) AS avg_results, MAX(created_at) AS last_searched_at").
+		Where("created_at >= ?", time.Now().AddDate(0, 0, -days))
+	if zeroResultsOnly {
+		query = query.Where("result_count = 0")
+	}
+
+	stats := []models.SearchQueryStat{}
+	if err := query.Group("query").
+		Order("searches DESC, last_searched_at DESC").
+		Limit(limit).
+		Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// PurgeSearchLogs deletes logged searches older than SEARCH_LOG_RETENTION_DAYS (default 90)
+func PurgeSearchLogs() error {
+	cutoff := time.Now().AddDate(0, 0, -envInt("SEARCH_LOG_RETENTION_DAYS", 90))
+	return configs.DB.Where("created_at < ?", cutoff).Delete(&models.SearchQueryLog{}).Error
+}
+
+// StartSearchLogCleanupJob periodically removes old search logs
+func StartSearchLogCleanupJob() {
+	runPeriodically("search-log-cleanup", envDuration("SEARCH_LOG_CLEANUP_INTERVAL", 24*time.Hour), PurgeSearchLogs)
+}
+
+// recordSearch logs a search in the background so it never slows down results
+func recordSearch(query models.SearchQuery, total int64) {
+	go func() {
+		if err := LogSearchQuery(query.Query, total, query.UserID); err != nil {
+			log.Printf("Failed to log search query: %v", err)
+		}
+	}()
+}
+
+// suggest runs the autocomplete lookups concurrently. normalize wraps a
+// column or placeholder so matching ignores case, and accents when the
+// database supports it.
+func suggest(db *gorm.DB, prefix string, limit int, normalize func(string) string) (models.Suggestions, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), envDuration("SEARCH_SUGGEST_TIMEOUT", 300*time.Millisecond))
+	defer cancel()
+	db = db.WithContext(ctx)
+
+	pattern := escapeLike(prefix) + "%"
+	wordPattern := "% " + pattern
+	matches := func(column string) string {
+		return fmt.Sprintf("(%s LIKE %s OR %s LIKE %s)", normalize(column), normalize("?"), normalize(column), normalize("?"))
+	}
+
+	result := emptySuggestions()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	run := func(name string, lookup func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := lookup(); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s suggestions: %w", name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Product names starting with the prefix or containing a word that does
+	run("product", func() error {
+		return withPurchaseCounts(db.Table("products")).
+			Where("products.deleted_at IS NULL AND products.is_available = ?", true).
+			Where(matches("products.name"), pattern, wordPattern).
+			Order("purchase_count DESC, products.rating DESC, products.name").
+			Limit(limit).
+			Scan(&result.Products).Error
+	})
+
+	run("brand", func() error {
+		return db.Table("products").
+			Select("products.brand AS name, COUNT(DISTINCT products.id) AS products").
+			Joins("LEFT JOIN purchase_histories ON products.id = purchase_histories.product_id").
+			Where("products.deleted_at IS NULL AND products.is_available = ? AND products.brand <> ''", true).
+			Where(matches("products.brand"), pattern, wordPattern).
+			Group("products.brand").
+			Order("COUNT(purchase_histories.id) DESC, COUNT(DISTINCT products.id) DESC").
+			Limit(sideSuggestSize).
+			Scan(&result.Brands).Error
+	})
+
+	run("category", func() error {
+		return db.Table("categories").
+			Select("categories.id, categories.name, categories.slug").
+			Joins("LEFT JOIN products ON products.category_id = categories.id AND products.deleted_at IS NULL").
+			Joins("LEFT JOIN purchase_histories ON products.id = purchase_histories.product_id").
+			Where("categories.deleted_at IS NULL").
+			Where(matches("categories.name"), pattern, wordPattern).
+			Group("categories.id").
+			Order("COUNT(purchase_histories.id) DESC, categories.sort_order, categories.name").
+			Limit(sideSuggestSize).
+			Scan(&result.Categories).Error
+	})
+
+	// Earlier searches that found something
+	run("query", func() error {
+		return db.Model(&models.SearchQueryLog{}).
+			Where("created_at >= ? AND result_count > 0", time.Now().AddDate(0, 0, -30)).
+			Where(fmt.Sprintf("%s LIKE %s", normalize("query"), normalize("?")), pattern).
+			Group("query").
+			Order("COUNT(*) DESC").
+			Limit(sideSuggestSize).
+			Pluck("query", &result.Queries).Error
+	})
+
+	wg.Wait()
+
+	// Partial suggestions are still useful; only fail when nothing worked
+	for _, err := range errs {
+		log.Printf("Autocomplete: %v", err)
+	}
+	if len(errs) == 4 {
+		return result, errs[0]
+	}
+	return result, nil
+}
+
+// emptySuggestions returns suggestions with empty, non-nil lists
+func emptySuggestions() models.Suggestions {
+	return models.Suggestions{
+		Products:   []models.ProductSuggestion{},
+		Brands:     []models.BrandSuggestion{},
+		Categories: []models.CategorySuggestion{},
+		Queries:    []string{},
+	}
+}
+
+// normalizeSearchQuery lowercases a query and collapses whitespace
+func normalizeSearchQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"literally-backend/pkg/storage"
+	"literally-backend/pkg/utils"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	_ "image/gif"
+)
+
+// Rendition bounds; originals are kept untouched
+const (
+	thumbnailSize = 200
+	mediumSize    = 800
+
+	// maxImagePixels guards against decompression bombs
+	maxImagePixels = 40_000_000
+)
+
+// allowedImageTypes maps accepted content types to the extension of the original
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+var (
+	blobStore     *storage.LocalStore
+	blobStoreOnce sync.Once
+)
+
+// StoredImage describes an uploaded image and its renditions
+type StoredImage struct {
+	Key          string
+	URL          string
+	MediumURL    string
+	ThumbnailURL string
+	ContentType  string
+	Size         int64
+	Width        int
+	Height       int
+}
+
+// BlobStore returns the store uploads are written to, configured by
+// UPLOAD_DIR and UPLOAD_BASE_URL
+func BlobStore() storage.BlobStore {
+	return localBlobStore()
+}
+
+// UploadDir returns the directory served by the static uploads route
+func UploadDir() string {
+	return localBlobStore().Root()
+}
+
+func localBlobStore() *storage.LocalStore {
+	blobStoreOnce.Do(func() {
+		dir := os.Getenv("UPLOAD_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		baseURL := os.Getenv("UPLOAD_BASE_URL")
+		if baseURL == "" {
+			baseURL = "/uploads"
+		}
+		blobStore = storage.NewLocalStore(dir, baseURL)
+	})
+	return blobStore
+}
+
+// MaxUploadSize returns the largest accepted image in bytes (UPLOAD_MAX_SIZE_MB, default 5)
+func MaxUploadSize() int64 {
+	return int64(envInt("UPLOAD_MAX_SIZE_MB", 5)) << 20
+}
+
+// storeImage validates an uploaded image and stores it together with its
+// medium and thumbnail renditions under prefix
+func storeImage(prefix string, file *multipart.FileHeader) (StoredImage, error) {
+	maxSize := MaxUploadSize()
+	if file.Size > maxSize {
+		return StoredImage{}, fmt.Errorf("image exceeds the maximum size of %d MB", maxSize>>20)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return StoredImage{}, err
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxSize+1))
+	if err != nil {
+		return StoredImage{}, err
+	}
+	if int64(len(data)) > maxSize {
+		return StoredImage{}, fmt.Errorf("image exceeds the maximum size of %d MB", maxSize>>20)
+	}
+
+	// Trust the bytes, not the client supplied header
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		return StoredImage{}, errors.New("unsupported image type, use JPEG, PNG or GIF")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return StoredImage{}, errors.New("invalid image file")
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return StoredImage{}, errors.New("image dimensions are too large")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return StoredImage{}, errors.New("invalid image file")
+	}
+
+	key := path.Join(prefix, utils.GenerateRandomString(24)+ext)
+	store := BlobStore()
+
+	if err := store.Put(key, bytes.NewReader(data), contentType); err != nil {
+		return StoredImage{}, err
+	}
+
+	renditions := map[string]int{
+		renditionKey(key, "medium"): mediumSize,
+		renditionKey(key, "thumb"):  thumbnailSize,
+	}
+	for rendition, size := range renditions {
+		if err := putRendition(store, rendition, img, size); err != nil {
+			deleteImageBlobs(key)
+			return StoredImage{}, err
+		}
+	}
+
+	return StoredImage{
+		Key:          key,
+		URL:          store.URL(key),
+		MediumURL:    store.URL(renditionKey(key, "medium")),
+		ThumbnailURL: store.URL(renditionKey(key, "thumb")),
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        config.Width,
+		Height:       config.Height,
+	}, nil
+}
+
+// putRendition resizes img to fit a size x size box and stores it. JPEG
+// sources stay JPEG; PNG and GIF renditions are PNG to keep transparency.
+func putRendition(store storage.BlobStore, key string, img image.Image, size int) error {
+	resized := utils.ResizeToFit(img, size, size)
+
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if strings.HasSuffix(key, ".jpg") {
+		contentType = "image/jpeg"
+		if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85}); err != nil {
+			return err
+		}
+	} else if err := png.Encode(&buf, resized); err != nil {
+		return err
+	}
+
+	return store.Put(key, &buf, contentType)
+}
+
+// renditionKey derives the key of a rendition from the original's key,
+// e.g. "products/1/abc.jpg" -> "products/1/abc_thumb.jpg"
+func renditionKey(key, name string) string {
+	ext := path.Ext(key)
+	base := strings.TrimSuffix(key, ext)
+	if ext != ".jpg" {
+		ext = ".png"
+	}
+	return base + "_" + name + ext
+}
+
+// deleteImageBlobs removes an original image and its renditions. Failures are
+// logged only, a leftover file must not fail the request that removed it.
+func deleteImageBlobs(key string) {
+	if key == "" {
+		return
+	}
+	store := BlobStore()
+	for _, k := range []string{key, renditionKey(key, "medium"), renditionKey(key, "thumb")} {
+		if err := store.Delete(k); err != nil {
+			log.Printf("Failed to delete blob %s: %v", k, err)
+		}
+	}
+}
@@ -4,16 +4,43 @@ import (
 	"errors"
 	"literally-backend/configs"
 	"literally-backend/internal/models"
+	"literally-backend/pkg/pagination"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// GetAllUsers returns all users
-func GetAllUsers() []models.User {
-	var users []models.User
-	configs.DB.Find(&users)
-	return users
+// UserListSpec lists the sorts and filters available on user listings
+var UserListSpec = pagination.Spec{
+	Sorts: map[string]string{
+		"name":       "name",
+		"email":      "email",
+		"created_at": "created_at",
+	},
+	Filters: map[string]string{
+		"status": "status",
+		"gender": "gender",
+	},
+	DefaultSort: "-created_at",
+}
+
+// GetAllUsers returns one page of users
+func GetAllUsers(params pagination.Params) ([]models.User, pagination.Page, error) {
+	users := []models.User{}
+	page, err := pagination.Find(configs.DB, params, &users)
+	return users, page, err
+}
+
+// GetAllUsersAdmin returns one page of users for admin, applying the "show deleted" filter
+func GetAllUsersAdmin(deleted string, params pagination.Params) ([]models.User, pagination.Page, error) {
+	query, err := scopeDeleted(configs.DB, deleted)
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	users := []models.User{}
+	page, err := pagination.Find(query, params, &users)
+	return users, page, err
 }
 
 // GetUserByID returns a user by ID
@@ -39,13 +66,14 @@ func GetUserByEmail(email string) (models.User, bool) {
 // CreateUser creates a new user
 func CreateUser(req models.CreateUserRequest) (models.User, error) {
 	// Check if email already exists
+	// (deleted accounts included, the unique index still covers them)
 	var existingUser models.User
-	if err := configs.DB.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	if err := configs.DB.Unscoped().Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 		return models.User{}, errors.New("email already exists")
 	}
 
 	// Check if phone number already exists
-	if err := configs.DB.Where("phone_number = ?", req.PhoneNumber).First(&existingUser).Error; err == nil {
+	if err := configs.DB.Unscoped().Where("phone_number = ?", req.PhoneNumber).First(&existingUser).Error; err == nil {
 		return models.User{}, errors.New("phone number already exists")
 	}
 
@@ -68,13 +96,14 @@ func CreateUser(req models.CreateUserRequest) (models.User, error) {
 // RegisterUser registers a new user
 func RegisterUser(req models.RegisterRequest) (models.User, error) {
 	// Check if email already exists
+	// (deleted accounts included, the unique index still covers them)
 	var existingUser models.User
-	if err := configs.DB.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	if err := configs.DB.Unscoped().Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 		return models.User{}, errors.New("email already exists")
 	}
 
 	// Check if phone number already exists
-	if err := configs.DB.Where("phone_number = ?", req.PhoneNumber).First(&existingUser).Error; err == nil {
+	if err := configs.DB.Unscoped().Where("phone_number = ?", req.PhoneNumber).First(&existingUser).Error; err == nil {
 		return models.User{}, errors.New("phone number already exists")
 	}
 
@@ -124,8 +153,55 @@ func LoginUser(req models.LoginRequest) (models.User, error) {
 	return user, nil
 }
 
-// UpdateUser updates an existing user
-func UpdateUser(id uint, req models.UpdateUserRequest) (models.User, error) {
+// UpdateUser applies a partial update to a user, changing only the fields
+// present in the request
+func UpdateUser(id, version uint, req models.UpdateUserRequest) (models.User, error) {
+	updates := make(map[string]interface{})
+
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Email != nil {
+		updates["email"] = *req.Email
+	}
+	if req.PhoneNumber != nil {
+		updates["phone_number"] = *req.PhoneNumber
+	}
+	if req.Photo != nil {
+		updates["photo"] = *req.Photo
+	}
+	if req.FullName != nil {
+		updates["full_name"] = *req.FullName
+	}
+	if req.DateOfBirth != nil {
+		updates["date_of_birth"] = *req.DateOfBirth
+	}
+	if req.Address != nil {
+		updates["address"] = *req.Address
+	}
+	if req.Gender != nil {
+		updates["gender"] = *req.Gender
+	}
+
+	return saveUser(id, version, updates)
+}
+
+// ReplaceUser replaces the editable fields of a user, clearing the optional
+// fields missing from the request
+func ReplaceUser(id, version uint, req models.ReplaceUserRequest) (models.User, error) {
+	return saveUser(id, version, map[string]interface{}{
+		"name":          req.Name,
+		"email":         req.Email,
+		"phone_number":  req.PhoneNumber,
+		"full_name":     req.FullName,
+		"date_of_birth": req.DateOfBirth,
+		"address":       req.Address,
+		"gender":        req.Gender,
+	})
+}
+
+// saveUser checks and writes column updates to a user at the given version
+func saveUser(id, version uint, updates map[string]interface{}) (models.User, error) {
 	var user models.User
 
 	// Find the user
@@ -135,53 +211,35 @@ func UpdateUser(id uint, req models.UpdateUserRequest) (models.User, error) {
 		}
 		return models.User{}, err
 	}
+	if err := checkVersion(user.Version, version); err != nil {
+		return models.User{}, err
+	}
+
+	fieldErrors := models.FieldErrors{}
 
 	// Check if new email already exists (excluding current user)
-	if req.Email != "" && req.Email != user.Email {
+	if email, ok := updates["email"].(string); ok && email != user.Email {
 		var existingUser models.User
-		if err := configs.DB.Where("email = ? AND id != ?", req.Email, id).First(&existingUser).Error; err == nil {
-			return models.User{}, errors.New("email already exists")
+		if err := configs.DB.Unscoped().Where("email = ? AND id != ?", email, id).First(&existingUser).Error; err == nil {
+			fieldErrors["email"] = "email already exists"
 		}
 	}
 
 	// Check if new phone number already exists (excluding current user)
-	if req.PhoneNumber != "" && req.PhoneNumber != user.PhoneNumber {
+	if phone, ok := updates["phone_number"].(string); ok && phone != user.PhoneNumber {
 		var existingUser models.User
-		if err := configs.DB.Where("phone_number = ? AND id != ?", req.PhoneNumber, id).First(&existingUser).Error; err == nil {
-			return models.User{}, errors.New("phone number already exists")
+		if err := configs.DB.Unscoped().Where("phone_number = ? AND id != ?", phone, id).First(&existingUser).Error; err == nil {
+			fieldErrors["phone_number"] = "phone number already exists"
 		}
 	}
 
-	// Update fields
-	updates := make(map[string]interface{})
-
-	if req.Name != "" {
-		updates["name"] = req.Name
-	}
-	if req.Email != "" {
-		updates["email"] = req.Email
-	}
-	if req.PhoneNumber != "" {
-		updates["phone_number"] = req.PhoneNumber
-	}
-	if req.Photo != "" {
-		updates["photo"] = req.Photo
-	}
-	if req.FullName != "" {
-		updates["full_name"] = req.FullName
-	}
-	if req.DateOfBirth != nil {
-		updates["date_of_birth"] = req.DateOfBirth
-	}
-	if req.Address != "" {
-		updates["address"] = req.Address
-	}
-	if req.Gender != "" {
-		updates["gender"] = req.Gender
+	if len(fieldErrors) > 0 {
+		return models.User{}, fieldErrors
 	}
 
 	// Update the user
-	if err := configs.DB.Model(&user).Updates(updates).Error; err != nil {
+	result := configs.DB.Model(&user).Scopes(atVersion(version)).Updates(updates)
+	if err := versionedResult(result, version); err != nil {
 		return models.User{}, err
 	}
 
@@ -191,30 +249,62 @@ func UpdateUser(id uint, req models.UpdateUserRequest) (models.User, error) {
 	return user, nil
 }
 
-// DeleteUser deletes a user by ID
-func DeleteUser(id uint) error {
-	result := configs.DB.Delete(&models.User{}, id)
-	if result.Error != nil {
-		return result.Error
+// DeleteUser soft-deletes a user by ID at the given version and signs them
+// out everywhere
+func DeleteUser(id, version uint) error {
+	if err := userAtVersion(id, version); err != nil {
+		return err
+	}
+
+	result := configs.DB.Scopes(atVersion(version)).Delete(&models.User{}, id)
+	if err := versionedResult(result, version); err != nil {
+		return err
 	}
 
 	if result.RowsAffected == 0 {
 		return errors.New("user not found")
 	}
 
+	if _, err := RevokeAllSessions(id); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// UpdateUserStatus updates user status by ID
-func UpdateUserStatus(id uint, status string) error {
-	result := configs.DB.Model(&models.User{}).Where("id = ?", id).Update("status", status)
-	if result.Error != nil {
-		return result.Error
+// UpdateUserStatus updates user status by ID at the given version
+func UpdateUserStatus(id, version uint, status string) error {
+	if err := userAtVersion(id, version); err != nil {
+		return err
+	}
+
+	result := configs.DB.Model(&models.User{}).Where("id = ?", id).Scopes(atVersion(version)).Update("status", status)
+	if err := versionedResult(result, version); err != nil {
+		return err
 	}
 
 	if result.RowsAffected == 0 {
 		return errors.New("user not found")
 	}
 
+	// Inactive or suspended users are signed out everywhere
+	if status != "ACTIVE" {
+		if _, err := RevokeAllSessions(id); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// userAtVersion checks that a user exists and is still at version
+func userAtVersion(id, version uint) error {
+	var user models.User
+	if err := configs.DB.Select("id", "version").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		return err
+	}
+	return checkVersion(user.Version, version)
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"errors"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GetProductVariants returns the variants of a product ordered by price
+func GetProductVariants(productID uint) []models.ProductVariant {
+	var variants []models.ProductVariant
+	configs.DB.Where("product_id = ?", productID).Order("price, id").Find(&variants)
+	return variants
+}
+
+// CreateProductVariant adds a variant to a product, recording its stock as
+// received
+func CreateProductVariant(adminID, productID uint, req models.CreateVariantRequest) (models.ProductVariant, error) {
+	if _, found := GetProductByID(productID); !found {
+		return models.ProductVariant{}, errors.New("product not found")
+	}
+
+	if skuExists(req.SKU, 0) {
+		return models.ProductVariant{}, errors.New("variant with this SKU already exists")
+	}
+	if err := validateCompareAtPrice(req.Price, req.CompareAtPrice); err != nil {
+		return models.ProductVariant{}, err
+	}
+
+	variant := models.ProductVariant{
+		ProductID:   productID,
+		SKU:         req.SKU,
+		Color:       req.Color,
+		RAM:         req.RAM,
+		Storage:     req.Storage,
+		Price:       req.Price,
+		Stock:       req.Stock,
+		ImageUrl:    req.ImageUrl,
+		IsAvailable: true,
+
+		CompareAtPrice: req.CompareAtPrice,
+	}
+
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&variant).Error; err != nil {
+			return err
+		}
+		entry := models.PriceHistory{ProductID: productID, VariantID: &variant.ID, Source: models.PriceSourceManual}
+		if err := recordInitialPrice(tx, entry, variant.Price, variant.CompareAtPrice); err != nil {
+			return err
+		}
+		stockEntry := models.InventoryMovement{ProductID: productID, VariantID: &variant.ID, Reason: models.MovementReceiving, AdminID: &adminID}
+		if err := recordInitialStock(tx, stockEntry, variant.Stock); err != nil {
+			return err
+		}
+		return syncVariantProductPrice(tx, productID)
+	})
+	if err != nil {
+		return models.ProductVariant{}, err
+	}
+
+	return variant, nil
+}
+
+// UpdateProductVariant updates a variant of a product
+func UpdateProductVariant(adminID, productID, variantID uint, req models.UpdateVariantRequest) (models.ProductVariant, error) {
+	variant, err := findProductVariant(configs.DB, productID, variantID)
+	if err != nil {
+		return models.ProductVariant{}, err
+	}
+
+	if req.SKU != "" && req.SKU != variant.SKU && skuExists(req.SKU, variant.ID) {
+		return models.ProductVariant{}, errors.New("variant with this SKU already exists")
+	}
+
+	// Update fields
+	updates := make(map[string]interface{})
+
+	if req.SKU != "" {
+		updates["sku"] = req.SKU
+	}
+	if req.Color != "" {
+		updates["color"] = req.Color
+	}
+	if req.RAM != "" {
+		updates["ram"] = req.RAM
+	}
+	if req.Storage != "" {
+		updates["storage"] = req.Storage
+	}
+	if req.Price != nil {
+		updates["price"] = *req.Price
+	}
+	if req.Stock != nil {
+		updates["stock"] = *req.Stock
+	}
+	if req.ImageUrl != "" {
+		updates["image_url"] = req.ImageUrl
+	}
+	if req.IsAvailable != nil {
+		updates["is_available"] = *req.IsAvailable
+	}
+	if req.CompareAtPrice != nil {
+		updates["compare_at_price"] = compareAtUpdate(*req.CompareAtPrice)
+	}
+
+	price := variant.Price
+	if req.Price != nil {
+		price = *req.Price
+	}
+	compareAt := variant.CompareAtPrice
+	if req.CompareAtPrice != nil {
+		compareAt = req.CompareAtPrice
+		if *compareAt == 0 {
+			compareAt = nil
+		}
+	}
+	if err := validateCompareAtPrice(price, compareAt); err != nil {
+		return models.ProductVariant{}, err
+	}
+
+	previous, previousCompareAt := variant.Price, variant.CompareAtPrice
+	err = configs.DB.Transaction(func(tx *gorm.DB) error {
+		stockEntry := models.InventoryMovement{ProductID: productID, VariantID: &variant.ID, Reason: models.MovementAdjustment, AdminID: &adminID}
+		if err := trackStock(tx, stockEntry, func() error {
+			return tx.Model(&variant).Updates(updates).Error
+		}); err != nil {
+			return err
+		}
+		if err := tx.First(&variant, variant.ID).Error; err != nil {
+			return err
+		}
+		entry := models.PriceHistory{ProductID: productID, VariantID: &variant.ID, Source: models.PriceSourceManual}
+		if err := recordVariantPrice(tx, entry, previous, previousCompareAt, variant.Price, variant.CompareAtPrice); err != nil {
+			return err
+		}
+		return syncVariantProductPrice(tx, productID)
+	})
+	if err != nil {
+		return models.ProductVariant{}, err
+	}
+
+	return variant, nil
+}
+
+// DeleteProductVariant soft-deletes a variant of a product; its remaining
+// stock is written off in the inventory ledger
+func DeleteProductVariant(adminID, productID, variantID uint) error {
+	variant, err := findProductVariant(configs.DB, productID, variantID)
+	if err != nil {
+		return err
+	}
+
+	return configs.DB.Transaction(func(tx *gorm.DB) error {
+		stockEntry := models.InventoryMovement{
+			ProductID: productID,
+			VariantID: &variant.ID,
+			Reason:    models.MovementAdjustment,
+			AdminID:   &adminID,
+			Note:      "Variant deleted",
+		}
+		if err := trackStock(tx, stockEntry, func() error {
+			return tx.Model(&variant).Update("stock", 0).Error
+		}); err != nil {
+			return err
+		}
+		if err := tx.Delete(&variant).Error; err != nil {
+			return err
+		}
+		return syncVariantProductPrice(tx, productID)
+	})
+}
+
+// Helper functions
+
+func findProductVariant(db *gorm.DB, productID, variantID uint) (models.ProductVariant, error) {
+	var variant models.ProductVariant
+	if err := db.Where("id = ? AND product_id = ?", variantID, productID).First(&variant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ProductVariant{}, errors.New("variant not found")
+		}
+		return models.ProductVariant{}, err
+	}
+	return variant, nil
+}
+
+func skuExists(sku string, excludeID uint) bool {
+	var count int64
+	configs.DB.Unscoped().Model(&models.ProductVariant{}).Where("sku = ? AND id <> ?", sku, excludeID).Count(&count)
+	return count > 0
+}
+
+// resolveLineVariant loads the variant referenced by a cart or order line.
+// Products with variants must be bought through one of them; products
+// without variants are bought directly.
+func resolveLineVariant(db *gorm.DB, product models.Product, variantID *uint) (*models.ProductVariant, error) {
+	if variantID == nil {
+		var count int64
+		if err := db.Model(&models.ProductVariant{}).Where("product_id = ?", product.ID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.New("variant_id is required for product " + product.Name)
+		}
+		return nil, nil
+	}
+
+	variant, err := findProductVariant(db, product.ID, *variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !variant.IsAvailable {
+		return nil, errors.New("variant is not available")
+	}
+
+	return &variant, nil
+}
+
+// lineStock returns the stock available for a cart or order line
+func lineStock(product models.Product, variant *models.ProductVariant) int {
+	if variant != nil {
+		return variant.Stock
+	}
+	return product.Stock
+}
+
+// linePrice returns the unit price of a cart or order line
+func linePrice(product models.Product, variant *models.ProductVariant) float64 {
+	if variant != nil {
+		return variant.Price
+	}
+	return product.Price
+}
+
+// lineName returns a display name of a cart or order line
+func lineName(product models.Product, variant *models.ProductVariant) string {
+	if variant != nil && variant.Label() != "" {
+		return product.Name + " (" + variant.Label() + ")"
+	}
+	return product.Name
+}
+
+// syncProductFromVariants keeps the product's aggregate stock, base price and
+// availability in line with its variants. Availability only changes when the
+// product sells out, unless it takes pre-orders, or comes back in stock, so an
+// admin's choice otherwise stands.
+func syncProductFromVariants(tx *gorm.DB, productID uint) error {
+	var variantCount int64
+	if err := tx.Model(&models.ProductVariant{}).Where("product_id = ?", productID).Count(&variantCount).Error; err != nil {
+		return err
+	}
+
+	// Products without variants manage their own stock and price
+	if variantCount == 0 {
+		return nil
+	}
+
+	var summary struct {
+		Stock    int
+		MinPrice float64
+	}
+	if err := tx.Model(&models.ProductVariant{}).
+		Select("COALESCE(SUM(stock), 0) AS stock, COALESCE(MIN(price), 0) AS min_price").
+		Where("product_id = ? AND is_available = ?", productID, true).
+		Scan(&summary).Error; err != nil {
+		return err
+	}
+
+	// A new price is an edit of the product, stock and availability that
+	// follow orders are not
+	if err := tx.Model(&models.Product{}).
+		Where("id = ? AND price <> ?", productID, summary.MinPrice).
+		Update("price", summary.MinPrice).Error; err != nil {
+		return err
+	}
+
+	// The expression sees the stock before this update
+	available := gorm.Expr("is_available AND (stock <= 0 OR is_preorder)")
+	if summary.Stock > 0 {
+		available = gorm.Expr("is_available OR stock <= 0")
+	}
+	return tx.Model(&models.Product{}).Scopes(configs.KeepVersion).Where("id = ?", productID).Updates(map[string]interface{}{
+		"stock":        summary.Stock,
+		"is_available": available,
+	}).Error
+}
+
+// syncVariantProductPrice syncs a product from its variants after a variant
+// changed, recording the derived product price in the price history
+func syncVariantProductPrice(tx *gorm.DB, productID uint) error {
+	err := trackProductPrice(tx, models.PriceHistory{ProductID: productID, Source: models.PriceSourceVariant}, func() error {
+		return syncProductFromVariants(tx, productID)
+	})
+	if err != nil {
+		return err
+	}
+	return touchProduct(tx, productID)
+}
+
+// attachPriceRanges fills MinPrice and MaxPrice on products from their variants
+func attachPriceRanges(products []models.Product) {
+	if len(products) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+
+	var ranges []struct {
+		ProductID uint
+		MinPrice  float64
+		MaxPrice  float64
+	}
+	configs.DB.Model(&models.ProductVariant{}).
+		Select("product_id, MIN(price) AS min_price, MAX(price) AS max_price").
+		Where("product_id IN ? AND is_available = ?", ids, true).
+		Group("product_id").
+		Scan(&ranges)
+
+	byProduct := make(map[uint]int, len(ranges))
+	for i, r := range ranges {
+		byProduct[r.ProductID] = i
+	}
+
+	for i := range products {
+		if idx, ok := byProduct[products[i].ID]; ok {
+			products[i].MinPrice = ranges[idx].MinPrice
+			products[i].MaxPrice = ranges[idx].MaxPrice
+		} else {
+			products[i].MinPrice = products[i].Price
+			products[i].MaxPrice = products[i].Price
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"fmt"
+	"literally-backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestUnavailableProductWithVariantsStaysUnavailable(t *testing.T) {
+	db := openTestDB(t)
+
+	product := createStockedProduct(t, db, 0)
+	if _, err := CreateProductVariant(0, product.ID, models.CreateVariantRequest{
+		SKU:   fmt.Sprintf("VAR-%d", time.Now().UnixNano()),
+		Price: 100,
+		Stock: 5,
+	}); err != nil {
+		t.Fatalf("create variant: %v", err)
+	}
+	db.First(&product, product.ID)
+
+	unavailable := false
+	updated, err := UpdateProduct(0, product.ID, product.Version, models.UpdateProductRequest{IsAvailable: &unavailable})
+	if err != nil {
+		t.Fatalf("update product: %v", err)
+	}
+	if updated.IsAvailable || updated.Stock != 5 {
+		t.Errorf("product available %v with stock %d, want unavailable with 5", updated.IsAvailable, updated.Stock)
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+	"literally-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict is returned when a versioned resource changed after the
+// client read it, so applying the client's edit would overwrite that change
+var ErrVersionConflict = errors.New("resource has been modified since it was retrieved")
+
+// checkVersion compares the version a client edits with the current one.
+// Version 0 skips the check.
+func checkVersion(current, version uint) error {
+	if version != 0 && current != version {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// atVersion limits an update or delete to the row version the client read,
+// which closes the gap between checkVersion and the write. Version 0 leaves
+// the query unchanged.
+func atVersion(version uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if version == 0 {
+			return db
+		}
+		return db.Where("version = ?", version)
+	}
+}
+
+// touchProduct bumps the version of a product whose variants, images or
+// attributes changed, since they are part of the product's representation
+func touchProduct(tx *gorm.DB, productID uint) error {
+	return tx.Model(&models.Product{}).Where("id = ?", productID).Update("version", gorm.Expr("version + 1")).Error
+}
+
+// versionedResult turns a versioned write that matched no row into
+// ErrVersionConflict, the row having been found beforehand
+func versionedResult(result *gorm.DB, version uint) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if version != 0 && result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
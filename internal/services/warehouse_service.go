@@ -0,0 +1,459 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/configs"
+	"literally-backend/internal/models"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// ErrWarehouseNotFound is returned for a warehouse ID that does not exist
+var ErrWarehouseNotFound = errors.New("warehouse not found")
+
+// regionOrder places the regions from north to south, so that the distance
+// between two regions is the difference of their positions
+var regionOrder = map[string]int{
+	models.RegionNorth:   0,
+	models.RegionCentral: 1,
+	models.RegionSouth:   2,
+}
+
+// provinceRegions maps province names, as folded by provinceKey, to their
+// region. Names from before and after the 2025 province mergers are both
+// listed, with common short forms.
+var provinceRegions = func() map[string]string {
+	regions := map[string][]string{
+		models.RegionNorth: {
+			"ha noi", "hanoi", "hai phong", "quang ninh", "hai duong", "hung yen", "bac ninh", "bac giang",
+			"vinh phuc", "phu tho", "thai nguyen", "bac kan", "cao bang", "lang son", "tuyen quang",
+			"ha giang", "lao cai", "yen bai", "dien bien", "lai chau", "son la", "hoa binh", "ha nam",
+			"nam dinh", "thai binh", "ninh binh",
+		},
+		models.RegionCentral: {
+			"thanh hoa", "nghe an", "ha tinh", "quang binh", "quang tri", "thua thien hue", "hue",
+			"da nang", "quang nam", "quang ngai", "binh dinh", "phu yen", "khanh hoa", "ninh thuan",
+			"binh thuan", "kon tum", "gia lai", "dak lak", "daklak", "dak nong", "lam dong",
+		},
+		models.RegionSouth: {
+			"ho chi minh", "hcm", "hcmc", "sai gon", "saigon", "binh duong", "dong nai", "ba ria vung tau",
+			"vung tau", "binh phuoc", "tay ninh", "long an", "tien giang", "ben tre", "tra vinh",
+			"vinh long", "dong thap", "an giang", "kien giang", "can tho", "hau giang", "soc trang",
+			"bac lieu", "ca mau",
+		},
+	}
+
+	byProvince := make(map[string]string)
+	for region, provinces := range regions {
+		for _, province := range provinces {
+			byProvince[province] = region
+		}
+	}
+	return byProvince
+}()
+
+// GetWarehouses returns all warehouses, the default one first
+func GetWarehouses() ([]models.Warehouse, error) {
+	warehouses := []models.Warehouse{}
+	err := configs.DB.Order("is_default DESC, priority, id").Find(&warehouses).Error
+	return warehouses, err
+}
+
+// CreateWarehouse adds a warehouse. The first warehouse becomes the default.
+func CreateWarehouse(req models.CreateWarehouseRequest) (models.Warehouse, error) {
+	if warehouseCodeTaken(req.Code) {
+		return models.Warehouse{}, errors.New("warehouse with this code already exists")
+	}
+
+	warehouse := models.Warehouse{
+		Code:     req.Code,
+		Name:     req.Name,
+		Province: req.Province,
+		Region:   req.Region,
+		Priority: req.Priority,
+	}
+
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Warehouse{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&warehouse).Error; err != nil {
+			return err
+		}
+		if req.IsDefault || count == 0 {
+			return setDefaultWarehouse(tx, &warehouse)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Warehouse{}, err
+	}
+
+	return warehouse, nil
+}
+
+// UpdateWarehouse changes the fields of a warehouse present in the request.
+// The default warehouse stays the default until another one is made default.
+func UpdateWarehouse(id uint, req models.UpdateWarehouseRequest) (models.Warehouse, error) {
+	var warehouse models.Warehouse
+	if err := configs.DB.First(&warehouse, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Warehouse{}, ErrWarehouseNotFound
+		}
+		return models.Warehouse{}, err
+	}
+
+	if req.IsDefault != nil && !*req.IsDefault && warehouse.IsDefault {
+		return models.Warehouse{}, errors.New("make another warehouse the default instead")
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Province != nil {
+		updates["province"] = *req.Province
+	}
+	if req.Region != nil {
+		updates["region"] = *req.Region
+	}
+	if req.Priority != nil {
+		updates["priority"] = *req.Priority
+	}
+
+	err := configs.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&warehouse).Updates(updates).Error; err != nil {
+			return err
+		}
+		if req.IsDefault != nil && *req.IsDefault {
+			return setDefaultWarehouse(tx, &warehouse)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Warehouse{}, err
+	}
+
+	configs.DB.First(&warehouse, id)
+	return warehouse, nil
+}
+
+// GetProductWarehouseStock returns the stock a product and its variants have
+// in each warehouse
+func GetProductWarehouseStock(productID uint) ([]models.WarehouseStock, error) {
+	stocks := []models.WarehouseStock{}
+	err := configs.DB.Preload("Warehouse").
+		Where("product_id = ?", productID).
+		Order("variant_id NULLS FIRST, warehouse_id").
+		Find(&stocks).Error
+	return stocks, err
+}
+
+// PlaceUnassignedStock puts stock that no warehouse holds yet, such as stock
+// from before warehouses existed, into the default warehouse
+func PlaceUnassignedStock() error {
+	warehouse, err := defaultWarehouse(configs.DB)
+	if err != nil {
+		return err
+	}
+
+	var unplaced []struct {
+		ProductID uint
+		VariantID *uint
+		Stock     int
+	}
+	if err := configs.DB.Model(&models.Product{}).Unscoped().
+		Select("products.id AS product_id, products.stock - COALESCE(SUM(warehouse_stocks.stock), 0) AS stock").
+		Joins("LEFT JOIN warehouse_stocks ON warehouse_stocks.product_id = products.id AND warehouse_stocks.variant_id IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.deleted_at IS NULL)").
+		Group("products.id").
+		Having("products.stock > COALESCE(SUM(warehouse_stocks.stock), 0)").
+		Scan(&unplaced).Error; err != nil {
+		return err
+	}
+
+	var unplacedVariants []struct {
+		ProductID uint
+		VariantID *uint
+		Stock     int
+	}
+	if err := configs.DB.Model(&models.ProductVariant{}).
+		Select("product_variants.product_id, product_variants.id AS variant_id, product_variants.stock - COALESCE(SUM(warehouse_stocks.stock), 0) AS stock").
+		Joins("LEFT JOIN warehouse_stocks ON warehouse_stocks.variant_id = product_variants.id").
+		Group("product_variants.id").
+		Having("product_variants.stock > COALESCE(SUM(warehouse_stocks.stock), 0)").
+		Scan(&unplacedVariants).Error; err != nil {
+		return err
+	}
+
+	unplaced = append(unplaced, unplacedVariants...)
+	for _, item := range unplaced {
+		if err := addWarehouseStock(configs.DB, warehouse.ID, item.ProductID, item.VariantID, item.Stock); err != nil {
+			return err
+		}
+	}
+
+	if len(unplaced) > 0 {
+		log.Printf("Placed the stock of %d products and variants in warehouse %s", len(unplaced), warehouse.Code)
+	}
+	return nil
+}
+
+// fulfillmentStrategy returns the FULFILLMENT_STRATEGY in use, NEAREST by default
+func fulfillmentStrategy() string {
+	if strings.EqualFold(os.Getenv("FULFILLMENT_STRATEGY"), models.FulfillmentSingle) {
+		return models.FulfillmentSingle
+	}
+	return models.FulfillmentNearest
+}
+
+// allocateStock picks the warehouses that lines are taken from. Lines that
+// name a warehouse keep it. The others are taken from the warehouses nearest
+// to province first, split across warehouses when one runs short; with the
+// SINGLE strategy one warehouse takes them all when it can. The stock of the
+// lines must be locked.
+func allocateStock(tx *gorm.DB, lines []stockLine, province string) ([]stockLine, error) {
+	warehouses, err := warehousesByDistance(tx, province)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := warehouseLevels(tx, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	var single *uint
+	if fulfillmentStrategy() == models.FulfillmentSingle {
+		needs := make(map[stockKey]int)
+		for _, line := range lines {
+			if line.WarehouseID == nil {
+				needs[lineKey(line)] += line.Quantity
+			}
+		}
+		for i := range warehouses {
+			if holdsAll(levels, warehouses[i].ID, needs) {
+				single = &warehouses[i].ID
+				break
+			}
+		}
+	}
+
+	var allocated []stockLine
+	for _, line := range lines {
+		if line.WarehouseID != nil {
+			allocated = append(allocated, line)
+			continue
+		}
+		if single != nil {
+			line.WarehouseID = single
+			allocated = append(allocated, line)
+			continue
+		}
+
+		key := lineKey(line)
+		remaining := line.Quantity
+		for i := range warehouses {
+			take := min(levels[key][warehouses[i].ID], remaining)
+			if take <= 0 {
+				continue
+			}
+			levels[key][warehouses[i].ID] -= take
+
+			part := line
+			part.WarehouseID = &warehouses[i].ID
+			part.Quantity = take
+			allocated = append(allocated, part)
+
+			remaining -= take
+			if remaining == 0 {
+				break
+			}
+		}
+		if remaining > 0 {
+			return nil, insufficientStock(tx, line)
+		}
+	}
+
+	return allocated, nil
+}
+
+// holdsAll reports whether a warehouse holds every needed quantity
+func holdsAll(levels map[stockKey]map[uint]int, warehouseID uint, needs map[stockKey]int) bool {
+	for key, quantity := range needs {
+		if levels[key][warehouseID] < quantity {
+			return false
+		}
+	}
+	return true
+}
+
+// warehouseLevels returns the stock the products and variants of lines have
+// in each warehouse, by warehouse ID
+func warehouseLevels(tx *gorm.DB, lines []stockLine) (map[stockKey]map[uint]int, error) {
+	productIDs := make([]uint, len(lines))
+	for i, line := range lines {
+		productIDs[i] = line.ProductID
+	}
+
+	var stocks []models.WarehouseStock
+	if err := tx.Where("product_id IN ? AND stock > 0", productIDs).Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+
+	levels := make(map[stockKey]map[uint]int)
+	for _, stock := range stocks {
+		key := lineKey(stockLine{ProductID: stock.ProductID, VariantID: stock.VariantID})
+		if levels[key] == nil {
+			levels[key] = make(map[uint]int)
+		}
+		levels[key][stock.WarehouseID] = stock.Stock
+	}
+	return levels, nil
+}
+
+// warehousesByDistance returns all warehouses, nearest to province first and
+// in priority order at the same distance
+func warehousesByDistance(tx *gorm.DB, province string) ([]models.Warehouse, error) {
+	var warehouses []models.Warehouse
+	if err := tx.Order("priority, id").Find(&warehouses).Error; err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(warehouses, func(i, j int) bool {
+		return warehouseDistance(warehouses[i], province) < warehouseDistance(warehouses[j], province)
+	})
+	return warehouses, nil
+}
+
+// warehouseDistance ranks how far a warehouse is from a province: 0 in the
+// same province, 1 in the same region and one more for each region between
+// them. Every warehouse outside an unknown province ranks 1.
+func warehouseDistance(warehouse models.Warehouse, province string) int {
+	key := provinceKey(province)
+	if key != "" && provinceKey(warehouse.Province) == key {
+		return 0
+	}
+
+	region, known := provinceRegions[key]
+	if !known {
+		return 1
+	}
+	distance := regionOrder[warehouse.Region] - regionOrder[region]
+	if distance < 0 {
+		distance = -distance
+	}
+	return 1 + distance
+}
+
+// provinceKey folds a province name to lowercase ASCII words without the
+// administrative prefix, so that "TP. Hồ Chí Minh" and "Ho Chi Minh City"
+// match
+func provinceKey(province string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(province)) {
+		switch {
+		case r == 'đ':
+			b.WriteRune('d')
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	key := strings.Join(strings.Fields(b.String()), " ")
+	for _, prefix := range []string{"thanh pho ", "tp ", "tinh "} {
+		key = strings.TrimPrefix(key, prefix)
+	}
+	return strings.TrimSuffix(key, " city")
+}
+
+// defaultWarehouse returns the warehouse that holds stock not placed
+// anywhere else
+func defaultWarehouse(tx *gorm.DB) (models.Warehouse, error) {
+	var warehouse models.Warehouse
+	if err := tx.Order("is_default DESC, priority, id").First(&warehouse).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Warehouse{}, errors.New("no warehouse configured")
+		}
+		return models.Warehouse{}, err
+	}
+	return warehouse, nil
+}
+
+// lineWarehouse returns the warehouse of a line, the default warehouse when
+// it names none
+func lineWarehouse(tx *gorm.DB, line stockLine) (uint, error) {
+	if line.WarehouseID != nil {
+		return *line.WarehouseID, nil
+	}
+	warehouse, err := defaultWarehouse(tx)
+	return warehouse.ID, err
+}
+
+// addWarehouseStock adds quantity units, or takes them out when negative, to
+// the stock a product or variant has in a warehouse. The stock of a warehouse
+// cannot go below 0.
+func addWarehouseStock(tx *gorm.DB, warehouseID, productID uint, variantID *uint, quantity int) error {
+	if quantity == 0 {
+		return nil
+	}
+
+	result := tx.Model(&models.WarehouseStock{}).
+		Where("warehouse_id = ? AND product_id = ? AND stock >= ?", warehouseID, productID, -quantity).
+		Scopes(forVariant(variantID)).
+		Update("stock", gorm.Expr("stock + ?", quantity))
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	if quantity < 0 {
+		var warehouse models.Warehouse
+		tx.First(&warehouse, warehouseID)
+		return fmt.Errorf("%w in warehouse %s", ErrInsufficientStock, warehouse.Code)
+	}
+	return tx.Create(&models.WarehouseStock{
+		WarehouseID: warehouseID,
+		ProductID:   productID,
+		VariantID:   variantID,
+		Stock:       quantity,
+	}).Error
+}
+
+// forVariant limits a stock query to a variant, or to the product itself
+// when variantID is nil
+func forVariant(variantID *uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if variantID == nil {
+			return db.Where("variant_id IS NULL")
+		}
+		return db.Where("variant_id = ?", *variantID)
+	}
+}
+
+// setDefaultWarehouse makes a warehouse the default one
+func setDefaultWarehouse(tx *gorm.DB, warehouse *models.Warehouse) error {
+	if err := tx.Model(&models.Warehouse{}).
+		Where("id <> ? AND is_default = ?", warehouse.ID, true).
+		Update("is_default", false).Error; err != nil {
+		return err
+	}
+	warehouse.IsDefault = true
+	return tx.Model(warehouse).Update("is_default", true).Error
+}
+
+// warehouseCodeTaken reports whether a warehouse uses code
+func warehouseCodeTaken(code string) bool {
+	var count int64
+	configs.DB.Model(&models.Warehouse{}).Where("code = ?", code).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"literally-backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestWarehouseDistance(t *testing.T) {
+	hanoi := models.Warehouse{Province: "Hà Nội", Region: models.RegionNorth}
+	danang := models.Warehouse{Province: "Đà Nẵng", Region: models.RegionCentral}
+	saigon := models.Warehouse{Province: "TP. Hồ Chí Minh", Region: models.RegionSouth}
+
+	tests := []struct {
+		province string
+		want     [3]int // Hà Nội, Đà Nẵng, Hồ Chí Minh
+	}{
+		{province: "Ho Chi Minh City", want: [3]int{3, 2, 0}},
+		{province: "Thành phố Hồ Chí Minh", want: [3]int{3, 2, 0}},
+		{province: "Cần Thơ", want: [3]int{3, 2, 1}},
+		{province: "Huế", want: [3]int{2, 1, 2}},
+		{province: "Tỉnh Bắc Ninh", want: [3]int{1, 2, 3}},
+		{province: "Atlantis", want: [3]int{1, 1, 1}},
+		{province: "", want: [3]int{1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		var got [3]int
+		for i, warehouse := range []models.Warehouse{hanoi, danang, saigon} {
+			got[i] = warehouseDistance(warehouse, tt.province)
+		}
+		if got != tt.want {
+			t.Errorf("distances to %q = %v, want %v", tt.province, got, tt.want)
+		}
+	}
+}
+
+func TestAllocateStockFromNearestWarehouses(t *testing.T) {
+	db := openTestDB(t)
+	t.Setenv("FULFILLMENT_STRATEGY", models.FulfillmentNearest)
+
+	// Rolled back, so the warehouses are not seen by other tests
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	product := models.Product{Name: "Split stock " + t.Name(), Price: 100, IsAvailable: true}
+	if err := tx.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+
+	unique := time.Now().UnixNano()
+	hanoi := models.Warehouse{Code: fmt.Sprintf("HN-%d", unique), Name: "Hà Nội", Province: "Hà Nội", Region: models.RegionNorth}
+	saigon := models.Warehouse{Code: fmt.Sprintf("SG-%d", unique), Name: "Sài Gòn", Province: "TP. Hồ Chí Minh", Region: models.RegionSouth}
+	for _, warehouse := range []*models.Warehouse{&hanoi, &saigon} {
+		if err := tx.Create(warehouse).Error; err != nil {
+			t.Fatalf("create warehouse: %v", err)
+		}
+		if err := addWarehouseStock(tx, warehouse.ID, product.ID, nil, 3); err != nil {
+			t.Fatalf("stock warehouse: %v", err)
+		}
+	}
+
+	type part struct {
+		warehouseID uint
+		quantity    int
+	}
+	tests := []struct {
+		name     string
+		province string
+		quantity int
+		want     []part
+		wantErr  error
+	}{
+		{name: "southern order", province: "Cần Thơ", quantity: 2, want: []part{{saigon.ID, 2}}},
+		{name: "northern order", province: "Hà Nội", quantity: 2, want: []part{{hanoi.ID, 2}}},
+		{name: "split when short", province: "Cần Thơ", quantity: 5, want: []part{{saigon.ID, 3}, {hanoi.ID, 2}}},
+		{name: "more than all warehouses", province: "Cần Thơ", quantity: 7, wantErr: ErrInsufficientStock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocated, err := allocateStock(tx, []stockLine{{ProductID: product.ID, Quantity: tt.quantity}}, tt.province)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+
+			var got []part
+			for _, line := range allocated {
+				got = append(got, part{*line.WarehouseID, line.Quantity})
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("allocated %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+// Package pagination pages, sorts and filters list queries.
+//
+// Lists are paged either by page number (offset mode) or by an opaque cursor
+// holding the sort values of the last row returned (keyset mode). Cursors
+// stay fast on deep pages and do not skip or repeat rows when rows are
+// inserted between requests, but they cannot jump to a page or report a total.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Paging defaults
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Paging modes
+const (
+	ModeOffset = "offset"
+	ModeCursor = "cursor"
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Spec describes how a list can be sorted and filtered. Map keys are the
+// names clients use in the query string, values the columns they stand for.
+type Spec struct {
+	Sorts   map[string]string
+	Filters map[string]string
+
+	// DefaultSort is used when no sort is requested, e.g. "-created_at"
+	DefaultSort string
+
+	// Key is a unique column appended to every sort so the order is total
+	// and cursors are unambiguous. Defaults to "id".
+	Key string
+}
+
+// SortField is one column of an ORDER BY
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// Filter restricts a column to one of several values
+type Filter struct {
+	Column string
+	Values []string
+}
+
+// Params are the parsed paging, sorting and filter parameters of a request
+type Params struct {
+	Mode    string
+	Page    int
+	Limit   int
+	Cursor  string
+	Sort    []SortField
+	Filters []Filter
+}
+
+// Page describes the page of a list that was returned
+type Page struct {
+	Mode       string `json:"mode"`
+	Limit      int    `json:"limit"`
+	Page       int    `json:"page,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+	TotalPages *int64 `json:"total_pages,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Parse reads page, limit, cursor and sort parameters plus the filters of
+// spec from a query string. Passing a cursor parameter, even an empty one
+// for the first page, selects keyset mode; page is ignored then.
+//
+// Sort is a comma separated list of sort names, each optionally prefixed
+// with "-" for descending order: sort=price,-created_at.
+func Parse(query url.Values, spec Spec) (Params, error) {
+	params := Params{Mode: ModeOffset, Page: 1, Limit: DefaultLimit}
+
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+		params.Limit = min(limit, MaxLimit)
+	}
+	if cursor, ok := query["cursor"]; ok {
+		params.Mode = ModeCursor
+		params.Cursor = cursor[0]
+	} else if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 0 {
+		params.Page = page
+	}
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = spec.DefaultSort
+	}
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(sort, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimLeft(name, "+-")
+
+		column, ok := spec.Sorts[name]
+		if !ok {
+			return params, fmt.Errorf("%w: unknown field %q, use one of %s", ErrInvalidSort, name, strings.Join(sortedKeys(spec.Sorts), ", "))
+		}
+		if seen[column] {
+			continue
+		}
+		seen[column] = true
+		params.Sort = append(params.Sort, SortField{Column: column, Desc: desc})
+	}
+
+	key := spec.Key
+	if key == "" {
+		key = "id"
+	}
+	if !seen[key] {
+		desc := len(params.Sort) > 0 && params.Sort[len(params.Sort)-1].Desc
+		params.Sort = append(params.Sort, SortField{Column: key, Desc: desc})
+	}
+
+	for _, name := range sortedKeys(spec.Filters) {
+		if value := query.Get(name); value != "" {
+			params.Filters = append(params.Filters, Filter{Column: spec.Filters[name], Values: strings.Split(value, ",")})
+		}
+	}
+
+	return params, nil
+}
+
+// NewOffsetPage describes a page of an offset paged list with total rows
+func NewOffsetPage(page, limit int, total int64) Page {
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+	return Page{
+		Mode:       ModeOffset,
+		Limit:      limit,
+		Page:       page,
+		Total:      &total,
+		TotalPages: &totalPages,
+		HasMore:    int64(page) < totalPages,
+	}
+}
+
+// Find applies params to query and loads one page into dest. The query must
+// select the model of T; scopes (preloads, for example) apply to loading
+// the page only, not to counting.
+func Find[T any](query *gorm.DB, params Params, dest *[]T, scopes ...func(*gorm.DB) *gorm.DB) (Page, error) {
+	query = query.Model(new(T))
+	for _, filter := range params.Filters {
+		query = query.Where(filter.Column+" IN ?", filter.Values)
+	}
+
+	for _, field := range params.Sort {
+		if field.Desc {
+			query = query.Order(field.Column + " DESC")
+		} else {
+			query = query.Order(field.Column)
+		}
+	}
+
+	if params.Mode != ModeCursor {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return Page{}, err
+		}
+		if err := query.Scopes(scopes...).
+			Offset((params.Page - 1) * params.Limit).
+			Limit(params.Limit).
+			Find(dest).Error; err != nil {
+			return Page{}, err
+		}
+		return NewOffsetPage(params.Page, params.Limit, total), nil
+	}
+
+	if params.Cursor != "" {
+		values, err := decodeCursor(params.Cursor, params.Sort)
+		if err != nil {
+			return Page{}, err
+		}
+		condition, args := keysetCondition(params.Sort, values)
+		query = query.Where(condition, args...)
+	}
+
+	// One extra row tells whether there is a next page
+	if err := query.Scopes(scopes...).Limit(params.Limit + 1).Find(dest).Error; err != nil {
+		return Page{}, err
+	}
+
+	page := Page{Mode: ModeCursor, Limit: params.Limit}
+	if len(*dest) > params.Limit {
+		*dest = (*dest)[:params.Limit]
+		cursor, err := encodeCursor(query, params.Sort, (*dest)[params.Limit-1])
+		if err != nil {
+			return Page{}, err
+		}
+		page.HasMore = true
+		page.NextCursor = cursor
+	}
+	return page, nil
+}
+
+// cursor is the decoded form of an opaque cursor: the sort it belongs to and
+// the sort values of the last row of the previous page
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// encodeCursor builds the cursor pointing after row
+func encodeCursor[T any](db *gorm.DB, sort []SortField, row T) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&row); err != nil {
+		return "", err
+	}
+
+	value := reflect.ValueOf(&row).Elem()
+	c := cursor{Sort: sortSignature(sort)}
+	for _, field := range sort {
+		// Sort columns may be qualified with their table
+		column := field.Column[strings.LastIndex(field.Column, ".")+1:]
+		schemaField := stmt.Schema.LookUpField(column)
+		if schemaField == nil {
+			return "", fmt.Errorf("%w: column %s is not a field of %s", ErrInvalidSort, column, stmt.Schema.Name)
+		}
+		fieldValue, _ := schemaField.ValueOf(db.Statement.Context, value)
+		c.Values = append(c.Values, fieldValue)
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the sort values held by a cursor, rejecting cursors
+// issued for a different sort
+func decodeCursor(encoded string, sort []SortField) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sortSignature(sort) || len(c.Values) != len(sort) {
+		return nil, fmt.Errorf("%w: the cursor belongs to a different sort", ErrInvalidCursor)
+	}
+	return c.Values, nil
+}
+
+// keysetCondition selects the rows that come after values in the sort order:
+// (a > x) OR (a = x AND b < y) OR (a = x AND b = y AND id > z)
+func keysetCondition(sort []SortField, values []interface{}) (string, []interface{}) {
+	var alternatives []string
+	var args []interface{}
+	for i, field := range sort {
+		var conditions []string
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, sort[j].Column+" = ?")
+			args = append(args, values[j])
+		}
+		operator := " > ?"
+		if field.Desc {
+			operator = " < ?"
+		}
+		conditions = append(conditions, field.Column+operator)
+		args = append(args, values[i])
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// sortSignature identifies a sort order, e.g. "price,-created_at,id"
+func sortSignature(sort []SortField) string {
+	parts := make([]string, len(sort))
+	for i, field := range sort {
+		parts[i] = field.Column
+		if field.Desc {
+			parts[i] = "-" + field.Column
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,336 @@
+// Package spreadsheet reads and writes the first worksheet of XLSX files.
+// It covers plain tabular data only: no styles, formulas or merged cells.
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidXLSX = errors.New("not a valid XLSX file")
+
+// ReadXLSX returns the cells of the first worksheet. Row i of the result is
+// spreadsheet row i+1, so row numbers can be reported back to users; empty
+// rows are kept as nil.
+func ReadXLSX(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidXLSX
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return nil, ErrInvalidXLSX
+	}
+
+	var sharedStrings []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if sharedStrings, err = readSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return readSheet(sheet, sharedStrings)
+}
+
+// firstSheetPath resolves the part name of the first sheet in the workbook
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(files["xl/workbook.xml"], &workbook); err != nil || len(workbook.Sheets) == 0 {
+		return "", ErrInvalidXLSX
+	}
+	if err := decodePart(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return "", ErrInvalidXLSX
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].ID {
+			continue
+		}
+		// Targets are relative to xl/ unless absolute
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	// Strict OOXML uses other namespaces; the first sheet is conventionally sheet1
+	if _, ok := files["xl/worksheets/sheet1.xml"]; ok {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	return "", ErrInvalidXLSX
+}
+
+// richText is the text of a shared or inline string, optionally split into
+// formatted runs
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	var strs []string
+	err := streamElements(f, "si", func(d *xml.Decoder, start xml.StartElement) error {
+		var item richText
+		if err := d.DecodeElement(&item, &start); err != nil {
+			return err
+		}
+		strs = append(strs, item.String())
+		return nil
+	})
+	return strs, err
+}
+
+func readSheet(f *zip.File, sharedStrings []string) ([][]string, error) {
+	type cell struct {
+		Ref    string    `xml:"r,attr"`
+		Type   string    `xml:"t,attr"`
+		Value  string    `xml:"v"`
+		Inline *richText `xml:"is"`
+	}
+	type row struct {
+		Number int    `xml:"r,attr"`
+		Cells  []cell `xml:"c"`
+	}
+
+	var rows [][]string
+	err := streamElements(f, "row", func(d *xml.Decoder, start xml.StartElement) error {
+		var r row
+		if err := d.DecodeElement(&r, &start); err != nil {
+			return err
+		}
+		if r.Number <= 0 {
+			r.Number = len(rows) + 1
+		}
+		for len(rows) < r.Number {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for _, c := range r.Cells {
+			column := len(values)
+			if c.Ref != "" {
+				if column = columnIndex(c.Ref); column < 0 {
+					return ErrInvalidXLSX
+				}
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+
+			switch c.Type {
+			case "s":
+				i, err := strconv.Atoi(c.Value)
+				if err != nil || i < 0 || i >= len(sharedStrings) {
+					return ErrInvalidXLSX
+				}
+				values[column] = sharedStrings[i]
+			case "inlineStr":
+				if c.Inline != nil {
+					values[column] = c.Inline.String()
+				}
+			case "b":
+				values[column] = strconv.FormatBool(c.Value == "1")
+			default:
+				values[column] = c.Value
+			}
+		}
+		rows[r.Number-1] = values
+		return nil
+	})
+	return rows, err
+}
+
+// columnIndex converts the column letters of a cell reference like "AB12" to
+// a zero-based index
+func columnIndex(ref string) int {
+	index := 0
+	letters := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		index = index*26 + int(ch-'A'+1)
+		letters++
+	}
+	if letters == 0 {
+		return -1
+	}
+	return index - 1
+}
+
+// streamElements calls fn for every element with the given local name, so
+// large sheets are never held in memory as a document tree
+func streamElements(f *zip.File, name string, fn func(*xml.Decoder, xml.StartElement) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return ErrInvalidXLSX
+	}
+	defer rc.Close()
+
+	d := xml.NewDecoder(rc)
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == name {
+			if err := fn(d, start); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func decodePart(f *zip.File, v interface{}) error {
+	if f == nil {
+		return ErrInvalidXLSX
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// XLSXWriter streams rows into a single-sheet XLSX file. Every row is
+// written through to the underlying writer on Flush, so large exports do not
+// build up in memory.
+type XLSXWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+}
+
+// Static parts of a minimal workbook with one sheet
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// NewXLSXWriter starts an XLSX file with one sheet named sheetName
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		if err := writePart(zw, part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheetName))
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` +
+		name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	if err := writePart(zw, "xl/workbook.xml", workbook); err != nil {
+		return nil, err
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+
+	return &XLSXWriter{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Strings are written as text, integers and floats
+// as numbers and booleans as TRUE/FALSE.
+func (w *XLSXWriter) WriteRow(values ...interface{}) error {
+	var b strings.Builder
+	b.WriteString("<row>")
+	for _, value := range values {
+		switch v := value.(type) {
+		case int:
+			fmt.Fprintf(&b, "<c><v>%d</v></c>", v)
+		case uint:
+			fmt.Fprintf(&b, "<c><v>%d</v></c>", v)
+		case float64:
+			fmt.Fprintf(&b, "<c><v>%s</v></c>", strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			flag := 0
+			if v {
+				flag = 1
+			}
+			fmt.Fprintf(&b, `<c t="b"><v>%d</v></c>`, flag)
+		default:
+			b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(&b, []byte(fmt.Sprint(v)))
+			b.WriteString("</t></is></c>")
+		}
+	}
+	b.WriteString("</row>")
+
+	_, err := io.WriteString(w.sheet, b.String())
+	return err
+}
+
+// Flush writes buffered rows to the underlying writer
+func (w *XLSXWriter) Flush() error {
+	return w.zw.Flush()
+}
+
+// Close finishes the sheet and the file
+func (w *XLSXWriter) Close() error {
+	if _, err := io.WriteString(w.sheet, "</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+func writePart(zw *zip.Writer, name, content string) error {
+	part, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(part, content)
+	return err
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// ResizeToFit scales img down so that it fits within maxWidth x maxHeight
+// while keeping its aspect ratio. Images that already fit are returned as is.
+// Each destination pixel is the alpha-weighted average of the source pixels it
+// covers, which keeps downscaled photos sharp without external libraries.
+func ResizeToFit(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxWidth && height <= maxHeight {
+		return img
+	}
+
+	scale := math.Min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+	dstWidth := max(1, int(math.Round(float64(width)*scale)))
+	dstHeight := max(1, int(math.Round(float64(height)*scale)))
+
+	src := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		sy0, sy1 := sourceSpan(y, dstHeight, height)
+		for x := 0; x < dstWidth; x++ {
+			sx0, sx1 := sourceSpan(x, dstWidth, width)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				offset := src.PixOffset(sx0, sy)
+				for sx := sx0; sx < sx1; sx++ {
+					p := src.Pix[offset : offset+4]
+					alpha := uint64(p[3])
+					r += uint64(p[0]) * alpha
+					g += uint64(p[1]) * alpha
+					b += uint64(p[2]) * alpha
+					a += alpha
+					n++
+					offset += 4
+				}
+			}
+
+			d := dst.PixOffset(x, y)
+			if a > 0 {
+				dst.Pix[d] = uint8(r / a)
+				dst.Pix[d+1] = uint8(g / a)
+				dst.Pix[d+2] = uint8(b / a)
+			}
+			dst.Pix[d+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+// sourceSpan returns the source pixel range covered by destination pixel i
+func sourceSpan(i, dstSize, srcSize int) (int, int) {
+	start := i * srcSize / dstSize
+	end := (i + 1) * srcSize / dstSize
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
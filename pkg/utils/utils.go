@@ -66,3 +66,36 @@ func ReadJSONBody(body io.Reader, v interface{}) error {
 	decoder := json.NewDecoder(body)
 	return decoder.Decode(v)
 }
+
+// slugFolder maps accented Latin letters (including Vietnamese) to ASCII
+var slugFolder = strings.NewReplacer(
+	"à", "a", "á", "a", "ạ", "a", "ả", "a", "ã", "a", "â", "a", "ầ", "a", "ấ", "a", "ậ", "a", "ẩ", "a", "ẫ", "a",
+	"ă", "a", "ằ", "a", "ắ", "a", "ặ", "a", "ẳ", "a", "ẵ", "a", "ä", "a", "å", "a",
+	"è", "e", "é", "e", "ẹ", "e", "ẻ", "e", "ẽ", "e", "ê", "e", "ề", "e", "ế", "e", "ệ", "e", "ể", "e", "ễ", "e", "ë", "e",
+	"ì", "i", "í", "i", "ị", "i", "ỉ", "i", "ĩ", "i", "î", "i", "ï", "i",
+	"ò", "o", "ó", "o", "ọ", "o", "ỏ", "o", "õ", "o", "ô", "o", "ồ", "o", "ố", "o", "ộ", "o", "ổ", "o", "ỗ", "o",
+	"ơ", "o", "ờ", "o", "ớ", "o", "ợ", "o", "ở", "o", "ỡ", "o", "ö", "o", "ø", "o",
+	"ù", "u", "ú", "u", "ụ", "u", "ủ", "u", "ũ", "u", "ư", "u", "ừ", "u", "ứ", "u", "ự", "u", "ử", "u", "ữ", "u", "û", "u", "ü", "u",
+	"ỳ", "y", "ý", "y", "ỵ", "y", "ỷ", "y", "ỹ", "y", "ÿ", "y",
+	"đ", "d", "ç", "c", "ñ", "n", "ß", "ss",
+)
+
+// Slugify converts text to a lowercase, hyphen-separated URL slug,
+// e.g. "Điện thoại & Máy tính bảng" -> "dien-thoai-may-tinh-bang"
+func Slugify(text string) string {
+	folded := slugFolder.Replace(strings.ToLower(text))
+
+	var b strings.Builder
+	hyphen := false
+	for _, r := range folded {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			hyphen = false
+		} else if !hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,25 @@
+package utils
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Điện thoại & Máy tính bảng", "dien-thoai-may-tinh-bang"},
+		{"Phụ kiện", "phu-kien"},
+		{"iPhone 15 Pro Max", "iphone-15-pro-max"},
+		{"  Tai nghe -- Bluetooth  ", "tai-nghe-bluetooth"},
+		{"Đồng hồ thông minh!", "dong-ho-thong-minh"},
+		{"Straße", "strasse"},
+		{"", ""},
+		{"---", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Slugify(tt.text); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}